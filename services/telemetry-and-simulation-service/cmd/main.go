@@ -1,44 +1,235 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/m/internal/ext"
+	"github.com/m/internal/geojson"
+	"github.com/m/internal/grpcapi"
+	"github.com/m/internal/osm"
+	"github.com/m/internal/replay"
 	"github.com/m/internal/simulation/entities"
 	simulationengine "github.com/m/internal/simulation/simulation-engine"
+	"github.com/m/internal/telemetry"
+	"github.com/m/internal/websockets"
+	"google.golang.org/grpc"
 )
 
 func main() {
+	// SIGINT or SIGTERM shuts down gracefully, so that recordings are
+	// finished and their manifests get an end time.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	config := simulationengine.NewMapGenerator(2000, 2000, 12345, simulationengine.AlgoDelaunay, 100, 0)
-	graph := config.Generate()
+	config.RoadHierarchy = &simulationengine.RoadHierarchyConfig{RingRoad: true}
+	config.Zoning = &simulationengine.ZoningConfig{CommercialRadius: 0.25, IndustrialShare: 0.2}
+	config.Depots = 3
+	config.ChargingStations = 5
+	config.ParkingLots = 4
+	// OSM_FILE or GEOJSON_FILE simulates on an imported map instead of a
+	// generated one.
+	osmFile, geojsonFile := os.Getenv("OSM_FILE"), os.Getenv("GEOJSON_FILE")
+	var graph *entities.MapGraph
+	switch {
+	case osmFile != "":
+		imported, err := osm.Import(osmFile, nil)
+		if err != nil {
+			log.Fatalf("osm import: %v", err)
+		}
+		simulationengine.PlaceFacilities(imported, config.Depots, config.ChargingStations)
+		graph = imported
+	case geojsonFile != "":
+		// The map brings its own facilities.
+		imported, err := geojson.ImportMap(geojsonFile, nil)
+		if err != nil {
+			log.Fatalf("geojson import: %v", err)
+		}
+		graph = imported
+	default:
+		graph = config.Generate()
+	}
+	// Trucks may not turn around in the street, and routes avoid needless
+	// turns.
+	graph.Turns = simulationengine.DefaultTurnCosts()
 
 	engine := simulationengine.NewSimulationEngine(graph, 100*time.Millisecond)
+	engine.Energy = simulationengine.DefaultEnergyConfig()
+	if err := engine.Energy.Validate(); err != nil {
+		log.Fatalf("energy config: %v", err)
+	}
+	engine.Reliability = simulationengine.DefaultReliabilityConfig()
+	engine.Dispatch = simulationengine.DefaultDispatchConfig()
+	engine.Dispatch.Generator = &simulationengine.OrderGeneratorConfig{
+		Rate:           0.05,
+		Load:           1,
+		PickupWindow:   600,
+		DeliveryWindow: 1800,
+	}
+
+	// Busy junctions get actuated signals, smaller ones give way to their
+	// main road.
+	engine.ControlIntersections(entities.ControlActuated, 5)
+	engine.ControlIntersections(entities.ControlPriority, 3)
 
 	spawnConfig := &simulationengine.VehicleSpawnConfig{
 		SpawnStrategy:  simulationengine.SpawnRandom,
 		TargetStrategy: simulationengine.TargetRandom,
 		AllowSameNode:  false,
 	}
+	// Vehicles head for a new target after a short pause, so traffic keeps
+	// flowing for as long as the simulation runs.
+	engine.OnArrival = &entities.ArrivalBehavior{
+		Mode:    entities.ArrivalNewTarget,
+		Target:  string(spawnConfig.TargetStrategy),
+		IdleMin: 5,
+		IdleMax: 30,
+	}
 
-	for i := 0; i < 10; i++ {
-		vehicle := &entities.Vehicle{
-			ID:    fmt.Sprintf("vehicle-%d", i),
-			Type:  entities.VehicleTypSedan,
-			State: entities.VehicleState{Status: entities.VehicleStatusIdle},
+	// WS_ALLOWED_ORIGINS lists, comma separated, the other origins whose
+	// pages may open the telemetry socket.
+	hubConfig := websockets.DefaultHubConfig()
+	if origins := os.Getenv("WS_ALLOWED_ORIGINS"); origins != "" {
+		for _, o := range strings.Split(origins, ",") {
+			hubConfig.AllowedOrigins = append(hubConfig.AllowedOrigins, strings.TrimSpace(o))
 		}
-		simulationengine.AssignVehicleRoute(vehicle, graph, spawnConfig)
-		engine.AddVehicle(vehicle)
 	}
+	hub := websockets.NewHubWithConfig(hubConfig)
+	sse := ext.NewSSEBroker(4096)
+	grpcServer := grpcapi.NewServer(engine, spawnConfig)
+	pipeline := telemetry.NewPipeline(simulationengine.MultiEmitter{hub, sse, grpcServer.Telemetry}, telemetry.DefaultPipelineConfig())
+	pipeline.Start()
+	defer pipeline.Close()
+	engine.Emitter = pipeline
+
+	api := &ext.SimulationAPI{Engine: engine, Telemetry: pipeline}
 
-	engine.Start()
+	// REPLAY_DIR plays a recorded run back through the same endpoints
+	// instead of simulating.
+	if dir := os.Getenv("REPLAY_DIR"); dir != "" {
+		// The run's map is read from the recording.
+		player, err := replay.Load(dir, nil)
+		if err != nil {
+			log.Fatalf("replay: %v", err)
+		}
+		defer player.Close()
+		player.Emitter = pipeline
+		engine.Graph = player.Graph
+		api.Replay = player
+
+		replayAPI := &ext.ReplayAPI{Player: player}
+		http.HandleFunc("/api/replay/status", replayAPI.Status)
+		http.HandleFunc("/api/replay/play", replayAPI.Play)
+		http.HandleFunc("/api/replay/pause", replayAPI.Pause)
+		http.HandleFunc("/api/replay/step", replayAPI.Step)
+		http.HandleFunc("/api/replay/seek", replayAPI.Seek)
+	} else {
+		// The recorder sees every event, before sampling.
+		if dir := os.Getenv("TELEMETRY_RECORD_DIR"); dir != "" {
+			manifest, err := telemetry.NewManifest(engine.RunID, config.Seed, config, map[string]interface{}{
+				"update_rate":        engine.UpdateRate.String(),
+				"telemetry_interval": engine.TelemetryInterval.String(),
+				"spawn":              spawnConfig,
+				"energy":             engine.Energy,
+				"reliability":        engine.Reliability,
+				"dispatch":           engine.Dispatch,
+				"on_arrival":         engine.OnArrival,
+				"intersections":      engine.ListIntersections(),
+				"turns":              graph.Turns,
+				"osm_file":           osmFile,
+				"geojson_file":       geojsonFile,
+			})
+			if err != nil {
+				log.Fatalf("telemetry manifest: %v", err)
+			}
+			recorder, err := telemetry.NewRecorder(telemetry.RecorderConfig{
+				Dir:           dir,
+				Compression:   telemetry.Compression(os.Getenv("TELEMETRY_RECORD_COMPRESSION")),
+				MaxFileBytes:  256 << 20,
+				MaxFileAge:    time.Hour,
+				FlushInterval: 5 * time.Second,
+			}, manifest)
+			if err != nil {
+				log.Fatalf("telemetry recorder: %v", err)
+			}
+			defer recorder.Close()
+			if err := recorder.WriteGraph(graph); err != nil {
+				log.Fatalf("telemetry recorder: %v", err)
+			}
+			engine.Emitter = simulationengine.MultiEmitter{pipeline, recorder}
+		}
+
+		if _, err := engine.CreateFleet(entities.Fleet{
+			ID:     "default",
+			Name:   "Default fleet",
+			Config: entities.FleetConfig{DefaultVehicleType: entities.VehicleTypSedan, EnergyThresholdAlert: 0.2},
+		}); err != nil {
+			log.Fatalf("create fleet: %v", err)
+		}
+
+		for i := 0; i < 10; i++ {
+			vehicle := &entities.Vehicle{
+				ID:    fmt.Sprintf("vehicle-%d", i),
+				State: entities.VehicleState{Status: entities.VehicleStatusIdle},
+			}
+			simulationengine.AssignVehicleRoute(vehicle, graph, spawnConfig)
+			if err := engine.AddVehicleToFleet(vehicle, "default", "bootstrap", 0); err != nil {
+				log.Fatalf("add vehicle: %v", err)
+			}
+		}
+
+		engine.Start()
+	}
 
-	api := &ext.SimulationAPI{Engine: engine}
 	http.HandleFunc("/api/simulation/start", api.StartSimulation)
 	http.HandleFunc("/api/simulation/stop", api.StopSimulation)
 	http.HandleFunc("/api/simulation/vehicles", api.GetVehicles)
+	http.HandleFunc("/api/simulation/stream", sse.ServeSSE)
+	http.HandleFunc("/api/telemetry/stats", api.GetTelemetryStats)
+	http.HandleFunc("/ws", hub.ServeWS)
+	(&ext.FleetAPI{Engine: engine}).Register(http.DefaultServeMux)
+	(&ext.FacilityAPI{Engine: engine}).Register(http.DefaultServeMux)
+	(&ext.OrderAPI{Engine: engine}).Register(http.DefaultServeMux)
+	(&ext.TourAPI{Engine: engine}).Register(http.DefaultServeMux)
+	(&ext.TransitAPI{Engine: engine}).Register(http.DefaultServeMux)
+	(&ext.IntersectionAPI{Engine: engine}).Register(http.DefaultServeMux)
+	(&ext.GeoJSONAPI{Engine: engine, Replay: api.Replay}).Register(http.DefaultServeMux)
+
+	lis, err := net.Listen("tcp", ":9090")
+	if err != nil {
+		log.Fatalf("grpc listen: %v", err)
+	}
+	srv := grpc.NewServer()
+	grpcServer.Register(srv)
+	go srv.Serve(lis)
+
+	// Requests share ctx, so open SSE streams end on shutdown too.
+	server := &http.Server{Addr: ":8081", BaseContext: func(net.Listener) context.Context { return ctx }}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("http: %v", err)
+			stop()
+		}
+	}()
 
-	http.ListenAndServe(":8081", nil)
+	<-ctx.Done()
+	log.Println("shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	server.Shutdown(shutdownCtx)
+	hub.Close()
+	srv.Stop()
+	// Stop the vehicles before the deferred closes of the recorder and the
+	// pipeline, so nothing is emitted into them afterwards.
+	engine.Stop()
 }
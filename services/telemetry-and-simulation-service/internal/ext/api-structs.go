@@ -4,32 +4,55 @@ import (
 	"encoding/json"
 	"net/http"
 
-	"github.com/m/internal/simulation/entities"
+	"github.com/m/internal/replay"
 	simulationengine "github.com/m/internal/simulation/simulation-engine"
+	"github.com/m/internal/telemetry"
 )
 
+// SimulationAPI serves the live engine, or the replayed run when Replay is
+// set so the frontend works the same in both modes.
 type SimulationAPI struct {
-	Engine *simulationengine.SimulationEngine
+	Engine    *simulationengine.SimulationEngine
+	Telemetry *telemetry.Pipeline
+	Replay    *replay.Player
 }
 
 func (api *SimulationAPI) StartSimulation(w http.ResponseWriter, r *http.Request) {
+	if api.Replay != nil {
+		if err := api.Replay.Play(1); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"status": "started"})
+		return
+	}
 	api.Engine.Start()
 	json.NewEncoder(w).Encode(map[string]string{"status": "started"})
 }
 
 func (api *SimulationAPI) StopSimulation(w http.ResponseWriter, r *http.Request) {
+	if api.Replay != nil {
+		api.Replay.Pause()
+		json.NewEncoder(w).Encode(map[string]string{"status": "stopped"})
+		return
+	}
 	api.Engine.Stop()
 	json.NewEncoder(w).Encode(map[string]string{"status": "stopped"})
 }
 
 func (api *SimulationAPI) GetVehicles(w http.ResponseWriter, r *http.Request) {
-	api.Engine.Mutex.RLock()
-	defer api.Engine.Mutex.RUnlock()
-
-	vehicles := make([]*entities.Vehicle, 0, len(api.Engine.Vehicles))
-	for _, v := range api.Engine.Vehicles {
-		vehicles = append(vehicles, v)
+	if api.Replay != nil {
+		json.NewEncoder(w).Encode(api.Replay.Vehicles())
+		return
 	}
 
-	json.NewEncoder(w).Encode(vehicles)
+	json.NewEncoder(w).Encode(api.Engine.ListActiveVehicles())
+}
+
+func (api *SimulationAPI) GetTelemetryStats(w http.ResponseWriter, r *http.Request) {
+	if api.Telemetry == nil {
+		http.Error(w, "telemetry pipeline not configured", http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(api.Telemetry.Stats())
 }
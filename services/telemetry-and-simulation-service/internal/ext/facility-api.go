@@ -0,0 +1,37 @@
+package ext
+
+import (
+	"encoding/json"
+	"net/http"
+
+	simulationengine "github.com/m/internal/simulation/simulation-engine"
+)
+
+type FacilityAPI struct {
+	Engine *simulationengine.SimulationEngine
+}
+
+// Register mounts the charger and parking statistics under /api/facilities.
+func (api *FacilityAPI) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/facilities", api.ListFacilities)
+	mux.HandleFunc("GET /api/facilities/{nodeID}", api.GetFacility)
+}
+
+func (api *FacilityAPI) ListFacilities(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(api.Engine.FacilityStats())
+}
+
+func (api *FacilityAPI) GetFacility(w http.ResponseWriter, r *http.Request) {
+	nodeID := r.PathValue("nodeID")
+	stats := []simulationengine.FacilityStats{}
+	for _, st := range api.Engine.FacilityStats() {
+		if st.NodeID == nodeID {
+			stats = append(stats, st)
+		}
+	}
+	if len(stats) == 0 {
+		http.Error(w, "facility not found", http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(stats)
+}
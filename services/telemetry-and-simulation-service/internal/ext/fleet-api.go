@@ -0,0 +1,194 @@
+package ext
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/m/internal/simulation/entities"
+	simulationengine "github.com/m/internal/simulation/simulation-engine"
+)
+
+type FleetAPI struct {
+	Engine *simulationengine.SimulationEngine
+}
+
+// MetricsResponse is the body of GET /api/metrics.
+type MetricsResponse struct {
+	Global entities.FleetMetrics            `json:"global"`
+	Fleets map[string]entities.FleetMetrics `json:"fleets"`
+}
+
+type AssignVehicleRequest struct {
+	VehicleID  string `json:"vehicle_id"`
+	AssignedBy string `json:"assigned_by"`
+	Priority   int    `json:"priority"`
+}
+
+// Register mounts the fleet endpoints under /api/fleets.
+func (api *FleetAPI) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/fleets", api.ListFleets)
+	mux.HandleFunc("POST /api/fleets", api.CreateFleet)
+	mux.HandleFunc("GET /api/fleets/{id}", api.GetFleet)
+	mux.HandleFunc("PATCH /api/fleets/{id}", api.UpdateFleet)
+	mux.HandleFunc("DELETE /api/fleets/{id}", api.DeleteFleet)
+	mux.HandleFunc("GET /api/fleets/{id}/vehicles", api.ListFleetVehicles)
+	mux.HandleFunc("POST /api/fleets/{id}/vehicles", api.AssignVehicle)
+	mux.HandleFunc("DELETE /api/fleets/{id}/vehicles/{vehicleID}", api.UnassignVehicle)
+	mux.HandleFunc("GET /api/fleets/{id}/assignments", api.ListAssignments)
+	mux.HandleFunc("GET /api/fleets/{id}/metrics", api.GetFleetMetrics)
+	mux.HandleFunc("GET /api/metrics", api.GetMetrics)
+}
+
+func (api *FleetAPI) ListFleets(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(api.Engine.ListFleets())
+}
+
+func (api *FleetAPI) CreateFleet(w http.ResponseWriter, r *http.Request) {
+	var fleet entities.Fleet
+	if err := json.NewDecoder(r.Body).Decode(&fleet); err != nil {
+		http.Error(w, "invalid fleet: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	created, err := api.Engine.CreateFleet(fleet)
+	if err != nil {
+		writeFleetError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func (api *FleetAPI) GetFleet(w http.ResponseWriter, r *http.Request) {
+	fleet, ok := api.Engine.GetFleet(r.PathValue("id"))
+	if !ok {
+		http.Error(w, "fleet not found", http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(fleet)
+}
+
+func (api *FleetAPI) UpdateFleet(w http.ResponseWriter, r *http.Request) {
+	var update simulationengine.FleetUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		http.Error(w, "invalid update: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	fleet, err := api.Engine.UpdateFleet(r.PathValue("id"), update)
+	if err != nil {
+		writeFleetError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(fleet)
+}
+
+func (api *FleetAPI) DeleteFleet(w http.ResponseWriter, r *http.Request) {
+	if err := api.Engine.DeleteFleet(r.PathValue("id")); err != nil {
+		writeFleetError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (api *FleetAPI) ListFleetVehicles(w http.ResponseWriter, r *http.Request) {
+	fleet, ok := api.Engine.GetFleet(r.PathValue("id"))
+	if !ok {
+		http.Error(w, "fleet not found", http.StatusNotFound)
+		return
+	}
+
+	// Vehicles keep moving while the response is written, so each one is
+	// serialized under its own lock and the snapshots are encoded after.
+	vehicles := make([]json.RawMessage, 0, len(fleet.VehicleIDs))
+	for _, id := range fleet.VehicleIDs {
+		v, ok := api.Engine.GetVehicle(id)
+		if !ok {
+			continue
+		}
+		v.Mutex.Lock()
+		data, err := json.Marshal(v)
+		v.Mutex.Unlock()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		vehicles = append(vehicles, data)
+	}
+	json.NewEncoder(w).Encode(vehicles)
+}
+
+func (api *FleetAPI) AssignVehicle(w http.ResponseWriter, r *http.Request) {
+	var req AssignVehicleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid assignment: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.AssignedBy == "" {
+		req.AssignedBy = "api"
+	}
+
+	assignment, err := api.Engine.AssignVehicle(r.PathValue("id"), req.VehicleID, req.AssignedBy, req.Priority)
+	if err != nil {
+		writeFleetError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(assignment)
+}
+
+func (api *FleetAPI) UnassignVehicle(w http.ResponseWriter, r *http.Request) {
+	vehicleID := r.PathValue("vehicleID")
+	if a, ok := api.Engine.GetAssignment(vehicleID); !ok || a.FleetID != r.PathValue("id") {
+		http.Error(w, "vehicle is not assigned to this fleet", http.StatusNotFound)
+		return
+	}
+
+	if err := api.Engine.UnassignVehicle(vehicleID); err != nil {
+		writeFleetError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (api *FleetAPI) ListAssignments(w http.ResponseWriter, r *http.Request) {
+	assignments, err := api.Engine.FleetAssignments(r.PathValue("id"))
+	if err != nil {
+		writeFleetError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(assignments)
+}
+
+func (api *FleetAPI) GetFleetMetrics(w http.ResponseWriter, r *http.Request) {
+	metrics, err := api.Engine.FleetMetrics(r.PathValue("id"))
+	if err != nil {
+		writeFleetError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(metrics)
+}
+
+func (api *FleetAPI) GetMetrics(w http.ResponseWriter, r *http.Request) {
+	resp := MetricsResponse{
+		Global: api.Engine.GlobalMetrics(),
+		Fleets: make(map[string]entities.FleetMetrics),
+	}
+	for _, fleet := range api.Engine.ListFleets() {
+		resp.Fleets[fleet.ID] = fleet.Metrics
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func writeFleetError(w http.ResponseWriter, err error) {
+	code := http.StatusBadRequest
+	switch {
+	case errors.Is(err, simulationengine.ErrFleetNotFound), errors.Is(err, simulationengine.ErrVehicleNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, simulationengine.ErrFleetExists), errors.Is(err, simulationengine.ErrVehicleExists),
+		errors.Is(err, simulationengine.ErrFleetFull),
+		errors.Is(err, simulationengine.ErrAssignmentConflict):
+		code = http.StatusConflict
+	}
+	http.Error(w, err.Error(), code)
+}
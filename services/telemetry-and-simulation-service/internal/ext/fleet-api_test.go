@@ -0,0 +1,103 @@
+package ext
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/m/internal/simulation/entities"
+	simulationengine "github.com/m/internal/simulation/simulation-engine"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFleetAPI(t *testing.T) {
+	engine := simulationengine.NewSimulationEngine(&entities.MapGraph{}, time.Hour)
+	engine.AddVehicle(&entities.Vehicle{ID: "v1"})
+
+	mux := http.NewServeMux()
+	(&FleetAPI{Engine: engine}).Register(mux)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+
+	rec := do("POST", "/api/fleets", `{"id":"f1","name":"Fleet 1","config":{"max_vehicles":1,"default_vehicle_type":"truck"}}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	assert.Equal(t, http.StatusConflict, do("POST", "/api/fleets", `{"id":"f1"}`).Code)
+
+	rec = do("POST", "/api/fleets/f1/vehicles", `{"vehicle_id":"v1","priority":2}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var assignment entities.FleetAssignment
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&assignment))
+	assert.Equal(t, "api", assignment.AssignedBy)
+	assert.Equal(t, 2, assignment.Priority)
+
+	assert.Equal(t, http.StatusNotFound, do("POST", "/api/fleets/f1/vehicles", `{"vehicle_id":"nope"}`).Code)
+
+	rec = do("PATCH", "/api/fleets/f1", `{"status":"paused"}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var fleet entities.Fleet
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&fleet))
+	assert.Equal(t, entities.FleetStatusPaused, fleet.Status)
+	assert.Equal(t, []string{"v1"}, fleet.VehicleIDs)
+
+	rec = do("GET", "/api/fleets/f1/vehicles", "")
+	var vehicles []entities.Vehicle
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&vehicles))
+	require.Len(t, vehicles, 1)
+	assert.Equal(t, entities.VehicleTypeTruck, vehicles[0].Type)
+
+	rec = do("GET", "/api/fleets/f1/metrics", "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var metrics entities.FleetMetrics
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&metrics))
+	assert.Equal(t, 1, metrics.TotalVehicles)
+	assert.Equal(t, http.StatusNotFound, do("GET", "/api/fleets/nope/metrics", "").Code)
+
+	rec = do("GET", "/api/metrics", "")
+	var all MetricsResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&all))
+	assert.Equal(t, 1, all.Global.TotalVehicles)
+	assert.Contains(t, all.Fleets, "f1")
+
+	assert.Equal(t, http.StatusNoContent, do("DELETE", "/api/fleets/f1/vehicles/v1", "").Code)
+	assert.Equal(t, http.StatusNotFound, do("DELETE", "/api/fleets/f1/vehicles/v1", "").Code)
+	assert.Equal(t, http.StatusNoContent, do("DELETE", "/api/fleets/f1", "").Code)
+	assert.Equal(t, http.StatusNotFound, do("GET", "/api/fleets/f1", "").Code)
+}
+
+func TestFleetAPI_ListsMovingVehicles(t *testing.T) {
+	cfg := simulationengine.NewMapGenerator(300, 300, 3, simulationengine.AlgoGrid, 0, 0)
+	cfg.Grid = &simulationengine.GridConfig{BlockSize: 100}
+	engine := simulationengine.NewSimulationEngine(cfg.Generate(), time.Millisecond)
+	_, err := engine.CreateFleet(entities.Fleet{ID: "f1"})
+	require.NoError(t, err)
+
+	vehicle := &entities.Vehicle{ID: "v1"}
+	require.NoError(t, simulationengine.AssignVehicleRouteWithNodes(vehicle, engine.Graph, "grid-0-0", "grid-3-3"))
+	require.NoError(t, engine.AddVehicleToFleet(vehicle, "f1", "test", 0))
+
+	mux := http.NewServeMux()
+	(&FleetAPI{Engine: engine}).Register(mux)
+
+	engine.Start()
+	defer engine.Stop()
+
+	for i := 0; i < 20; i++ {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/fleets/f1/vehicles", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var vehicles []entities.Vehicle
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&vehicles))
+		require.Len(t, vehicles, 1)
+		assert.Equal(t, "v1", vehicles[0].ID)
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,126 @@
+package ext
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/m/internal/geojson"
+	"github.com/m/internal/replay"
+	"github.com/m/internal/simulation/entities"
+	simulationengine "github.com/m/internal/simulation/simulation-engine"
+)
+
+// GeoJSONAPI serves the map, routes and vehicles as GeoJSON for GIS tools,
+// from the replayed run when Replay is set. The origin_lat and origin_lon
+// query parameters, with an optional meters_per_unit, georeference the
+// output in place of the map's own Georef.
+type GeoJSONAPI struct {
+	Engine *simulationengine.SimulationEngine
+	Replay *replay.Player
+}
+
+// Register mounts the GeoJSON exports under /api/geojson.
+func (api *GeoJSONAPI) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/geojson/map", api.GetMap)
+	mux.HandleFunc("GET /api/geojson/vehicles", api.GetVehicles)
+	mux.HandleFunc("GET /api/geojson/routes", api.GetVehicleRoutes)
+	mux.HandleFunc("GET /api/geojson/route", api.GetRoute)
+}
+
+func (api *GeoJSONAPI) graph() *entities.MapGraph {
+	if api.Replay != nil {
+		return api.Replay.Graph
+	}
+	return api.Engine.Graph
+}
+
+func (api *GeoJSONAPI) vehicles() []*entities.Vehicle {
+	if api.Replay != nil {
+		return api.Replay.Vehicles()
+	}
+	return api.Engine.ListActiveVehicles()
+}
+
+// georef reads the query's georeference; nil leaves it to the map.
+func georef(r *http.Request) (*entities.Georef, bool) {
+	q := r.URL.Query()
+	if q.Get("origin_lat") == "" && q.Get("origin_lon") == "" {
+		return nil, true
+	}
+	var ref entities.Georef
+	var err error
+	if ref.OriginLat, err = strconv.ParseFloat(q.Get("origin_lat"), 64); err != nil || ref.OriginLat < -90 || ref.OriginLat > 90 {
+		return nil, false
+	}
+	if ref.OriginLon, err = strconv.ParseFloat(q.Get("origin_lon"), 64); err != nil || ref.OriginLon < -180 || ref.OriginLon > 180 {
+		return nil, false
+	}
+	if s := q.Get("meters_per_unit"); s != "" {
+		if ref.MetersPerUnit, err = strconv.ParseFloat(s, 64); err != nil || ref.MetersPerUnit <= 0 {
+			return nil, false
+		}
+	}
+	return &ref, true
+}
+
+func writeGeoJSON(w http.ResponseWriter, fc *geojson.FeatureCollection, err error) {
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", geojson.ContentType)
+	json.NewEncoder(w).Encode(fc)
+}
+
+func (api *GeoJSONAPI) GetMap(w http.ResponseWriter, r *http.Request) {
+	ref, ok := georef(r)
+	if !ok {
+		http.Error(w, "invalid georeference", http.StatusBadRequest)
+		return
+	}
+	fc, err := geojson.Map(api.graph(), ref)
+	writeGeoJSON(w, fc, err)
+}
+
+func (api *GeoJSONAPI) GetVehicles(w http.ResponseWriter, r *http.Request) {
+	ref, ok := georef(r)
+	if !ok {
+		http.Error(w, "invalid georeference", http.StatusBadRequest)
+		return
+	}
+	if ref == nil {
+		ref = api.graph().Georef
+	}
+	fc, err := geojson.Vehicles(api.vehicles(), ref)
+	writeGeoJSON(w, fc, err)
+}
+
+func (api *GeoJSONAPI) GetVehicleRoutes(w http.ResponseWriter, r *http.Request) {
+	ref, ok := georef(r)
+	if !ok {
+		http.Error(w, "invalid georeference", http.StatusBadRequest)
+		return
+	}
+	fc, err := geojson.VehicleRoutes(api.graph(), api.vehicles(), ref)
+	writeGeoJSON(w, fc, err)
+}
+
+// GetRoute plans the routes between the from and to nodes.
+func (api *GeoJSONAPI) GetRoute(w http.ResponseWriter, r *http.Request) {
+	ref, ok := georef(r)
+	if !ok {
+		http.Error(w, "invalid georeference", http.StatusBadRequest)
+		return
+	}
+	g := api.graph()
+	from, to := r.URL.Query().Get("from"), r.URL.Query().Get("to")
+	for _, node := range []string{from, to} {
+		if _, ok := g.Nodes[node]; !ok {
+			http.Error(w, "node not found: "+node, http.StatusNotFound)
+			return
+		}
+	}
+	fc, err := geojson.Routes(g, simulationengine.Dijkstra(g, from, to), ref)
+	writeGeoJSON(w, fc, err)
+}
@@ -0,0 +1,59 @@
+package ext
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/m/internal/geojson"
+	"github.com/m/internal/simulation/entities"
+	simulationengine "github.com/m/internal/simulation/simulation-engine"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeoJSONAPI(t *testing.T) {
+	cfg := simulationengine.NewMapGenerator(300, 300, 3, simulationengine.AlgoGrid, 0, 0)
+	cfg.Grid = &simulationengine.GridConfig{BlockSize: 100}
+	engine := simulationengine.NewSimulationEngine(cfg.Generate(), time.Hour)
+	engine.AddVehicle(&entities.Vehicle{ID: "v1", State: entities.VehicleState{CurrentPosition: entities.Vector2D{X: 100, Y: 100}}})
+
+	mux := http.NewServeMux()
+	(&GeoJSONAPI{Engine: engine}).Register(mux)
+
+	get := func(path string) (*httptest.ResponseRecorder, geojson.FeatureCollection) {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		var fc geojson.FeatureCollection
+		if rec.Code == http.StatusOK {
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&fc))
+		}
+		return rec, fc
+	}
+
+	rec, fc := get("/api/geojson/map?origin_lat=52.5&origin_lon=13.4")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, geojson.ContentType, rec.Header().Get("Content-Type"))
+	assert.Len(t, fc.Features, len(engine.Graph.Nodes)+len(engine.Graph.Edges))
+	assert.Equal(t, &entities.Georef{OriginLat: 52.5, OriginLon: 13.4}, fc.Georef)
+
+	rec, fc = get("/api/geojson/vehicles")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Len(t, fc.Features, 1)
+	assert.Equal(t, "v1", fc.Features[0].ID)
+
+	rec, fc = get("/api/geojson/route?from=grid-0-0&to=grid-3-3")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Len(t, fc.Features, 1)
+	var line [][2]float64
+	require.NoError(t, json.Unmarshal(fc.Features[0].Geometry.Coordinates, &line))
+	assert.Equal(t, [2]float64{0, 0}, line[0])
+	assert.Equal(t, [2]float64{300, 300}, line[len(line)-1])
+
+	rec, _ = get("/api/geojson/route?from=grid-0-0&to=nope")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec, _ = get("/api/geojson/map?origin_lat=91&origin_lon=0")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,68 @@
+package ext
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/m/internal/simulation/entities"
+	simulationengine "github.com/m/internal/simulation/simulation-engine"
+)
+
+type IntersectionAPI struct {
+	Engine *simulationengine.SimulationEngine
+}
+
+// Register mounts the intersection controllers under /api/intersections,
+// keyed by node ID.
+func (api *IntersectionAPI) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/intersections", api.ListIntersections)
+	mux.HandleFunc("GET /api/intersections/{node}", api.GetIntersection)
+	mux.HandleFunc("PUT /api/intersections/{node}", api.SetControl)
+	mux.HandleFunc("DELETE /api/intersections/{node}", api.RemoveControl)
+}
+
+func (api *IntersectionAPI) ListIntersections(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(api.Engine.ListIntersections())
+}
+
+func (api *IntersectionAPI) GetIntersection(w http.ResponseWriter, r *http.Request) {
+	st, ok := api.Engine.GetIntersection(r.PathValue("node"))
+	if !ok {
+		http.Error(w, "intersection control not found", http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(st)
+}
+
+func (api *IntersectionAPI) SetControl(w http.ResponseWriter, r *http.Request) {
+	var control entities.IntersectionControl
+	if err := json.NewDecoder(r.Body).Decode(&control); err != nil {
+		http.Error(w, "invalid control: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	control.NodeID = r.PathValue("node")
+
+	st, err := api.Engine.SetIntersectionControl(control)
+	if err != nil {
+		writeIntersectionError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(st)
+}
+
+func (api *IntersectionAPI) RemoveControl(w http.ResponseWriter, r *http.Request) {
+	if err := api.Engine.RemoveIntersectionControl(r.PathValue("node")); err != nil {
+		writeIntersectionError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeIntersectionError(w http.ResponseWriter, err error) {
+	code := http.StatusBadRequest
+	if errors.Is(err, simulationengine.ErrIntersectionNotFound) {
+		code = http.StatusNotFound
+	}
+	http.Error(w, err.Error(), code)
+}
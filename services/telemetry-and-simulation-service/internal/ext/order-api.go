@@ -0,0 +1,78 @@
+package ext
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/m/internal/simulation/entities"
+	simulationengine "github.com/m/internal/simulation/simulation-engine"
+)
+
+type OrderAPI struct {
+	Engine *simulationengine.SimulationEngine
+}
+
+// Register mounts the order endpoints under /api/orders. Time windows in
+// POST bodies are seconds from submission.
+func (api *OrderAPI) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/orders", api.ListOrders)
+	mux.HandleFunc("POST /api/orders", api.SubmitOrder)
+	mux.HandleFunc("GET /api/orders/stats", api.GetOrderStats)
+	mux.HandleFunc("GET /api/orders/{id}", api.GetOrder)
+	mux.HandleFunc("DELETE /api/orders/{id}", api.CancelOrder)
+}
+
+func (api *OrderAPI) ListOrders(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(api.Engine.ListOrders())
+}
+
+func (api *OrderAPI) SubmitOrder(w http.ResponseWriter, r *http.Request) {
+	var order entities.Order
+	if err := json.NewDecoder(r.Body).Decode(&order); err != nil {
+		http.Error(w, "invalid order: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	created, err := api.Engine.SubmitOrder(order)
+	if err != nil {
+		writeOrderError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func (api *OrderAPI) GetOrderStats(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(api.Engine.OrderStats())
+}
+
+func (api *OrderAPI) GetOrder(w http.ResponseWriter, r *http.Request) {
+	order, ok := api.Engine.GetOrder(r.PathValue("id"))
+	if !ok {
+		http.Error(w, "order not found", http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(order)
+}
+
+func (api *OrderAPI) CancelOrder(w http.ResponseWriter, r *http.Request) {
+	if err := api.Engine.CancelOrder(r.PathValue("id")); err != nil {
+		writeOrderError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeOrderError(w http.ResponseWriter, err error) {
+	code := http.StatusBadRequest
+	switch {
+	case errors.Is(err, simulationengine.ErrOrderNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, simulationengine.ErrOrderInProgress):
+		code = http.StatusConflict
+	case errors.Is(err, simulationengine.ErrNoDispatch):
+		code = http.StatusServiceUnavailable
+	}
+	http.Error(w, err.Error(), code)
+}
@@ -0,0 +1,51 @@
+package ext
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/m/internal/simulation/entities"
+	simulationengine "github.com/m/internal/simulation/simulation-engine"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderAPI(t *testing.T) {
+	graph := &entities.MapGraph{Nodes: map[string]*entities.MapNode{"A": {ID: "A"}, "B": {ID: "B"}}}
+	engine := simulationengine.NewSimulationEngine(graph, time.Hour)
+
+	mux := http.NewServeMux()
+	(&OrderAPI{Engine: engine}).Register(mux)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+
+	assert.Equal(t, http.StatusServiceUnavailable, do("POST", "/api/orders", `{"pickup_node":"A","dropoff_node":"B"}`).Code)
+	engine.Dispatch = simulationengine.DefaultDispatchConfig()
+
+	rec := do("POST", "/api/orders", `{"id":"o1","pickup_node":"A","dropoff_node":"B","load":2,"pickup_window":{"latest":600}}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var order entities.Order
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&order))
+	assert.Equal(t, entities.OrderPending, order.Status)
+	assert.Equal(t, 600.0, order.PickupWindow.Latest)
+
+	assert.Equal(t, http.StatusBadRequest, do("POST", "/api/orders", `{"pickup_node":"A","dropoff_node":"X"}`).Code)
+	assert.Equal(t, http.StatusOK, do("GET", "/api/orders/o1", "").Code)
+	assert.Equal(t, http.StatusNotFound, do("GET", "/api/orders/nope", "").Code)
+
+	assert.Equal(t, http.StatusNoContent, do("DELETE", "/api/orders/o1", "").Code)
+	assert.Equal(t, http.StatusBadRequest, do("DELETE", "/api/orders/o1", "").Code)
+
+	rec = do("GET", "/api/orders/stats", "")
+	var stats simulationengine.OrderStats
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&stats))
+	assert.Equal(t, 1, stats.Cancelled)
+}
@@ -0,0 +1,98 @@
+package ext
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/m/internal/replay"
+)
+
+// ReplayAPI controls a replay.Player. Play takes an optional "speed" and
+// "tick" rate; Seek takes either an RFC 3339 "t" or an "offset" duration
+// from the start of the run.
+type ReplayAPI struct {
+	Player *replay.Player
+}
+
+func (api *ReplayAPI) Status(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(api.Player.Status())
+}
+
+func (api *ReplayAPI) Play(w http.ResponseWriter, r *http.Request) {
+	speed := 1.0
+	if s := r.URL.Query().Get("speed"); s != "" {
+		var err error
+		if speed, err = strconv.ParseFloat(s, 64); err != nil {
+			http.Error(w, "invalid speed", http.StatusBadRequest)
+			return
+		}
+	}
+	if s := r.URL.Query().Get("tick"); s != "" {
+		tick, err := time.ParseDuration(s)
+		if err != nil {
+			http.Error(w, "invalid tick", http.StatusBadRequest)
+			return
+		}
+		if err := api.Player.SetTickRate(tick); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if err := api.Player.Play(speed); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	json.NewEncoder(w).Encode(api.Player.Status())
+}
+
+func (api *ReplayAPI) Pause(w http.ResponseWriter, r *http.Request) {
+	api.Player.Pause()
+	json.NewEncoder(w).Encode(api.Player.Status())
+}
+
+func (api *ReplayAPI) Step(w http.ResponseWriter, r *http.Request) {
+	var dt time.Duration
+	if s := r.URL.Query().Get("dt"); s != "" {
+		var err error
+		if dt, err = time.ParseDuration(s); err != nil {
+			http.Error(w, "invalid dt", http.StatusBadRequest)
+			return
+		}
+	}
+	if err := api.Player.Step(dt); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	json.NewEncoder(w).Encode(api.Player.Status())
+}
+
+func (api *ReplayAPI) Seek(w http.ResponseWriter, r *http.Request) {
+	var target time.Time
+	query := r.URL.Query()
+
+	switch {
+	case query.Get("t") != "":
+		t, err := time.Parse(time.RFC3339Nano, query.Get("t"))
+		if err != nil {
+			http.Error(w, "invalid t", http.StatusBadRequest)
+			return
+		}
+		target = t
+	case query.Get("offset") != "":
+		offset, err := time.ParseDuration(query.Get("offset"))
+		if err != nil {
+			http.Error(w, "invalid offset", http.StatusBadRequest)
+			return
+		}
+		start, _ := api.Player.Bounds()
+		target = start.Add(offset)
+	default:
+		http.Error(w, "t or offset required", http.StatusBadRequest)
+		return
+	}
+
+	api.Player.Seek(target)
+	json.NewEncoder(w).Encode(api.Player.Status())
+}
@@ -0,0 +1,282 @@
+package ext
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/m/internal/simulation/entities"
+	"github.com/m/internal/telemetry"
+)
+
+const (
+	SSEPositionEvent = "position"
+	// SSEResetEvent tells a client its resume point is gone: either the
+	// entries after it were evicted from the replay buffer ("gap") or the ID
+	// was never issued by this process, e.g. after a restart ("unknown_id").
+	// The client should resync its state from the REST API.
+	SSEResetEvent = "reset"
+)
+
+type sseReset struct {
+	Reason string `json:"reason"`
+	Missed uint64 `json:"missed,omitempty"`
+}
+
+type sseEntry struct {
+	ID        uint64
+	Event     string
+	VehicleID string
+	FleetID   string
+	Data      []byte
+	position  *entities.BasicVehiclePosEvent
+	event     *entities.VehicleEvent
+}
+
+// SSEBroker implements entities.TelemetryEmitter and serves the emitted
+// telemetry as text/event-stream. Every entry gets a monotonically increasing
+// ID and the most recent replaySize entries are kept so reconnecting clients can
+// resume from Last-Event-ID.
+type SSEBroker struct {
+	KeepAliveInterval time.Duration
+
+	mu      sync.Mutex
+	nextID  uint64
+	replay  []sseEntry
+	head    int
+	count   int
+	changed chan struct{}
+}
+
+func NewSSEBroker(replaySize int) *SSEBroker {
+	if replaySize <= 0 {
+		replaySize = 1
+	}
+	return &SSEBroker{
+		KeepAliveInterval: 15 * time.Second,
+		replay:            make([]sseEntry, replaySize),
+		changed:           make(chan struct{}),
+	}
+}
+
+func (b *SSEBroker) EmitPosition(event entities.BasicVehiclePosEvent) error {
+	data, err := telemetry.JSONSerializer{}.MarshalPosition(event)
+	if err != nil {
+		return err
+	}
+	return b.publish(sseEntry{
+		Event:     SSEPositionEvent,
+		VehicleID: event.VehicleID,
+		FleetID:   event.FleetID,
+		Data:      data,
+		position:  &event,
+	})
+}
+
+func (b *SSEBroker) EmitEvent(event entities.VehicleEvent) error {
+	data, err := telemetry.JSONSerializer{}.MarshalEvent(event)
+	if err != nil {
+		return err
+	}
+	return b.publish(sseEntry{
+		Event:     string(event.EventType),
+		VehicleID: event.VehicleID,
+		FleetID:   event.FleetID,
+		Data:      data,
+		event:     &event,
+	})
+}
+
+func (b *SSEBroker) publish(entry sseEntry) error {
+	b.mu.Lock()
+	b.nextID++
+	entry.ID = b.nextID
+	b.replay[(b.head+b.count)%len(b.replay)] = entry
+	if b.count < len(b.replay) {
+		b.count++
+	} else {
+		b.head = (b.head + 1) % len(b.replay)
+	}
+	close(b.changed)
+	b.changed = make(chan struct{})
+	b.mu.Unlock()
+
+	return nil
+}
+
+// since returns buffered entries with an ID greater than lastID, how many
+// entries after lastID were already evicted from the buffer, and a channel
+// that is closed on the next publish.
+func (b *SSEBroker) since(lastID uint64) ([]sseEntry, uint64, chan struct{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var missed uint64
+	if b.count > 0 {
+		if oldest := b.replay[b.head].ID; oldest > lastID+1 {
+			missed = oldest - lastID - 1
+		}
+	}
+
+	n := 0
+	for n < b.count && b.replay[(b.head+b.count-1-n)%len(b.replay)].ID > lastID {
+		n++
+	}
+
+	out := make([]sseEntry, n)
+	for i := 0; i < n; i++ {
+		out[i] = b.replay[(b.head+b.count-n+i)%len(b.replay)]
+	}
+	return out, missed, b.changed
+}
+
+func (b *SSEBroker) LastID() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.nextID
+}
+
+// encode returns the entry payload in the requested format. JSON is
+// pre-encoded at publish time; binary formats are base64 encoded since SSE
+// is a text protocol.
+func (e sseEntry) encode(serializer telemetry.Serializer) ([]byte, error) {
+	if serializer.Format() == telemetry.FormatJSON {
+		return e.Data, nil
+	}
+
+	var data []byte
+	var err error
+	if e.position != nil {
+		data, err = serializer.MarshalPosition(*e.position)
+	} else {
+		data, err = serializer.MarshalEvent(*e.event)
+	}
+	if err != nil || !serializer.Binary() {
+		return data, err
+	}
+	return []byte(base64.StdEncoding.EncodeToString(data)), nil
+}
+
+// ServeSSE streams telemetry. Optional query parameters: events (comma list
+// of "position" and/or VehicleEvent types), vehicles, fleet, format (json or
+// proto) and last_event_id for clients that cannot set the Last-Event-ID
+// header. Without a resume point only new entries are streamed. A resume
+// point that can no longer be honored gets an SSEResetEvent first.
+func (b *SSEBroker) ServeSSE(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	head := b.LastID()
+	lastID := head
+	resume := r.Header.Get("Last-Event-ID")
+	if resume == "" {
+		resume = r.URL.Query().Get("last_event_id")
+	}
+	if resume != "" {
+		id, err := strconv.ParseUint(resume, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+		lastID = id
+	}
+
+	serializer, err := telemetry.NewSerializer(r.URL.Query().Get("format"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	events := toSet(r.URL.Query().Get("events"))
+	vehicles := toSet(r.URL.Query().Get("vehicles"))
+	fleets := toSet(r.URL.Query().Get("fleet"))
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, "retry: 2000\n\n")
+	if lastID > head {
+		lastID = head
+		writeReset(w, head, sseReset{Reason: "unknown_id"})
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(b.KeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		entries, missed, changed := b.since(lastID)
+
+		if missed > 0 {
+			if err := writeReset(w, lastID+missed, sseReset{Reason: "gap", Missed: missed}); err != nil {
+				return
+			}
+		}
+		for _, e := range entries {
+			lastID = e.ID
+			if !matches(events, e.Event) || !matches(vehicles, e.VehicleID) || !matches(fleets, e.FleetID) {
+				continue
+			}
+			data, err := e.encode(serializer)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Event, data); err != nil {
+				return
+			}
+		}
+		if len(entries) > 0 || missed > 0 {
+			flusher.Flush()
+		}
+
+		select {
+		case <-changed:
+		case <-keepAlive.C:
+			if _, err := fmt.Fprintf(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// writeReset sends an SSEResetEvent. Its ID is the last one the client
+// should consider seen, so an EventSource resumes from there.
+func writeReset(w http.ResponseWriter, id uint64, reset sseReset) error {
+	data, err := json.Marshal(reset)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", id, SSEResetEvent, data)
+	return err
+}
+
+func toSet(raw string) map[string]bool {
+	if raw == "" {
+		return nil
+	}
+	set := make(map[string]bool)
+	for _, p := range strings.Split(raw, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			set[p] = true
+		}
+	}
+	return set
+}
+
+func matches(set map[string]bool, value string) bool {
+	return set == nil || set[value]
+}
@@ -0,0 +1,158 @@
+package ext
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/m/internal/simulation/entities"
+	"github.com/m/internal/telemetry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sseFrame struct {
+	id    string
+	event string
+	data  string
+}
+
+func readFrames(t *testing.T, scanner *bufio.Scanner, n int) []sseFrame {
+	frames := []sseFrame{}
+	cur := sseFrame{}
+	for len(frames) < n && scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if cur.id != "" {
+				frames = append(frames, cur)
+			}
+			cur = sseFrame{}
+		case strings.HasPrefix(line, "id: "):
+			cur.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			cur.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			cur.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+	require.Len(t, frames, n)
+	return frames
+}
+
+func openStream(t *testing.T, ctx context.Context, url, lastEventID string) *bufio.Scanner {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	require.NoError(t, err)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	return bufio.NewScanner(resp.Body)
+}
+
+func TestSSEBroker_ReplayRingKeepsMostRecent(t *testing.T) {
+	b := NewSSEBroker(3)
+	for i := 0; i < 5; i++ {
+		b.EmitPosition(entities.BasicVehiclePosEvent{VehicleID: "v1"})
+	}
+
+	entries, missed, _ := b.since(0)
+	require.Len(t, entries, 3)
+	assert.Equal(t, []uint64{3, 4, 5}, []uint64{entries[0].ID, entries[1].ID, entries[2].ID})
+	assert.Equal(t, uint64(2), missed)
+
+	entries, missed, _ = b.since(4)
+	assert.Zero(t, missed)
+	require.Len(t, entries, 1)
+	assert.Equal(t, uint64(5), entries[0].ID)
+}
+
+func TestSSEBroker_StreamsAndResumes(t *testing.T) {
+	b := NewSSEBroker(16)
+	server := httptest.NewServer(http.HandlerFunc(b.ServeSSE))
+	defer server.Close()
+
+	b.EmitPosition(entities.BasicVehiclePosEvent{VehicleID: "v1", Progress: 0.1})
+	b.EmitEvent(entities.VehicleEvent{VehicleID: "v1", EventType: entities.EventRouteStarted})
+	b.EmitPosition(entities.BasicVehiclePosEvent{VehicleID: "v2", Progress: 0.2})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	frames := readFrames(t, openStream(t, ctx, server.URL, "1"), 2)
+	assert.Equal(t, "2", frames[0].id)
+	assert.Equal(t, string(entities.EventRouteStarted), frames[0].event)
+	assert.Equal(t, "3", frames[1].id)
+	assert.Equal(t, SSEPositionEvent, frames[1].event)
+	assert.Contains(t, frames[1].data, `"vehicle_id":"v2"`)
+
+	live := openStream(t, ctx, server.URL+"?vehicles=v1&events=position", "")
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		b.EmitPosition(entities.BasicVehiclePosEvent{VehicleID: "v2"})
+		b.EmitEvent(entities.VehicleEvent{VehicleID: "v1", EventType: entities.EventRouteCompleted})
+		b.EmitPosition(entities.BasicVehiclePosEvent{VehicleID: "v1", Progress: 0.5})
+	}()
+
+	frames = readFrames(t, live, 1)
+	assert.Equal(t, "6", frames[0].id)
+	assert.Contains(t, frames[0].data, `"progress":0.5`)
+}
+
+func TestSSEBroker_ResetsLostResumePoints(t *testing.T) {
+	b := NewSSEBroker(2)
+	server := httptest.NewServer(http.HandlerFunc(b.ServeSSE))
+	defer server.Close()
+
+	for i := 0; i < 4; i++ {
+		b.EmitPosition(entities.BasicVehiclePosEvent{VehicleID: "v1"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	frames := readFrames(t, openStream(t, ctx, server.URL, "1"), 3)
+	assert.Equal(t, sseFrame{id: "2", event: SSEResetEvent, data: `{"reason":"gap","missed":1}`}, frames[0])
+	assert.Equal(t, "3", frames[1].id)
+	assert.Equal(t, "4", frames[2].id)
+
+	restarted := openStream(t, ctx, server.URL, "900")
+	frames = readFrames(t, restarted, 1)
+	assert.Equal(t, sseFrame{id: "4", event: SSEResetEvent, data: `{"reason":"unknown_id"}`}, frames[0])
+
+	b.EmitPosition(entities.BasicVehiclePosEvent{VehicleID: "v1"})
+	frames = readFrames(t, restarted, 1)
+	assert.Equal(t, "5", frames[0].id)
+}
+
+func TestSSEBroker_ProtoFormat(t *testing.T) {
+	b := NewSSEBroker(16)
+	server := httptest.NewServer(http.HandlerFunc(b.ServeSSE))
+	defer server.Close()
+
+	b.EmitPosition(entities.BasicVehiclePosEvent{VehicleID: "v1", RunID: "run-1", Sequence: 3})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	frames := readFrames(t, openStream(t, ctx, server.URL+"?format=proto", "0"), 1)
+
+	raw, err := base64.StdEncoding.DecodeString(frames[0].data)
+	require.NoError(t, err)
+	rec, err := telemetry.ProtoSerializer{}.Unmarshal(raw)
+	require.NoError(t, err)
+	require.NotNil(t, rec.Position)
+	assert.Equal(t, "run-1", rec.Position.RunID)
+	assert.Equal(t, uint64(3), rec.Position.Sequence)
+	assert.Equal(t, entities.TelemetrySchemaVersion, rec.Position.SchemaVersion)
+}
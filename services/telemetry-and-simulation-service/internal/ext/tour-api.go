@@ -0,0 +1,64 @@
+package ext
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	simulationengine "github.com/m/internal/simulation/simulation-engine"
+)
+
+type TourAPI struct {
+	Engine *simulationengine.SimulationEngine
+}
+
+// Register mounts the VRP planner under /api/tours.
+func (api *TourAPI) Register(mux *http.ServeMux) {
+	mux.HandleFunc("POST /api/tours/plan", api.PlanTours)
+	mux.HandleFunc("POST /api/tours/execute", api.ExecuteTours)
+}
+
+// PlanTours solves the posted VRPProblem. With ?execute=true the tours are
+// started right away.
+func (api *TourAPI) PlanTours(w http.ResponseWriter, r *http.Request) {
+	var problem simulationengine.VRPProblem
+	if err := json.NewDecoder(r.Body).Decode(&problem); err != nil {
+		http.Error(w, "invalid problem: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	solution, err := api.Engine.PlanTours(problem)
+	if err != nil {
+		writeTourError(w, err)
+		return
+	}
+	if r.URL.Query().Get("execute") == "true" {
+		if err := api.Engine.ExecuteTours(solution); err != nil {
+			writeTourError(w, err)
+			return
+		}
+	}
+	json.NewEncoder(w).Encode(solution)
+}
+
+func (api *TourAPI) ExecuteTours(w http.ResponseWriter, r *http.Request) {
+	var solution simulationengine.VRPSolution
+	if err := json.NewDecoder(r.Body).Decode(&solution); err != nil {
+		http.Error(w, "invalid solution: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := api.Engine.ExecuteTours(solution); err != nil {
+		writeTourError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeTourError(w http.ResponseWriter, err error) {
+	code := http.StatusBadRequest
+	if errors.Is(err, simulationengine.ErrVehicleNotFound) {
+		code = http.StatusNotFound
+	}
+	http.Error(w, err.Error(), code)
+}
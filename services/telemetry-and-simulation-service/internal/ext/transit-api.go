@@ -0,0 +1,71 @@
+package ext
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/m/internal/simulation/entities"
+	simulationengine "github.com/m/internal/simulation/simulation-engine"
+)
+
+type TransitAPI struct {
+	Engine *simulationengine.SimulationEngine
+}
+
+// Register mounts the transit line endpoints under /api/transit. Headways,
+// departures and stop offsets are seconds from simulation start.
+func (api *TransitAPI) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/transit/lines", api.ListLines)
+	mux.HandleFunc("POST /api/transit/lines", api.AddLine)
+	mux.HandleFunc("GET /api/transit/lines/{id}", api.GetLine)
+	mux.HandleFunc("DELETE /api/transit/lines/{id}", api.RemoveLine)
+}
+
+func (api *TransitAPI) ListLines(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(api.Engine.ListTransitLines())
+}
+
+func (api *TransitAPI) AddLine(w http.ResponseWriter, r *http.Request) {
+	var line entities.TransitLine
+	if err := json.NewDecoder(r.Body).Decode(&line); err != nil {
+		http.Error(w, "invalid line: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	created, err := api.Engine.AddTransitLine(line)
+	if err != nil {
+		writeTransitError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func (api *TransitAPI) GetLine(w http.ResponseWriter, r *http.Request) {
+	line, ok := api.Engine.GetTransitLine(r.PathValue("id"))
+	if !ok {
+		http.Error(w, "transit line not found", http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(line)
+}
+
+func (api *TransitAPI) RemoveLine(w http.ResponseWriter, r *http.Request) {
+	if err := api.Engine.RemoveTransitLine(r.PathValue("id")); err != nil {
+		writeTransitError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeTransitError(w http.ResponseWriter, err error) {
+	code := http.StatusBadRequest
+	switch {
+	case errors.Is(err, simulationengine.ErrTransitLineNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, simulationengine.ErrTransitLineExists):
+		code = http.StatusConflict
+	}
+	http.Error(w, err.Error(), code)
+}
@@ -0,0 +1,2097 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: simulation/v1/simulation.proto
+
+package simulationv1
+
+import (
+	v1 "github.com/m/internal/gen/telemetry/v1"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type TelemetryStream int32
+
+const (
+	TelemetryStream_TELEMETRY_STREAM_UNSPECIFIED TelemetryStream = 0
+	TelemetryStream_TELEMETRY_STREAM_POSITIONS   TelemetryStream = 1
+	TelemetryStream_TELEMETRY_STREAM_EVENTS      TelemetryStream = 2
+)
+
+// Enum value maps for TelemetryStream.
+var (
+	TelemetryStream_name = map[int32]string{
+		0: "TELEMETRY_STREAM_UNSPECIFIED",
+		1: "TELEMETRY_STREAM_POSITIONS",
+		2: "TELEMETRY_STREAM_EVENTS",
+	}
+	TelemetryStream_value = map[string]int32{
+		"TELEMETRY_STREAM_UNSPECIFIED": 0,
+		"TELEMETRY_STREAM_POSITIONS":   1,
+		"TELEMETRY_STREAM_EVENTS":      2,
+	}
+)
+
+func (x TelemetryStream) Enum() *TelemetryStream {
+	p := new(TelemetryStream)
+	*p = x
+	return p
+}
+
+func (x TelemetryStream) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (TelemetryStream) Descriptor() protoreflect.EnumDescriptor {
+	return file_simulation_v1_simulation_proto_enumTypes[0].Descriptor()
+}
+
+func (TelemetryStream) Type() protoreflect.EnumType {
+	return &file_simulation_v1_simulation_proto_enumTypes[0]
+}
+
+func (x TelemetryStream) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use TelemetryStream.Descriptor instead.
+func (TelemetryStream) EnumDescriptor() ([]byte, []int) {
+	return file_simulation_v1_simulation_proto_rawDescGZIP(), []int{0}
+}
+
+type Vector2D struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	X             float64                `protobuf:"fixed64,1,opt,name=x,proto3" json:"x,omitempty"`
+	Y             float64                `protobuf:"fixed64,2,opt,name=y,proto3" json:"y,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Vector2D) Reset() {
+	*x = Vector2D{}
+	mi := &file_simulation_v1_simulation_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Vector2D) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Vector2D) ProtoMessage() {}
+
+func (x *Vector2D) ProtoReflect() protoreflect.Message {
+	mi := &file_simulation_v1_simulation_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Vector2D.ProtoReflect.Descriptor instead.
+func (*Vector2D) Descriptor() ([]byte, []int) {
+	return file_simulation_v1_simulation_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Vector2D) GetX() float64 {
+	if x != nil {
+		return x.X
+	}
+	return 0
+}
+
+func (x *Vector2D) GetY() float64 {
+	if x != nil {
+		return x.Y
+	}
+	return 0
+}
+
+type Bounds struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MinX          float64                `protobuf:"fixed64,1,opt,name=min_x,json=minX,proto3" json:"min_x,omitempty"`
+	MinY          float64                `protobuf:"fixed64,2,opt,name=min_y,json=minY,proto3" json:"min_y,omitempty"`
+	MaxX          float64                `protobuf:"fixed64,3,opt,name=max_x,json=maxX,proto3" json:"max_x,omitempty"`
+	MaxY          float64                `protobuf:"fixed64,4,opt,name=max_y,json=maxY,proto3" json:"max_y,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Bounds) Reset() {
+	*x = Bounds{}
+	mi := &file_simulation_v1_simulation_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Bounds) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Bounds) ProtoMessage() {}
+
+func (x *Bounds) ProtoReflect() protoreflect.Message {
+	mi := &file_simulation_v1_simulation_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Bounds.ProtoReflect.Descriptor instead.
+func (*Bounds) Descriptor() ([]byte, []int) {
+	return file_simulation_v1_simulation_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Bounds) GetMinX() float64 {
+	if x != nil {
+		return x.MinX
+	}
+	return 0
+}
+
+func (x *Bounds) GetMinY() float64 {
+	if x != nil {
+		return x.MinY
+	}
+	return 0
+}
+
+func (x *Bounds) GetMaxX() float64 {
+	if x != nil {
+		return x.MaxX
+	}
+	return 0
+}
+
+func (x *Bounds) GetMaxY() float64 {
+	if x != nil {
+		return x.MaxY
+	}
+	return 0
+}
+
+type EngineStatus struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Running       bool                   `protobuf:"varint,1,opt,name=running,proto3" json:"running,omitempty"`
+	Paused        bool                   `protobuf:"varint,2,opt,name=paused,proto3" json:"paused,omitempty"`
+	VehicleCount  int32                  `protobuf:"varint,3,opt,name=vehicle_count,json=vehicleCount,proto3" json:"vehicle_count,omitempty"`
+	UpdateRate    *durationpb.Duration   `protobuf:"bytes,4,opt,name=update_rate,json=updateRate,proto3" json:"update_rate,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EngineStatus) Reset() {
+	*x = EngineStatus{}
+	mi := &file_simulation_v1_simulation_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EngineStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EngineStatus) ProtoMessage() {}
+
+func (x *EngineStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_simulation_v1_simulation_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EngineStatus.ProtoReflect.Descriptor instead.
+func (*EngineStatus) Descriptor() ([]byte, []int) {
+	return file_simulation_v1_simulation_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *EngineStatus) GetRunning() bool {
+	if x != nil {
+		return x.Running
+	}
+	return false
+}
+
+func (x *EngineStatus) GetPaused() bool {
+	if x != nil {
+		return x.Paused
+	}
+	return false
+}
+
+func (x *EngineStatus) GetVehicleCount() int32 {
+	if x != nil {
+		return x.VehicleCount
+	}
+	return 0
+}
+
+func (x *EngineStatus) GetUpdateRate() *durationpb.Duration {
+	if x != nil {
+		return x.UpdateRate
+	}
+	return nil
+}
+
+type AssignedRoute struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Edges            []string               `protobuf:"bytes,1,rep,name=edges,proto3" json:"edges,omitempty"`
+	CurrentEdgeIndex int32                  `protobuf:"varint,2,opt,name=current_edge_index,json=currentEdgeIndex,proto3" json:"current_edge_index,omitempty"`
+	CurrentNode      string                 `protobuf:"bytes,3,opt,name=current_node,json=currentNode,proto3" json:"current_node,omitempty"`
+	TargetNode       string                 `protobuf:"bytes,4,opt,name=target_node,json=targetNode,proto3" json:"target_node,omitempty"`
+	StartNode        string                 `protobuf:"bytes,5,opt,name=start_node,json=startNode,proto3" json:"start_node,omitempty"`
+	EndNode          string                 `protobuf:"bytes,6,opt,name=end_node,json=endNode,proto3" json:"end_node,omitempty"`
+	StartedAt        *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	CompletedAt      *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=completed_at,json=completedAt,proto3" json:"completed_at,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *AssignedRoute) Reset() {
+	*x = AssignedRoute{}
+	mi := &file_simulation_v1_simulation_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AssignedRoute) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AssignedRoute) ProtoMessage() {}
+
+func (x *AssignedRoute) ProtoReflect() protoreflect.Message {
+	mi := &file_simulation_v1_simulation_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AssignedRoute.ProtoReflect.Descriptor instead.
+func (*AssignedRoute) Descriptor() ([]byte, []int) {
+	return file_simulation_v1_simulation_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *AssignedRoute) GetEdges() []string {
+	if x != nil {
+		return x.Edges
+	}
+	return nil
+}
+
+func (x *AssignedRoute) GetCurrentEdgeIndex() int32 {
+	if x != nil {
+		return x.CurrentEdgeIndex
+	}
+	return 0
+}
+
+func (x *AssignedRoute) GetCurrentNode() string {
+	if x != nil {
+		return x.CurrentNode
+	}
+	return ""
+}
+
+func (x *AssignedRoute) GetTargetNode() string {
+	if x != nil {
+		return x.TargetNode
+	}
+	return ""
+}
+
+func (x *AssignedRoute) GetStartNode() string {
+	if x != nil {
+		return x.StartNode
+	}
+	return ""
+}
+
+func (x *AssignedRoute) GetEndNode() string {
+	if x != nil {
+		return x.EndNode
+	}
+	return ""
+}
+
+func (x *AssignedRoute) GetStartedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartedAt
+	}
+	return nil
+}
+
+func (x *AssignedRoute) GetCompletedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CompletedAt
+	}
+	return nil
+}
+
+type Vehicle struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Id             string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Type           string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	FleetId        string                 `protobuf:"bytes,3,opt,name=fleet_id,json=fleetId,proto3" json:"fleet_id,omitempty"`
+	Status         string                 `protobuf:"bytes,4,opt,name=status,proto3" json:"status,omitempty"`
+	Position       *Vector2D              `protobuf:"bytes,5,opt,name=position,proto3" json:"position,omitempty"`
+	Velocity       *Vector2D              `protobuf:"bytes,6,opt,name=velocity,proto3" json:"velocity,omitempty"`
+	CurrentEdge    string                 `protobuf:"bytes,7,opt,name=current_edge,json=currentEdge,proto3" json:"current_edge,omitempty"`
+	ProgressOnEdge float64                `protobuf:"fixed64,8,opt,name=progress_on_edge,json=progressOnEdge,proto3" json:"progress_on_edge,omitempty"`
+	Route          *AssignedRoute         `protobuf:"bytes,9,opt,name=route,proto3" json:"route,omitempty"`
+	LastUpdateTime *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=last_update_time,json=lastUpdateTime,proto3" json:"last_update_time,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Vehicle) Reset() {
+	*x = Vehicle{}
+	mi := &file_simulation_v1_simulation_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Vehicle) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Vehicle) ProtoMessage() {}
+
+func (x *Vehicle) ProtoReflect() protoreflect.Message {
+	mi := &file_simulation_v1_simulation_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Vehicle.ProtoReflect.Descriptor instead.
+func (*Vehicle) Descriptor() ([]byte, []int) {
+	return file_simulation_v1_simulation_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Vehicle) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Vehicle) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Vehicle) GetFleetId() string {
+	if x != nil {
+		return x.FleetId
+	}
+	return ""
+}
+
+func (x *Vehicle) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Vehicle) GetPosition() *Vector2D {
+	if x != nil {
+		return x.Position
+	}
+	return nil
+}
+
+func (x *Vehicle) GetVelocity() *Vector2D {
+	if x != nil {
+		return x.Velocity
+	}
+	return nil
+}
+
+func (x *Vehicle) GetCurrentEdge() string {
+	if x != nil {
+		return x.CurrentEdge
+	}
+	return ""
+}
+
+func (x *Vehicle) GetProgressOnEdge() float64 {
+	if x != nil {
+		return x.ProgressOnEdge
+	}
+	return 0
+}
+
+func (x *Vehicle) GetRoute() *AssignedRoute {
+	if x != nil {
+		return x.Route
+	}
+	return nil
+}
+
+func (x *Vehicle) GetLastUpdateTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastUpdateTime
+	}
+	return nil
+}
+
+type Route struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Edges         []string               `protobuf:"bytes,1,rep,name=edges,proto3" json:"edges,omitempty"`
+	StartNode     string                 `protobuf:"bytes,2,opt,name=start_node,json=startNode,proto3" json:"start_node,omitempty"`
+	EndNode       string                 `protobuf:"bytes,3,opt,name=end_node,json=endNode,proto3" json:"end_node,omitempty"`
+	TotalDistance float64                `protobuf:"fixed64,4,opt,name=total_distance,json=totalDistance,proto3" json:"total_distance,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Route) Reset() {
+	*x = Route{}
+	mi := &file_simulation_v1_simulation_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Route) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Route) ProtoMessage() {}
+
+func (x *Route) ProtoReflect() protoreflect.Message {
+	mi := &file_simulation_v1_simulation_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Route.ProtoReflect.Descriptor instead.
+func (*Route) Descriptor() ([]byte, []int) {
+	return file_simulation_v1_simulation_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Route) GetEdges() []string {
+	if x != nil {
+		return x.Edges
+	}
+	return nil
+}
+
+func (x *Route) GetStartNode() string {
+	if x != nil {
+		return x.StartNode
+	}
+	return ""
+}
+
+func (x *Route) GetEndNode() string {
+	if x != nil {
+		return x.EndNode
+	}
+	return ""
+}
+
+func (x *Route) GetTotalDistance() float64 {
+	if x != nil {
+		return x.TotalDistance
+	}
+	return 0
+}
+
+type StartRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StartRequest) Reset() {
+	*x = StartRequest{}
+	mi := &file_simulation_v1_simulation_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StartRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StartRequest) ProtoMessage() {}
+
+func (x *StartRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_simulation_v1_simulation_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StartRequest.ProtoReflect.Descriptor instead.
+func (*StartRequest) Descriptor() ([]byte, []int) {
+	return file_simulation_v1_simulation_proto_rawDescGZIP(), []int{6}
+}
+
+type StartResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Status        *EngineStatus          `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StartResponse) Reset() {
+	*x = StartResponse{}
+	mi := &file_simulation_v1_simulation_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StartResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StartResponse) ProtoMessage() {}
+
+func (x *StartResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_simulation_v1_simulation_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StartResponse.ProtoReflect.Descriptor instead.
+func (*StartResponse) Descriptor() ([]byte, []int) {
+	return file_simulation_v1_simulation_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *StartResponse) GetStatus() *EngineStatus {
+	if x != nil {
+		return x.Status
+	}
+	return nil
+}
+
+type StopRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StopRequest) Reset() {
+	*x = StopRequest{}
+	mi := &file_simulation_v1_simulation_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StopRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StopRequest) ProtoMessage() {}
+
+func (x *StopRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_simulation_v1_simulation_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StopRequest.ProtoReflect.Descriptor instead.
+func (*StopRequest) Descriptor() ([]byte, []int) {
+	return file_simulation_v1_simulation_proto_rawDescGZIP(), []int{8}
+}
+
+type StopResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Status        *EngineStatus          `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StopResponse) Reset() {
+	*x = StopResponse{}
+	mi := &file_simulation_v1_simulation_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StopResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StopResponse) ProtoMessage() {}
+
+func (x *StopResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_simulation_v1_simulation_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StopResponse.ProtoReflect.Descriptor instead.
+func (*StopResponse) Descriptor() ([]byte, []int) {
+	return file_simulation_v1_simulation_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *StopResponse) GetStatus() *EngineStatus {
+	if x != nil {
+		return x.Status
+	}
+	return nil
+}
+
+type PauseRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PauseRequest) Reset() {
+	*x = PauseRequest{}
+	mi := &file_simulation_v1_simulation_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PauseRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PauseRequest) ProtoMessage() {}
+
+func (x *PauseRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_simulation_v1_simulation_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PauseRequest.ProtoReflect.Descriptor instead.
+func (*PauseRequest) Descriptor() ([]byte, []int) {
+	return file_simulation_v1_simulation_proto_rawDescGZIP(), []int{10}
+}
+
+type PauseResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Status        *EngineStatus          `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PauseResponse) Reset() {
+	*x = PauseResponse{}
+	mi := &file_simulation_v1_simulation_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PauseResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PauseResponse) ProtoMessage() {}
+
+func (x *PauseResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_simulation_v1_simulation_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PauseResponse.ProtoReflect.Descriptor instead.
+func (*PauseResponse) Descriptor() ([]byte, []int) {
+	return file_simulation_v1_simulation_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *PauseResponse) GetStatus() *EngineStatus {
+	if x != nil {
+		return x.Status
+	}
+	return nil
+}
+
+type ResumeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResumeRequest) Reset() {
+	*x = ResumeRequest{}
+	mi := &file_simulation_v1_simulation_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResumeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResumeRequest) ProtoMessage() {}
+
+func (x *ResumeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_simulation_v1_simulation_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResumeRequest.ProtoReflect.Descriptor instead.
+func (*ResumeRequest) Descriptor() ([]byte, []int) {
+	return file_simulation_v1_simulation_proto_rawDescGZIP(), []int{12}
+}
+
+type ResumeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Status        *EngineStatus          `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResumeResponse) Reset() {
+	*x = ResumeResponse{}
+	mi := &file_simulation_v1_simulation_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResumeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResumeResponse) ProtoMessage() {}
+
+func (x *ResumeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_simulation_v1_simulation_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResumeResponse.ProtoReflect.Descriptor instead.
+func (*ResumeResponse) Descriptor() ([]byte, []int) {
+	return file_simulation_v1_simulation_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ResumeResponse) GetStatus() *EngineStatus {
+	if x != nil {
+		return x.Status
+	}
+	return nil
+}
+
+type StepRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Simulated time per step; defaults to the engine update rate.
+	Dt *durationpb.Duration `protobuf:"bytes,1,opt,name=dt,proto3" json:"dt,omitempty"`
+	// Number of steps to run; defaults to 1, at most 10000.
+	Steps         uint32 `protobuf:"varint,2,opt,name=steps,proto3" json:"steps,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StepRequest) Reset() {
+	*x = StepRequest{}
+	mi := &file_simulation_v1_simulation_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StepRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StepRequest) ProtoMessage() {}
+
+func (x *StepRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_simulation_v1_simulation_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StepRequest.ProtoReflect.Descriptor instead.
+func (*StepRequest) Descriptor() ([]byte, []int) {
+	return file_simulation_v1_simulation_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *StepRequest) GetDt() *durationpb.Duration {
+	if x != nil {
+		return x.Dt
+	}
+	return nil
+}
+
+func (x *StepRequest) GetSteps() uint32 {
+	if x != nil {
+		return x.Steps
+	}
+	return 0
+}
+
+type StepResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Status        *EngineStatus          `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StepResponse) Reset() {
+	*x = StepResponse{}
+	mi := &file_simulation_v1_simulation_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StepResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StepResponse) ProtoMessage() {}
+
+func (x *StepResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_simulation_v1_simulation_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StepResponse.ProtoReflect.Descriptor instead.
+func (*StepResponse) Descriptor() ([]byte, []int) {
+	return file_simulation_v1_simulation_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *StepResponse) GetStatus() *EngineStatus {
+	if x != nil {
+		return x.Status
+	}
+	return nil
+}
+
+type GetStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStatusRequest) Reset() {
+	*x = GetStatusRequest{}
+	mi := &file_simulation_v1_simulation_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStatusRequest) ProtoMessage() {}
+
+func (x *GetStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_simulation_v1_simulation_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStatusRequest.ProtoReflect.Descriptor instead.
+func (*GetStatusRequest) Descriptor() ([]byte, []int) {
+	return file_simulation_v1_simulation_proto_rawDescGZIP(), []int{16}
+}
+
+type GetStatusResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Status        *EngineStatus          `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStatusResponse) Reset() {
+	*x = GetStatusResponse{}
+	mi := &file_simulation_v1_simulation_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStatusResponse) ProtoMessage() {}
+
+func (x *GetStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_simulation_v1_simulation_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStatusResponse.ProtoReflect.Descriptor instead.
+func (*GetStatusResponse) Descriptor() ([]byte, []int) {
+	return file_simulation_v1_simulation_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *GetStatusResponse) GetStatus() *EngineStatus {
+	if x != nil {
+		return x.Status
+	}
+	return nil
+}
+
+type CreateVehicleRequest struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Id      string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Type    string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	FleetId string                 `protobuf:"bytes,3,opt,name=fleet_id,json=fleetId,proto3" json:"fleet_id,omitempty"`
+	// When start_node or end_node is empty a random one is chosen.
+	StartNode     string `protobuf:"bytes,4,opt,name=start_node,json=startNode,proto3" json:"start_node,omitempty"`
+	EndNode       string `protobuf:"bytes,5,opt,name=end_node,json=endNode,proto3" json:"end_node,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateVehicleRequest) Reset() {
+	*x = CreateVehicleRequest{}
+	mi := &file_simulation_v1_simulation_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateVehicleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateVehicleRequest) ProtoMessage() {}
+
+func (x *CreateVehicleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_simulation_v1_simulation_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateVehicleRequest.ProtoReflect.Descriptor instead.
+func (*CreateVehicleRequest) Descriptor() ([]byte, []int) {
+	return file_simulation_v1_simulation_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *CreateVehicleRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *CreateVehicleRequest) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *CreateVehicleRequest) GetFleetId() string {
+	if x != nil {
+		return x.FleetId
+	}
+	return ""
+}
+
+func (x *CreateVehicleRequest) GetStartNode() string {
+	if x != nil {
+		return x.StartNode
+	}
+	return ""
+}
+
+func (x *CreateVehicleRequest) GetEndNode() string {
+	if x != nil {
+		return x.EndNode
+	}
+	return ""
+}
+
+type CreateVehicleResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Vehicle       *Vehicle               `protobuf:"bytes,1,opt,name=vehicle,proto3" json:"vehicle,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateVehicleResponse) Reset() {
+	*x = CreateVehicleResponse{}
+	mi := &file_simulation_v1_simulation_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateVehicleResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateVehicleResponse) ProtoMessage() {}
+
+func (x *CreateVehicleResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_simulation_v1_simulation_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateVehicleResponse.ProtoReflect.Descriptor instead.
+func (*CreateVehicleResponse) Descriptor() ([]byte, []int) {
+	return file_simulation_v1_simulation_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *CreateVehicleResponse) GetVehicle() *Vehicle {
+	if x != nil {
+		return x.Vehicle
+	}
+	return nil
+}
+
+type GetVehicleRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetVehicleRequest) Reset() {
+	*x = GetVehicleRequest{}
+	mi := &file_simulation_v1_simulation_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetVehicleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetVehicleRequest) ProtoMessage() {}
+
+func (x *GetVehicleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_simulation_v1_simulation_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetVehicleRequest.ProtoReflect.Descriptor instead.
+func (*GetVehicleRequest) Descriptor() ([]byte, []int) {
+	return file_simulation_v1_simulation_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *GetVehicleRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type GetVehicleResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Vehicle       *Vehicle               `protobuf:"bytes,1,opt,name=vehicle,proto3" json:"vehicle,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetVehicleResponse) Reset() {
+	*x = GetVehicleResponse{}
+	mi := &file_simulation_v1_simulation_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetVehicleResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetVehicleResponse) ProtoMessage() {}
+
+func (x *GetVehicleResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_simulation_v1_simulation_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetVehicleResponse.ProtoReflect.Descriptor instead.
+func (*GetVehicleResponse) Descriptor() ([]byte, []int) {
+	return file_simulation_v1_simulation_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *GetVehicleResponse) GetVehicle() *Vehicle {
+	if x != nil {
+		return x.Vehicle
+	}
+	return nil
+}
+
+type ListVehiclesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FleetId       string                 `protobuf:"bytes,1,opt,name=fleet_id,json=fleetId,proto3" json:"fleet_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListVehiclesRequest) Reset() {
+	*x = ListVehiclesRequest{}
+	mi := &file_simulation_v1_simulation_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListVehiclesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListVehiclesRequest) ProtoMessage() {}
+
+func (x *ListVehiclesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_simulation_v1_simulation_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListVehiclesRequest.ProtoReflect.Descriptor instead.
+func (*ListVehiclesRequest) Descriptor() ([]byte, []int) {
+	return file_simulation_v1_simulation_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *ListVehiclesRequest) GetFleetId() string {
+	if x != nil {
+		return x.FleetId
+	}
+	return ""
+}
+
+type ListVehiclesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Vehicles      []*Vehicle             `protobuf:"bytes,1,rep,name=vehicles,proto3" json:"vehicles,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListVehiclesResponse) Reset() {
+	*x = ListVehiclesResponse{}
+	mi := &file_simulation_v1_simulation_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListVehiclesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListVehiclesResponse) ProtoMessage() {}
+
+func (x *ListVehiclesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_simulation_v1_simulation_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListVehiclesResponse.ProtoReflect.Descriptor instead.
+func (*ListVehiclesResponse) Descriptor() ([]byte, []int) {
+	return file_simulation_v1_simulation_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *ListVehiclesResponse) GetVehicles() []*Vehicle {
+	if x != nil {
+		return x.Vehicles
+	}
+	return nil
+}
+
+type UpdateVehicleRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Type          *string                `protobuf:"bytes,2,opt,name=type,proto3,oneof" json:"type,omitempty"`
+	FleetId       *string                `protobuf:"bytes,3,opt,name=fleet_id,json=fleetId,proto3,oneof" json:"fleet_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateVehicleRequest) Reset() {
+	*x = UpdateVehicleRequest{}
+	mi := &file_simulation_v1_simulation_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateVehicleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateVehicleRequest) ProtoMessage() {}
+
+func (x *UpdateVehicleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_simulation_v1_simulation_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateVehicleRequest.ProtoReflect.Descriptor instead.
+func (*UpdateVehicleRequest) Descriptor() ([]byte, []int) {
+	return file_simulation_v1_simulation_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *UpdateVehicleRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *UpdateVehicleRequest) GetType() string {
+	if x != nil && x.Type != nil {
+		return *x.Type
+	}
+	return ""
+}
+
+func (x *UpdateVehicleRequest) GetFleetId() string {
+	if x != nil && x.FleetId != nil {
+		return *x.FleetId
+	}
+	return ""
+}
+
+type UpdateVehicleResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Vehicle       *Vehicle               `protobuf:"bytes,1,opt,name=vehicle,proto3" json:"vehicle,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateVehicleResponse) Reset() {
+	*x = UpdateVehicleResponse{}
+	mi := &file_simulation_v1_simulation_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateVehicleResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateVehicleResponse) ProtoMessage() {}
+
+func (x *UpdateVehicleResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_simulation_v1_simulation_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateVehicleResponse.ProtoReflect.Descriptor instead.
+func (*UpdateVehicleResponse) Descriptor() ([]byte, []int) {
+	return file_simulation_v1_simulation_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *UpdateVehicleResponse) GetVehicle() *Vehicle {
+	if x != nil {
+		return x.Vehicle
+	}
+	return nil
+}
+
+type DeleteVehicleRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteVehicleRequest) Reset() {
+	*x = DeleteVehicleRequest{}
+	mi := &file_simulation_v1_simulation_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteVehicleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteVehicleRequest) ProtoMessage() {}
+
+func (x *DeleteVehicleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_simulation_v1_simulation_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteVehicleRequest.ProtoReflect.Descriptor instead.
+func (*DeleteVehicleRequest) Descriptor() ([]byte, []int) {
+	return file_simulation_v1_simulation_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *DeleteVehicleRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type DeleteVehicleResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteVehicleResponse) Reset() {
+	*x = DeleteVehicleResponse{}
+	mi := &file_simulation_v1_simulation_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteVehicleResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteVehicleResponse) ProtoMessage() {}
+
+func (x *DeleteVehicleResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_simulation_v1_simulation_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteVehicleResponse.ProtoReflect.Descriptor instead.
+func (*DeleteVehicleResponse) Descriptor() ([]byte, []int) {
+	return file_simulation_v1_simulation_proto_rawDescGZIP(), []int{27}
+}
+
+type RequestRouteRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	StartNode string                 `protobuf:"bytes,1,opt,name=start_node,json=startNode,proto3" json:"start_node,omitempty"`
+	EndNode   string                 `protobuf:"bytes,2,opt,name=end_node,json=endNode,proto3" json:"end_node,omitempty"`
+	// When set the vehicle is rerouted from its current position and
+	// start_node is ignored.
+	VehicleId     string `protobuf:"bytes,3,opt,name=vehicle_id,json=vehicleId,proto3" json:"vehicle_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RequestRouteRequest) Reset() {
+	*x = RequestRouteRequest{}
+	mi := &file_simulation_v1_simulation_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RequestRouteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RequestRouteRequest) ProtoMessage() {}
+
+func (x *RequestRouteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_simulation_v1_simulation_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RequestRouteRequest.ProtoReflect.Descriptor instead.
+func (*RequestRouteRequest) Descriptor() ([]byte, []int) {
+	return file_simulation_v1_simulation_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *RequestRouteRequest) GetStartNode() string {
+	if x != nil {
+		return x.StartNode
+	}
+	return ""
+}
+
+func (x *RequestRouteRequest) GetEndNode() string {
+	if x != nil {
+		return x.EndNode
+	}
+	return ""
+}
+
+func (x *RequestRouteRequest) GetVehicleId() string {
+	if x != nil {
+		return x.VehicleId
+	}
+	return ""
+}
+
+type RequestRouteResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Route         *Route                 `protobuf:"bytes,1,opt,name=route,proto3" json:"route,omitempty"`
+	EstimatedTime *durationpb.Duration   `protobuf:"bytes,2,opt,name=estimated_time,json=estimatedTime,proto3" json:"estimated_time,omitempty"`
+	Assigned      bool                   `protobuf:"varint,3,opt,name=assigned,proto3" json:"assigned,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RequestRouteResponse) Reset() {
+	*x = RequestRouteResponse{}
+	mi := &file_simulation_v1_simulation_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RequestRouteResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RequestRouteResponse) ProtoMessage() {}
+
+func (x *RequestRouteResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_simulation_v1_simulation_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RequestRouteResponse.ProtoReflect.Descriptor instead.
+func (*RequestRouteResponse) Descriptor() ([]byte, []int) {
+	return file_simulation_v1_simulation_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *RequestRouteResponse) GetRoute() *Route {
+	if x != nil {
+		return x.Route
+	}
+	return nil
+}
+
+func (x *RequestRouteResponse) GetEstimatedTime() *durationpb.Duration {
+	if x != nil {
+		return x.EstimatedTime
+	}
+	return nil
+}
+
+func (x *RequestRouteResponse) GetAssigned() bool {
+	if x != nil {
+		return x.Assigned
+	}
+	return false
+}
+
+type SubscribeTelemetryRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Empty means all streams.
+	Streams       []TelemetryStream `protobuf:"varint,1,rep,packed,name=streams,proto3,enum=simulation.v1.TelemetryStream" json:"streams,omitempty"`
+	FleetIds      []string          `protobuf:"bytes,2,rep,name=fleet_ids,json=fleetIds,proto3" json:"fleet_ids,omitempty"`
+	VehicleIds    []string          `protobuf:"bytes,3,rep,name=vehicle_ids,json=vehicleIds,proto3" json:"vehicle_ids,omitempty"`
+	EventTypes    []string          `protobuf:"bytes,4,rep,name=event_types,json=eventTypes,proto3" json:"event_types,omitempty"`
+	Bbox          *Bounds           `protobuf:"bytes,5,opt,name=bbox,proto3" json:"bbox,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubscribeTelemetryRequest) Reset() {
+	*x = SubscribeTelemetryRequest{}
+	mi := &file_simulation_v1_simulation_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubscribeTelemetryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubscribeTelemetryRequest) ProtoMessage() {}
+
+func (x *SubscribeTelemetryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_simulation_v1_simulation_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubscribeTelemetryRequest.ProtoReflect.Descriptor instead.
+func (*SubscribeTelemetryRequest) Descriptor() ([]byte, []int) {
+	return file_simulation_v1_simulation_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *SubscribeTelemetryRequest) GetStreams() []TelemetryStream {
+	if x != nil {
+		return x.Streams
+	}
+	return nil
+}
+
+func (x *SubscribeTelemetryRequest) GetFleetIds() []string {
+	if x != nil {
+		return x.FleetIds
+	}
+	return nil
+}
+
+func (x *SubscribeTelemetryRequest) GetVehicleIds() []string {
+	if x != nil {
+		return x.VehicleIds
+	}
+	return nil
+}
+
+func (x *SubscribeTelemetryRequest) GetEventTypes() []string {
+	if x != nil {
+		return x.EventTypes
+	}
+	return nil
+}
+
+func (x *SubscribeTelemetryRequest) GetBbox() *Bounds {
+	if x != nil {
+		return x.Bbox
+	}
+	return nil
+}
+
+type SubscribeTelemetryResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Payload:
+	//
+	//	*SubscribeTelemetryResponse_Position
+	//	*SubscribeTelemetryResponse_Event
+	Payload isSubscribeTelemetryResponse_Payload `protobuf_oneof:"payload"`
+	// Number of messages dropped for this subscriber because it fell behind.
+	Dropped       uint64 `protobuf:"varint,3,opt,name=dropped,proto3" json:"dropped,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubscribeTelemetryResponse) Reset() {
+	*x = SubscribeTelemetryResponse{}
+	mi := &file_simulation_v1_simulation_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubscribeTelemetryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubscribeTelemetryResponse) ProtoMessage() {}
+
+func (x *SubscribeTelemetryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_simulation_v1_simulation_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubscribeTelemetryResponse.ProtoReflect.Descriptor instead.
+func (*SubscribeTelemetryResponse) Descriptor() ([]byte, []int) {
+	return file_simulation_v1_simulation_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *SubscribeTelemetryResponse) GetPayload() isSubscribeTelemetryResponse_Payload {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *SubscribeTelemetryResponse) GetPosition() *v1.VehiclePosition {
+	if x != nil {
+		if x, ok := x.Payload.(*SubscribeTelemetryResponse_Position); ok {
+			return x.Position
+		}
+	}
+	return nil
+}
+
+func (x *SubscribeTelemetryResponse) GetEvent() *v1.VehicleEvent {
+	if x != nil {
+		if x, ok := x.Payload.(*SubscribeTelemetryResponse_Event); ok {
+			return x.Event
+		}
+	}
+	return nil
+}
+
+func (x *SubscribeTelemetryResponse) GetDropped() uint64 {
+	if x != nil {
+		return x.Dropped
+	}
+	return 0
+}
+
+type isSubscribeTelemetryResponse_Payload interface {
+	isSubscribeTelemetryResponse_Payload()
+}
+
+type SubscribeTelemetryResponse_Position struct {
+	Position *v1.VehiclePosition `protobuf:"bytes,1,opt,name=position,proto3,oneof"`
+}
+
+type SubscribeTelemetryResponse_Event struct {
+	Event *v1.VehicleEvent `protobuf:"bytes,2,opt,name=event,proto3,oneof"`
+}
+
+func (*SubscribeTelemetryResponse_Position) isSubscribeTelemetryResponse_Payload() {}
+
+func (*SubscribeTelemetryResponse_Event) isSubscribeTelemetryResponse_Payload() {}
+
+var File_simulation_v1_simulation_proto protoreflect.FileDescriptor
+
+const file_simulation_v1_simulation_proto_rawDesc = "" +
+	"\n" +
+	"\x1esimulation/v1/simulation.proto\x12\rsimulation.v1\x1a\x1egoogle/protobuf/duration.proto\x1a\x1fgoogle/protobuf/timestamp.proto\x1a\x1ctelemetry/v1/telemetry.proto\"&\n" +
+	"\bVector2D\x12\f\n" +
+	"\x01x\x18\x01 \x01(\x01R\x01x\x12\f\n" +
+	"\x01y\x18\x02 \x01(\x01R\x01y\"\\\n" +
+	"\x06Bounds\x12\x13\n" +
+	"\x05min_x\x18\x01 \x01(\x01R\x04minX\x12\x13\n" +
+	"\x05min_y\x18\x02 \x01(\x01R\x04minY\x12\x13\n" +
+	"\x05max_x\x18\x03 \x01(\x01R\x04maxX\x12\x13\n" +
+	"\x05max_y\x18\x04 \x01(\x01R\x04maxY\"\xa1\x01\n" +
+	"\fEngineStatus\x12\x18\n" +
+	"\arunning\x18\x01 \x01(\bR\arunning\x12\x16\n" +
+	"\x06paused\x18\x02 \x01(\bR\x06paused\x12#\n" +
+	"\rvehicle_count\x18\x03 \x01(\x05R\fvehicleCount\x12:\n" +
+	"\vupdate_rate\x18\x04 \x01(\v2\x19.google.protobuf.DurationR\n" +
+	"updateRate\"\xcb\x02\n" +
+	"\rAssignedRoute\x12\x14\n" +
+	"\x05edges\x18\x01 \x03(\tR\x05edges\x12,\n" +
+	"\x12current_edge_index\x18\x02 \x01(\x05R\x10currentEdgeIndex\x12!\n" +
+	"\fcurrent_node\x18\x03 \x01(\tR\vcurrentNode\x12\x1f\n" +
+	"\vtarget_node\x18\x04 \x01(\tR\n" +
+	"targetNode\x12\x1d\n" +
+	"\n" +
+	"start_node\x18\x05 \x01(\tR\tstartNode\x12\x19\n" +
+	"\bend_node\x18\x06 \x01(\tR\aendNode\x129\n" +
+	"\n" +
+	"started_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\tstartedAt\x12=\n" +
+	"\fcompleted_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\vcompletedAt\"\x91\x03\n" +
+	"\aVehicle\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x19\n" +
+	"\bfleet_id\x18\x03 \x01(\tR\afleetId\x12\x16\n" +
+	"\x06status\x18\x04 \x01(\tR\x06status\x123\n" +
+	"\bposition\x18\x05 \x01(\v2\x17.simulation.v1.Vector2DR\bposition\x123\n" +
+	"\bvelocity\x18\x06 \x01(\v2\x17.simulation.v1.Vector2DR\bvelocity\x12!\n" +
+	"\fcurrent_edge\x18\a \x01(\tR\vcurrentEdge\x12(\n" +
+	"\x10progress_on_edge\x18\b \x01(\x01R\x0eprogressOnEdge\x122\n" +
+	"\x05route\x18\t \x01(\v2\x1c.simulation.v1.AssignedRouteR\x05route\x12D\n" +
+	"\x10last_update_time\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\x0elastUpdateTime\"~\n" +
+	"\x05Route\x12\x14\n" +
+	"\x05edges\x18\x01 \x03(\tR\x05edges\x12\x1d\n" +
+	"\n" +
+	"start_node\x18\x02 \x01(\tR\tstartNode\x12\x19\n" +
+	"\bend_node\x18\x03 \x01(\tR\aendNode\x12%\n" +
+	"\x0etotal_distance\x18\x04 \x01(\x01R\rtotalDistance\"\x0e\n" +
+	"\fStartRequest\"D\n" +
+	"\rStartResponse\x123\n" +
+	"\x06status\x18\x01 \x01(\v2\x1b.simulation.v1.EngineStatusR\x06status\"\r\n" +
+	"\vStopRequest\"C\n" +
+	"\fStopResponse\x123\n" +
+	"\x06status\x18\x01 \x01(\v2\x1b.simulation.v1.EngineStatusR\x06status\"\x0e\n" +
+	"\fPauseRequest\"D\n" +
+	"\rPauseResponse\x123\n" +
+	"\x06status\x18\x01 \x01(\v2\x1b.simulation.v1.EngineStatusR\x06status\"\x0f\n" +
+	"\rResumeRequest\"E\n" +
+	"\x0eResumeResponse\x123\n" +
+	"\x06status\x18\x01 \x01(\v2\x1b.simulation.v1.EngineStatusR\x06status\"N\n" +
+	"\vStepRequest\x12)\n" +
+	"\x02dt\x18\x01 \x01(\v2\x19.google.protobuf.DurationR\x02dt\x12\x14\n" +
+	"\x05steps\x18\x02 \x01(\rR\x05steps\"C\n" +
+	"\fStepResponse\x123\n" +
+	"\x06status\x18\x01 \x01(\v2\x1b.simulation.v1.EngineStatusR\x06status\"\x12\n" +
+	"\x10GetStatusRequest\"H\n" +
+	"\x11GetStatusResponse\x123\n" +
+	"\x06status\x18\x01 \x01(\v2\x1b.simulation.v1.EngineStatusR\x06status\"\x8f\x01\n" +
+	"\x14CreateVehicleRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x19\n" +
+	"\bfleet_id\x18\x03 \x01(\tR\afleetId\x12\x1d\n" +
+	"\n" +
+	"start_node\x18\x04 \x01(\tR\tstartNode\x12\x19\n" +
+	"\bend_node\x18\x05 \x01(\tR\aendNode\"I\n" +
+	"\x15CreateVehicleResponse\x120\n" +
+	"\avehicle\x18\x01 \x01(\v2\x16.simulation.v1.VehicleR\avehicle\"#\n" +
+	"\x11GetVehicleRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"F\n" +
+	"\x12GetVehicleResponse\x120\n" +
+	"\avehicle\x18\x01 \x01(\v2\x16.simulation.v1.VehicleR\avehicle\"0\n" +
+	"\x13ListVehiclesRequest\x12\x19\n" +
+	"\bfleet_id\x18\x01 \x01(\tR\afleetId\"J\n" +
+	"\x14ListVehiclesResponse\x122\n" +
+	"\bvehicles\x18\x01 \x03(\v2\x16.simulation.v1.VehicleR\bvehicles\"u\n" +
+	"\x14UpdateVehicleRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x17\n" +
+	"\x04type\x18\x02 \x01(\tH\x00R\x04type\x88\x01\x01\x12\x1e\n" +
+	"\bfleet_id\x18\x03 \x01(\tH\x01R\afleetId\x88\x01\x01B\a\n" +
+	"\x05_typeB\v\n" +
+	"\t_fleet_id\"I\n" +
+	"\x15UpdateVehicleResponse\x120\n" +
+	"\avehicle\x18\x01 \x01(\v2\x16.simulation.v1.VehicleR\avehicle\"&\n" +
+	"\x14DeleteVehicleRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\x17\n" +
+	"\x15DeleteVehicleResponse\"n\n" +
+	"\x13RequestRouteRequest\x12\x1d\n" +
+	"\n" +
+	"start_node\x18\x01 \x01(\tR\tstartNode\x12\x19\n" +
+	"\bend_node\x18\x02 \x01(\tR\aendNode\x12\x1d\n" +
+	"\n" +
+	"vehicle_id\x18\x03 \x01(\tR\tvehicleId\"\xa0\x01\n" +
+	"\x14RequestRouteResponse\x12*\n" +
+	"\x05route\x18\x01 \x01(\v2\x14.simulation.v1.RouteR\x05route\x12@\n" +
+	"\x0eestimated_time\x18\x02 \x01(\v2\x19.google.protobuf.DurationR\restimatedTime\x12\x1a\n" +
+	"\bassigned\x18\x03 \x01(\bR\bassigned\"\xdf\x01\n" +
+	"\x19SubscribeTelemetryRequest\x128\n" +
+	"\astreams\x18\x01 \x03(\x0e2\x1e.simulation.v1.TelemetryStreamR\astreams\x12\x1b\n" +
+	"\tfleet_ids\x18\x02 \x03(\tR\bfleetIds\x12\x1f\n" +
+	"\vvehicle_ids\x18\x03 \x03(\tR\n" +
+	"vehicleIds\x12\x1f\n" +
+	"\vevent_types\x18\x04 \x03(\tR\n" +
+	"eventTypes\x12)\n" +
+	"\x04bbox\x18\x05 \x01(\v2\x15.simulation.v1.BoundsR\x04bbox\"\xb2\x01\n" +
+	"\x1aSubscribeTelemetryResponse\x12;\n" +
+	"\bposition\x18\x01 \x01(\v2\x1d.telemetry.v1.VehiclePositionH\x00R\bposition\x122\n" +
+	"\x05event\x18\x02 \x01(\v2\x1a.telemetry.v1.VehicleEventH\x00R\x05event\x12\x18\n" +
+	"\adropped\x18\x03 \x01(\x04R\adroppedB\t\n" +
+	"\apayload*p\n" +
+	"\x0fTelemetryStream\x12 \n" +
+	"\x1cTELEMETRY_STREAM_UNSPECIFIED\x10\x00\x12\x1e\n" +
+	"\x1aTELEMETRY_STREAM_POSITIONS\x10\x01\x12\x1b\n" +
+	"\x17TELEMETRY_STREAM_EVENTS\x10\x022\xba\b\n" +
+	"\x11SimulationService\x12B\n" +
+	"\x05Start\x12\x1b.simulation.v1.StartRequest\x1a\x1c.simulation.v1.StartResponse\x12?\n" +
+	"\x04Stop\x12\x1a.simulation.v1.StopRequest\x1a\x1b.simulation.v1.StopResponse\x12B\n" +
+	"\x05Pause\x12\x1b.simulation.v1.PauseRequest\x1a\x1c.simulation.v1.PauseResponse\x12E\n" +
+	"\x06Resume\x12\x1c.simulation.v1.ResumeRequest\x1a\x1d.simulation.v1.ResumeResponse\x12?\n" +
+	"\x04Step\x12\x1a.simulation.v1.StepRequest\x1a\x1b.simulation.v1.StepResponse\x12N\n" +
+	"\tGetStatus\x12\x1f.simulation.v1.GetStatusRequest\x1a .simulation.v1.GetStatusResponse\x12Z\n" +
+	"\rCreateVehicle\x12#.simulation.v1.CreateVehicleRequest\x1a$.simulation.v1.CreateVehicleResponse\x12Q\n" +
+	"\n" +
+	"GetVehicle\x12 .simulation.v1.GetVehicleRequest\x1a!.simulation.v1.GetVehicleResponse\x12W\n" +
+	"\fListVehicles\x12\".simulation.v1.ListVehiclesRequest\x1a#.simulation.v1.ListVehiclesResponse\x12Z\n" +
+	"\rUpdateVehicle\x12#.simulation.v1.UpdateVehicleRequest\x1a$.simulation.v1.UpdateVehicleResponse\x12Z\n" +
+	"\rDeleteVehicle\x12#.simulation.v1.DeleteVehicleRequest\x1a$.simulation.v1.DeleteVehicleResponse\x12W\n" +
+	"\fRequestRoute\x12\".simulation.v1.RequestRouteRequest\x1a#.simulation.v1.RequestRouteResponse\x12k\n" +
+	"\x12SubscribeTelemetry\x12(.simulation.v1.SubscribeTelemetryRequest\x1a).simulation.v1.SubscribeTelemetryResponse0\x01BT\n" +
+	"\x1acom.fleetsim.simulation.v1P\x01Z4github.com/m/internal/gen/simulation/v1;simulationv1b\x06proto3"
+
+var (
+	file_simulation_v1_simulation_proto_rawDescOnce sync.Once
+	file_simulation_v1_simulation_proto_rawDescData []byte
+)
+
+func file_simulation_v1_simulation_proto_rawDescGZIP() []byte {
+	file_simulation_v1_simulation_proto_rawDescOnce.Do(func() {
+		file_simulation_v1_simulation_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_simulation_v1_simulation_proto_rawDesc), len(file_simulation_v1_simulation_proto_rawDesc)))
+	})
+	return file_simulation_v1_simulation_proto_rawDescData
+}
+
+var file_simulation_v1_simulation_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_simulation_v1_simulation_proto_msgTypes = make([]protoimpl.MessageInfo, 32)
+var file_simulation_v1_simulation_proto_goTypes = []any{
+	(TelemetryStream)(0),               // 0: simulation.v1.TelemetryStream
+	(*Vector2D)(nil),                   // 1: simulation.v1.Vector2D
+	(*Bounds)(nil),                     // 2: simulation.v1.Bounds
+	(*EngineStatus)(nil),               // 3: simulation.v1.EngineStatus
+	(*AssignedRoute)(nil),              // 4: simulation.v1.AssignedRoute
+	(*Vehicle)(nil),                    // 5: simulation.v1.Vehicle
+	(*Route)(nil),                      // 6: simulation.v1.Route
+	(*StartRequest)(nil),               // 7: simulation.v1.StartRequest
+	(*StartResponse)(nil),              // 8: simulation.v1.StartResponse
+	(*StopRequest)(nil),                // 9: simulation.v1.StopRequest
+	(*StopResponse)(nil),               // 10: simulation.v1.StopResponse
+	(*PauseRequest)(nil),               // 11: simulation.v1.PauseRequest
+	(*PauseResponse)(nil),              // 12: simulation.v1.PauseResponse
+	(*ResumeRequest)(nil),              // 13: simulation.v1.ResumeRequest
+	(*ResumeResponse)(nil),             // 14: simulation.v1.ResumeResponse
+	(*StepRequest)(nil),                // 15: simulation.v1.StepRequest
+	(*StepResponse)(nil),               // 16: simulation.v1.StepResponse
+	(*GetStatusRequest)(nil),           // 17: simulation.v1.GetStatusRequest
+	(*GetStatusResponse)(nil),          // 18: simulation.v1.GetStatusResponse
+	(*CreateVehicleRequest)(nil),       // 19: simulation.v1.CreateVehicleRequest
+	(*CreateVehicleResponse)(nil),      // 20: simulation.v1.CreateVehicleResponse
+	(*GetVehicleRequest)(nil),          // 21: simulation.v1.GetVehicleRequest
+	(*GetVehicleResponse)(nil),         // 22: simulation.v1.GetVehicleResponse
+	(*ListVehiclesRequest)(nil),        // 23: simulation.v1.ListVehiclesRequest
+	(*ListVehiclesResponse)(nil),       // 24: simulation.v1.ListVehiclesResponse
+	(*UpdateVehicleRequest)(nil),       // 25: simulation.v1.UpdateVehicleRequest
+	(*UpdateVehicleResponse)(nil),      // 26: simulation.v1.UpdateVehicleResponse
+	(*DeleteVehicleRequest)(nil),       // 27: simulation.v1.DeleteVehicleRequest
+	(*DeleteVehicleResponse)(nil),      // 28: simulation.v1.DeleteVehicleResponse
+	(*RequestRouteRequest)(nil),        // 29: simulation.v1.RequestRouteRequest
+	(*RequestRouteResponse)(nil),       // 30: simulation.v1.RequestRouteResponse
+	(*SubscribeTelemetryRequest)(nil),  // 31: simulation.v1.SubscribeTelemetryRequest
+	(*SubscribeTelemetryResponse)(nil), // 32: simulation.v1.SubscribeTelemetryResponse
+	(*durationpb.Duration)(nil),        // 33: google.protobuf.Duration
+	(*timestamppb.Timestamp)(nil),      // 34: google.protobuf.Timestamp
+	(*v1.VehiclePosition)(nil),         // 35: telemetry.v1.VehiclePosition
+	(*v1.VehicleEvent)(nil),            // 36: telemetry.v1.VehicleEvent
+}
+var file_simulation_v1_simulation_proto_depIdxs = []int32{
+	33, // 0: simulation.v1.EngineStatus.update_rate:type_name -> google.protobuf.Duration
+	34, // 1: simulation.v1.AssignedRoute.started_at:type_name -> google.protobuf.Timestamp
+	34, // 2: simulation.v1.AssignedRoute.completed_at:type_name -> google.protobuf.Timestamp
+	1,  // 3: simulation.v1.Vehicle.position:type_name -> simulation.v1.Vector2D
+	1,  // 4: simulation.v1.Vehicle.velocity:type_name -> simulation.v1.Vector2D
+	4,  // 5: simulation.v1.Vehicle.route:type_name -> simulation.v1.AssignedRoute
+	34, // 6: simulation.v1.Vehicle.last_update_time:type_name -> google.protobuf.Timestamp
+	3,  // 7: simulation.v1.StartResponse.status:type_name -> simulation.v1.EngineStatus
+	3,  // 8: simulation.v1.StopResponse.status:type_name -> simulation.v1.EngineStatus
+	3,  // 9: simulation.v1.PauseResponse.status:type_name -> simulation.v1.EngineStatus
+	3,  // 10: simulation.v1.ResumeResponse.status:type_name -> simulation.v1.EngineStatus
+	33, // 11: simulation.v1.StepRequest.dt:type_name -> google.protobuf.Duration
+	3,  // 12: simulation.v1.StepResponse.status:type_name -> simulation.v1.EngineStatus
+	3,  // 13: simulation.v1.GetStatusResponse.status:type_name -> simulation.v1.EngineStatus
+	5,  // 14: simulation.v1.CreateVehicleResponse.vehicle:type_name -> simulation.v1.Vehicle
+	5,  // 15: simulation.v1.GetVehicleResponse.vehicle:type_name -> simulation.v1.Vehicle
+	5,  // 16: simulation.v1.ListVehiclesResponse.vehicles:type_name -> simulation.v1.Vehicle
+	5,  // 17: simulation.v1.UpdateVehicleResponse.vehicle:type_name -> simulation.v1.Vehicle
+	6,  // 18: simulation.v1.RequestRouteResponse.route:type_name -> simulation.v1.Route
+	33, // 19: simulation.v1.RequestRouteResponse.estimated_time:type_name -> google.protobuf.Duration
+	0,  // 20: simulation.v1.SubscribeTelemetryRequest.streams:type_name -> simulation.v1.TelemetryStream
+	2,  // 21: simulation.v1.SubscribeTelemetryRequest.bbox:type_name -> simulation.v1.Bounds
+	35, // 22: simulation.v1.SubscribeTelemetryResponse.position:type_name -> telemetry.v1.VehiclePosition
+	36, // 23: simulation.v1.SubscribeTelemetryResponse.event:type_name -> telemetry.v1.VehicleEvent
+	7,  // 24: simulation.v1.SimulationService.Start:input_type -> simulation.v1.StartRequest
+	9,  // 25: simulation.v1.SimulationService.Stop:input_type -> simulation.v1.StopRequest
+	11, // 26: simulation.v1.SimulationService.Pause:input_type -> simulation.v1.PauseRequest
+	13, // 27: simulation.v1.SimulationService.Resume:input_type -> simulation.v1.ResumeRequest
+	15, // 28: simulation.v1.SimulationService.Step:input_type -> simulation.v1.StepRequest
+	17, // 29: simulation.v1.SimulationService.GetStatus:input_type -> simulation.v1.GetStatusRequest
+	19, // 30: simulation.v1.SimulationService.CreateVehicle:input_type -> simulation.v1.CreateVehicleRequest
+	21, // 31: simulation.v1.SimulationService.GetVehicle:input_type -> simulation.v1.GetVehicleRequest
+	23, // 32: simulation.v1.SimulationService.ListVehicles:input_type -> simulation.v1.ListVehiclesRequest
+	25, // 33: simulation.v1.SimulationService.UpdateVehicle:input_type -> simulation.v1.UpdateVehicleRequest
+	27, // 34: simulation.v1.SimulationService.DeleteVehicle:input_type -> simulation.v1.DeleteVehicleRequest
+	29, // 35: simulation.v1.SimulationService.RequestRoute:input_type -> simulation.v1.RequestRouteRequest
+	31, // 36: simulation.v1.SimulationService.SubscribeTelemetry:input_type -> simulation.v1.SubscribeTelemetryRequest
+	8,  // 37: simulation.v1.SimulationService.Start:output_type -> simulation.v1.StartResponse
+	10, // 38: simulation.v1.SimulationService.Stop:output_type -> simulation.v1.StopResponse
+	12, // 39: simulation.v1.SimulationService.Pause:output_type -> simulation.v1.PauseResponse
+	14, // 40: simulation.v1.SimulationService.Resume:output_type -> simulation.v1.ResumeResponse
+	16, // 41: simulation.v1.SimulationService.Step:output_type -> simulation.v1.StepResponse
+	18, // 42: simulation.v1.SimulationService.GetStatus:output_type -> simulation.v1.GetStatusResponse
+	20, // 43: simulation.v1.SimulationService.CreateVehicle:output_type -> simulation.v1.CreateVehicleResponse
+	22, // 44: simulation.v1.SimulationService.GetVehicle:output_type -> simulation.v1.GetVehicleResponse
+	24, // 45: simulation.v1.SimulationService.ListVehicles:output_type -> simulation.v1.ListVehiclesResponse
+	26, // 46: simulation.v1.SimulationService.UpdateVehicle:output_type -> simulation.v1.UpdateVehicleResponse
+	28, // 47: simulation.v1.SimulationService.DeleteVehicle:output_type -> simulation.v1.DeleteVehicleResponse
+	30, // 48: simulation.v1.SimulationService.RequestRoute:output_type -> simulation.v1.RequestRouteResponse
+	32, // 49: simulation.v1.SimulationService.SubscribeTelemetry:output_type -> simulation.v1.SubscribeTelemetryResponse
+	37, // [37:50] is the sub-list for method output_type
+	24, // [24:37] is the sub-list for method input_type
+	24, // [24:24] is the sub-list for extension type_name
+	24, // [24:24] is the sub-list for extension extendee
+	0,  // [0:24] is the sub-list for field type_name
+}
+
+func init() { file_simulation_v1_simulation_proto_init() }
+func file_simulation_v1_simulation_proto_init() {
+	if File_simulation_v1_simulation_proto != nil {
+		return
+	}
+	file_simulation_v1_simulation_proto_msgTypes[24].OneofWrappers = []any{}
+	file_simulation_v1_simulation_proto_msgTypes[31].OneofWrappers = []any{
+		(*SubscribeTelemetryResponse_Position)(nil),
+		(*SubscribeTelemetryResponse_Event)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_simulation_v1_simulation_proto_rawDesc), len(file_simulation_v1_simulation_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   32,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_simulation_v1_simulation_proto_goTypes,
+		DependencyIndexes: file_simulation_v1_simulation_proto_depIdxs,
+		EnumInfos:         file_simulation_v1_simulation_proto_enumTypes,
+		MessageInfos:      file_simulation_v1_simulation_proto_msgTypes,
+	}.Build()
+	File_simulation_v1_simulation_proto = out.File
+	file_simulation_v1_simulation_proto_goTypes = nil
+	file_simulation_v1_simulation_proto_depIdxs = nil
+}
@@ -0,0 +1,596 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: telemetry/v1/telemetry.proto
+
+package telemetryv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Severity int32
+
+const (
+	Severity_SEVERITY_UNSPECIFIED Severity = 0
+	Severity_SEVERITY_INFO        Severity = 1
+	Severity_SEVERITY_WARNING     Severity = 2
+	Severity_SEVERITY_CRITICAL    Severity = 3
+)
+
+// Enum value maps for Severity.
+var (
+	Severity_name = map[int32]string{
+		0: "SEVERITY_UNSPECIFIED",
+		1: "SEVERITY_INFO",
+		2: "SEVERITY_WARNING",
+		3: "SEVERITY_CRITICAL",
+	}
+	Severity_value = map[string]int32{
+		"SEVERITY_UNSPECIFIED": 0,
+		"SEVERITY_INFO":        1,
+		"SEVERITY_WARNING":     2,
+		"SEVERITY_CRITICAL":    3,
+	}
+)
+
+func (x Severity) Enum() *Severity {
+	p := new(Severity)
+	*p = x
+	return p
+}
+
+func (x Severity) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Severity) Descriptor() protoreflect.EnumDescriptor {
+	return file_telemetry_v1_telemetry_proto_enumTypes[0].Descriptor()
+}
+
+func (Severity) Type() protoreflect.EnumType {
+	return &file_telemetry_v1_telemetry_proto_enumTypes[0]
+}
+
+func (x Severity) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Severity.Descriptor instead.
+func (Severity) EnumDescriptor() ([]byte, []int) {
+	return file_telemetry_v1_telemetry_proto_rawDescGZIP(), []int{0}
+}
+
+type Vector2D struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	X             float64                `protobuf:"fixed64,1,opt,name=x,proto3" json:"x,omitempty"`
+	Y             float64                `protobuf:"fixed64,2,opt,name=y,proto3" json:"y,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Vector2D) Reset() {
+	*x = Vector2D{}
+	mi := &file_telemetry_v1_telemetry_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Vector2D) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Vector2D) ProtoMessage() {}
+
+func (x *Vector2D) ProtoReflect() protoreflect.Message {
+	mi := &file_telemetry_v1_telemetry_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Vector2D.ProtoReflect.Descriptor instead.
+func (*Vector2D) Descriptor() ([]byte, []int) {
+	return file_telemetry_v1_telemetry_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Vector2D) GetX() float64 {
+	if x != nil {
+		return x.X
+	}
+	return 0
+}
+
+func (x *Vector2D) GetY() float64 {
+	if x != nil {
+		return x.Y
+	}
+	return 0
+}
+
+type VehiclePosition struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SchemaVersion uint32                 `protobuf:"varint,1,opt,name=schema_version,json=schemaVersion,proto3" json:"schema_version,omitempty"`
+	RunId         string                 `protobuf:"bytes,2,opt,name=run_id,json=runId,proto3" json:"run_id,omitempty"`
+	FleetId       string                 `protobuf:"bytes,3,opt,name=fleet_id,json=fleetId,proto3" json:"fleet_id,omitempty"`
+	VehicleId     string                 `protobuf:"bytes,4,opt,name=vehicle_id,json=vehicleId,proto3" json:"vehicle_id,omitempty"`
+	// Monotonic per vehicle across positions and events within a run.
+	Sequence      uint64                 `protobuf:"varint,5,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	EdgeId        string                 `protobuf:"bytes,6,opt,name=edge_id,json=edgeId,proto3" json:"edge_id,omitempty"`
+	FromNodeId    string                 `protobuf:"bytes,7,opt,name=from_node_id,json=fromNodeId,proto3" json:"from_node_id,omitempty"`
+	Progress      float64                `protobuf:"fixed64,8,opt,name=progress,proto3" json:"progress,omitempty"`
+	Position      *Vector2D              `protobuf:"bytes,9,opt,name=position,proto3" json:"position,omitempty"`
+	Timestamp     *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VehiclePosition) Reset() {
+	*x = VehiclePosition{}
+	mi := &file_telemetry_v1_telemetry_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VehiclePosition) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VehiclePosition) ProtoMessage() {}
+
+func (x *VehiclePosition) ProtoReflect() protoreflect.Message {
+	mi := &file_telemetry_v1_telemetry_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VehiclePosition.ProtoReflect.Descriptor instead.
+func (*VehiclePosition) Descriptor() ([]byte, []int) {
+	return file_telemetry_v1_telemetry_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *VehiclePosition) GetSchemaVersion() uint32 {
+	if x != nil {
+		return x.SchemaVersion
+	}
+	return 0
+}
+
+func (x *VehiclePosition) GetRunId() string {
+	if x != nil {
+		return x.RunId
+	}
+	return ""
+}
+
+func (x *VehiclePosition) GetFleetId() string {
+	if x != nil {
+		return x.FleetId
+	}
+	return ""
+}
+
+func (x *VehiclePosition) GetVehicleId() string {
+	if x != nil {
+		return x.VehicleId
+	}
+	return ""
+}
+
+func (x *VehiclePosition) GetSequence() uint64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+func (x *VehiclePosition) GetEdgeId() string {
+	if x != nil {
+		return x.EdgeId
+	}
+	return ""
+}
+
+func (x *VehiclePosition) GetFromNodeId() string {
+	if x != nil {
+		return x.FromNodeId
+	}
+	return ""
+}
+
+func (x *VehiclePosition) GetProgress() float64 {
+	if x != nil {
+		return x.Progress
+	}
+	return 0
+}
+
+func (x *VehiclePosition) GetPosition() *Vector2D {
+	if x != nil {
+		return x.Position
+	}
+	return nil
+}
+
+func (x *VehiclePosition) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+type VehicleEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SchemaVersion uint32                 `protobuf:"varint,1,opt,name=schema_version,json=schemaVersion,proto3" json:"schema_version,omitempty"`
+	RunId         string                 `protobuf:"bytes,2,opt,name=run_id,json=runId,proto3" json:"run_id,omitempty"`
+	FleetId       string                 `protobuf:"bytes,3,opt,name=fleet_id,json=fleetId,proto3" json:"fleet_id,omitempty"`
+	VehicleId     string                 `protobuf:"bytes,4,opt,name=vehicle_id,json=vehicleId,proto3" json:"vehicle_id,omitempty"`
+	Sequence      uint64                 `protobuf:"varint,5,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	// Open set, e.g. "route_started", "route_completed", "energy_low".
+	EventType     string                 `protobuf:"bytes,6,opt,name=event_type,json=eventType,proto3" json:"event_type,omitempty"`
+	Severity      Severity               `protobuf:"varint,7,opt,name=severity,proto3,enum=telemetry.v1.Severity" json:"severity,omitempty"`
+	Timestamp     *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Data          *structpb.Struct       `protobuf:"bytes,9,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VehicleEvent) Reset() {
+	*x = VehicleEvent{}
+	mi := &file_telemetry_v1_telemetry_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VehicleEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VehicleEvent) ProtoMessage() {}
+
+func (x *VehicleEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_telemetry_v1_telemetry_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VehicleEvent.ProtoReflect.Descriptor instead.
+func (*VehicleEvent) Descriptor() ([]byte, []int) {
+	return file_telemetry_v1_telemetry_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *VehicleEvent) GetSchemaVersion() uint32 {
+	if x != nil {
+		return x.SchemaVersion
+	}
+	return 0
+}
+
+func (x *VehicleEvent) GetRunId() string {
+	if x != nil {
+		return x.RunId
+	}
+	return ""
+}
+
+func (x *VehicleEvent) GetFleetId() string {
+	if x != nil {
+		return x.FleetId
+	}
+	return ""
+}
+
+func (x *VehicleEvent) GetVehicleId() string {
+	if x != nil {
+		return x.VehicleId
+	}
+	return ""
+}
+
+func (x *VehicleEvent) GetSequence() uint64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+func (x *VehicleEvent) GetEventType() string {
+	if x != nil {
+		return x.EventType
+	}
+	return ""
+}
+
+func (x *VehicleEvent) GetSeverity() Severity {
+	if x != nil {
+		return x.Severity
+	}
+	return Severity_SEVERITY_UNSPECIFIED
+}
+
+func (x *VehicleEvent) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *VehicleEvent) GetData() *structpb.Struct {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type TelemetryEnvelope struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Payload:
+	//
+	//	*TelemetryEnvelope_Position
+	//	*TelemetryEnvelope_Event
+	Payload       isTelemetryEnvelope_Payload `protobuf_oneof:"payload"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TelemetryEnvelope) Reset() {
+	*x = TelemetryEnvelope{}
+	mi := &file_telemetry_v1_telemetry_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TelemetryEnvelope) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TelemetryEnvelope) ProtoMessage() {}
+
+func (x *TelemetryEnvelope) ProtoReflect() protoreflect.Message {
+	mi := &file_telemetry_v1_telemetry_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TelemetryEnvelope.ProtoReflect.Descriptor instead.
+func (*TelemetryEnvelope) Descriptor() ([]byte, []int) {
+	return file_telemetry_v1_telemetry_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *TelemetryEnvelope) GetPayload() isTelemetryEnvelope_Payload {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *TelemetryEnvelope) GetPosition() *VehiclePosition {
+	if x != nil {
+		if x, ok := x.Payload.(*TelemetryEnvelope_Position); ok {
+			return x.Position
+		}
+	}
+	return nil
+}
+
+func (x *TelemetryEnvelope) GetEvent() *VehicleEvent {
+	if x != nil {
+		if x, ok := x.Payload.(*TelemetryEnvelope_Event); ok {
+			return x.Event
+		}
+	}
+	return nil
+}
+
+type isTelemetryEnvelope_Payload interface {
+	isTelemetryEnvelope_Payload()
+}
+
+type TelemetryEnvelope_Position struct {
+	Position *VehiclePosition `protobuf:"bytes,1,opt,name=position,proto3,oneof"`
+}
+
+type TelemetryEnvelope_Event struct {
+	Event *VehicleEvent `protobuf:"bytes,2,opt,name=event,proto3,oneof"`
+}
+
+func (*TelemetryEnvelope_Position) isTelemetryEnvelope_Payload() {}
+
+func (*TelemetryEnvelope_Event) isTelemetryEnvelope_Payload() {}
+
+type TelemetryBatch struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Items         []*TelemetryEnvelope   `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TelemetryBatch) Reset() {
+	*x = TelemetryBatch{}
+	mi := &file_telemetry_v1_telemetry_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TelemetryBatch) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TelemetryBatch) ProtoMessage() {}
+
+func (x *TelemetryBatch) ProtoReflect() protoreflect.Message {
+	mi := &file_telemetry_v1_telemetry_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TelemetryBatch.ProtoReflect.Descriptor instead.
+func (*TelemetryBatch) Descriptor() ([]byte, []int) {
+	return file_telemetry_v1_telemetry_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *TelemetryBatch) GetItems() []*TelemetryEnvelope {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+var File_telemetry_v1_telemetry_proto protoreflect.FileDescriptor
+
+const file_telemetry_v1_telemetry_proto_rawDesc = "" +
+	"\n" +
+	"\x1ctelemetry/v1/telemetry.proto\x12\ftelemetry.v1\x1a\x1cgoogle/protobuf/struct.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"&\n" +
+	"\bVector2D\x12\f\n" +
+	"\x01x\x18\x01 \x01(\x01R\x01x\x12\f\n" +
+	"\x01y\x18\x02 \x01(\x01R\x01y\"\xea\x02\n" +
+	"\x0fVehiclePosition\x12%\n" +
+	"\x0eschema_version\x18\x01 \x01(\rR\rschemaVersion\x12\x15\n" +
+	"\x06run_id\x18\x02 \x01(\tR\x05runId\x12\x19\n" +
+	"\bfleet_id\x18\x03 \x01(\tR\afleetId\x12\x1d\n" +
+	"\n" +
+	"vehicle_id\x18\x04 \x01(\tR\tvehicleId\x12\x1a\n" +
+	"\bsequence\x18\x05 \x01(\x04R\bsequence\x12\x17\n" +
+	"\aedge_id\x18\x06 \x01(\tR\x06edgeId\x12 \n" +
+	"\ffrom_node_id\x18\a \x01(\tR\n" +
+	"fromNodeId\x12\x1a\n" +
+	"\bprogress\x18\b \x01(\x01R\bprogress\x122\n" +
+	"\bposition\x18\t \x01(\v2\x16.telemetry.v1.Vector2DR\bposition\x128\n" +
+	"\ttimestamp\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\"\xdc\x02\n" +
+	"\fVehicleEvent\x12%\n" +
+	"\x0eschema_version\x18\x01 \x01(\rR\rschemaVersion\x12\x15\n" +
+	"\x06run_id\x18\x02 \x01(\tR\x05runId\x12\x19\n" +
+	"\bfleet_id\x18\x03 \x01(\tR\afleetId\x12\x1d\n" +
+	"\n" +
+	"vehicle_id\x18\x04 \x01(\tR\tvehicleId\x12\x1a\n" +
+	"\bsequence\x18\x05 \x01(\x04R\bsequence\x12\x1d\n" +
+	"\n" +
+	"event_type\x18\x06 \x01(\tR\teventType\x122\n" +
+	"\bseverity\x18\a \x01(\x0e2\x16.telemetry.v1.SeverityR\bseverity\x128\n" +
+	"\ttimestamp\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x12+\n" +
+	"\x04data\x18\t \x01(\v2\x17.google.protobuf.StructR\x04data\"\x8f\x01\n" +
+	"\x11TelemetryEnvelope\x12;\n" +
+	"\bposition\x18\x01 \x01(\v2\x1d.telemetry.v1.VehiclePositionH\x00R\bposition\x122\n" +
+	"\x05event\x18\x02 \x01(\v2\x1a.telemetry.v1.VehicleEventH\x00R\x05eventB\t\n" +
+	"\apayload\"G\n" +
+	"\x0eTelemetryBatch\x125\n" +
+	"\x05items\x18\x01 \x03(\v2\x1f.telemetry.v1.TelemetryEnvelopeR\x05items*d\n" +
+	"\bSeverity\x12\x18\n" +
+	"\x14SEVERITY_UNSPECIFIED\x10\x00\x12\x11\n" +
+	"\rSEVERITY_INFO\x10\x01\x12\x14\n" +
+	"\x10SEVERITY_WARNING\x10\x02\x12\x15\n" +
+	"\x11SEVERITY_CRITICAL\x10\x03BQ\n" +
+	"\x19com.fleetsim.telemetry.v1P\x01Z2github.com/m/internal/gen/telemetry/v1;telemetryv1b\x06proto3"
+
+var (
+	file_telemetry_v1_telemetry_proto_rawDescOnce sync.Once
+	file_telemetry_v1_telemetry_proto_rawDescData []byte
+)
+
+func file_telemetry_v1_telemetry_proto_rawDescGZIP() []byte {
+	file_telemetry_v1_telemetry_proto_rawDescOnce.Do(func() {
+		file_telemetry_v1_telemetry_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_telemetry_v1_telemetry_proto_rawDesc), len(file_telemetry_v1_telemetry_proto_rawDesc)))
+	})
+	return file_telemetry_v1_telemetry_proto_rawDescData
+}
+
+var file_telemetry_v1_telemetry_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_telemetry_v1_telemetry_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_telemetry_v1_telemetry_proto_goTypes = []any{
+	(Severity)(0),                 // 0: telemetry.v1.Severity
+	(*Vector2D)(nil),              // 1: telemetry.v1.Vector2D
+	(*VehiclePosition)(nil),       // 2: telemetry.v1.VehiclePosition
+	(*VehicleEvent)(nil),          // 3: telemetry.v1.VehicleEvent
+	(*TelemetryEnvelope)(nil),     // 4: telemetry.v1.TelemetryEnvelope
+	(*TelemetryBatch)(nil),        // 5: telemetry.v1.TelemetryBatch
+	(*timestamppb.Timestamp)(nil), // 6: google.protobuf.Timestamp
+	(*structpb.Struct)(nil),       // 7: google.protobuf.Struct
+}
+var file_telemetry_v1_telemetry_proto_depIdxs = []int32{
+	1, // 0: telemetry.v1.VehiclePosition.position:type_name -> telemetry.v1.Vector2D
+	6, // 1: telemetry.v1.VehiclePosition.timestamp:type_name -> google.protobuf.Timestamp
+	0, // 2: telemetry.v1.VehicleEvent.severity:type_name -> telemetry.v1.Severity
+	6, // 3: telemetry.v1.VehicleEvent.timestamp:type_name -> google.protobuf.Timestamp
+	7, // 4: telemetry.v1.VehicleEvent.data:type_name -> google.protobuf.Struct
+	2, // 5: telemetry.v1.TelemetryEnvelope.position:type_name -> telemetry.v1.VehiclePosition
+	3, // 6: telemetry.v1.TelemetryEnvelope.event:type_name -> telemetry.v1.VehicleEvent
+	4, // 7: telemetry.v1.TelemetryBatch.items:type_name -> telemetry.v1.TelemetryEnvelope
+	8, // [8:8] is the sub-list for method output_type
+	8, // [8:8] is the sub-list for method input_type
+	8, // [8:8] is the sub-list for extension type_name
+	8, // [8:8] is the sub-list for extension extendee
+	0, // [0:8] is the sub-list for field type_name
+}
+
+func init() { file_telemetry_v1_telemetry_proto_init() }
+func file_telemetry_v1_telemetry_proto_init() {
+	if File_telemetry_v1_telemetry_proto != nil {
+		return
+	}
+	file_telemetry_v1_telemetry_proto_msgTypes[3].OneofWrappers = []any{
+		(*TelemetryEnvelope_Position)(nil),
+		(*TelemetryEnvelope_Event)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_telemetry_v1_telemetry_proto_rawDesc), len(file_telemetry_v1_telemetry_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   5,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_telemetry_v1_telemetry_proto_goTypes,
+		DependencyIndexes: file_telemetry_v1_telemetry_proto_depIdxs,
+		EnumInfos:         file_telemetry_v1_telemetry_proto_enumTypes,
+		MessageInfos:      file_telemetry_v1_telemetry_proto_msgTypes,
+	}.Build()
+	File_telemetry_v1_telemetry_proto = out.File
+	file_telemetry_v1_telemetry_proto_goTypes = nil
+	file_telemetry_v1_telemetry_proto_depIdxs = nil
+}
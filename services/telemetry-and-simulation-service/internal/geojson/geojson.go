@@ -0,0 +1,320 @@
+package geojson
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/m/internal/simulation/entities"
+)
+
+const ContentType = "application/geo+json"
+
+// FeatureCollection is a GeoJSON document. With a Georef, coordinates are
+// longitude and latitude and Georef records how they map back onto the
+// plane; without one they are the planar map coordinates, which GIS tools
+// can show as a local, unprojected layer, and Planar is set.
+type FeatureCollection struct {
+	Type          string           `json:"type"`
+	Features      []Feature        `json:"features"`
+	Georef        *entities.Georef `json:"georef,omitempty"`
+	Planar        bool             `json:"planar,omitempty"`
+	FastestRoutes bool             `json:"fastest_routes,omitempty"`
+}
+
+type Feature struct {
+	Type string `json:"type"`
+	// ID is a string on export; other tools may write numbers.
+	ID       any      `json:"id,omitempty"`
+	Geometry Geometry `json:"geometry"`
+	// Properties are the fields of the node, edge, route or vehicle, plus
+	// a kind naming which it is, so each can go on its own layer.
+	Properties json.RawMessage `json:"properties"`
+}
+
+// Geometry is a Point, whose Coordinates are one position, or a
+// LineString, whose Coordinates are a list of them.
+type Geometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+type Position [2]float64
+
+type NodeProperties struct {
+	Kind         string                     `json:"kind"`
+	ID           string                     `json:"id"`
+	Type         entities.NodeType          `json:"type,omitempty"`
+	Zone         entities.ZoneType          `json:"zone,omitempty"`
+	Production   float64                    `json:"production,omitempty"`
+	Attraction   float64                    `json:"attraction,omitempty"`
+	Facility     *entities.NodeFacility     `json:"facility,omitempty"`
+	Restrictions []entities.TurnRestriction `json:"restrictions,omitempty"`
+}
+
+type EdgeProperties struct {
+	Kind           string             `json:"kind"`
+	ID             string             `json:"id"`
+	From           string             `json:"from"`
+	To             string             `json:"to"`
+	Length         float64            `json:"length"`
+	BaseSpeedLimit float64            `json:"base_speed_limit"`
+	SurfaceQuality float64            `json:"surface_quality"`
+	Bidirectional  *bool              `json:"bidirectional"`
+	Class          entities.RoadClass `json:"class,omitempty"`
+	Lanes          int                `json:"lanes,omitempty"`
+	Capacity       float64            `json:"capacity,omitempty"`
+}
+
+type RouteProperties struct {
+	Kind          string   `json:"kind"`
+	VehicleID     string   `json:"vehicle_id,omitempty"`
+	StartNode     string   `json:"start_node"`
+	EndNode       string   `json:"end_node"`
+	Edges         []string `json:"edges"`
+	TotalDistance float64  `json:"total_distance"`
+}
+
+type VehicleProperties struct {
+	Kind           string                 `json:"kind"`
+	ID             string                 `json:"id"`
+	Type           entities.VehicleType   `json:"type,omitempty"`
+	FleetID        string                 `json:"fleet_id,omitempty"`
+	Status         entities.VehicleStatus `json:"status"`
+	CurrentEdge    string                 `json:"current_edge,omitempty"`
+	ProgressOnEdge float64                `json:"progress_on_edge"`
+	Speed          float64                `json:"speed"`
+	// Heading is in degrees clockwise from north, for icon rotation; it is
+	// left out while the vehicle stands still.
+	Heading    *float64  `json:"heading,omitempty"`
+	TargetNode string    `json:"target_node,omitempty"`
+	Energy     *float64  `json:"energy_level,omitempty"`
+	UpdatedAt  time.Time `json:"last_update_time"`
+}
+
+const (
+	KindNode    = "node"
+	KindEdge    = "edge"
+	KindRoute   = "route"
+	KindVehicle = "vehicle"
+)
+
+func newCollection(ref *entities.Georef) *FeatureCollection {
+	return &FeatureCollection{Type: "FeatureCollection", Features: []Feature{}, Georef: ref, Planar: ref == nil}
+}
+
+func position(ref *entities.Georef, p entities.Vector2D) Position {
+	if ref == nil {
+		return Position{p.X, p.Y}
+	}
+	lon, lat := ref.LonLat(p)
+	return Position{lon, lat}
+}
+
+func (fc *FeatureCollection) add(id, geometry string, coordinates, properties any) error {
+	coords, err := json.Marshal(coordinates)
+	if err != nil {
+		return err
+	}
+	props, err := json.Marshal(properties)
+	if err != nil {
+		return err
+	}
+	fc.Features = append(fc.Features, Feature{
+		Type:       "Feature",
+		ID:         id,
+		Geometry:   Geometry{Type: geometry, Coordinates: coords},
+		Properties: props,
+	})
+	return nil
+}
+
+// Map exports the nodes of the graph as Points and its edges as
+// LineStrings through their geometry, sorted by ID. A nil ref uses the
+// graph's own Georef.
+func Map(g *entities.MapGraph, ref *entities.Georef) (*FeatureCollection, error) {
+	if ref == nil {
+		ref = g.Georef
+	}
+	fc := newCollection(ref)
+	fc.FastestRoutes = g.FastestRoutes
+
+	for _, id := range sortedKeys(g.Nodes) {
+		n := g.Nodes[id]
+		err := fc.add(id, "Point", position(ref, n.Position), NodeProperties{
+			Kind:         KindNode,
+			ID:           n.ID,
+			Type:         n.Type,
+			Zone:         n.Zone,
+			Production:   n.Production,
+			Attraction:   n.Attraction,
+			Facility:     n.Facility,
+			Restrictions: n.Restrictions,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, id := range sortedKeys(g.Edges) {
+		e := g.Edges[id]
+		line, err := edgeLine(g, e, ref)
+		if err != nil {
+			return nil, err
+		}
+		bidirectional := e.Bidirectional
+		err = fc.add(id, "LineString", line, EdgeProperties{
+			Kind:           KindEdge,
+			ID:             e.ID,
+			From:           e.From,
+			To:             e.To,
+			Length:         e.Length,
+			BaseSpeedLimit: e.BaseSpeedLimit,
+			SurfaceQuality: e.SurfaceQuality,
+			Bidirectional:  &bidirectional,
+			Class:          e.Class,
+			Lanes:          e.Lanes,
+			Capacity:       e.Capacity,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return fc, nil
+}
+
+// edgeLine runs from the edge's From node through its geometry to To.
+func edgeLine(g *entities.MapGraph, e *entities.MapEdge, ref *entities.Georef) ([]Position, error) {
+	from, to := g.Nodes[e.From], g.Nodes[e.To]
+	if from == nil || to == nil {
+		return nil, fmt.Errorf("edge %s: missing node", e.ID)
+	}
+	line := make([]Position, 0, len(e.Geometry)+2)
+	line = append(line, position(ref, from.Position))
+	for _, p := range e.Geometry {
+		line = append(line, position(ref, p))
+	}
+	return append(line, position(ref, to.Position)), nil
+}
+
+// Routes exports each route as a LineString in the direction it is
+// driven.
+func Routes(g *entities.MapGraph, routes []*entities.Route, ref *entities.Georef) (*FeatureCollection, error) {
+	if ref == nil {
+		ref = g.Georef
+	}
+	fc := newCollection(ref)
+	for i, r := range routes {
+		if err := addRoute(fc, g, ref, fmt.Sprintf("route-%d", i), RouteProperties{
+			Kind:          KindRoute,
+			StartNode:     r.StartNode,
+			EndNode:       r.EndNode,
+			Edges:         r.Edges,
+			TotalDistance: r.TotalDistance,
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return fc, nil
+}
+
+// VehicleRoutes exports the route each vehicle is assigned, skipping
+// vehicles without one.
+func VehicleRoutes(g *entities.MapGraph, vehicles []*entities.Vehicle, ref *entities.Georef) (*FeatureCollection, error) {
+	if ref == nil {
+		ref = g.Georef
+	}
+	fc := newCollection(ref)
+	for _, v := range vehicles {
+		v.Mutex.Lock()
+		var props RouteProperties
+		if v.Route != nil {
+			props = RouteProperties{
+				Kind:      KindRoute,
+				VehicleID: v.ID,
+				StartNode: v.Route.StartNode,
+				EndNode:   v.Route.EndNode,
+				Edges:     append([]string(nil), v.Route.Edges...),
+			}
+		}
+		v.Mutex.Unlock()
+		if props.Kind == "" {
+			continue
+		}
+		for _, id := range props.Edges {
+			if e := g.Edges[id]; e != nil {
+				props.TotalDistance += e.Length
+			}
+		}
+		if err := addRoute(fc, g, ref, v.ID, props); err != nil {
+			return nil, err
+		}
+	}
+	return fc, nil
+}
+
+func addRoute(fc *FeatureCollection, g *entities.MapGraph, ref *entities.Georef, id string, props RouteProperties) error {
+	line := []Position{}
+	node := props.StartNode
+	for _, edgeID := range props.Edges {
+		e := g.Edges[edgeID]
+		if e == nil {
+			return fmt.Errorf("route %s: edge %s not found", id, edgeID)
+		}
+		part, err := edgeLine(g, e, ref)
+		if err != nil {
+			return err
+		}
+		if e.From != node && e.To == node {
+			for i, j := 0, len(part)-1; i < j; i, j = i+1, j-1 {
+				part[i], part[j] = part[j], part[i]
+			}
+			node = e.From
+		} else {
+			node = e.To
+		}
+		if len(line) > 0 {
+			part = part[1:]
+		}
+		line = append(line, part...)
+	}
+	return fc.add(id, "LineString", line, props)
+}
+
+// Vehicles exports where each vehicle is now as a Point.
+func Vehicles(vehicles []*entities.Vehicle, ref *entities.Georef) (*FeatureCollection, error) {
+	fc := newCollection(ref)
+	for _, v := range vehicles {
+		v.Mutex.Lock()
+		props := VehicleProperties{
+			Kind:           KindVehicle,
+			ID:             v.ID,
+			Type:           v.Type,
+			FleetID:        v.AssignedFleetID,
+			Status:         v.State.Status,
+			CurrentEdge:    v.State.CurrentEdge,
+			ProgressOnEdge: v.State.ProgressOnEdge,
+			Speed:          math.Hypot(v.State.Velocity.X, v.State.Velocity.Y),
+			UpdatedAt:      v.State.LastUpdateTime,
+		}
+		if props.Speed > 0 {
+			heading := math.Mod(math.Atan2(v.State.Velocity.X, v.State.Velocity.Y)*180/math.Pi+360, 360)
+			props.Heading = &heading
+		}
+		if v.Route != nil {
+			props.TargetNode = v.Route.EndNode
+		}
+		if v.Energy != nil {
+			level := v.Energy.Level
+			props.Energy = &level
+		}
+		pos := position(ref, v.State.CurrentPosition)
+		v.Mutex.Unlock()
+
+		if err := fc.add(v.ID, "Point", pos, props); err != nil {
+			return nil, err
+		}
+	}
+	return fc, nil
+}
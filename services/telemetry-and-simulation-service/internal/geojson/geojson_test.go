@@ -0,0 +1,244 @@
+package geojson
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/m/internal/simulation/entities"
+	simulationengine "github.com/m/internal/simulation/simulation-engine"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lineGraph is A -> B, bending through (50, 10), and a two-way street from
+// C to B.
+func lineGraph() *entities.MapGraph {
+	node := func(id string, x, y float64) *entities.MapNode {
+		return &entities.MapNode{ID: id, Position: entities.Vector2D{X: x, Y: y}, Type: entities.NodeTypeIntersection, Connections: map[string]bool{}}
+	}
+	g := &entities.MapGraph{
+		Nodes: map[string]*entities.MapNode{"A": node("A", 0, 0), "B": node("B", 100, 0), "C": node("C", 100, 100)},
+		Edges: map[string]*entities.MapEdge{
+			"e1": {ID: "e1", From: "A", To: "B", Length: 102, BaseSpeedLimit: 10, SurfaceQuality: 1, Geometry: []entities.Vector2D{{X: 50, Y: 10}}},
+			"e2": {ID: "e2", From: "C", To: "B", Length: 100, BaseSpeedLimit: 10, SurfaceQuality: 1, Bidirectional: true},
+		},
+	}
+	g.Nodes["A"].Connections["B"] = true
+	g.Nodes["B"].Connections["C"] = true
+	g.Nodes["C"].Connections["B"] = true
+	return g
+}
+
+func roundTrip(t *testing.T, fc *FeatureCollection) *FeatureCollection {
+	data, err := json.Marshal(fc)
+	require.NoError(t, err)
+	var out FeatureCollection
+	require.NoError(t, json.Unmarshal(data, &out))
+	return &out
+}
+
+func coordinates(t *testing.T, f Feature, v any) {
+	require.NoError(t, json.Unmarshal(f.Geometry.Coordinates, v))
+}
+
+func TestMap_RoundTrip(t *testing.T) {
+	cfg := simulationengine.NewMapGenerator(600, 600, 7, simulationengine.AlgoGrid, 0, 0)
+	cfg.Grid = &simulationengine.GridConfig{BlockSize: 100}
+	cfg.OneWayFraction = 0.2
+	cfg.Depots = 1
+	cfg.ChargingStations = 1
+	g := cfg.Generate()
+	for _, e := range g.Edges {
+		from, to := g.Nodes[e.From].Position, g.Nodes[e.To].Position
+		e.Geometry = []entities.Vector2D{{X: (from.X+to.X)/2 + 3, Y: (from.Y+to.Y)/2 - 3}}
+		break
+	}
+	g.Nodes["grid-1-1"].Restrictions = []entities.TurnRestriction{{From: "a", To: "b", Only: true}}
+
+	ref := &entities.Georef{OriginLat: 52.52, OriginLon: 13.4, MetersPerUnit: 1}
+	fc, err := Map(g, ref)
+	require.NoError(t, err)
+	assert.Len(t, fc.Features, len(g.Nodes)+len(g.Edges))
+	var buf bytes.Buffer
+	require.NoError(t, json.NewEncoder(&buf).Encode(fc))
+
+	got, err := ReadMap(&buf, nil)
+	require.NoError(t, err)
+	assert.Equal(t, ref, got.Georef)
+	assert.Equal(t, g.FastestRoutes, got.FastestRoutes)
+
+	require.Equal(t, sortedKeys(g.Nodes), sortedKeys(got.Nodes))
+	for id, n := range g.Nodes {
+		m := got.Nodes[id]
+		assert.InDelta(t, n.Position.X, m.Position.X, 1e-6, id)
+		assert.InDelta(t, n.Position.Y, m.Position.Y, 1e-6, id)
+		m.Position = n.Position
+		assert.Equal(t, n, m, id)
+	}
+
+	require.Equal(t, sortedKeys(g.Edges), sortedKeys(got.Edges))
+	for id, e := range g.Edges {
+		f := got.Edges[id]
+		require.Len(t, f.Geometry, len(e.Geometry), id)
+		for i := range e.Geometry {
+			assert.InDelta(t, e.Geometry[i].X, f.Geometry[i].X, 1e-6, id)
+			assert.InDelta(t, e.Geometry[i].Y, f.Geometry[i].Y, 1e-6, id)
+		}
+		f.Geometry = e.Geometry
+		assert.Equal(t, e.Conditions.EffectiveSpeedLimit, f.Conditions.EffectiveSpeedLimit, id)
+		f.Conditions = e.Conditions
+		assert.Equal(t, e, f, id)
+	}
+}
+
+func TestMap_Coordinates(t *testing.T) {
+	g := lineGraph()
+	fc, err := Map(g, nil)
+	require.NoError(t, err)
+	fc = roundTrip(t, fc)
+	assert.Nil(t, fc.Georef)
+	assert.True(t, fc.Planar)
+	require.Equal(t, "e1", fc.Features[3].ID)
+	var line [][2]float64
+	coordinates(t, fc.Features[3], &line)
+	assert.Equal(t, [][2]float64{{0, 0}, {50, 10}, {100, 0}}, line)
+
+	// 1 km north and east of 52°N.
+	g.Nodes["C"].Position = entities.Vector2D{X: 1000, Y: 1000}
+	fc, err = Map(g, &entities.Georef{OriginLat: 52, OriginLon: 13})
+	require.NoError(t, err)
+	fc = roundTrip(t, fc)
+	assert.Equal(t, "C", fc.Features[2].ID)
+	var point [2]float64
+	coordinates(t, fc.Features[2], &point)
+	assert.InDelta(t, 13.014607, point[0], 1e-6)
+	assert.InDelta(t, 52.008993, point[1], 1e-6)
+
+	var props EdgeProperties
+	require.NoError(t, json.Unmarshal(fc.Features[4].Properties, &props))
+	assert.Equal(t, KindEdge, props.Kind)
+	assert.Equal(t, "C", props.From)
+	assert.True(t, *props.Bidirectional)
+}
+
+func TestRoutes_FollowDrivingDirection(t *testing.T) {
+	g := lineGraph()
+	fc, err := Routes(g, []*entities.Route{{Edges: []string{"e1", "e2"}, StartNode: "A", EndNode: "C", TotalDistance: 202}}, nil)
+	require.NoError(t, err)
+	fc = roundTrip(t, fc)
+	require.Len(t, fc.Features, 1)
+	var line [][2]float64
+	coordinates(t, fc.Features[0], &line)
+	assert.Equal(t, [][2]float64{{0, 0}, {50, 10}, {100, 0}, {100, 100}}, line)
+
+	vehicles := []*entities.Vehicle{
+		{ID: "v1", Route: &entities.AssignedRoute{Edges: []string{"e2"}, StartNode: "B", EndNode: "C"}},
+		{ID: "v2"},
+	}
+	fc, err = VehicleRoutes(g, vehicles, nil)
+	require.NoError(t, err)
+	fc = roundTrip(t, fc)
+	require.Len(t, fc.Features, 1)
+	coordinates(t, fc.Features[0], &line)
+	assert.Equal(t, [][2]float64{{100, 0}, {100, 100}}, line)
+	var props RouteProperties
+	require.NoError(t, json.Unmarshal(fc.Features[0].Properties, &props))
+	assert.Equal(t, RouteProperties{Kind: KindRoute, VehicleID: "v1", StartNode: "B", EndNode: "C", Edges: []string{"e2"}, TotalDistance: 100}, props)
+
+	_, err = Routes(g, []*entities.Route{{Edges: []string{"nope"}, StartNode: "A"}}, nil)
+	assert.Error(t, err)
+}
+
+func TestVehicles_Snapshot(t *testing.T) {
+	vehicles := []*entities.Vehicle{
+		{ID: "north", State: entities.VehicleState{CurrentPosition: entities.Vector2D{X: 10, Y: 20}, Velocity: entities.Vector2D{Y: 5}, Status: entities.VehicleStatusMoving}},
+		{ID: "east", State: entities.VehicleState{Velocity: entities.Vector2D{X: 3}}, Energy: &entities.EnergyState{Level: 40}},
+		{ID: "parked", Route: &entities.AssignedRoute{EndNode: "C"}},
+	}
+	fc, err := Vehicles(vehicles, nil)
+	require.NoError(t, err)
+	fc = roundTrip(t, fc)
+	require.Len(t, fc.Features, 3)
+
+	var point [2]float64
+	coordinates(t, fc.Features[0], &point)
+	assert.Equal(t, [2]float64{10, 20}, point)
+
+	props := make([]VehicleProperties, 3)
+	for i, f := range fc.Features {
+		require.NoError(t, json.Unmarshal(f.Properties, &props[i]))
+		assert.Equal(t, KindVehicle, props[i].Kind)
+	}
+	assert.Equal(t, 5.0, props[0].Speed)
+	assert.Equal(t, 0.0, *props[0].Heading)
+	assert.Equal(t, 90.0, *props[1].Heading)
+	assert.Equal(t, 40.0, *props[1].Energy)
+	assert.Nil(t, props[2].Heading)
+	assert.Equal(t, "C", props[2].TargetNode)
+}
+
+func TestReadMap_DrawnLines(t *testing.T) {
+	doc := `{"type": "FeatureCollection", "planar": true, "features": [
+		{"type": "Feature", "id": 7, "geometry": {"type": "LineString", "coordinates": [[0, 0], [30, 40]]}, "properties": null},
+		{"type": "Feature", "geometry": {"type": "LineString", "coordinates": [[30.001, 40], [30, 100], [60, 100]]},
+		 "properties": {"class": "arterial", "bidirectional": false}},
+		{"type": "Feature", "geometry": {"type": "Polygon", "coordinates": [[[0, 0], [1, 0], [1, 1], [0, 0]]]}},
+		{"type": "Feature", "geometry": {"type": "Point", "coordinates": [5, 5]}, "properties": {"kind": "vehicle", "id": "v1"}}
+	]}`
+	g, err := ReadMap(strings.NewReader(doc), nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"node-0", "node-1", "node-2"}, sortedKeys(g.Nodes))
+	assert.Equal(t, []string{"7", "edge-1"}, sortedKeys(g.Edges))
+
+	first := g.Edges["7"]
+	assert.Equal(t, "node-0", first.From)
+	assert.Equal(t, "node-1", first.To)
+	assert.Equal(t, 50.0, first.Length)
+	assert.True(t, first.Bidirectional)
+	assert.Equal(t, entities.RoadLocal, first.Class)
+	assert.Equal(t, simulationengine.RoadClasses[entities.RoadLocal].Speed, first.BaseSpeedLimit)
+
+	// The second line starts within snapping distance of the first's end.
+	second := g.Edges["edge-1"]
+	assert.Equal(t, "node-1", second.From)
+	assert.Equal(t, "node-2", second.To)
+	assert.Equal(t, []entities.Vector2D{{X: 30, Y: 100}}, second.Geometry)
+	assert.False(t, second.Bidirectional)
+	assert.Equal(t, entities.RoadArterial, second.Class)
+	assert.True(t, g.Nodes["node-1"].Connections["node-2"])
+	assert.False(t, g.Nodes["node-2"].Connections["node-1"])
+
+	_, err = ReadMap(strings.NewReader(`{"type": "Feature"}`), nil)
+	assert.Error(t, err)
+	_, err = ReadMap(strings.NewReader(`{"type": "FeatureCollection", "planar": true, "features": [
+		{"type": "Feature", "geometry": {"type": "LineString", "coordinates": [[0, 0], [1, 0]]}, "properties": {"class": "highway"}}]}`), nil)
+	assert.ErrorContains(t, err, `unknown road class "highway"`)
+	_, err = ReadMap(strings.NewReader(`{"type": "FeatureCollection", "features": [{"type": "Feature", "geometry": {"type": "LineString", "coordinates": [[0, 0]]}}]}`), nil)
+	assert.Error(t, err)
+}
+
+func TestReadMap_LonLat(t *testing.T) {
+	// Two streets drawn in a GIS tool: one north from the corner, and one
+	// about 7m east of its start, which must not snap onto it.
+	doc := `{"type": "FeatureCollection", "features": [
+		{"type": "Feature", "geometry": {"type": "LineString", "coordinates": [[13.4, 52.5], [13.4, 52.501]]}},
+		{"type": "Feature", "geometry": {"type": "LineString", "coordinates": [[13.4001, 52.5], [13.4001, 52.499]]}}
+	]}`
+	g, err := ReadMap(strings.NewReader(doc), nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, &entities.Georef{OriginLat: 52.499, OriginLon: 13.4, MetersPerUnit: 1}, g.Georef)
+	assert.Len(t, g.Nodes, 4)
+	for _, e := range g.Edges {
+		assert.InDelta(t, 111.2, e.Length, 0.1)
+	}
+	assert.InDelta(t, 111.2, g.Nodes["node-0"].Position.Y, 0.1)
+	assert.InDelta(t, 6.8, g.Nodes["node-2"].Position.X, 0.1)
+
+	_, err = ReadMap(strings.NewReader(`{"type": "FeatureCollection", "features": [
+		{"type": "Feature", "geometry": {"type": "Point", "coordinates": [500, 20]}}]}`), nil)
+	assert.ErrorContains(t, err, "not a longitude and latitude")
+}
@@ -0,0 +1,344 @@
+package geojson
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+
+	"github.com/m/internal/simulation/entities"
+	simulationengine "github.com/m/internal/simulation/simulation-engine"
+)
+
+// snap is how close, in map units, a line's end must be to a node to
+// join it.
+const snap = 0.01
+
+// ImportMap reads a GeoJSON file into a graph; see ReadMap.
+func ImportMap(path string, ref *entities.Georef) (*entities.MapGraph, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadMap(f, ref)
+}
+
+// ReadMap builds a graph from the Points and LineStrings of a GeoJSON
+// FeatureCollection, such as one written by Map or drawn in a GIS tool.
+// Coordinates are projected onto the plane with ref, or else the
+// document's own georef. Documents Map marked planar keep their
+// coordinates; any other is longitude and latitude, as RFC 7946 has it, and
+// is georeferenced at its south-west corner in meters. Points are nodes and
+// LineStrings edges, with their fields read from the properties Map writes.
+// A line joins the nodes named by its from and to properties, or else those
+// at its ends, which are created when there are none. Edges without a class
+// are local streets, and routes, vehicles and other geometry are skipped.
+func ReadMap(r io.Reader, ref *entities.Georef) (*entities.MapGraph, error) {
+	var fc FeatureCollection
+	if err := json.NewDecoder(r).Decode(&fc); err != nil {
+		return nil, fmt.Errorf("parse geojson: %w", err)
+	}
+	if fc.Type != "FeatureCollection" {
+		return nil, fmt.Errorf("parse geojson: %q is not a FeatureCollection", fc.Type)
+	}
+	if ref == nil {
+		ref = fc.Georef
+	}
+	if ref == nil && !fc.Planar {
+		var err error
+		if ref, err = cornerGeoref(fc); err != nil {
+			return nil, fmt.Errorf("parse geojson: %w", err)
+		}
+	}
+
+	b := &mapBuilder{
+		g: &entities.MapGraph{
+			Nodes:         make(map[string]*entities.MapNode),
+			Edges:         make(map[string]*entities.MapEdge),
+			FastestRoutes: fc.FastestRoutes,
+			Georef:        ref,
+		},
+		ref:   ref,
+		cells: make(map[[2]int64][]string),
+	}
+	for i, f := range fc.Features {
+		if f.Geometry.Type != "Point" {
+			continue
+		}
+		if err := b.addNode(i, f); err != nil {
+			return nil, err
+		}
+	}
+	for i, f := range fc.Features {
+		if f.Geometry.Type != "LineString" {
+			continue
+		}
+		if err := b.addEdge(i, f); err != nil {
+			return nil, err
+		}
+	}
+	return b.g, nil
+}
+
+// cornerGeoref places the origin at the south-west corner of the Points and
+// LineStrings, like an OpenStreetMap import; nil when there are none.
+func cornerGeoref(fc FeatureCollection) (*entities.Georef, error) {
+	minLon, minLat := math.Inf(1), math.Inf(1)
+	visit := func(p []float64) error {
+		if len(p) < 2 {
+			return nil
+		}
+		if p[0] < -180 || p[0] > 180 || p[1] < -90 || p[1] > 90 {
+			return fmt.Errorf("position %v is not a longitude and latitude; give a georef or mark the document planar", p)
+		}
+		minLon, minLat = min(minLon, p[0]), min(minLat, p[1])
+		return nil
+	}
+
+	for i, f := range fc.Features {
+		var line [][]float64
+		switch f.Geometry.Type {
+		case "Point":
+			var p []float64
+			if err := json.Unmarshal(f.Geometry.Coordinates, &p); err != nil {
+				return nil, fmt.Errorf("feature %d coordinates: %w", i, err)
+			}
+			line = [][]float64{p}
+		case "LineString":
+			if err := json.Unmarshal(f.Geometry.Coordinates, &line); err != nil {
+				return nil, fmt.Errorf("feature %d coordinates: %w", i, err)
+			}
+		}
+		for _, p := range line {
+			if err := visit(p); err != nil {
+				return nil, fmt.Errorf("feature %d: %w", i, err)
+			}
+		}
+	}
+	if math.IsInf(minLon, 1) {
+		return nil, nil
+	}
+	return &entities.Georef{OriginLat: minLat, OriginLon: minLon, MetersPerUnit: 1}, nil
+}
+
+type mapBuilder struct {
+	g   *entities.MapGraph
+	ref *entities.Georef
+	// cells indexes the nodes by the snap-sized cell they fall in.
+	cells map[[2]int64][]string
+	// created counts the nodes named node-<n> for unnamed line ends.
+	created int
+}
+
+func (b *mapBuilder) planar(p []float64) (entities.Vector2D, error) {
+	if len(p) < 2 {
+		return entities.Vector2D{}, fmt.Errorf("position %v has fewer than 2 coordinates", p)
+	}
+	if b.ref == nil {
+		return entities.Vector2D{X: p[0], Y: p[1]}, nil
+	}
+	return b.ref.Planar(p[0], p[1]), nil
+}
+
+func cellOf(p entities.Vector2D) [2]int64 {
+	return [2]int64{int64(math.Floor(p.X / snap)), int64(math.Floor(p.Y / snap))}
+}
+
+// featureID names a feature by its id property, else its GeoJSON id, else
+// prefix and its index in the document.
+func featureID(f Feature, id, prefix string, i int) string {
+	switch {
+	case id != "":
+		return id
+	case f.ID != nil:
+		return fmt.Sprint(f.ID)
+	}
+	return fmt.Sprintf("%s-%d", prefix, i)
+}
+
+func properties(f Feature, i int, v any) error {
+	if len(f.Properties) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(f.Properties, v); err != nil {
+		return fmt.Errorf("feature %d properties: %w", i, err)
+	}
+	return nil
+}
+
+func (b *mapBuilder) addNode(i int, f Feature) error {
+	var props NodeProperties
+	if err := properties(f, i, &props); err != nil {
+		return err
+	}
+	if props.Kind != "" && props.Kind != KindNode {
+		return nil
+	}
+	var coords []float64
+	if err := json.Unmarshal(f.Geometry.Coordinates, &coords); err != nil {
+		return fmt.Errorf("feature %d coordinates: %w", i, err)
+	}
+	p, err := b.planar(coords)
+	if err != nil {
+		return fmt.Errorf("feature %d: %w", i, err)
+	}
+
+	id := featureID(f, props.ID, "node", i)
+	if _, dup := b.g.Nodes[id]; dup {
+		return fmt.Errorf("feature %d: duplicate node %s", i, id)
+	}
+	n := &entities.MapNode{
+		ID:           id,
+		Position:     p,
+		Type:         props.Type,
+		Connections:  make(map[string]bool),
+		Facility:     props.Facility,
+		Restrictions: props.Restrictions,
+		Zone:         props.Zone,
+		Production:   props.Production,
+		Attraction:   props.Attraction,
+	}
+	if n.Type == "" {
+		n.Type = entities.NodeTypeIntersection
+	}
+	b.addNodeAt(n)
+	return nil
+}
+
+func (b *mapBuilder) addNodeAt(n *entities.MapNode) {
+	b.g.Nodes[n.ID] = n
+	c := cellOf(n.Position)
+	b.cells[c] = append(b.cells[c], n.ID)
+}
+
+// endpoint is the node named id, or else the nearest node within snap of
+// p, or else a new node there, named id if that is free.
+func (b *mapBuilder) endpoint(id string, p entities.Vector2D) *entities.MapNode {
+	if n, ok := b.g.Nodes[id]; ok {
+		return n
+	}
+
+	var best *entities.MapNode
+	bestDist := snap
+	c := cellOf(p)
+	for dx := int64(-1); dx <= 1; dx++ {
+		for dy := int64(-1); dy <= 1; dy++ {
+			for _, id := range b.cells[[2]int64{c[0] + dx, c[1] + dy}] {
+				n := b.g.Nodes[id]
+				if d := math.Hypot(n.Position.X-p.X, n.Position.Y-p.Y); d <= bestDist {
+					best, bestDist = n, d
+				}
+			}
+		}
+	}
+	if best != nil {
+		return best
+	}
+
+	for id == "" || b.g.Nodes[id] != nil {
+		id = fmt.Sprintf("node-%d", b.created)
+		b.created++
+	}
+	n := &entities.MapNode{
+		ID:          id,
+		Position:    p,
+		Type:        entities.NodeTypeIntersection,
+		Connections: make(map[string]bool),
+	}
+	b.addNodeAt(n)
+	return n
+}
+
+func (b *mapBuilder) addEdge(i int, f Feature) error {
+	var props EdgeProperties
+	if err := properties(f, i, &props); err != nil {
+		return err
+	}
+	if props.Kind != "" && props.Kind != KindEdge {
+		return nil
+	}
+	var coords [][]float64
+	if err := json.Unmarshal(f.Geometry.Coordinates, &coords); err != nil {
+		return fmt.Errorf("feature %d coordinates: %w", i, err)
+	}
+	if len(coords) < 2 {
+		return fmt.Errorf("feature %d: line with %d positions", i, len(coords))
+	}
+	line := make([]entities.Vector2D, len(coords))
+	for k, c := range coords {
+		p, err := b.planar(c)
+		if err != nil {
+			return fmt.Errorf("feature %d: %w", i, err)
+		}
+		line[k] = p
+	}
+
+	id := featureID(f, props.ID, "edge", i)
+	if _, dup := b.g.Edges[id]; dup {
+		return fmt.Errorf("feature %d: duplicate edge %s", i, id)
+	}
+	from := b.endpoint(props.From, line[0])
+	to := b.endpoint(props.To, line[len(line)-1])
+	if from == to {
+		return fmt.Errorf("feature %d: edge %s starts and ends at node %s", i, id, from.ID)
+	}
+
+	e := &entities.MapEdge{
+		ID:             id,
+		From:           from.ID,
+		To:             to.ID,
+		Length:         props.Length,
+		SurfaceQuality: props.SurfaceQuality,
+		Bidirectional:  props.Bidirectional == nil || *props.Bidirectional,
+		Conditions:     &entities.RoadConditions{WeatherMultiplier: 1.0},
+	}
+	if len(line) > 2 {
+		e.Geometry = line[1 : len(line)-1]
+	}
+	if e.Length <= 0 {
+		for k := 1; k < len(line); k++ {
+			e.Length += math.Hypot(line[k].X-line[k-1].X, line[k].Y-line[k-1].Y)
+		}
+	}
+	if e.SurfaceQuality <= 0 {
+		e.SurfaceQuality = 1.0
+	}
+
+	class := props.Class
+	if class == "" {
+		class = entities.RoadLocal
+	}
+	if _, ok := simulationengine.RoadClasses[class]; !ok {
+		return fmt.Errorf("feature %d: edge %s has unknown road class %q", i, id, class)
+	}
+	simulationengine.SetRoadClass(e, class)
+	if props.BaseSpeedLimit > 0 {
+		e.BaseSpeedLimit = props.BaseSpeedLimit
+		e.Conditions.EffectiveSpeedLimit = props.BaseSpeedLimit
+	}
+	if props.Lanes > 0 {
+		e.Lanes = props.Lanes
+	}
+	if props.Capacity > 0 {
+		e.Capacity = props.Capacity
+	}
+	b.g.Edges[id] = e
+
+	from.Connections[to.ID] = true
+	if e.Bidirectional {
+		to.Connections[from.ID] = true
+	}
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,65 @@
+package grpcapi
+
+import (
+	simulationv1 "github.com/m/internal/gen/simulation/v1"
+	"github.com/m/internal/simulation/entities"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func toProtoVector(v entities.Vector2D) *simulationv1.Vector2D {
+	return &simulationv1.Vector2D{X: v.X, Y: v.Y}
+}
+
+// toProtoVehicle expects the caller to hold vehicle.Mutex.
+func toProtoVehicle(v *entities.Vehicle) *simulationv1.Vehicle {
+	out := &simulationv1.Vehicle{
+		Id:             v.ID,
+		Type:           string(v.Type),
+		FleetId:        v.AssignedFleetID,
+		Status:         string(v.State.Status),
+		Position:       toProtoVector(v.State.CurrentPosition),
+		Velocity:       toProtoVector(v.State.Velocity),
+		CurrentEdge:    v.State.CurrentEdge,
+		ProgressOnEdge: v.State.ProgressOnEdge,
+	}
+
+	if !v.State.LastUpdateTime.IsZero() {
+		out.LastUpdateTime = timestamppb.New(v.State.LastUpdateTime)
+	}
+
+	if r := v.Route; r != nil {
+		out.Route = &simulationv1.AssignedRoute{
+			Edges:            append([]string(nil), r.Edges...),
+			CurrentEdgeIndex: int32(r.CurrentEdgeIndex),
+			CurrentNode:      r.CurrentNode,
+			TargetNode:       r.TargetNode,
+			StartNode:        r.StartNode,
+			EndNode:          r.EndNode,
+		}
+		if !r.StartedAt.IsZero() {
+			out.Route.StartedAt = timestamppb.New(r.StartedAt)
+		}
+		if r.CompletedAt != nil {
+			out.Route.CompletedAt = timestamppb.New(*r.CompletedAt)
+		}
+	}
+
+	return out
+}
+
+func toProtoRoute(r *entities.Route) *simulationv1.Route {
+	return &simulationv1.Route{
+		Edges:         r.Edges,
+		StartNode:     r.StartNode,
+		EndNode:       r.EndNode,
+		TotalDistance: r.TotalDistance,
+	}
+}
+
+func durationOrNil(d *durationpb.Duration) *durationpb.Duration {
+	if d == nil || (d.Seconds == 0 && d.Nanos == 0) {
+		return nil
+	}
+	return d
+}
@@ -0,0 +1,345 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/google/uuid"
+	simulationv1 "github.com/m/internal/gen/simulation/v1"
+	"github.com/m/internal/simulation/entities"
+	simulationengine "github.com/m/internal/simulation/simulation-engine"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const (
+	// assignedBy is recorded on fleet assignments made through the gRPC API.
+	assignedBy = "grpc"
+	// MaxSteps bounds a single Step call so one request cannot hold the
+	// engine for an unbounded time.
+	MaxSteps = 10000
+)
+
+type Server struct {
+	simulationv1.UnimplementedSimulationServiceServer
+
+	Engine      *simulationengine.SimulationEngine
+	SpawnConfig *simulationengine.VehicleSpawnConfig
+	Telemetry   *Broadcaster
+}
+
+func NewServer(engine *simulationengine.SimulationEngine, spawnConfig *simulationengine.VehicleSpawnConfig) *Server {
+	return &Server{
+		Engine:      engine,
+		SpawnConfig: spawnConfig,
+		Telemetry:   NewBroadcaster(1024),
+	}
+}
+
+func (s *Server) Register(registrar grpc.ServiceRegistrar) {
+	simulationv1.RegisterSimulationServiceServer(registrar, s)
+}
+
+func (s *Server) status() *simulationv1.EngineStatus {
+	s.Engine.Mutex.RLock()
+	defer s.Engine.Mutex.RUnlock()
+
+	return &simulationv1.EngineStatus{
+		Running:      s.Engine.IsRunning,
+		Paused:       s.Engine.IsPaused(),
+		VehicleCount: int32(len(s.Engine.Vehicles)),
+		UpdateRate:   durationpb.New(s.Engine.UpdateRate),
+	}
+}
+
+func (s *Server) Start(ctx context.Context, req *simulationv1.StartRequest) (*simulationv1.StartResponse, error) {
+	s.Engine.Start()
+	return &simulationv1.StartResponse{Status: s.status()}, nil
+}
+
+func (s *Server) Stop(ctx context.Context, req *simulationv1.StopRequest) (*simulationv1.StopResponse, error) {
+	s.Engine.Stop()
+	return &simulationv1.StopResponse{Status: s.status()}, nil
+}
+
+func (s *Server) Pause(ctx context.Context, req *simulationv1.PauseRequest) (*simulationv1.PauseResponse, error) {
+	s.Engine.Pause()
+	return &simulationv1.PauseResponse{Status: s.status()}, nil
+}
+
+func (s *Server) Resume(ctx context.Context, req *simulationv1.ResumeRequest) (*simulationv1.ResumeResponse, error) {
+	s.Engine.Resume()
+	return &simulationv1.ResumeResponse{Status: s.status()}, nil
+}
+
+func (s *Server) Step(ctx context.Context, req *simulationv1.StepRequest) (*simulationv1.StepResponse, error) {
+	dt := s.Engine.UpdateRate
+	if d := durationOrNil(req.GetDt()); d != nil {
+		dt = d.AsDuration()
+	}
+	if dt <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "dt must be positive")
+	}
+
+	steps := req.GetSteps()
+	if steps == 0 {
+		steps = 1
+	}
+	if steps > MaxSteps {
+		return nil, status.Errorf(codes.InvalidArgument, "steps must be at most %d", MaxSteps)
+	}
+
+	for i := uint32(0); i < steps; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, status.FromContextError(err).Err()
+		}
+		if err := s.Engine.Step(dt); err != nil {
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
+	}
+
+	return &simulationv1.StepResponse{Status: s.status()}, nil
+}
+
+func (s *Server) GetStatus(ctx context.Context, req *simulationv1.GetStatusRequest) (*simulationv1.GetStatusResponse, error) {
+	return &simulationv1.GetStatusResponse{Status: s.status()}, nil
+}
+
+func (s *Server) CreateVehicle(ctx context.Context, req *simulationv1.CreateVehicleRequest) (*simulationv1.CreateVehicleResponse, error) {
+	id := req.GetId()
+	if id == "" {
+		id = uuid.New().String()
+	}
+	vehicleType, err := parseVehicleType(req.GetType())
+	if err != nil {
+		return nil, err
+	}
+
+	vehicle := &entities.Vehicle{
+		ID:    id,
+		Type:  vehicleType,
+		State: entities.VehicleState{Status: entities.VehicleStatusIdle},
+	}
+
+	if req.GetStartNode() == "" && req.GetEndNode() == "" {
+		err = simulationengine.AssignVehicleRoute(vehicle, s.Engine.Graph, s.SpawnConfig)
+	} else {
+		start, end := req.GetStartNode(), req.GetEndNode()
+		if start == "" {
+			start = s.randomNode(end)
+		}
+		if end == "" {
+			end = s.randomNode(start)
+		}
+		err = simulationengine.AssignVehicleRouteWithNodes(vehicle, s.Engine.Graph, start, end)
+	}
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if req.GetFleetId() != "" {
+		if err := s.Engine.AddVehicleToFleet(vehicle, req.GetFleetId(), assignedBy, 0); err != nil {
+			return nil, fleetError(err)
+		}
+	} else if err := s.Engine.InsertVehicle(vehicle); err != nil {
+		return nil, fleetError(err)
+	}
+
+	vehicle.Mutex.Lock()
+	defer vehicle.Mutex.Unlock()
+	return &simulationv1.CreateVehicleResponse{Vehicle: toProtoVehicle(vehicle)}, nil
+}
+
+func (s *Server) GetVehicle(ctx context.Context, req *simulationv1.GetVehicleRequest) (*simulationv1.GetVehicleResponse, error) {
+	vehicle, ok := s.Engine.GetVehicle(req.GetId())
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "vehicle %s not found", req.GetId())
+	}
+
+	vehicle.Mutex.Lock()
+	defer vehicle.Mutex.Unlock()
+	return &simulationv1.GetVehicleResponse{Vehicle: toProtoVehicle(vehicle)}, nil
+}
+
+func (s *Server) ListVehicles(ctx context.Context, req *simulationv1.ListVehiclesRequest) (*simulationv1.ListVehiclesResponse, error) {
+	resp := &simulationv1.ListVehiclesResponse{}
+
+	for _, v := range s.Engine.ListVehicles() {
+		v.Mutex.Lock()
+		if req.GetFleetId() == "" || v.AssignedFleetID == req.GetFleetId() {
+			resp.Vehicles = append(resp.Vehicles, toProtoVehicle(v))
+		}
+		v.Mutex.Unlock()
+	}
+
+	return resp, nil
+}
+
+func (s *Server) UpdateVehicle(ctx context.Context, req *simulationv1.UpdateVehicleRequest) (*simulationv1.UpdateVehicleResponse, error) {
+	vehicle, ok := s.Engine.GetVehicle(req.GetId())
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "vehicle %s not found", req.GetId())
+	}
+
+	var vehicleType entities.VehicleType
+	if req.Type != nil {
+		var err error
+		if vehicleType, err = parseVehicleType(req.GetType()); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.FleetId != nil {
+		var err error
+		if req.GetFleetId() == "" {
+			err = s.Engine.UnassignVehicle(vehicle.ID)
+		} else {
+			_, err = s.Engine.AssignVehicle(req.GetFleetId(), vehicle.ID, assignedBy, 0)
+		}
+		if err != nil {
+			return nil, fleetError(err)
+		}
+	}
+
+	vehicle.Mutex.Lock()
+	defer vehicle.Mutex.Unlock()
+
+	if req.Type != nil {
+		vehicle.Type = vehicleType
+	}
+
+	return &simulationv1.UpdateVehicleResponse{Vehicle: toProtoVehicle(vehicle)}, nil
+}
+
+func (s *Server) DeleteVehicle(ctx context.Context, req *simulationv1.DeleteVehicleRequest) (*simulationv1.DeleteVehicleResponse, error) {
+	if _, ok := s.Engine.GetVehicle(req.GetId()); !ok {
+		return nil, status.Errorf(codes.NotFound, "vehicle %s not found", req.GetId())
+	}
+
+	s.Engine.RemoveVehicle(req.GetId())
+	return &simulationv1.DeleteVehicleResponse{}, nil
+}
+
+func (s *Server) RequestRoute(ctx context.Context, req *simulationv1.RequestRouteRequest) (*simulationv1.RequestRouteResponse, error) {
+	if req.GetVehicleId() != "" {
+		if err := s.Engine.RouteVehicle(req.GetVehicleId(), req.GetEndNode()); err != nil {
+			if _, ok := s.Engine.GetVehicle(req.GetVehicleId()); !ok {
+				return nil, status.Error(codes.NotFound, err.Error())
+			}
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
+
+		vehicle, _ := s.Engine.GetVehicle(req.GetVehicleId())
+		vehicle.Mutex.Lock()
+		route := &entities.Route{
+			Edges:     append([]string(nil), vehicle.Route.Edges...),
+			StartNode: vehicle.Route.StartNode,
+			EndNode:   vehicle.Route.EndNode,
+		}
+		vehicle.Mutex.Unlock()
+
+		total, eta := s.measure(route.Edges)
+		route.TotalDistance = total
+		return &simulationv1.RequestRouteResponse{
+			Route:         toProtoRoute(route),
+			EstimatedTime: durationpb.New(eta),
+			Assigned:      true,
+		}, nil
+	}
+
+	for _, node := range []string{req.GetStartNode(), req.GetEndNode()} {
+		if _, ok := s.Engine.Graph.Nodes[node]; !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "node %q not found in graph", node)
+		}
+	}
+
+	routes := simulationengine.Dijkstra(s.Engine.Graph, req.GetStartNode(), req.GetEndNode())
+	if len(routes) == 0 {
+		return nil, status.Errorf(codes.NotFound, "no route found from %s to %s", req.GetStartNode(), req.GetEndNode())
+	}
+
+	_, eta := s.measure(routes[0].Edges)
+	return &simulationv1.RequestRouteResponse{
+		Route:         toProtoRoute(routes[0]),
+		EstimatedTime: durationpb.New(eta),
+	}, nil
+}
+
+func (s *Server) SubscribeTelemetry(req *simulationv1.SubscribeTelemetryRequest, stream grpc.ServerStreamingServer[simulationv1.SubscribeTelemetryResponse]) error {
+	sub := s.Telemetry.subscribe(req)
+	defer s.Telemetry.unsubscribe(sub)
+
+	for {
+		select {
+		case msg := <-sub.out:
+			resp := &simulationv1.SubscribeTelemetryResponse{Payload: msg.Payload, Dropped: sub.dropped.Load()}
+			if err := stream.Send(resp); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+func (s *Server) measure(edgeIDs []string) (float64, time.Duration) {
+	total := 0.0
+	seconds := 0.0
+	for _, id := range edgeIDs {
+		edge := s.Engine.Graph.Edges[id]
+		if edge == nil {
+			continue
+		}
+		total += edge.Length
+		speed := edge.BaseSpeedLimit
+		if edge.Conditions != nil && edge.Conditions.EffectiveSpeedLimit > 0 {
+			speed = edge.Conditions.EffectiveSpeedLimit
+		}
+		if speed > 0 {
+			seconds += edge.Length / speed
+		}
+	}
+	return total, time.Duration(seconds * float64(time.Second))
+}
+
+func (s *Server) randomNode(exclude string) string {
+	ids := make([]string, 0, len(s.Engine.Graph.Nodes))
+	for id := range s.Engine.Graph.Nodes {
+		if id != exclude {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return exclude
+	}
+	return ids[rand.IntN(len(ids))]
+}
+
+func parseVehicleType(raw string) (entities.VehicleType, error) {
+	switch entities.VehicleType(raw) {
+	case "":
+		return entities.VehicleTypSedan, nil
+	case entities.VehicleTypSedan, entities.VehicleTypeTruck, entities.VehicleTypeDrone:
+		return entities.VehicleType(raw), nil
+	default:
+		return "", status.Errorf(codes.InvalidArgument, "unknown vehicle type %q", raw)
+	}
+}
+
+// fleetError maps fleet registry errors to gRPC status codes.
+func fleetError(err error) error {
+	switch {
+	case errors.Is(err, simulationengine.ErrFleetNotFound), errors.Is(err, simulationengine.ErrVehicleNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, simulationengine.ErrVehicleExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, simulationengine.ErrFleetFull), errors.Is(err, simulationengine.ErrAssignmentConflict):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+}
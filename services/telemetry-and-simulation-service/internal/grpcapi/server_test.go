@@ -0,0 +1,193 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	simulationv1 "github.com/m/internal/gen/simulation/v1"
+	"github.com/m/internal/simulation/entities"
+	simulationengine "github.com/m/internal/simulation/simulation-engine"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func lineGraph() *entities.MapGraph {
+	nodes := map[string]*entities.MapNode{
+		"A": {ID: "A", Position: entities.Vector2D{X: 0, Y: 0}, Connections: map[string]bool{"B": true}},
+		"B": {ID: "B", Position: entities.Vector2D{X: 100, Y: 0}, Connections: map[string]bool{"A": true, "C": true}},
+		"C": {ID: "C", Position: entities.Vector2D{X: 200, Y: 0}, Connections: map[string]bool{"B": true}},
+	}
+	edges := map[string]*entities.MapEdge{
+		"A-B": {ID: "A-B", From: "A", To: "B", Length: 100, BaseSpeedLimit: 10, Bidirectional: true, Conditions: &entities.RoadConditions{EffectiveSpeedLimit: 10}},
+		"B-C": {ID: "B-C", From: "B", To: "C", Length: 100, BaseSpeedLimit: 10, Bidirectional: true, Conditions: &entities.RoadConditions{EffectiveSpeedLimit: 10}},
+	}
+	return &entities.MapGraph{Nodes: nodes, Edges: edges}
+}
+
+func newTestClient(t *testing.T) (simulationv1.SimulationServiceClient, *Server) {
+	engine := simulationengine.NewSimulationEngine(lineGraph(), time.Hour)
+	server := NewServer(engine, &simulationengine.VehicleSpawnConfig{})
+	engine.Emitter = server.Telemetry
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	server.Register(srv)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return simulationv1.NewSimulationServiceClient(conn), server
+}
+
+func TestServer_VehicleCRUD(t *testing.T) {
+	client, server := newTestClient(t)
+	ctx := context.Background()
+
+	for _, id := range []string{"f1", "f2"} {
+		_, err := server.Engine.CreateFleet(entities.Fleet{ID: id, Config: entities.FleetConfig{MaxVehicles: 1}})
+		require.NoError(t, err)
+	}
+
+	_, err := client.CreateVehicle(ctx, &simulationv1.CreateVehicleRequest{Id: "v0", FleetId: "missing"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	created, err := client.CreateVehicle(ctx, &simulationv1.CreateVehicleRequest{
+		Id: "v1", Type: "truck", FleetId: "f1", StartNode: "A", EndNode: "C",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"A-B", "B-C"}, created.Vehicle.Route.Edges)
+	assert.Equal(t, "truck", created.Vehicle.Type)
+
+	_, err = client.CreateVehicle(ctx, &simulationv1.CreateVehicleRequest{Id: "v1"})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	created, err = client.CreateVehicle(ctx, &simulationv1.CreateVehicleRequest{Id: "v-race", StartNode: "A", EndNode: "B"})
+	require.NoError(t, err)
+	errs := make(chan error, 8)
+	for i := 0; i < cap(errs); i++ {
+		go func() {
+			_, err := client.CreateVehicle(ctx, &simulationv1.CreateVehicleRequest{Id: "v-dup", StartNode: "A", EndNode: "B"})
+			errs <- err
+		}()
+	}
+	succeeded := 0
+	for i := 0; i < cap(errs); i++ {
+		if err := <-errs; err == nil {
+			succeeded++
+		} else {
+			assert.Equal(t, codes.AlreadyExists, status.Code(err))
+		}
+	}
+	assert.Equal(t, 1, succeeded)
+	for _, id := range []string{"v-race", "v-dup"} {
+		_, err = client.DeleteVehicle(ctx, &simulationv1.DeleteVehicleRequest{Id: id})
+		require.NoError(t, err)
+	}
+
+	_, err = client.CreateVehicle(ctx, &simulationv1.CreateVehicleRequest{Id: "v2", Type: "boat"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	fleet := "f2"
+	updated, err := client.UpdateVehicle(ctx, &simulationv1.UpdateVehicleRequest{Id: "v1", FleetId: &fleet})
+	require.NoError(t, err)
+	assert.Equal(t, "f2", updated.Vehicle.FleetId)
+	assert.Equal(t, "truck", updated.Vehicle.Type)
+
+	_, err = client.CreateVehicle(ctx, &simulationv1.CreateVehicleRequest{Id: "v3", FleetId: "f2"})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	list, err := client.ListVehicles(ctx, &simulationv1.ListVehiclesRequest{FleetId: "f2"})
+	require.NoError(t, err)
+	require.Len(t, list.Vehicles, 1)
+
+	_, err = client.DeleteVehicle(ctx, &simulationv1.DeleteVehicleRequest{Id: "v1"})
+	require.NoError(t, err)
+
+	_, err = client.GetVehicle(ctx, &simulationv1.GetVehicleRequest{Id: "v1"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestServer_PauseStepAndRoute(t *testing.T) {
+	client, server := newTestClient(t)
+	ctx := context.Background()
+
+	_, err := client.CreateVehicle(ctx, &simulationv1.CreateVehicleRequest{Id: "v1", StartNode: "A", EndNode: "B"})
+	require.NoError(t, err)
+
+	_, err = client.Start(ctx, &simulationv1.StartRequest{})
+	require.NoError(t, err)
+	defer client.Stop(ctx, &simulationv1.StopRequest{})
+
+	_, err = client.Step(ctx, &simulationv1.StepRequest{})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	paused, err := client.Pause(ctx, &simulationv1.PauseRequest{})
+	require.NoError(t, err)
+	assert.True(t, paused.Status.Paused)
+
+	_, err = client.Step(ctx, &simulationv1.StepRequest{Steps: MaxSteps + 1})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = server.Step(cancelled, &simulationv1.StepRequest{Steps: MaxSteps})
+	assert.Equal(t, codes.Canceled, status.Code(err))
+
+	_, err = client.Step(ctx, &simulationv1.StepRequest{Dt: durationpb.New(time.Second), Steps: 5})
+	require.NoError(t, err)
+
+	got, err := client.GetVehicle(ctx, &simulationv1.GetVehicleRequest{Id: "v1"})
+	require.NoError(t, err)
+	assert.InDelta(t, 50.0, got.Vehicle.Position.X, 1e-9)
+
+	routed, err := client.RequestRoute(ctx, &simulationv1.RequestRouteRequest{VehicleId: "v1", EndNode: "C"})
+	require.NoError(t, err)
+	assert.True(t, routed.Assigned)
+	assert.Equal(t, []string{"A-B", "B-C"}, routed.Route.Edges)
+
+	plain, err := client.RequestRoute(ctx, &simulationv1.RequestRouteRequest{StartNode: "A", EndNode: "C"})
+	require.NoError(t, err)
+	assert.Equal(t, 200.0, plain.Route.TotalDistance)
+	assert.Equal(t, 20*time.Second, plain.EstimatedTime.AsDuration())
+}
+
+func TestServer_SubscribeTelemetry(t *testing.T) {
+	client, server := newTestClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	stream, err := client.SubscribeTelemetry(ctx, &simulationv1.SubscribeTelemetryRequest{
+		Streams:    []simulationv1.TelemetryStream{simulationv1.TelemetryStream_TELEMETRY_STREAM_POSITIONS},
+		VehicleIds: []string{"v1"},
+	})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		server.Telemetry.mu.RLock()
+		defer server.Telemetry.mu.RUnlock()
+		return len(server.Telemetry.subs) == 1
+	}, time.Second, 5*time.Millisecond)
+
+	server.Telemetry.EmitEvent(entities.VehicleEvent{VehicleID: "v1", EventType: entities.EventRouteStarted})
+	server.Telemetry.EmitPosition(entities.BasicVehiclePosEvent{VehicleID: "v2"})
+	server.Telemetry.EmitPosition(entities.BasicVehiclePosEvent{VehicleID: "v1", Progress: 0.25, Timestamp: time.Now()})
+
+	msg, err := stream.Recv()
+	require.NoError(t, err)
+	require.NotNil(t, msg.GetPosition())
+	assert.Equal(t, "v1", msg.GetPosition().VehicleId)
+	assert.Equal(t, 0.25, msg.GetPosition().Progress)
+}
@@ -0,0 +1,155 @@
+package grpcapi
+
+import (
+	"sync"
+	"sync/atomic"
+
+	simulationv1 "github.com/m/internal/gen/simulation/v1"
+	"github.com/m/internal/simulation/entities"
+	"github.com/m/internal/telemetry"
+)
+
+type subscription struct {
+	req     *simulationv1.SubscribeTelemetryRequest
+	fleets  map[string]bool
+	ids     map[string]bool
+	types   map[string]bool
+	out     chan *simulationv1.SubscribeTelemetryResponse
+	dropped atomic.Uint64
+}
+
+// Broadcaster implements entities.TelemetryEmitter for gRPC subscribers. Each
+// subscriber has a bounded queue; when it is full the message is dropped and
+// counted rather than blocking the engine.
+type Broadcaster struct {
+	BufferSize int
+
+	mu   sync.RWMutex
+	subs map[*subscription]bool
+}
+
+func NewBroadcaster(bufferSize int) *Broadcaster {
+	return &Broadcaster{
+		BufferSize: bufferSize,
+		subs:       make(map[*subscription]bool),
+	}
+}
+
+func (b *Broadcaster) subscribe(req *simulationv1.SubscribeTelemetryRequest) *subscription {
+	sub := &subscription{
+		req:    req,
+		fleets: toSet(req.GetFleetIds()),
+		ids:    toSet(req.GetVehicleIds()),
+		types:  toSet(req.GetEventTypes()),
+		out:    make(chan *simulationv1.SubscribeTelemetryResponse, b.BufferSize),
+	}
+
+	b.mu.Lock()
+	b.subs[sub] = true
+	b.mu.Unlock()
+	return sub
+}
+
+func (b *Broadcaster) unsubscribe(sub *subscription) {
+	b.mu.Lock()
+	delete(b.subs, sub)
+	b.mu.Unlock()
+}
+
+func (b *Broadcaster) EmitPosition(event entities.BasicVehiclePosEvent) error {
+	var msg *simulationv1.SubscribeTelemetryResponse
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for sub := range b.subs {
+		if !sub.wantsStream(simulationv1.TelemetryStream_TELEMETRY_STREAM_POSITIONS) ||
+			!sub.matchVehicle(event.VehicleID, event.FleetID) || !sub.inBounds(event.Position) {
+			continue
+		}
+		if msg == nil {
+			msg = &simulationv1.SubscribeTelemetryResponse{
+				Payload: &simulationv1.SubscribeTelemetryResponse_Position{Position: telemetry.ToProtoPosition(event)},
+			}
+		}
+		sub.send(msg)
+	}
+	return nil
+}
+
+func (b *Broadcaster) EmitEvent(event entities.VehicleEvent) error {
+	var msg *simulationv1.SubscribeTelemetryResponse
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for sub := range b.subs {
+		if !sub.wantsStream(simulationv1.TelemetryStream_TELEMETRY_STREAM_EVENTS) ||
+			!sub.matchVehicle(event.VehicleID, event.FleetID) {
+			continue
+		}
+		if sub.types != nil && !sub.types[string(event.EventType)] {
+			continue
+		}
+		if pos, ok := event.Data["position"].(entities.Vector2D); ok && !sub.inBounds(pos) {
+			continue
+		}
+		if msg == nil {
+			msg = &simulationv1.SubscribeTelemetryResponse{
+				Payload: &simulationv1.SubscribeTelemetryResponse_Event{Event: telemetry.ToProtoEvent(event)},
+			}
+		}
+		sub.send(msg)
+	}
+	return nil
+}
+
+func (s *subscription) send(msg *simulationv1.SubscribeTelemetryResponse) {
+	select {
+	case s.out <- msg:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+func (s *subscription) wantsStream(stream simulationv1.TelemetryStream) bool {
+	streams := s.req.GetStreams()
+	if len(streams) == 0 {
+		return true
+	}
+	for _, st := range streams {
+		if st == stream {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *subscription) matchVehicle(vehicleID, fleetID string) bool {
+	if s.ids != nil && !s.ids[vehicleID] {
+		return false
+	}
+	if s.fleets != nil && !s.fleets[fleetID] {
+		return false
+	}
+	return true
+}
+
+func (s *subscription) inBounds(p entities.Vector2D) bool {
+	b := s.req.GetBbox()
+	if b == nil {
+		return true
+	}
+	return p.X >= b.MinX && p.X <= b.MaxX && p.Y >= b.MinY && p.Y <= b.MaxY
+}
+
+func toSet(values []string) map[string]bool {
+	if len(values) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
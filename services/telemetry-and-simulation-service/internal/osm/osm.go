@@ -0,0 +1,607 @@
+package osm
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/m/internal/simulation/entities"
+	simulationengine "github.com/m/internal/simulation/simulation-engine"
+)
+
+type Config struct {
+	// Highways maps the highway tags to import to road classes; ways with
+	// any other highway tag are left out. Nil imports DrivableHighways.
+	Highways map[string]entities.RoadClass
+}
+
+// DrivableHighways are the highway tags open to general motor traffic.
+var DrivableHighways = map[string]entities.RoadClass{
+	"motorway":       entities.RoadMotorway,
+	"motorway_link":  entities.RoadMotorway,
+	"trunk":          entities.RoadMotorway,
+	"trunk_link":     entities.RoadMotorway,
+	"primary":        entities.RoadArterial,
+	"primary_link":   entities.RoadArterial,
+	"secondary":      entities.RoadArterial,
+	"secondary_link": entities.RoadArterial,
+	"tertiary":       entities.RoadCollector,
+	"tertiary_link":  entities.RoadCollector,
+	"unclassified":   entities.RoadCollector,
+	"residential":    entities.RoadLocal,
+	"living_street":  entities.RoadLocal,
+}
+
+type node struct {
+	lat, lon float64
+}
+
+type way struct {
+	id   int64
+	refs []int64
+	tags map[string]string
+}
+
+type member struct {
+	typ  string // node, way or relation
+	ref  int64
+	role string
+}
+
+type relation struct {
+	id      int64
+	members []member
+	tags    map[string]string
+}
+
+// data is the part of an extract the importer reads.
+type data struct {
+	nodes     map[int64]node
+	ways      []way
+	relations []relation
+}
+
+func newData() *data {
+	return &data{nodes: make(map[int64]node)}
+}
+
+// Import reads an OSM XML or PBF extract from a file and builds the road
+// graph from it; see Decode.
+func Import(path string, cfg *Config) (*entities.MapGraph, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Decode(f, cfg)
+}
+
+// Decode reads an OSM XML or PBF extract, telling them apart by the first
+// byte, and builds the road graph of its drivable ways. Nodes are named by
+// their OSM id and edges w<way id>-<n> for the n-th stretch of a way between
+// junctions. Positions are in meters east and north of the south-west corner
+// of the graph, which is its Georef. Stretches of road that meet end to end
+// with nothing else joining them are merged into one edge with the points
+// between in its Geometry, and only the largest strongly connected component
+// is kept, so every node can reach every other.
+func Decode(r io.Reader, cfg *Config) (*entities.MapGraph, error) {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(512)
+	head = bytes.TrimLeft(head, " \t\r\n\ufeff")
+	if len(head) == 0 {
+		return nil, errors.New("empty osm file")
+	}
+
+	var d *data
+	var err error
+	if head[0] == '<' {
+		d, err = readXML(br)
+	} else {
+		d, err = readPBF(br)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return build(d, cfg), nil
+}
+
+// road is what an edge inherits from its way. Only edges with the same road
+// are merged.
+type road struct {
+	class  entities.RoadClass
+	speed  float64
+	lanes  int
+	oneway bool
+}
+
+// segment is a stretch of way between two graph nodes, in the direction of
+// traffic if it is one-way.
+type segment struct {
+	id       string
+	from, to int64
+	via      []int64
+	ways     []int64
+	road     road
+}
+
+func (s *segment) other(n int64) int64 {
+	if s.from == n {
+		return s.to
+	}
+	return s.from
+}
+
+func (s *segment) reverse() {
+	s.from, s.to = s.to, s.from
+	for i, j := 0, len(s.via)-1; i < j; i, j = i+1, j-1 {
+		s.via[i], s.via[j] = s.via[j], s.via[i]
+	}
+}
+
+// enters and leaves tell whether traffic can drive the segment into or
+// out of node n.
+func (s *segment) enters(n int64) bool {
+	return s.to == n || (!s.road.oneway && s.from == n)
+}
+
+func (s *segment) leaves(n int64) bool {
+	return s.from == n || (!s.road.oneway && s.to == n)
+}
+
+func (s *segment) hasWay(id int64) bool {
+	for _, w := range s.ways {
+		if w == id {
+			return true
+		}
+	}
+	return false
+}
+
+type restriction struct {
+	from, to int64 // way ids
+	via      int64
+	only     bool
+}
+
+type builder struct {
+	data     *data
+	segments map[string]*segment
+	order    []string // segment ids in the order they were cut
+	incident map[int64][]string
+}
+
+func build(d *data, cfg *Config) *entities.MapGraph {
+	highways := DrivableHighways
+	if cfg != nil && cfg.Highways != nil {
+		highways = cfg.Highways
+	}
+
+	restrictions := parseRestrictions(d.relations)
+	// Junctions are the graph nodes: way ends, nodes shared by ways or
+	// visited twice by one, and the via nodes of turn restrictions.
+	junctions := make(map[int64]bool)
+	for _, r := range restrictions {
+		junctions[r.via] = true
+	}
+
+	type drivable struct {
+		id   int64
+		runs [][]int64
+		road road
+	}
+	var ways []drivable
+	uses := make(map[int64]int)
+	for _, w := range d.ways {
+		class, ok := highways[w.tags["highway"]]
+		if !ok || w.tags["area"] == "yes" {
+			continue
+		}
+		rd, reversed := wayRoad(w.tags, class)
+		refs := w.refs
+		if reversed {
+			refs = make([]int64, len(w.refs))
+			for i, ref := range w.refs {
+				refs[len(refs)-1-i] = ref
+			}
+		}
+		runs := presentRuns(d.nodes, refs)
+		if len(runs) == 0 {
+			continue
+		}
+		ways = append(ways, drivable{id: w.id, runs: runs, road: rd})
+		for _, run := range runs {
+			junctions[run[0]] = true
+			junctions[run[len(run)-1]] = true
+			for _, ref := range run {
+				uses[ref]++
+			}
+		}
+	}
+	for ref, n := range uses {
+		if n > 1 {
+			junctions[ref] = true
+		}
+	}
+
+	b := &builder{data: d, segments: make(map[string]*segment), incident: make(map[int64][]string)}
+	for _, w := range ways {
+		n := 0
+		for _, run := range w.runs {
+			start := 0
+			for i := 1; i < len(run); i++ {
+				if !junctions[run[i]] {
+					continue
+				}
+				if run[i] != run[start] {
+					b.add(&segment{
+						id:   fmt.Sprintf("w%d-%d", w.id, n),
+						from: run[start],
+						to:   run[i],
+						via:  append([]int64(nil), run[start+1:i]...),
+						ways: []int64{w.id},
+						road: w.road,
+					})
+					n++
+				}
+				start = i
+			}
+		}
+	}
+
+	b.mergeChains(junctions, restrictions)
+	g := b.graph(restrictions)
+	largestComponent(g)
+	b.place(g)
+	return g
+}
+
+// wayRoad reads the class, speed limit, lanes and direction of a way.
+// Reversed is true for ways one-way against the order of their nodes.
+func wayRoad(tags map[string]string, class entities.RoadClass) (rd road, reversed bool) {
+	spec := simulationengine.RoadClasses[class]
+	rd = road{class: class, speed: spec.Speed, lanes: spec.Lanes}
+	if speed, ok := parseMaxspeed(tags["maxspeed"]); ok {
+		rd.speed = speed
+	}
+
+	switch tags["oneway"] {
+	case "yes", "true", "1":
+		rd.oneway = true
+	case "-1", "reverse":
+		rd.oneway, reversed = true, true
+	case "no", "false", "0":
+	default:
+		hw := tags["highway"]
+		junction := tags["junction"]
+		rd.oneway = hw == "motorway" || hw == "motorway_link" || junction == "roundabout" || junction == "circular"
+	}
+
+	if lanes, err := strconv.Atoi(strings.TrimSpace(tags["lanes"])); err == nil && lanes > 0 {
+		if rd.oneway {
+			rd.lanes = lanes
+		} else {
+			rd.lanes = max(lanes/2, 1)
+		}
+	}
+	return rd, reversed
+}
+
+// parseMaxspeed reads a maxspeed tag in km/h, or in mph with that suffix,
+// as m/s. Zones like "DE:urban" and "none" are not a number and fall back
+// to the road class.
+func parseMaxspeed(v string) (float64, bool) {
+	v, _, _ = strings.Cut(v, ";")
+	v = strings.TrimSpace(v)
+	factor := 1 / 3.6
+	if s, ok := strings.CutSuffix(v, "mph"); ok {
+		v, factor = s, 0.44704
+	} else {
+		v = strings.TrimSuffix(strings.TrimSuffix(v, "km/h"), "kmh")
+	}
+	speed, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+	if err != nil || speed <= 0 {
+		return 0, false
+	}
+	return speed * factor, true
+}
+
+// presentRuns splits a way where the extract is missing its nodes, as at
+// the edge of a clipped extract, and drops repeated nodes.
+func presentRuns(nodes map[int64]node, refs []int64) [][]int64 {
+	var runs [][]int64
+	var run []int64
+	flush := func() {
+		if len(run) > 1 {
+			runs = append(runs, run)
+		}
+		run = nil
+	}
+	for _, ref := range refs {
+		if _, ok := nodes[ref]; !ok {
+			flush()
+			continue
+		}
+		if len(run) > 0 && run[len(run)-1] == ref {
+			continue
+		}
+		run = append(run, ref)
+	}
+	flush()
+	return runs
+}
+
+// parseRestrictions keeps the no_* and only_* turn restrictions from one
+// way to another through a node.
+func parseRestrictions(relations []relation) []restriction {
+	var out []restriction
+	for _, rel := range relations {
+		if rel.tags["type"] != "restriction" {
+			continue
+		}
+		kind := rel.tags["restriction"]
+		if kind == "" {
+			kind = rel.tags["restriction:motorcar"]
+		}
+		r := restriction{only: strings.HasPrefix(kind, "only_")}
+		if !r.only && !strings.HasPrefix(kind, "no_") {
+			continue
+		}
+
+		var from, via, to int
+		for _, m := range rel.members {
+			switch {
+			case m.role == "from" && m.typ == "way":
+				r.from = m.ref
+				from++
+			case m.role == "via" && m.typ == "node":
+				r.via = m.ref
+				via++
+			case m.role == "via":
+				via = 2 // via ways are not supported
+			case m.role == "to" && m.typ == "way":
+				r.to = m.ref
+				to++
+			}
+		}
+		if from == 1 && via == 1 && to == 1 {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func (b *builder) add(s *segment) {
+	b.segments[s.id] = s
+	b.order = append(b.order, s.id)
+	b.incident[s.from] = append(b.incident[s.from], s.id)
+	b.incident[s.to] = append(b.incident[s.to], s.id)
+}
+
+// mergeChains removes the junctions where just two segments of the same
+// road meet end to end, joining the segments into one. Merges that would
+// close a loop or run alongside an existing segment are skipped, as are
+// restriction via nodes.
+func (b *builder) mergeChains(junctions map[int64]bool, restrictions []restriction) {
+	vias := make(map[int64]bool, len(restrictions))
+	for _, r := range restrictions {
+		vias[r.via] = true
+	}
+	ids := make([]int64, 0, len(junctions))
+	for id := range junctions {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	for _, n := range ids {
+		inc := b.incident[n]
+		if vias[n] || len(inc) != 2 || inc[0] == inc[1] {
+			continue
+		}
+		a, c := b.segments[inc[0]], b.segments[inc[1]]
+		if a.road != c.road {
+			continue
+		}
+		if a.road.oneway {
+			if a.to != n {
+				a, c = c, a
+			}
+			if a.to != n || c.from != n {
+				continue
+			}
+		} else {
+			if a.to != n {
+				a.reverse()
+			}
+			if c.from != n {
+				c.reverse()
+			}
+		}
+		if a.from == c.to || b.joined(a.from, c.to) {
+			continue
+		}
+
+		a.via = append(append(a.via, n), c.via...)
+		a.to = c.to
+		for _, w := range c.ways {
+			if !a.hasWay(w) {
+				a.ways = append(a.ways, w)
+			}
+		}
+		delete(b.segments, c.id)
+		delete(b.incident, n)
+		for i, id := range b.incident[c.to] {
+			if id == c.id {
+				b.incident[c.to][i] = a.id
+			}
+		}
+	}
+}
+
+// joined tells whether a segment already runs between two nodes.
+func (b *builder) joined(x, y int64) bool {
+	for _, id := range b.incident[x] {
+		if b.segments[id].other(x) == y {
+			return true
+		}
+	}
+	return false
+}
+
+// graph turns the segments into a graph with the turn restrictions
+// attached to their via nodes. Positions are set later by place.
+func (b *builder) graph(restrictions []restriction) *entities.MapGraph {
+	g := &entities.MapGraph{
+		Nodes:         make(map[string]*entities.MapNode),
+		Edges:         make(map[string]*entities.MapEdge),
+		FastestRoutes: true,
+	}
+	graphNode := func(id int64) *entities.MapNode {
+		key := strconv.FormatInt(id, 10)
+		n, ok := g.Nodes[key]
+		if !ok {
+			n = &entities.MapNode{ID: key, Type: entities.NodeTypeIntersection, Connections: make(map[string]bool)}
+			g.Nodes[key] = n
+		}
+		return n
+	}
+
+	for _, id := range b.order {
+		s, ok := b.segments[id]
+		if !ok {
+			continue
+		}
+		from, to := graphNode(s.from), graphNode(s.to)
+		e := &entities.MapEdge{
+			ID:             s.id,
+			From:           from.ID,
+			To:             to.ID,
+			SurfaceQuality: 1.0,
+			Bidirectional:  !s.road.oneway,
+			Conditions:     &entities.RoadConditions{WeatherMultiplier: 1.0},
+		}
+		simulationengine.SetRoadClass(e, s.road.class)
+		e.BaseSpeedLimit = s.road.speed
+		e.Conditions.EffectiveSpeedLimit = s.road.speed
+		e.Lanes = s.road.lanes
+		e.Capacity = float64(s.road.lanes) * simulationengine.RoadClasses[s.road.class].LaneCapacity
+		g.Edges[e.ID] = e
+
+		from.Connections[to.ID] = true
+		if e.Bidirectional {
+			to.Connections[from.ID] = true
+		}
+	}
+
+	for _, r := range restrictions {
+		var from, to *segment
+		for _, id := range b.incident[r.via] {
+			s := b.segments[id]
+			if from == nil && s.hasWay(r.from) && s.enters(r.via) {
+				from = s
+			}
+			if to == nil && s.hasWay(r.to) && s.leaves(r.via) {
+				to = s
+			}
+		}
+		if from == nil || to == nil {
+			continue
+		}
+		n := g.Nodes[strconv.FormatInt(r.via, 10)]
+		n.Restrictions = append(n.Restrictions, entities.TurnRestriction{From: from.id, To: to.id, Only: r.only})
+	}
+	return g
+}
+
+// largestComponent drops everything outside the largest strongly
+// connected component.
+func largestComponent(g *entities.MapGraph) {
+	var keep []string
+	for _, c := range simulationengine.StronglyConnectedComponents(g) {
+		if len(c) > len(keep) {
+			keep = c
+		}
+	}
+	kept := make(map[string]bool, len(keep))
+	for _, id := range keep {
+		kept[id] = true
+	}
+
+	for id := range g.Nodes {
+		if !kept[id] {
+			delete(g.Nodes, id)
+		}
+	}
+	for id, e := range g.Edges {
+		if !kept[e.From] || !kept[e.To] {
+			delete(g.Edges, id)
+		}
+	}
+	for _, n := range g.Nodes {
+		for id := range n.Connections {
+			if !kept[id] {
+				delete(n.Connections, id)
+			}
+		}
+		restrictions := n.Restrictions[:0]
+		for _, r := range n.Restrictions {
+			if g.Edges[r.From] != nil && g.Edges[r.To] != nil {
+				restrictions = append(restrictions, r)
+			}
+		}
+		n.Restrictions = restrictions
+		if len(n.Restrictions) == 0 {
+			n.Restrictions = nil
+		}
+	}
+	g.Invalidate()
+}
+
+// place georeferences the graph at its south-west corner and sets the
+// positions, edge geometry and lengths in meters.
+func (b *builder) place(g *entities.MapGraph) {
+	minLat, minLon := math.Inf(1), math.Inf(1)
+	visit := func(id int64) {
+		n := b.data.nodes[id]
+		minLat, minLon = min(minLat, n.lat), min(minLon, n.lon)
+	}
+	for id := range g.Edges {
+		s := b.segments[id]
+		visit(s.from)
+		visit(s.to)
+		for _, v := range s.via {
+			visit(v)
+		}
+	}
+	if len(g.Edges) == 0 {
+		return
+	}
+
+	g.Georef = &entities.Georef{OriginLat: minLat, OriginLon: minLon, MetersPerUnit: 1}
+	project := func(id int64) entities.Vector2D {
+		n := b.data.nodes[id]
+		return g.Georef.Planar(n.lon, n.lat)
+	}
+
+	for id, e := range g.Edges {
+		s := b.segments[id]
+		from, to := project(s.from), project(s.to)
+		g.Nodes[e.From].Position = from
+		g.Nodes[e.To].Position = to
+
+		prev := from
+		for _, v := range s.via {
+			p := project(v)
+			e.Geometry = append(e.Geometry, p)
+			e.Length += math.Hypot(p.X-prev.X, p.Y-prev.Y)
+			prev = p
+		}
+		e.Length += math.Hypot(to.X-prev.X, to.Y-prev.Y)
+	}
+}
@@ -0,0 +1,184 @@
+package osm
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/m/internal/simulation/entities"
+	simulationengine "github.com/m/internal/simulation/simulation-engine"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestImport_XML(t *testing.T) {
+	g, err := Import("testdata/sample.osm", nil)
+	require.NoError(t, err)
+
+	// The footway and service road are not drivable, node 23 can only be
+	// left by a one-way street, and the island is cut off.
+	assert.Equal(t, []string{"1", "20", "22", "24", "3", "32", "7", "8"}, sortedKeys(g.Nodes))
+	assert.Equal(t, []string{"w100-0", "w101-1", "w102-0", "w110-0", "w111-1", "w112-0", "w113-0", "w120-0"}, sortedKeys(g.Edges))
+	assert.True(t, g.FastestRoutes)
+	assert.Len(t, simulationengine.StronglyConnectedComponents(g), 1)
+
+	// Node 20 is the southernmost and node 1 the westernmost.
+	assert.InDelta(t, 0, g.Nodes["1"].Position.X, 1e-9)
+	assert.InDelta(t, 0, g.Nodes["20"].Position.Y, 1e-9)
+	assert.InDelta(t, 111.2, g.Nodes["1"].Position.Y, 0.1)
+	require.NotNil(t, g.Georef)
+	assert.Equal(t, entities.Georef{OriginLat: 52.519, OriginLon: 13.4, MetersPerUnit: 1}, *g.Georef)
+	lon, lat := g.Georef.LonLat(g.Nodes["8"].Position)
+	assert.InDelta(t, 13.404, lon, 1e-9)
+	assert.InDelta(t, 52.52, lat, 1e-9)
+
+	// Hauptstraße runs on through node 5, where two of its ways meet.
+	main := g.Edges["w102-0"]
+	assert.Equal(t, "3", main.From)
+	assert.Equal(t, "7", main.To)
+	require.Len(t, main.Geometry, 3)
+	assert.InDelta(t, g.Nodes["3"].Position.X+2*(main.Geometry[1].X-g.Nodes["3"].Position.X), g.Nodes["7"].Position.X, 1e-6)
+	assert.Greater(t, main.Length, main.Geometry[1].X-g.Nodes["3"].Position.X+g.Nodes["7"].Position.X-main.Geometry[1].X)
+	assert.Equal(t, entities.RoadArterial, main.Class)
+	assert.InDelta(t, 50/3.6, main.BaseSpeedLimit, 1e-9)
+	assert.InDelta(t, 50/3.6, main.Conditions.EffectiveSpeedLimit, 1e-9)
+	assert.Equal(t, 2, main.Lanes)
+	assert.Equal(t, 1800.0, main.Capacity)
+	assert.True(t, main.Bidirectional)
+
+	// Different speed limits keep node 22 in the graph.
+	assert.InDelta(t, 20*0.44704, g.Edges["w112-0"].BaseSpeedLimit, 1e-9)
+	assert.Len(t, g.Edges["w112-0"].Geometry, 1)
+
+	oneway := g.Edges["w111-1"]
+	assert.False(t, oneway.Bidirectional)
+	assert.Equal(t, "7", oneway.From)
+	assert.Equal(t, "24", oneway.To)
+	assert.True(t, g.Nodes["7"].Connections["24"])
+	assert.False(t, g.Nodes["24"].Connections["7"])
+
+	ring := g.Edges["w120-0"]
+	assert.Equal(t, entities.RoadCollector, ring.Class)
+	assert.Equal(t, 1, ring.Lanes)
+	assert.InDelta(t, simulationengine.RoadClasses[entities.RoadCollector].Speed, ring.BaseSpeedLimit, 1e-9)
+
+	assert.Equal(t, []entities.TurnRestriction{{From: "w100-0", To: "w113-0"}}, g.Nodes["3"].Restrictions)
+	assert.Equal(t, []entities.TurnRestriction{{From: "w120-0", To: "w101-1", Only: true}}, g.Nodes["8"].Restrictions)
+
+	// No left turn from Hauptstraße into Westweg, and no turning round:
+	// go round by Nordstraße.
+	g.Turns = simulationengine.DefaultTurnCosts()
+	routes := simulationengine.Dijkstra(g, "1", "22")
+	require.NotEmpty(t, routes)
+	assert.Equal(t, []string{"w100-0", "w102-0", "w111-1", "w112-0"}, routes[0].Edges)
+}
+
+func TestImport_PBFMatchesXML(t *testing.T) {
+	fromXML, err := Import("testdata/sample.osm", nil)
+	require.NoError(t, err)
+	fromPBF, err := Import("testdata/sample.osm.pbf", nil)
+	require.NoError(t, err)
+
+	require.Equal(t, sortedKeys(fromXML.Nodes), sortedKeys(fromPBF.Nodes))
+	for id, n := range fromXML.Nodes {
+		p := fromPBF.Nodes[id]
+		assert.InDelta(t, n.Position.X, p.Position.X, 1e-6, id)
+		assert.InDelta(t, n.Position.Y, p.Position.Y, 1e-6, id)
+		assert.Equal(t, n.Connections, p.Connections, id)
+		assert.Equal(t, n.Restrictions, p.Restrictions, id)
+	}
+	require.Equal(t, sortedKeys(fromXML.Edges), sortedKeys(fromPBF.Edges))
+	for id, e := range fromXML.Edges {
+		p := fromPBF.Edges[id]
+		assert.InDelta(t, e.Length, p.Length, 1e-6, id)
+		assert.Len(t, p.Geometry, len(e.Geometry), id)
+		e.Length, p.Length, e.Geometry, p.Geometry = 0, 0, nil, nil
+		assert.Equal(t, e, p, id)
+	}
+}
+
+func TestImport_HighwayConfig(t *testing.T) {
+	g, err := Import("testdata/sample.osm", &Config{Highways: map[string]entities.RoadClass{
+		"primary":  entities.RoadArterial,
+		"tertiary": entities.RoadCollector,
+	}})
+	require.NoError(t, err)
+	// Without the side streets Hauptstraße only stops at the restriction
+	// and the Ringstraße junction.
+	assert.Equal(t, []string{"w100-0", "w102-0", "w120-0"}, sortedKeys(g.Edges))
+	assert.Equal(t, "8", g.Edges["w102-0"].To)
+	assert.Len(t, g.Edges["w102-0"].Geometry, 4)
+}
+
+func TestImport_RejectsBadInput(t *testing.T) {
+	_, err := Decode(strings.NewReader("  \n"), nil)
+	assert.Error(t, err)
+	_, err = Decode(strings.NewReader("<osm><node id="), nil)
+	assert.Error(t, err)
+	_, err = Decode(strings.NewReader("\x00\x00\x00\x10short"), nil)
+	assert.Error(t, err)
+}
+
+func TestWayRoad_Tags(t *testing.T) {
+	tests := []struct {
+		tags     map[string]string
+		class    entities.RoadClass
+		want     road
+		reversed bool
+	}{
+		{map[string]string{"highway": "residential"}, entities.RoadLocal, road{class: entities.RoadLocal, speed: 13.4, lanes: 1}, false},
+		{map[string]string{"maxspeed": "30 mph", "oneway": "-1"}, entities.RoadLocal, road{class: entities.RoadLocal, speed: 30 * 0.44704, lanes: 1, oneway: true}, true},
+		{map[string]string{"maxspeed": "DE:urban", "lanes": "3", "oneway": "yes"}, entities.RoadArterial, road{class: entities.RoadArterial, speed: 22.2, lanes: 3, oneway: true}, false},
+		{map[string]string{"highway": "motorway", "lanes": "6", "maxspeed": "120 km/h"}, entities.RoadMotorway, road{class: entities.RoadMotorway, speed: 120 / 3.6, lanes: 6, oneway: true}, false},
+		{map[string]string{"highway": "motorway", "oneway": "no", "lanes": "6"}, entities.RoadMotorway, road{class: entities.RoadMotorway, speed: 33.3, lanes: 3}, false},
+		{map[string]string{"junction": "roundabout", "maxspeed": "50;30"}, entities.RoadCollector, road{class: entities.RoadCollector, speed: 50 / 3.6, lanes: 1, oneway: true}, false},
+	}
+	for _, tt := range tests {
+		got, reversed := wayRoad(tt.tags, tt.class)
+		assert.Equal(t, tt.reversed, reversed, tt.tags)
+		assert.True(t, math.Abs(got.speed-tt.want.speed) < 1e-9, tt.tags)
+		got.speed, tt.want.speed = 0, 0
+		assert.Equal(t, tt.want, got, tt.tags)
+	}
+}
+
+func TestBuild_MergesOneWayChains(t *testing.T) {
+	d := newData()
+	for i := int64(1); i <= 5; i++ {
+		d.nodes[i] = node{lat: 52.52, lon: 13.4 + 0.001*float64(i)}
+	}
+	d.nodes[6] = node{lat: 52.521, lon: 13.403}
+	d.nodes[7] = node{lat: 52.522, lon: 13.403}
+	street := func(id int64, oneway string, refs ...int64) way {
+		return way{id: id, refs: refs, tags: map[string]string{"highway": "residential", "oneway": oneway}}
+	}
+	// One-way 1 -> 2 -> 3 -> 4 -> 5 with the middle way drawn against the
+	// traffic, back by a two-way street through 6, which has a spur to 7.
+	d.ways = []way{
+		street(1, "yes", 1, 2, 3),
+		street(2, "-1", 4, 3),
+		street(3, "yes", 4, 5),
+		street(4, "no", 5, 6, 1),
+		street(5, "no", 6, 7),
+	}
+
+	g := build(d, nil)
+	assert.Equal(t, []string{"1", "5", "6", "7"}, sortedKeys(g.Nodes))
+	assert.Equal(t, []string{"w1-0", "w4-0", "w4-1", "w5-0"}, sortedKeys(g.Edges))
+	e := g.Edges["w1-0"]
+	assert.Equal(t, "1", e.From)
+	assert.Equal(t, "5", e.To)
+	assert.False(t, e.Bidirectional)
+	assert.Len(t, e.Geometry, 3)
+	assert.InDelta(t, g.Nodes["5"].Position.X-g.Nodes["1"].Position.X, e.Length, 1e-6)
+}
@@ -0,0 +1,428 @@
+package osm
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Size limits from the PBF format specification.
+const (
+	maxBlobHeaderSize = 64 * 1024
+	maxBlobSize       = 32 * 1024 * 1024
+)
+
+// pbfFeatures are the required features readPBF understands.
+var pbfFeatures = map[string]bool{
+	"OsmSchema-V0.6": true,
+	"DenseNodes":     true,
+}
+
+// field is one field of a protobuf message: a varint, or the contents of a
+// length-delimited field.
+type field struct {
+	num    protowire.Number
+	typ    protowire.Type
+	varint uint64
+	bytes  []byte
+}
+
+// eachField calls fn for the fields of a message in order, skipping
+// fixed-width ones, which the OSM schema does not use.
+func eachField(b []byte, fn func(field) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		f := field{num: num, typ: typ}
+		switch typ {
+		case protowire.VarintType:
+			f.varint, n = protowire.ConsumeVarint(b)
+		case protowire.BytesType:
+			f.bytes, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		if typ == protowire.VarintType || typ == protowire.BytesType {
+			if err := fn(f); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// uints appends the values of a repeated varint field, packed or not.
+func (f field) uints(dst []uint64) ([]uint64, error) {
+	if f.typ == protowire.VarintType {
+		return append(dst, f.varint), nil
+	}
+	b := f.bytes
+	for len(b) > 0 {
+		v, n := protowire.ConsumeVarint(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		dst = append(dst, v)
+		b = b[n:]
+	}
+	return dst, nil
+}
+
+// sints appends the values of a repeated sint64 field.
+func (f field) sints(dst []int64) ([]int64, error) {
+	vs, err := f.uints(nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range vs {
+		dst = append(dst, protowire.DecodeZigZag(v))
+	}
+	return dst, nil
+}
+
+// undelta turns delta-coded values into absolute ones in place.
+func undelta(vs []int64) {
+	for i := 1; i < len(vs); i++ {
+		vs[i] += vs[i-1]
+	}
+}
+
+// readPBF reads an .osm.pbf file: a sequence of blobs, each a 4-byte
+// big-endian header length, a BlobHeader and a Blob, holding an OSMHeader
+// and then OSMData primitive blocks.
+func readPBF(r io.Reader) (*data, error) {
+	d := newData()
+	var zr *zstd.Decoder
+	defer func() {
+		if zr != nil {
+			zr.Close()
+		}
+	}()
+
+	for {
+		var size [4]byte
+		if _, err := io.ReadFull(r, size[:]); err == io.EOF {
+			return d, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("read pbf: %w", err)
+		}
+		n := binary.BigEndian.Uint32(size[:])
+		if n > maxBlobHeaderSize {
+			return nil, fmt.Errorf("read pbf: blob header of %d bytes", n)
+		}
+		header := make([]byte, n)
+		if _, err := io.ReadFull(r, header); err != nil {
+			return nil, fmt.Errorf("read pbf: %w", err)
+		}
+
+		var kind string
+		var dataSize uint64
+		err := eachField(header, func(f field) error {
+			switch f.num {
+			case 1:
+				kind = string(f.bytes)
+			case 3:
+				dataSize = f.varint
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("parse blob header: %w", err)
+		}
+		if dataSize > maxBlobSize {
+			return nil, fmt.Errorf("read pbf: blob of %d bytes", dataSize)
+		}
+		blob := make([]byte, dataSize)
+		if _, err := io.ReadFull(r, blob); err != nil {
+			return nil, fmt.Errorf("read pbf: %w", err)
+		}
+		if kind != "OSMHeader" && kind != "OSMData" {
+			continue
+		}
+
+		raw, err := blobData(blob, &zr)
+		if err != nil {
+			return nil, fmt.Errorf("read %s blob: %w", kind, err)
+		}
+		if kind == "OSMHeader" {
+			err = checkHeader(raw)
+		} else {
+			err = d.readBlock(raw)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", kind, err)
+		}
+	}
+}
+
+// blobData returns the uncompressed contents of a Blob.
+func blobData(blob []byte, zr **zstd.Decoder) ([]byte, error) {
+	var raw []byte
+	err := eachField(blob, func(f field) error {
+		var err error
+		switch f.num {
+		case 1:
+			raw = f.bytes
+		case 3:
+			var r io.ReadCloser
+			if r, err = zlib.NewReader(bytes.NewReader(f.bytes)); err == nil {
+				raw, err = io.ReadAll(io.LimitReader(r, maxBlobSize))
+				r.Close()
+			}
+		case 7:
+			if *zr == nil {
+				if *zr, err = zstd.NewReader(nil); err != nil {
+					return err
+				}
+			}
+			raw, err = (*zr).DecodeAll(f.bytes, nil)
+		case 4, 5, 6:
+			err = fmt.Errorf("unsupported compression (blob field %d)", f.num)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, errors.New("empty blob")
+	}
+	return raw, nil
+}
+
+func checkHeader(b []byte) error {
+	return eachField(b, func(f field) error {
+		if f.num == 4 && !pbfFeatures[string(f.bytes)] {
+			return fmt.Errorf("unsupported required feature %q", f.bytes)
+		}
+		return nil
+	})
+}
+
+// block is a PrimitiveBlock: its string table and coordinate encoding.
+type block struct {
+	strings     []string
+	granularity int64
+	latOffset   int64
+	lonOffset   int64
+}
+
+func (b *block) coord(offset, v int64) float64 {
+	return 1e-9 * float64(offset+b.granularity*v)
+}
+
+func (b *block) str(i uint64) (string, error) {
+	if i >= uint64(len(b.strings)) {
+		return "", fmt.Errorf("string %d out of range", i)
+	}
+	return b.strings[i], nil
+}
+
+func (b *block) tags(keys, vals []uint64) (map[string]string, error) {
+	if len(keys) != len(vals) {
+		return nil, errors.New("tag keys and values differ in length")
+	}
+	tags := make(map[string]string, len(keys))
+	for i := range keys {
+		k, err := b.str(keys[i])
+		if err != nil {
+			return nil, err
+		}
+		v, err := b.str(vals[i])
+		if err != nil {
+			return nil, err
+		}
+		tags[k] = v
+	}
+	return tags, nil
+}
+
+func (d *data) readBlock(raw []byte) error {
+	b := &block{granularity: 100}
+	var groups [][]byte
+	err := eachField(raw, func(f field) error {
+		switch f.num {
+		case 1:
+			return eachField(f.bytes, func(s field) error {
+				if s.num == 1 {
+					b.strings = append(b.strings, string(s.bytes))
+				}
+				return nil
+			})
+		case 2:
+			groups = append(groups, f.bytes)
+		case 17:
+			b.granularity = int64(f.varint)
+		case 19:
+			b.latOffset = int64(f.varint)
+		case 20:
+			b.lonOffset = int64(f.varint)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Groups are read after the whole block, as the string table and
+	// offsets may come after them.
+	for _, group := range groups {
+		err := eachField(group, func(f field) error {
+			switch f.num {
+			case 1:
+				return d.readNode(b, f.bytes)
+			case 2:
+				return d.readDenseNodes(b, f.bytes)
+			case 3:
+				return d.readWay(b, f.bytes)
+			case 4:
+				return d.readRelation(b, f.bytes)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *data) readNode(b *block, msg []byte) error {
+	var id, lat, lon int64
+	err := eachField(msg, func(f field) error {
+		switch f.num {
+		case 1:
+			id = protowire.DecodeZigZag(f.varint)
+		case 8:
+			lat = protowire.DecodeZigZag(f.varint)
+		case 9:
+			lon = protowire.DecodeZigZag(f.varint)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	d.nodes[id] = node{lat: b.coord(b.latOffset, lat), lon: b.coord(b.lonOffset, lon)}
+	return nil
+}
+
+func (d *data) readDenseNodes(b *block, msg []byte) error {
+	var ids, lats, lons []int64
+	err := eachField(msg, func(f field) error {
+		var err error
+		switch f.num {
+		case 1:
+			ids, err = f.sints(ids)
+		case 8:
+			lats, err = f.sints(lats)
+		case 9:
+			lons, err = f.sints(lons)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if len(lats) != len(ids) || len(lons) != len(ids) {
+		return errors.New("dense node ids and coordinates differ in length")
+	}
+	undelta(ids)
+	undelta(lats)
+	undelta(lons)
+	for i, id := range ids {
+		d.nodes[id] = node{lat: b.coord(b.latOffset, lats[i]), lon: b.coord(b.lonOffset, lons[i])}
+	}
+	return nil
+}
+
+func (d *data) readWay(b *block, msg []byte) error {
+	var w way
+	var keys, vals []uint64
+	err := eachField(msg, func(f field) error {
+		var err error
+		switch f.num {
+		case 1:
+			w.id = int64(f.varint)
+		case 2:
+			keys, err = f.uints(keys)
+		case 3:
+			vals, err = f.uints(vals)
+		case 8:
+			w.refs, err = f.sints(w.refs)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if w.tags, err = b.tags(keys, vals); err != nil {
+		return fmt.Errorf("way %d: %w", w.id, err)
+	}
+	undelta(w.refs)
+	d.ways = append(d.ways, w)
+	return nil
+}
+
+var memberTypes = []string{"node", "way", "relation"}
+
+func (d *data) readRelation(b *block, msg []byte) error {
+	var rel relation
+	var keys, vals, roles, types []uint64
+	var ids []int64
+	err := eachField(msg, func(f field) error {
+		var err error
+		switch f.num {
+		case 1:
+			rel.id = int64(f.varint)
+		case 2:
+			keys, err = f.uints(keys)
+		case 3:
+			vals, err = f.uints(vals)
+		case 8:
+			roles, err = f.uints(roles)
+		case 9:
+			ids, err = f.sints(ids)
+		case 10:
+			types, err = f.uints(types)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if rel.tags, err = b.tags(keys, vals); err != nil {
+		return fmt.Errorf("relation %d: %w", rel.id, err)
+	}
+	if len(roles) != len(ids) || len(types) != len(ids) {
+		return fmt.Errorf("relation %d: member fields differ in length", rel.id)
+	}
+	undelta(ids)
+	for i, id := range ids {
+		role, err := b.str(roles[i])
+		if err != nil {
+			return fmt.Errorf("relation %d: %w", rel.id, err)
+		}
+		if types[i] >= uint64(len(memberTypes)) {
+			return fmt.Errorf("relation %d: member type %d", rel.id, types[i])
+		}
+		rel.members = append(rel.members, member{typ: memberTypes[types[i]], ref: id, role: role})
+	}
+	d.relations = append(d.relations, rel)
+	return nil
+}
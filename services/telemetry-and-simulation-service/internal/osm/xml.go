@@ -0,0 +1,93 @@
+package osm
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+type xmlTag struct {
+	K string `xml:"k,attr"`
+	V string `xml:"v,attr"`
+}
+
+type xmlNode struct {
+	ID  int64   `xml:"id,attr"`
+	Lat float64 `xml:"lat,attr"`
+	Lon float64 `xml:"lon,attr"`
+}
+
+type xmlWay struct {
+	ID  int64 `xml:"id,attr"`
+	Nds []struct {
+		Ref int64 `xml:"ref,attr"`
+	} `xml:"nd"`
+	Tags []xmlTag `xml:"tag"`
+}
+
+type xmlRelation struct {
+	ID      int64 `xml:"id,attr"`
+	Members []struct {
+		Type string `xml:"type,attr"`
+		Ref  int64  `xml:"ref,attr"`
+		Role string `xml:"role,attr"`
+	} `xml:"member"`
+	Tags []xmlTag `xml:"tag"`
+}
+
+func xmlTags(tags []xmlTag) map[string]string {
+	m := make(map[string]string, len(tags))
+	for _, t := range tags {
+		m[t.K] = t.V
+	}
+	return m
+}
+
+// readXML streams an .osm file one element at a time, so only the parts
+// the importer keeps are held in memory.
+func readXML(r io.Reader) (*data, error) {
+	d := newData()
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return d, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse osm xml: %w", err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch start.Name.Local {
+		case "node":
+			var n xmlNode
+			if err := dec.DecodeElement(&n, &start); err != nil {
+				return nil, fmt.Errorf("parse osm xml: %w", err)
+			}
+			d.nodes[n.ID] = node{lat: n.Lat, lon: n.Lon}
+		case "way":
+			var w xmlWay
+			if err := dec.DecodeElement(&w, &start); err != nil {
+				return nil, fmt.Errorf("parse osm xml: %w", err)
+			}
+			refs := make([]int64, len(w.Nds))
+			for i, nd := range w.Nds {
+				refs[i] = nd.Ref
+			}
+			d.ways = append(d.ways, way{id: w.ID, refs: refs, tags: xmlTags(w.Tags)})
+		case "relation":
+			var rel xmlRelation
+			if err := dec.DecodeElement(&rel, &start); err != nil {
+				return nil, fmt.Errorf("parse osm xml: %w", err)
+			}
+			members := make([]member, len(rel.Members))
+			for i, m := range rel.Members {
+				members[i] = member{typ: m.Type, ref: m.Ref, role: m.Role}
+			}
+			d.relations = append(d.relations, relation{id: rel.ID, members: members, tags: xmlTags(rel.Tags)})
+		}
+	}
+}
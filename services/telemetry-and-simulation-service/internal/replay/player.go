@@ -0,0 +1,363 @@
+package replay
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/m/internal/simulation/entities"
+	simulationengine "github.com/m/internal/simulation/simulation-engine"
+	"github.com/m/internal/telemetry"
+)
+
+const (
+	DefaultTickRate         = 50 * time.Millisecond
+	DefaultKeyframeInterval = 5000
+)
+
+// Status is a snapshot of the player for the API.
+type Status struct {
+	RunID   string    `json:"run_id"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Clock   time.Time `json:"clock"`
+	Speed   float64   `json:"speed"`
+	Playing bool      `json:"playing"`
+	Cursor  int       `json:"cursor"`
+	Total   int       `json:"total"`
+}
+
+type keyframe struct {
+	index    int
+	vehicles map[string]*vehicleState
+}
+
+// Player re-emits a recorded run through a TelemetryEmitter and keeps the
+// vehicle state as of its clock, so the API can serve it in place of a live
+// engine. The state at time t is every record with a timestamp <= t applied
+// in recording order; the same seeks and steps always produce the same
+// emissions.
+type Player struct {
+	Graph   *entities.MapGraph
+	Emitter entities.TelemetryEmitter
+
+	manifest  telemetry.Manifest
+	records   []telemetry.Record
+	keyframes []keyframe
+
+	mu       sync.Mutex
+	tickRate time.Duration
+	cursor   int
+	clock    time.Time
+	speed    float64
+	playing  bool
+	vehicles map[string]*vehicleState
+	stop     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// LoadGraph reads the map of the run recorded in dir. Runs recorded without
+// their map fall back to regenerating it from the manifest's map config,
+// which only reproduces the seeded generators, such as grid and radial.
+func LoadGraph(dir string, manifest telemetry.Manifest) (*entities.MapGraph, error) {
+	if manifest.Graph != "" {
+		data, err := os.ReadFile(filepath.Join(dir, manifest.Graph))
+		if err != nil {
+			return nil, err
+		}
+		var graph entities.MapGraph
+		if err := json.Unmarshal(data, &graph); err != nil {
+			return nil, fmt.Errorf("parse map: %w", err)
+		}
+		return &graph, nil
+	}
+	if len(manifest.MapConfig) == 0 {
+		return nil, fmt.Errorf("run %s has no map", manifest.RunID)
+	}
+
+	var config simulationengine.MapGeneratorConfig
+	if err := json.Unmarshal(manifest.MapConfig, &config); err != nil {
+		return nil, fmt.Errorf("parse map config: %w", err)
+	}
+	return config.Generate(), nil
+}
+
+// Load reads the run recorded in dir into memory. A nil graph is loaded
+// with LoadGraph.
+func Load(dir string, graph *entities.MapGraph) (*Player, error) {
+	reader, err := telemetry.OpenRun(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	manifest := reader.Manifest()
+	if graph == nil {
+		if graph, err = LoadGraph(dir, manifest); err != nil {
+			return nil, err
+		}
+	}
+
+	var records []telemetry.Record
+	for {
+		rec, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, rec)
+	}
+
+	return NewPlayer(manifest, records, graph), nil
+}
+
+// NewPlayer builds a player over records already in timestamp order.
+func NewPlayer(manifest telemetry.Manifest, records []telemetry.Record, graph *entities.MapGraph) *Player {
+	p := &Player{
+		Graph:    graph,
+		tickRate: DefaultTickRate,
+		manifest: manifest,
+		records:  records,
+		speed:    1,
+	}
+	p.buildKeyframes(DefaultKeyframeInterval)
+
+	start, _ := p.Bounds()
+	p.seek(start)
+	return p
+}
+
+func (p *Player) Manifest() telemetry.Manifest {
+	return p.manifest
+}
+
+// Bounds returns the timestamps of the first and last record.
+func (p *Player) Bounds() (time.Time, time.Time) {
+	if len(p.records) == 0 {
+		return time.Time{}, time.Time{}
+	}
+	return p.records[0].Timestamp(), p.records[len(p.records)-1].Timestamp()
+}
+
+func (p *Player) Status() Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	start, end := p.Bounds()
+	return Status{
+		RunID:   p.manifest.RunID,
+		Start:   start,
+		End:     end,
+		Clock:   p.clock,
+		Speed:   p.speed,
+		Playing: p.playing,
+		Cursor:  p.cursor,
+		Total:   len(p.records),
+	}
+}
+
+// Play advances the clock in real time, speed times faster than wall clock.
+// SetTickRate sets how often a playing player advances its clock. It takes
+// effect on the next Play.
+func (p *Player) SetTickRate(tick time.Duration) error {
+	if tick <= 0 {
+		return fmt.Errorf("tick rate must be positive, got %v", tick)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.tickRate = tick
+	return nil
+}
+
+func (p *Player) Play(speed float64) error {
+	if speed <= 0 {
+		return fmt.Errorf("speed must be positive, got %v", speed)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.speed = speed
+	if p.playing {
+		return nil
+	}
+	p.playing = true
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.run(p.stop, p.tickRate)
+	return nil
+}
+
+func (p *Player) Pause() {
+	p.mu.Lock()
+	if !p.playing {
+		p.mu.Unlock()
+		return
+	}
+	p.playing = false
+	close(p.stop)
+	p.mu.Unlock()
+
+	p.wg.Wait()
+}
+
+func (p *Player) IsPlaying() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.playing
+}
+
+// Step advances a paused player by dt, or to the next recorded timestamp
+// when dt is zero.
+func (p *Player) Step(dt time.Duration) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.playing {
+		return fmt.Errorf("replay is playing; pause before stepping")
+	}
+	if dt < 0 {
+		return fmt.Errorf("cannot step backwards; use Seek")
+	}
+
+	target := p.clock.Add(dt)
+	if dt == 0 {
+		if p.cursor >= len(p.records) {
+			return nil
+		}
+		target = p.records[p.cursor].Timestamp()
+	}
+	p.advance(target)
+	return nil
+}
+
+// Seek rebuilds the state at t without emitting the records in between,
+// then emits the latest position of every vehicle so clients can redraw.
+func (p *Player) Seek(t time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.seek(t)
+	if p.Emitter == nil {
+		return
+	}
+	for _, id := range p.vehicleIDs() {
+		if pos := p.vehicles[id].lastPosition; pos != nil {
+			p.Emitter.EmitPosition(*pos)
+		}
+	}
+}
+
+// Vehicles returns the reconstructed vehicles, sorted by ID.
+func (p *Player) Vehicles() []*entities.Vehicle {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	out := make([]*entities.Vehicle, 0, len(p.vehicles))
+	for _, id := range p.vehicleIDs() {
+		out = append(out, p.vehicles[id].toVehicle())
+	}
+	return out
+}
+
+func (p *Player) Close() {
+	p.Pause()
+}
+
+func (p *Player) run(stop chan struct{}, tick time.Duration) {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	last := time.Now()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			elapsed := now.Sub(last)
+			last = now
+
+			p.mu.Lock()
+			p.advance(p.clock.Add(time.Duration(float64(elapsed) * p.speed)))
+			if p.cursor >= len(p.records) {
+				p.playing = false
+				close(p.stop)
+				p.mu.Unlock()
+				return
+			}
+			p.mu.Unlock()
+		}
+	}
+}
+
+// advance emits and applies every record up to and including target.
+// Expects p.mu to be held.
+func (p *Player) advance(target time.Time) {
+	for p.cursor < len(p.records) && !p.records[p.cursor].Timestamp().After(target) {
+		rec := p.records[p.cursor]
+		applyRecord(p.vehicles, rec, p.Graph)
+		p.emit(rec)
+		p.cursor++
+	}
+	p.clock = target
+}
+
+func (p *Player) emit(rec telemetry.Record) {
+	if p.Emitter == nil {
+		return
+	}
+	if rec.Position != nil {
+		p.Emitter.EmitPosition(*rec.Position)
+	} else if rec.Event != nil {
+		p.Emitter.EmitEvent(*rec.Event)
+	}
+}
+
+// seek expects p.mu to be held.
+func (p *Player) seek(t time.Time) {
+	idx := sort.Search(len(p.records), func(i int) bool {
+		return p.records[i].Timestamp().After(t)
+	})
+
+	k := sort.Search(len(p.keyframes), func(i int) bool {
+		return p.keyframes[i].index > idx
+	}) - 1
+
+	p.vehicles = cloneVehicles(p.keyframes[k].vehicles)
+	for i := p.keyframes[k].index; i < idx; i++ {
+		applyRecord(p.vehicles, p.records[i], p.Graph)
+	}
+	p.cursor, p.clock = idx, t
+}
+
+func (p *Player) buildKeyframes(interval int) {
+	vehicles := make(map[string]*vehicleState)
+	p.keyframes = []keyframe{{index: 0, vehicles: cloneVehicles(vehicles)}}
+
+	for i, rec := range p.records {
+		applyRecord(vehicles, rec, p.Graph)
+		if (i+1)%interval == 0 {
+			p.keyframes = append(p.keyframes, keyframe{index: i + 1, vehicles: cloneVehicles(vehicles)})
+		}
+	}
+}
+
+func (p *Player) vehicleIDs() []string {
+	ids := make([]string, 0, len(p.vehicles))
+	for id := range p.vehicles {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,211 @@
+package replay
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/m/internal/simulation/entities"
+	simulationengine "github.com/m/internal/simulation/simulation-engine"
+	"github.com/m/internal/telemetry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type captureEmitter struct {
+	mu      sync.Mutex
+	records []telemetry.Record
+}
+
+func (c *captureEmitter) EmitPosition(event entities.BasicVehiclePosEvent) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.records = append(c.records, telemetry.Record{Position: &event})
+	return nil
+}
+
+func (c *captureEmitter) EmitEvent(event entities.VehicleEvent) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.records = append(c.records, telemetry.Record{Event: &event})
+	return nil
+}
+
+func (c *captureEmitter) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.records)
+}
+
+var t0 = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func testGraph() *entities.MapGraph {
+	return &entities.MapGraph{
+		Nodes: map[string]*entities.MapNode{
+			"A": {ID: "A"}, "B": {ID: "B", Position: entities.Vector2D{X: 10}}, "C": {ID: "C", Position: entities.Vector2D{X: 20}},
+		},
+		Edges: map[string]*entities.MapEdge{
+			"A-B": {ID: "A-B", From: "A", To: "B", Length: 10},
+			"B-C": {ID: "B-C", From: "B", To: "C", Length: 10},
+		},
+	}
+}
+
+// record writes a run on testGraph where v1 drives A -> B -> C over 4
+// seconds and v2 appears at t0+2s.
+func record(t *testing.T) string {
+	t.Helper()
+
+	mapConfig := simulationengine.NewMapGenerator(200, 200, 7, simulationengine.AlgoKNN, 20, 3)
+	manifest, err := telemetry.NewManifest("run-1", mapConfig.Seed, mapConfig, nil)
+	require.NoError(t, err)
+	rec, err := telemetry.NewRecorder(telemetry.RecorderConfig{Dir: t.TempDir()}, manifest)
+	require.NoError(t, err)
+	require.NoError(t, rec.WriteGraph(testGraph()))
+
+	position := func(id, edge, from string, x, progress float64, at time.Duration) {
+		require.NoError(t, rec.EmitPosition(entities.BasicVehiclePosEvent{
+			RunID: "run-1", FleetID: "fleet-1", VehicleID: id, EdgeID: edge, FromNodeID: from,
+			Progress: progress, Position: entities.Vector2D{X: x}, Timestamp: t0.Add(at),
+		}))
+	}
+	event := func(id string, typ entities.EventType, at time.Duration) {
+		require.NoError(t, rec.EmitEvent(entities.VehicleEvent{
+			RunID: "run-1", FleetID: "fleet-1", VehicleID: id, EventType: typ, Timestamp: t0.Add(at),
+			Data: map[string]interface{}{"start_node": "A", "end_node": "C"},
+		}))
+	}
+
+	event("v1", entities.EventRouteStarted, 0)
+	position("v1", "A-B", "A", 0, 0, 0)
+	position("v1", "A-B", "A", 5, 0.5, time.Second)
+	position("v1", "B-C", "B", 10, 0, 2*time.Second)
+	position("v2", "A-B", "A", 0, 0, 2*time.Second)
+	position("v1", "B-C", "B", 15, 0.5, 3*time.Second)
+	position("v1", "B-C", "B", 20, 1, 4*time.Second)
+	event("v1", entities.EventRouteCompleted, 4*time.Second)
+	require.NoError(t, rec.Close())
+
+	return rec.Dir()
+}
+
+func TestPlayer_StepEmitsInOrder(t *testing.T) {
+	player, err := Load(record(t), testGraph())
+	require.NoError(t, err)
+	sink := &captureEmitter{}
+	player.Emitter = sink
+
+	// Records at the start instant are applied on load.
+	assert.Equal(t, 2, player.Status().Cursor)
+	assert.Len(t, player.Vehicles(), 1)
+
+	require.NoError(t, player.Step(time.Second))
+	require.Len(t, sink.records, 1)
+	assert.Equal(t, 5.0, sink.records[0].Position.Position.X)
+
+	require.NoError(t, player.Step(0))
+	assert.Len(t, sink.records, 3)
+	assert.Equal(t, t0.Add(2*time.Second), player.Status().Clock)
+
+	vehicles := player.Vehicles()
+	require.Len(t, vehicles, 2)
+	v1 := vehicles[0]
+	assert.Equal(t, "v1", v1.ID)
+	assert.Equal(t, "fleet-1", v1.AssignedFleetID)
+	assert.Equal(t, entities.VehicleStatusMoving, v1.State.Status)
+	assert.Equal(t, 5.0, v1.State.Velocity.X)
+	require.NotNil(t, v1.Route)
+	assert.Equal(t, []string{"A-B", "B-C"}, v1.Route.Edges)
+	assert.Equal(t, "C", v1.Route.TargetNode)
+
+	require.NoError(t, player.Step(time.Hour))
+	assert.Len(t, sink.records, 6)
+	v1 = player.Vehicles()[0]
+	assert.Equal(t, entities.VehicleStatusArrived, v1.State.Status)
+	require.NotNil(t, v1.Route.CompletedAt)
+
+	assert.Error(t, player.Step(-time.Second))
+}
+
+func TestPlayer_SeekMatchesStepping(t *testing.T) {
+	dir := record(t)
+
+	stepped, err := Load(dir, testGraph())
+	require.NoError(t, err)
+	require.NoError(t, stepped.Step(3*time.Second))
+
+	seeked, err := Load(dir, testGraph())
+	require.NoError(t, err)
+	seeked.buildKeyframes(2)
+	seeked.Step(4 * time.Second)
+
+	sink := &captureEmitter{}
+	seeked.Emitter = sink
+	seeked.Seek(t0.Add(3 * time.Second))
+
+	assert.Equal(t, stepped.Status().Cursor, seeked.Status().Cursor)
+	assert.Equal(t, stepped.Vehicles(), seeked.Vehicles())
+	// One redraw position per vehicle, sorted by ID.
+	require.Len(t, sink.records, 2)
+	assert.Equal(t, "v1", sink.records[0].Position.VehicleID)
+	assert.Equal(t, 15.0, sink.records[0].Position.Position.X)
+	assert.Equal(t, "v2", sink.records[1].Position.VehicleID)
+
+	seeked.Seek(t0.Add(-time.Minute))
+	assert.Empty(t, seeked.Vehicles())
+}
+
+func TestPlayer_PlayRunsToEnd(t *testing.T) {
+	player, err := Load(record(t), testGraph())
+	require.NoError(t, err)
+	assert.Error(t, player.SetTickRate(0))
+	assert.Error(t, player.SetTickRate(-time.Millisecond))
+	require.NoError(t, player.SetTickRate(time.Millisecond))
+	sink := &captureEmitter{}
+	player.Emitter = sink
+
+	assert.Error(t, player.Play(0))
+	require.NoError(t, player.Play(1000))
+	assert.Error(t, player.Step(time.Second))
+
+	assert.Eventually(t, func() bool { return !player.IsPlaying() }, 2*time.Second, time.Millisecond)
+	assert.Equal(t, 6, sink.len())
+	player.Close()
+}
+
+func TestLoadGraph_ReadsRecordedMap(t *testing.T) {
+	player, err := Load(record(t), nil)
+	require.NoError(t, err)
+	assert.Equal(t, testGraph(), player.Graph)
+
+	_, err = LoadGraph(t.TempDir(), telemetry.Manifest{RunID: "x"})
+	assert.Error(t, err)
+	_, err = LoadGraph(t.TempDir(), telemetry.Manifest{RunID: "x", Graph: "map.json"})
+	assert.Error(t, err)
+}
+
+func TestLoadGraph_RegeneratesSeededMap(t *testing.T) {
+	config := simulationengine.NewMapGenerator(300, 300, 7, simulationengine.AlgoGrid, 0, 0)
+	config.Grid = &simulationengine.GridConfig{BlockSize: 100}
+	manifest, err := telemetry.NewManifest("run-1", config.Seed, config, nil)
+	require.NoError(t, err)
+
+	graph, err := LoadGraph(t.TempDir(), manifest)
+	require.NoError(t, err)
+	original := config.Generate()
+	require.Equal(t, len(original.Nodes), len(graph.Nodes))
+	for id, n := range original.Nodes {
+		require.Contains(t, graph.Nodes, id)
+		assert.Equal(t, n.Position, graph.Nodes[id].Position, id)
+	}
+	for id := range original.Edges {
+		assert.Contains(t, graph.Edges, id)
+	}
+}
+
+func TestApplyPosition_ReversedEdge(t *testing.T) {
+	v := &vehicleState{route: &entities.AssignedRoute{StartNode: "C", EndNode: "A", CurrentNode: "C"}}
+	applyPosition(v, entities.BasicVehiclePosEvent{EdgeID: "B-C", FromNodeID: "C", Progress: 0.5}, testGraph())
+	assert.Equal(t, "C", v.route.CurrentNode)
+	assert.Equal(t, "B", v.route.TargetNode)
+}
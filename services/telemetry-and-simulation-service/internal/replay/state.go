@@ -0,0 +1,166 @@
+package replay
+
+import (
+	"github.com/m/internal/simulation/entities"
+	"github.com/m/internal/telemetry"
+)
+
+// vehicleState is what a recording tells us about a vehicle. The vehicle
+// type is not part of the telemetry schema and stays empty.
+type vehicleState struct {
+	id           string
+	fleetID      string
+	state        entities.VehicleState
+	route        *entities.AssignedRoute
+	lastPosition *entities.BasicVehiclePosEvent
+}
+
+func (v *vehicleState) clone() *vehicleState {
+	out := *v
+	if v.route != nil {
+		route := *v.route
+		route.Edges = append([]string(nil), v.route.Edges...)
+		if v.route.CompletedAt != nil {
+			completed := *v.route.CompletedAt
+			route.CompletedAt = &completed
+		}
+		out.route = &route
+	}
+	return &out
+}
+
+func (v *vehicleState) toVehicle() *entities.Vehicle {
+	c := v.clone()
+	return &entities.Vehicle{
+		ID:              c.id,
+		State:           c.state,
+		Route:           c.route,
+		AssignedFleetID: c.fleetID,
+	}
+}
+
+func cloneVehicles(vehicles map[string]*vehicleState) map[string]*vehicleState {
+	out := make(map[string]*vehicleState, len(vehicles))
+	for id, v := range vehicles {
+		out[id] = v.clone()
+	}
+	return out
+}
+
+func applyRecord(vehicles map[string]*vehicleState, rec telemetry.Record, graph *entities.MapGraph) {
+	if rec.Position != nil {
+		applyPosition(vehicle(vehicles, rec.Position.VehicleID, rec.Position.FleetID), *rec.Position, graph)
+	} else if rec.Event != nil && rec.Event.VehicleID != "" {
+		// Events without a vehicle, like fleet summaries, are only re-emitted.
+		applyEvent(vehicle(vehicles, rec.Event.VehicleID, rec.Event.FleetID), *rec.Event)
+	}
+}
+
+func vehicle(vehicles map[string]*vehicleState, id, fleetID string) *vehicleState {
+	v, ok := vehicles[id]
+	if !ok {
+		v = &vehicleState{id: id, state: entities.VehicleState{Status: entities.VehicleStatusIdle}}
+		vehicles[id] = v
+	}
+	v.fleetID = fleetID
+	return v
+}
+
+func applyPosition(v *vehicleState, pos entities.BasicVehiclePosEvent, graph *entities.MapGraph) {
+	if prev := v.lastPosition; prev != nil {
+		if dt := pos.Timestamp.Sub(prev.Timestamp).Seconds(); dt > 0 {
+			v.state.Velocity = entities.Vector2D{
+				X: (pos.Position.X - prev.Position.X) / dt,
+				Y: (pos.Position.Y - prev.Position.Y) / dt,
+			}
+		}
+	}
+
+	v.state.CurrentPosition = pos.Position
+	v.state.CurrentEdge = pos.EdgeID
+	v.state.ProgressOnEdge = pos.Progress
+	v.state.LastUpdateTime = pos.Timestamp
+	switch v.state.Status {
+	case entities.VehicleStatusArrived, entities.VehicleStatusBreakdown, entities.VehicleStatusCharging,
+		entities.VehicleStatusStranded, entities.VehicleStatusQueued:
+	default:
+		v.state.Status = entities.VehicleStatusMoving
+	}
+
+	if r := v.route; r != nil && pos.EdgeID != "" {
+		if n := len(r.Edges); n == 0 || r.Edges[n-1] != pos.EdgeID {
+			r.Edges = append(r.Edges, pos.EdgeID)
+		}
+		r.CurrentEdgeIndex = len(r.Edges) - 1
+		r.CurrentNode = pos.FromNodeID
+		if graph != nil {
+			if edge, ok := graph.Edges[pos.EdgeID]; ok {
+				r.TargetNode = edge.OtherEnd(pos.FromNodeID)
+			}
+		}
+	}
+
+	p := pos
+	v.lastPosition = &p
+}
+
+func applyEvent(v *vehicleState, ev entities.VehicleEvent) {
+	switch ev.EventType {
+	case entities.EventRouteStarted:
+		start, _ := ev.Data["start_node"].(string)
+		end, _ := ev.Data["end_node"].(string)
+		v.route = &entities.AssignedRoute{
+			StartNode:   start,
+			EndNode:     end,
+			CurrentNode: start,
+			StartedAt:   ev.Timestamp,
+		}
+		v.state.Status = entities.VehicleStatusMoving
+	case entities.EventRouteCompleted:
+		if v.route != nil {
+			completed := ev.Timestamp
+			v.route.CompletedAt = &completed
+			v.route.CurrentNode = v.route.EndNode
+		}
+		v.state.Status = entities.VehicleStatusArrived
+		v.state.Velocity = entities.Vector2D{}
+	case entities.EventBreakdownOccurred:
+		v.state.Status = entities.VehicleStatusBreakdown
+		v.state.Velocity = entities.Vector2D{}
+	case entities.EventEnergyDepleted:
+		v.state.Status = entities.VehicleStatusStranded
+		v.state.Velocity = entities.Vector2D{}
+	case entities.EventChargingStarted:
+		v.state.Status = entities.VehicleStatusCharging
+		v.state.Velocity = entities.Vector2D{}
+	case entities.EventChargingCompleted:
+		v.state.Status = entities.VehicleStatusArrived
+	case entities.EventQueueEntered:
+		v.state.Status = entities.VehicleStatusQueued
+		v.state.Velocity = entities.Vector2D{}
+	case entities.EventRepairCompleted:
+		// Towed vehicles are repaired at the depot, others continue on
+		// their edge.
+		switch ev.Data["mode"] {
+		case entities.RepairTow, string(entities.RepairTow):
+			v.state.Status = entities.VehicleStatusArrived
+		default:
+			v.state.Status = entities.VehicleStatusStopped
+		}
+	case entities.EventWaypointArrived:
+		if dwell, _ := ev.Data["dwell"].(float64); dwell > 0 {
+			v.state.Status = entities.VehicleStatusStopped
+			v.state.Velocity = entities.Vector2D{}
+		}
+	case entities.EventWaypointDeparted:
+		if v.state.Status == entities.VehicleStatusStopped {
+			v.state.Status = entities.VehicleStatusMoving
+			if v.route != nil && v.route.CompletedAt != nil {
+				v.state.Status = entities.VehicleStatusArrived
+			}
+		}
+	case entities.EventQueueExited:
+		// A charging_started follows for chargers; parked vehicles stay put.
+		v.state.Status = entities.VehicleStatusArrived
+	}
+}
@@ -0,0 +1,40 @@
+package entities
+
+// ArrivalMode is what a vehicle does once it has finished its route.
+type ArrivalMode string
+
+const (
+	// ArrivalIdle parks the vehicle for good; the dispatcher leaves it alone.
+	ArrivalIdle ArrivalMode = "idle"
+	// ArrivalReturnHome drives back to HomeDepot, or the nearest depot.
+	ArrivalReturnHome ArrivalMode = "return_home"
+	// ArrivalNewTarget picks another destination with Target.
+	ArrivalNewTarget ArrivalMode = "new_target"
+	// ArrivalPatrol loops through the Patrol nodes.
+	ArrivalPatrol ArrivalMode = "patrol"
+	// ArrivalDispatch parks the vehicle until the dispatcher gives it an
+	// order. Vehicles without a behavior do the same.
+	ArrivalDispatch ArrivalMode = "dispatch"
+)
+
+// ArrivalBehavior configures the re-tasking of a vehicle or, through
+// FleetConfig, of a whole fleet. The vehicle first waits a random time
+// between IdleMin and IdleMax seconds.
+type ArrivalBehavior struct {
+	Mode ArrivalMode `json:"mode"`
+	// Target is the target strategy for ArrivalNewTarget: "random",
+	// "farthest", or "attraction" and "production" to sample by trip weight.
+	Target    string   `json:"target,omitempty"`
+	HomeDepot string   `json:"home_depot,omitempty"`
+	Patrol    []string `json:"patrol,omitempty"`
+	IdleMin   float64  `json:"idle_min,omitempty"`
+	IdleMax   float64  `json:"idle_max,omitempty"`
+}
+
+// ArrivalState is a vehicle's progress between routes.
+type ArrivalState struct {
+	IdleRemaining float64 `json:"idle_remaining,omitempty"`
+	// PatrolIndex is the next node of the patrol circuit.
+	PatrolIndex int `json:"patrol_index"`
+	Retasks     int `json:"retasks"`
+}
@@ -0,0 +1,32 @@
+package entities
+
+type RepairMode string
+
+const (
+	RepairOnSite RepairMode = "on_site"
+	RepairTow    RepairMode = "tow"
+)
+
+type BreakdownPhase string
+
+const (
+	BreakdownRepairing   BreakdownPhase = "repairing"
+	BreakdownAwaitingTow BreakdownPhase = "awaiting_tow"
+	BreakdownTowing      BreakdownPhase = "towing"
+)
+
+// BreakdownState describes a failed vehicle until it is repaired. Times are
+// in simulated seconds.
+type BreakdownState struct {
+	Mode   RepairMode     `json:"mode"`
+	Phase  BreakdownPhase `json:"phase"`
+	EdgeID string         `json:"edge_id"`
+	// Remaining is the time left in the current phase; towing ends on
+	// arrival at Depot instead.
+	Remaining float64 `json:"remaining"`
+	Downtime  float64 `json:"downtime"`
+	// Depot is where the tow comes from and takes the vehicle; ResumeNode is
+	// where the vehicle continues once repaired.
+	Depot      string `json:"depot,omitempty"`
+	ResumeNode string `json:"resume_node,omitempty"`
+}
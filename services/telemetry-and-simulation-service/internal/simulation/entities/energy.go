@@ -0,0 +1,34 @@
+package entities
+
+type EnergySource string
+
+const (
+	EnergyBattery EnergySource = "battery"
+	EnergyFuel    EnergySource = "fuel"
+)
+
+// EnergyState is a vehicle's battery in kWh or tank in litres. Distances are
+// in meters and times in seconds.
+type EnergyState struct {
+	Source         EnergySource `json:"source"`
+	Capacity       float64      `json:"capacity"`
+	Level          float64      `json:"level"`
+	Consumed       float64      `json:"consumed"`
+	Distance       float64      `json:"distance"`
+	EstimatedRange float64      `json:"estimated_range"`
+	ChargingTime   float64      `json:"charging_time"`
+	ChargeSessions int          `json:"charge_sessions"`
+	// ChargerNode is the depot or charger the vehicle is heading to or
+	// charging at; ResumeNode is where it continues afterwards.
+	ChargerNode string  `json:"charger_node,omitempty"`
+	ResumeNode  string  `json:"resume_node,omitempty"`
+	LowAlerted  bool    `json:"low_alerted"`
+	LastSpeed   float64 `json:"-"`
+}
+
+func (e *EnergyState) Fraction() float64 {
+	if e.Capacity <= 0 {
+		return 0
+	}
+	return e.Level / e.Capacity
+}
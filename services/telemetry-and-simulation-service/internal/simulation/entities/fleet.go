@@ -26,9 +26,10 @@ type Fleet struct {
 }
 
 type FleetConfig struct {
-	MaxVehicles          int           `json:"max_vehicles"`
-	DefaultVehicleType   VehicleType   `json:"default_vehicle_type"`
-	EnergyThresholdAlert float64       `json:"energy_threshold_alert"`
+	MaxVehicles          int              `json:"max_vehicles"`
+	DefaultVehicleType   VehicleType      `json:"default_vehicle_type"`
+	EnergyThresholdAlert float64          `json:"energy_threshold_alert"`
+	OnArrival            *ArrivalBehavior `json:"on_arrival,omitempty"`
 }
 
 type FleetMetrics struct {
@@ -37,9 +38,14 @@ type FleetMetrics struct {
 	IdleVehicles          int       `json:"idle_vehicles"`
 	VehiclesInTransit     int       `json:"vehicles_in_transit"`
 	VehiclesBreakdown     int       `json:"vehicles_breakdown"`
+	VehiclesCharging      int       `json:"vehicles_charging"`
+	VehiclesStranded      int       `json:"vehicles_stranded"`
 	TotalDistanceTraveled float64   `json:"total_distance_traveled"`
 	AverageSpeed          float64   `json:"average_speed"`
 	TotalEnergyConsumed   float64   `json:"total_energy_consumed"`
+	TotalChargingTime     float64   `json:"total_charging_time"`
+	Breakdowns            int       `json:"breakdowns"`
+	TotalDowntime         float64   `json:"total_downtime"`
 	CompletedRoutes       int       `json:"completed_routes"`
 	ActiveRoutes          int       `json:"active_routes"`
 	TotalEvents           int       `json:"total_events"`
@@ -0,0 +1,62 @@
+package entities
+
+type ControlType string
+
+const (
+	ControlUncontrolled ControlType = "uncontrolled"
+	ControlPriority     ControlType = "priority"
+	ControlStopSign     ControlType = "stop_sign"
+	ControlFixedTime    ControlType = "fixed_time"
+	ControlActuated     ControlType = "actuated"
+)
+
+// IntersectionControl decides when vehicles arriving at a node on one of its
+// incoming edges may continue onto the next edge of their route. Vehicles
+// ending their route at the node are never held. Times are in seconds.
+type IntersectionControl struct {
+	NodeID string      `json:"node_id"`
+	Type   ControlType `json:"type"`
+	// Phases of a signal, served in order. Incoming edges not listed in
+	// any phase are not signalled.
+	Phases []SignalPhase `json:"phases,omitempty"`
+	// Offset shifts a fixed-time cycle so neighbouring signals can be
+	// coordinated.
+	Offset float64 `json:"offset,omitempty"`
+	// Major lists the approaches with right of way at a priority
+	// intersection; the others yield to them.
+	Major []string `json:"major,omitempty"`
+	// StopTime is the full stop every vehicle makes at a stop sign.
+	StopTime float64 `json:"stop_time,omitempty"`
+	// Headway is the least time between vehicles entering from one lane
+	// of a signal approach, the whole intersection at a stop sign, or the
+	// gap a yielding vehicle needs behind major traffic. Zero means 2s.
+	Headway float64 `json:"headway,omitempty"`
+}
+
+// SignalPhase gives the green to its edges. Green is the fixed green time of
+// a fixed-time signal and the maximum green of an actuated one, which ends
+// a phase after MinGreen once no vehicle has arrived on it for Extension
+// seconds and another phase has vehicles waiting.
+type SignalPhase struct {
+	Edges     []string `json:"edges"`
+	Green     float64  `json:"green"`
+	MinGreen  float64  `json:"min_green,omitempty"`
+	Extension float64  `json:"extension,omitempty"`
+	// Yellow is the clearance after the green during which no vehicle
+	// may enter.
+	Yellow float64 `json:"yellow"`
+}
+
+type SignalState string
+
+const (
+	SignalGreen  SignalState = "green"
+	SignalYellow SignalState = "yellow"
+)
+
+// IntersectionWait is a vehicle held at the end of an edge.
+type IntersectionWait struct {
+	NodeID string  `json:"node_id"`
+	EdgeID string  `json:"edge_id"`
+	Waited float64 `json:"waited"`
+}
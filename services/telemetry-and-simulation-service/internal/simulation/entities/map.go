@@ -2,13 +2,102 @@ package entities
 
 import (
 	"encoding/json"
+	"math"
 	"os"
+	"sync"
 	"time"
 )
 
 type MapGraph struct {
 	Nodes map[string]*MapNode `json:"nodes"`
 	Edges map[string]*MapEdge `json:"edges"`
+	Turns *TurnCosts          `json:"turns,omitempty"` // nil makes turns free
+	// FastestRoutes routes by travel time at the effective speed limits
+	// instead of by distance.
+	FastestRoutes bool `json:"fastest_routes,omitempty"`
+	// Georef places the map on the Earth; nil for generated maps.
+	Georef *Georef `json:"georef,omitempty"`
+
+	derived derivedIndexes
+}
+
+// derivedIndexes caches lookups computed from a graph, such as routing
+// adjacency, so that hot paths do not rebuild them on every query.
+type derivedIndexes struct {
+	mu         sync.Mutex
+	generation uint64
+	values     map[string]interface{}
+}
+
+// Derived returns the index stored under key, building it on first use.
+// Indexes may depend on anything in the graph; code that changes the
+// graph's topology or node attributes after it has been queried calls
+// Invalidate so they are rebuilt.
+func (g *MapGraph) Derived(key string, build func() interface{}) interface{} {
+	g.derived.mu.Lock()
+	if v, ok := g.derived.values[key]; ok {
+		g.derived.mu.Unlock()
+		return v
+	}
+	generation := g.derived.generation
+	g.derived.mu.Unlock()
+
+	// Built unlocked so that building one index can use another.
+	v := build()
+
+	g.derived.mu.Lock()
+	defer g.derived.mu.Unlock()
+	if existing, ok := g.derived.values[key]; ok {
+		return existing
+	}
+	if g.derived.generation == generation {
+		if g.derived.values == nil {
+			g.derived.values = make(map[string]interface{})
+		}
+		g.derived.values[key] = v
+	}
+	return v
+}
+
+// Invalidate drops every index built by Derived.
+func (g *MapGraph) Invalidate() {
+	g.derived.mu.Lock()
+	g.derived.generation++
+	g.derived.values = nil
+	g.derived.mu.Unlock()
+}
+
+// EarthRadius is the mean radius of the Earth in meters.
+const EarthRadius = 6371008.8
+
+// Georef places planar map coordinates on the Earth: (0, 0) is at
+// OriginLat, OriginLon, x runs east and y north, and a unit is
+// MetersPerUnit meters (zero counts as one). The projection is
+// equirectangular about the origin, which is accurate to well under a
+// percent across a city.
+type Georef struct {
+	OriginLat     float64 `json:"origin_lat"`
+	OriginLon     float64 `json:"origin_lon"`
+	MetersPerUnit float64 `json:"meters_per_unit,omitempty"`
+}
+
+func (r Georef) scale() (x, y float64) {
+	m := r.MetersPerUnit
+	if m == 0 {
+		m = 1
+	}
+	y = m / EarthRadius * 180 / math.Pi
+	return y / math.Cos(r.OriginLat*math.Pi/180), y
+}
+
+func (r Georef) LonLat(p Vector2D) (lon, lat float64) {
+	sx, sy := r.scale()
+	return r.OriginLon + p.X*sx, r.OriginLat + p.Y*sy
+}
+
+func (r Georef) Planar(lon, lat float64) Vector2D {
+	sx, sy := r.scale()
+	return Vector2D{X: (lon - r.OriginLon) / sx, Y: (lat - r.OriginLat) / sy}
 }
 
 type MapNode struct {
@@ -16,6 +105,33 @@ type MapNode struct {
 	Position    Vector2D        `json:"position"`
 	Type        NodeType        `json:"type"`
 	Connections map[string]bool `json:"connections"`
+	Facility    *NodeFacility   `json:"facility,omitempty"`
+
+	Restrictions []TurnRestriction `json:"restrictions,omitempty"`
+
+	Zone ZoneType `json:"zone,omitempty"`
+	// Production and Attraction weight the node as the origin and as the
+	// destination of trips.
+	Production float64 `json:"production,omitempty"`
+	Attraction float64 `json:"attraction,omitempty"`
+}
+
+type ZoneType string
+
+const (
+	ZoneResidential ZoneType = "residential"
+	ZoneCommercial  ZoneType = "commercial"
+	ZoneIndustrial  ZoneType = "industrial"
+)
+
+// NodeFacility is the service capacity of a depot, charging or parking node.
+// Zero slots means the node does not offer that service.
+type NodeFacility struct {
+	Chargers int `json:"chargers"`
+	// ChargePower caps battery charging per slot in kW; 0 leaves it to the
+	// vehicle's charge curve.
+	ChargePower  float64 `json:"charge_power"`
+	ParkingSlots int     `json:"parking_slots"`
 }
 
 type MapEdge struct {
@@ -27,9 +143,36 @@ type MapEdge struct {
 	SurfaceQuality float64 `json:"surface_quality"`
 	Bidirectional  bool    `json:"bidirectional"`
 
+	Class RoadClass `json:"class,omitempty"`
+	Lanes int       `json:"lanes,omitempty"` // per direction
+	// Capacity is the flow in vehicles per hour the edge carries in each
+	// direction.
+	Capacity float64 `json:"capacity,omitempty"`
+	// Geometry holds the points the road bends through between From and To,
+	// in order from From.
+	Geometry []Vector2D `json:"geometry,omitempty"`
+
 	Conditions *RoadConditions `json:"conditions"`
 }
 
+// OtherEnd is the node a vehicle entering the edge at node drives towards,
+// which on a bidirectional edge may be From.
+func (e *MapEdge) OtherEnd(node string) string {
+	if node == e.To && node != e.From {
+		return e.From
+	}
+	return e.To
+}
+
+type RoadClass string
+
+const (
+	RoadMotorway  RoadClass = "motorway"
+	RoadArterial  RoadClass = "arterial"
+	RoadCollector RoadClass = "collector"
+	RoadLocal     RoadClass = "local"
+)
+
 type RoadConditions struct {
 	Congestion          float64   `json:"congestion"`
 	WeatherMultiplier   float64   `json:"weather_multiplier"`
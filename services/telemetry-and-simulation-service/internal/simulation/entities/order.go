@@ -0,0 +1,52 @@
+package entities
+
+type OrderStatus string
+
+const (
+	OrderPending   OrderStatus = "pending"
+	OrderAssigned  OrderStatus = "assigned"
+	OrderPickedUp  OrderStatus = "picked_up"
+	OrderDelivered OrderStatus = "delivered"
+	OrderExpired   OrderStatus = "expired"
+	OrderCancelled OrderStatus = "cancelled"
+)
+
+// TimeWindow bounds a pickup or dropoff in seconds on the dispatch clock.
+// A zero Latest leaves the window open.
+type TimeWindow struct {
+	Earliest float64 `json:"earliest"`
+	Latest   float64 `json:"latest"`
+}
+
+// Order is a ride or delivery from PickupNode to DropoffNode. Times are in
+// seconds on the dispatch clock.
+type Order struct {
+	ID            string      `json:"id"`
+	PickupNode    string      `json:"pickup_node"`
+	DropoffNode   string      `json:"dropoff_node"`
+	Load          float64     `json:"load"`
+	PickupWindow  TimeWindow  `json:"pickup_window"`
+	DropoffWindow TimeWindow  `json:"dropoff_window"`
+	Status        OrderStatus `json:"status"`
+	VehicleID     string      `json:"vehicle_id,omitempty"`
+	CreatedAt     float64     `json:"created_at"`
+	AssignedAt    float64     `json:"assigned_at,omitempty"`
+	PickedUpAt    float64     `json:"picked_up_at,omitempty"`
+	DeliveredAt   float64     `json:"delivered_at,omitempty"`
+	// Late is set when the pickup or dropoff missed its window.
+	Late bool `json:"late"`
+}
+
+type StopKind string
+
+const (
+	StopPickup  StopKind = "pickup"
+	StopDropoff StopKind = "dropoff"
+)
+
+// Stop is one leg end of a vehicle's work plan.
+type Stop struct {
+	NodeID  string   `json:"node_id"`
+	OrderID string   `json:"order_id"`
+	Kind    StopKind `json:"kind"`
+}
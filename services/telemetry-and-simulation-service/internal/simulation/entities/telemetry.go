@@ -4,20 +4,33 @@ import (
 	"time"
 )
 
+// TelemetrySchemaVersion is bumped on any incompatible change to the
+// telemetry events below; see proto/telemetry/v1/telemetry.proto.
+const TelemetrySchemaVersion = 1
+
 type BasicVehiclePosEvent struct {
-	VehicleID  string    `json:"vehicle_id"`
-	EdgeID     string    `json:"edge_id"`
-	FromNodeID string    `json:"from_node_id"`
-	Progress   float64   `json:"progress"`
-	Timestamp  time.Time `json:"timestamp"`
+	SchemaVersion int       `json:"schema_version"`
+	RunID         string    `json:"run_id"`
+	FleetID       string    `json:"fleet_id"`
+	VehicleID     string    `json:"vehicle_id"`
+	Sequence      uint64    `json:"sequence"`
+	EdgeID        string    `json:"edge_id"`
+	FromNodeID    string    `json:"from_node_id"`
+	Progress      float64   `json:"progress"`
+	Position      Vector2D  `json:"position"`
+	Timestamp     time.Time `json:"timestamp"`
 }
 
 type VehicleEvent struct {
-	VehicleID string                 `json:"vehicle_id"`
-	EventType EventType              `json:"event_type"`
-	Timestamp time.Time              `json:"timestamp"`
-	Data      map[string]interface{} `json:"data"`
-	Severity  Severity               `json:"severity"`
+	SchemaVersion int                    `json:"schema_version"`
+	RunID         string                 `json:"run_id"`
+	FleetID       string                 `json:"fleet_id"`
+	VehicleID     string                 `json:"vehicle_id"`
+	Sequence      uint64                 `json:"sequence"`
+	EventType     EventType              `json:"event_type"`
+	Timestamp     time.Time              `json:"timestamp"`
+	Data          map[string]interface{} `json:"data"`
+	Severity      Severity               `json:"severity"`
 }
 
 type TelemetryMetrics struct {
@@ -0,0 +1,52 @@
+package entities
+
+// TransitLine is a fixed route served in a loop: vehicles leave the first
+// stop, call at every stop in order and return to the first one. Times are
+// simulated seconds since the line was added.
+type TransitLine struct {
+	ID    string        `json:"id"`
+	Name  string        `json:"name"`
+	Stops []TransitStop `json:"stops"`
+	// Headway spaces departures from the first stop evenly. Departures,
+	// when set, lists them explicitly instead and service ends after the
+	// last one.
+	Headway     float64     `json:"headway"`
+	Departures  []float64   `json:"departures,omitempty"`
+	Vehicles    int         `json:"vehicles"`
+	VehicleType VehicleType `json:"vehicle_type,omitempty"`
+	// EarlyTolerance and LateTolerance bound an on-time arrival; zero means
+	// 60s early and 300s late.
+	EarlyTolerance float64 `json:"early_tolerance,omitempty"`
+	LateTolerance  float64 `json:"late_tolerance,omitempty"`
+	// Schedule is the planned arrival at each stop relative to the trip's
+	// departure, with a final entry for the return to the first stop.
+	Schedule []float64 `json:"schedule"`
+}
+
+// TransitStop is a stop of a line. Offset fixes its scheduled arrival
+// relative to the trip's departure; zero derives it from free-flow travel
+// times and dwells.
+type TransitStop struct {
+	NodeID string  `json:"node_id"`
+	Dwell  float64 `json:"dwell"`
+	Offset float64 `json:"offset,omitempty"`
+}
+
+// TransitState is a vehicle's place in its line's schedule.
+type TransitState struct {
+	LineID    string  `json:"line_id"`
+	Trip      int     `json:"trip"`
+	TripStart float64 `json:"trip_start"`
+	Clock     float64 `json:"clock"`
+	// Deviation is the lateness at the last stop, negative when early.
+	Deviation float64 `json:"deviation"`
+	InService bool    `json:"in_service"`
+}
+
+type ScheduleAdherence string
+
+const (
+	AdherenceEarly  ScheduleAdherence = "early"
+	AdherenceOnTime ScheduleAdherence = "on_time"
+	AdherenceLate   ScheduleAdherence = "late"
+)
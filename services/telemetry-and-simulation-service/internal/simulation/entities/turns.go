@@ -0,0 +1,20 @@
+package entities
+
+// TurnRestriction forbids turning from edge From onto edge To at the node
+// holding it. With Only it forbids every turn from From except onto To.
+type TurnRestriction struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Only bool   `json:"only,omitempty"`
+}
+
+// TurnCosts makes routing pay for turns by their angle: turning through a
+// degrees costs Penalty * (a/180)^2 seconds, times LeftFactor for turns to
+// the left, which cross oncoming traffic. Turns sharper than UTurnAngle
+// degrees, and driving back along the same edge, are U-turns.
+type TurnCosts struct {
+	Penalty    float64 `json:"penalty"`
+	LeftFactor float64 `json:"left_factor,omitempty"`
+	UTurnAngle float64 `json:"u_turn_angle,omitempty"`
+	NoUTurns   bool    `json:"no_u_turns"`
+}
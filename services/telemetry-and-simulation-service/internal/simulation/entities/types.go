@@ -22,6 +22,9 @@ const (
     VehicleStatusStopped   VehicleStatus = "stopped"
     VehicleStatusArrived   VehicleStatus = "arrived"
     VehicleStatusBreakdown VehicleStatus = "breakdown"
+    VehicleStatusCharging  VehicleStatus = "charging"
+    VehicleStatusStranded  VehicleStatus = "stranded"
+    VehicleStatusQueued    VehicleStatus = "queued"
 )
 
 type WeatherCondition string
@@ -41,6 +44,7 @@ const (
     NodeTypeWaypoint     NodeType = "waypoint"
     NodeTypeParking      NodeType = "parking"
     NodeTypeDepot        NodeType = "depot"
+    NodeTypeCharging     NodeType = "charging"
 )
 
 type EventType string
@@ -54,6 +58,24 @@ const (
     EventBreakdownOccurred  EventType = "breakdown_occurred"
     EventEnergyLow          EventType = "energy_low"
     EventTrafficCongestion  EventType = "traffic_congestion"
+    EventFleetSummary       EventType = "fleet_summary"
+    EventEnergyDepleted     EventType = "energy_depleted"
+    EventChargingStarted    EventType = "charging_started"
+    EventChargingCompleted  EventType = "charging_completed"
+    EventQueueEntered       EventType = "queue_entered"
+    EventQueueExited        EventType = "queue_exited"
+    EventTowArrived         EventType = "tow_arrived"
+    EventRepairCompleted    EventType = "repair_completed"
+    EventOrderCreated       EventType = "order_created"
+    EventOrderAssigned      EventType = "order_assigned"
+    EventOrderPickedUp      EventType = "order_picked_up"
+    EventOrderDelivered     EventType = "order_delivered"
+    EventOrderExpired       EventType = "order_expired"
+    EventOrderCancelled     EventType = "order_cancelled"
+    EventWaypointArrived    EventType = "waypoint_arrived"
+    EventWaypointDeparted   EventType = "waypoint_departed"
+    EventScheduleAdherence  EventType = "schedule_adherence"
+    EventSignalChanged      EventType = "signal_changed"
 )
 
 
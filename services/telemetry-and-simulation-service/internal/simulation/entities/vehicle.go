@@ -6,13 +6,21 @@ import (
 )
 
 type Vehicle struct {
-	ID              string         `json:"id"`
-	Type            VehicleType    `json:"type"`
-	State           VehicleState   `json:"state"`
-	Route           *AssignedRoute `json:"route,omitempty"`
-	AssignedFleetID string         `json:"assigned_fleet_id"`
-	Mutex           sync.Mutex     `json:"-"`
-	StopChan        chan struct{}  `json:"-"`
+	ID              string            `json:"id"`
+	Type            VehicleType       `json:"type"`
+	State           VehicleState      `json:"state"`
+	Route           *AssignedRoute    `json:"route,omitempty"`
+	AssignedFleetID string            `json:"assigned_fleet_id"`
+	Energy          *EnergyState      `json:"energy,omitempty"`
+	Breakdown       *BreakdownState   `json:"breakdown,omitempty"`
+	Stops           []Stop            `json:"stops,omitempty"`
+	Load            float64           `json:"load,omitempty"`
+	OnArrival       *ArrivalBehavior  `json:"on_arrival,omitempty"`
+	Arrival         *ArrivalState     `json:"arrival,omitempty"`
+	Transit         *TransitState     `json:"transit,omitempty"`
+	Intersection    *IntersectionWait `json:"intersection,omitempty"`
+	Mutex           sync.Mutex        `json:"-"`
+	StopChan        chan struct{}     `json:"-"`
 }
 
 type VehicleState struct {
@@ -24,6 +32,9 @@ type VehicleState struct {
 	LastUpdateTime  time.Time     `json:"last_update_time"`
 }
 
+// AssignedRoute is a vehicle's route. Its current edge is driven from
+// CurrentNode to TargetNode, which is against the edge's From and To when a
+// bidirectional edge is taken in reverse.
 type AssignedRoute struct {
 	Edges            []string   `json:"edges"`
 	CurrentEdgeIndex int        `json:"current_edge_index"`
@@ -33,6 +44,29 @@ type AssignedRoute struct {
 	EndNode          string     `json:"end_node"`
 	StartedAt        time.Time  `json:"started_at"`
 	CompletedAt      *time.Time `json:"completed_at,omitempty"`
+	// Legs splits a multi-leg route at its waypoints. DwellRemaining counts
+	// down while the vehicle is stopped at Legs[CurrentLeg].
+	Legs           []RouteLeg `json:"legs,omitempty"`
+	CurrentLeg     int        `json:"current_leg,omitempty"`
+	DwellRemaining float64    `json:"dwell_remaining,omitempty"`
+}
+
+// Waypoint is a stop on a multi-leg route where the vehicle waits Dwell
+// seconds before moving on.
+type Waypoint struct {
+	NodeID string  `json:"node_id"`
+	Dwell  float64 `json:"dwell"`
+}
+
+// RouteLeg is the part of a route leading to one waypoint; it ends once the
+// vehicle has driven Edges[:EdgeEnd]. Times are simulated seconds.
+type RouteLeg struct {
+	Waypoint
+	EdgeEnd    int        `json:"edge_end"`
+	TravelTime float64    `json:"travel_time"`
+	DwellTime  float64    `json:"dwell_time"`
+	ArrivedAt  *time.Time `json:"arrived_at,omitempty"`
+	DepartedAt *time.Time `json:"departed_at,omitempty"`
 }
 
 type VehiclePosition struct {
@@ -0,0 +1,151 @@
+package simulationengine
+
+import (
+	"fmt"
+	"math/rand/v2"
+
+	"github.com/m/internal/simulation/entities"
+)
+
+// arrivalBehavior resolves what the vehicle does after arriving: its own
+// behavior, else its fleet's, else the engine default.
+func (s *SimulationEngine) arrivalBehavior(vehicle *entities.Vehicle) *entities.ArrivalBehavior {
+	vehicle.Mutex.Lock()
+	b, fleetID := vehicle.OnArrival, vehicle.AssignedFleetID
+	vehicle.Mutex.Unlock()
+	if b != nil {
+		return b
+	}
+
+	s.fleetMu.RLock()
+	if fleet, ok := s.fleets[fleetID]; ok {
+		b = fleet.Config.OnArrival
+	}
+	s.fleetMu.RUnlock()
+	if b != nil {
+		return b
+	}
+	return s.OnArrival
+}
+
+func validateArrival(b *entities.ArrivalBehavior) error {
+	if b == nil {
+		return nil
+	}
+
+	switch b.Mode {
+	case entities.ArrivalIdle, entities.ArrivalReturnHome, entities.ArrivalDispatch:
+	case entities.ArrivalNewTarget:
+		switch TargetStrategy(b.Target) {
+		case "", TargetRandom, TargetFarthest, TargetAttraction, TargetProduction:
+		default:
+			return fmt.Errorf("unknown arrival target %q", b.Target)
+		}
+	case entities.ArrivalPatrol:
+		if len(b.Patrol) == 0 {
+			return fmt.Errorf("patrol needs at least one node")
+		}
+	default:
+		return fmt.Errorf("unknown arrival mode %q", b.Mode)
+	}
+
+	if b.IdleMin < 0 || (b.IdleMax > 0 && b.IdleMax < b.IdleMin) {
+		return fmt.Errorf("idle time must satisfy 0 <= idle_min <= idle_max")
+	}
+	return nil
+}
+
+// retask sends a vehicle that has finished all its work on to its next
+// route once its idle time is up. Expects vehicle.Mutex to be held.
+func (s *SimulationEngine) retask(vehicle *entities.Vehicle, b *entities.ArrivalBehavior, arrived bool, dt float64) []pendingEvent {
+	r := vehicle.Route
+	st := vehicle.Arrival
+	done := r != nil && r.CompletedAt != nil && vehicle.State.Status == entities.VehicleStatusArrived &&
+		len(vehicle.Stops) == 0 && vehicle.Breakdown == nil && vehicle.Transit == nil
+	if !done || b == nil {
+		if st != nil {
+			st.IdleRemaining = 0
+		}
+		return nil
+	}
+	switch b.Mode {
+	case entities.ArrivalReturnHome, entities.ArrivalNewTarget, entities.ArrivalPatrol:
+	default:
+		return nil
+	}
+
+	if st == nil {
+		st = &entities.ArrivalState{}
+		vehicle.Arrival = st
+	}
+	if st.IdleRemaining > 0 {
+		st.IdleRemaining -= dt
+		if st.IdleRemaining > 0 {
+			return nil
+		}
+		st.IdleRemaining = 0
+	} else if arrived {
+		if st.IdleRemaining = idleTime(b); st.IdleRemaining > 0 {
+			return nil
+		}
+	}
+
+	from := r.EndNode
+	var err error
+	switch b.Mode {
+	case entities.ArrivalReturnHome:
+		home := b.HomeDepot
+		if home == "" {
+			home, _ = s.nearestDepot(from)
+		}
+		if home == "" || home == from {
+			return nil
+		}
+		err = RerouteVehicle(vehicle, s.Graph, home)
+	case entities.ArrivalNewTarget:
+		err = s.rerouteToNewTarget(vehicle, from, TargetStrategy(b.Target))
+	case entities.ArrivalPatrol:
+		if from == b.Patrol[st.PatrolIndex%len(b.Patrol)] {
+			st.PatrolIndex++
+		}
+		st.PatrolIndex %= len(b.Patrol)
+		next := b.Patrol[st.PatrolIndex]
+		if next == from {
+			return nil
+		}
+		err = RerouteVehicle(vehicle, s.Graph, next)
+	}
+	if err != nil {
+		return nil
+	}
+
+	st.Retasks++
+	return []pendingEvent{{entities.EventRouteStarted, entities.SeverityInfo, map[string]interface{}{"reason": string(b.Mode)}}}
+}
+
+// rerouteToNewTarget tries a few targets in case the first is unreachable.
+func (s *SimulationEngine) rerouteToNewTarget(vehicle *entities.Vehicle, from string, strategy TargetStrategy) error {
+	nodeIDs := graphNodeIDs(s.Graph)
+	if len(nodeIDs) < 2 {
+		return fmt.Errorf("no other node to go to")
+	}
+
+	var err error
+	for i := 0; i < 5; i++ {
+		target := selectTargetNode(nodeIDs, s.Graph, from, strategy, false)
+		if target == from {
+			continue
+		}
+		if err = RerouteVehicle(vehicle, s.Graph, target); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("no reachable target from %s: %v", from, err)
+}
+
+func idleTime(b *entities.ArrivalBehavior) float64 {
+	if b.IdleMax <= b.IdleMin {
+		return b.IdleMin
+	}
+	return b.IdleMin + rand.Float64()*(b.IdleMax-b.IdleMin)
+}
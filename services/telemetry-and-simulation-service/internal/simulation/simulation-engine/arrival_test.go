@@ -0,0 +1,89 @@
+package simulationengine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/m/internal/simulation/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArrival_PatrolLoop(t *testing.T) {
+	engine, emitter := waypointTestEngine(t)
+	v := &entities.Vehicle{ID: "v1", OnArrival: &entities.ArrivalBehavior{Mode: entities.ArrivalPatrol, Patrol: []string{"N2", "N4"}}}
+	require.NoError(t, AssignVehicleRouteWithNodes(v, engine.Graph, "N0", "N2"))
+	engine.AddVehicle(v)
+
+	var ends []string
+	for i := 0; i < 20; i++ {
+		require.NoError(t, engine.Step(10*time.Second))
+		ends = append(ends, v.Route.EndNode)
+	}
+	assert.Equal(t, entities.VehicleStatusMoving, v.State.Status)
+	assert.Contains(t, ends, "N4")
+	assert.Equal(t, "N2", ends[len(ends)-1])
+	assert.GreaterOrEqual(t, v.Arrival.Retasks, 3)
+
+	events := drainEvents(emitter)
+	assert.Equal(t, entities.EventRouteCompleted, events[0])
+	assert.Equal(t, entities.EventRouteStarted, events[1])
+}
+
+func TestArrival_IdleThenFarthest(t *testing.T) {
+	engine, _ := waypointTestEngine(t)
+	engine.OnArrival = &entities.ArrivalBehavior{Mode: entities.ArrivalNewTarget, Target: string(TargetFarthest), IdleMin: 30, IdleMax: 30}
+	v := &entities.Vehicle{ID: "v1"}
+	require.NoError(t, AssignVehicleRouteWithNodes(v, engine.Graph, "N0", "N2"))
+	engine.AddVehicle(v)
+
+	stepUntil(t, engine, func() bool { return v.Route.CompletedAt != nil })
+	for i := 0; i < 2; i++ {
+		require.NoError(t, engine.Step(10*time.Second))
+		assert.Equal(t, entities.VehicleStatusArrived, v.State.Status)
+		assert.True(t, holdVehicle(v))
+	}
+
+	require.NoError(t, engine.Step(10*time.Second))
+	assert.Equal(t, "N9", v.Route.EndNode)
+	assert.Equal(t, entities.VehicleStatusMoving, v.State.Status)
+	assert.Equal(t, 1, v.Arrival.Retasks)
+}
+
+func TestArrival_ReturnHomeAndFleetDefault(t *testing.T) {
+	engine, _ := energyTestEngine(t)
+	engine.Energy = nil
+
+	_, err := engine.CreateFleet(entities.Fleet{ID: "bad", Config: entities.FleetConfig{OnArrival: &entities.ArrivalBehavior{Mode: entities.ArrivalPatrol}}})
+	assert.ErrorIs(t, err, ErrInvalidFleet)
+	_, err = engine.CreateFleet(entities.Fleet{ID: "home", Config: entities.FleetConfig{OnArrival: &entities.ArrivalBehavior{Mode: entities.ArrivalReturnHome}}})
+	require.NoError(t, err)
+
+	v := &entities.Vehicle{ID: "v1"}
+	require.NoError(t, AssignVehicleRouteWithNodes(v, engine.Graph, "B", "C"))
+	require.NoError(t, engine.AddVehicleToFleet(v, "home", "test", 0))
+
+	stepUntil(t, engine, func() bool { return v.Route.EndNode == "D" && v.Route.CompletedAt != nil })
+	for i := 0; i < 3; i++ {
+		require.NoError(t, engine.Step(10*time.Second))
+	}
+	assert.Equal(t, "D", v.Route.EndNode)
+	assert.Equal(t, entities.VehicleStatusArrived, v.State.Status)
+	assert.Equal(t, 1, v.Arrival.Retasks)
+}
+
+func TestArrival_IdleVehiclesSkipDispatch(t *testing.T) {
+	engine, v, _ := orderTestEngine(t, NearestIdlePolicy{})
+	v.OnArrival = &entities.ArrivalBehavior{Mode: entities.ArrivalIdle}
+
+	_, err := engine.SubmitOrder(entities.Order{ID: "o1", PickupNode: "A", DropoffNode: "C"})
+	require.NoError(t, err)
+	require.NoError(t, engine.Step(10*time.Second))
+	o, _ := engine.GetOrder("o1")
+	assert.Equal(t, entities.OrderPending, o.Status)
+
+	v.OnArrival = &entities.ArrivalBehavior{Mode: entities.ArrivalDispatch}
+	require.NoError(t, engine.Step(10*time.Second))
+	o, _ = engine.GetOrder("o1")
+	assert.Equal(t, entities.OrderAssigned, o.Status)
+}
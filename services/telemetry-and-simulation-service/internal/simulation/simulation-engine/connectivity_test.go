@@ -214,6 +214,69 @@ func TestAllAlgorithms_WithConnectivityAndVariation(t *testing.T) {
 	fmt.Println(strings.Repeat("=", 80) + "\n")
 }
 
+func TestGraphConnectivity_OneWayStreets(t *testing.T) {
+	for _, algo := range []simulationengine.Algorithm{simulationengine.AlgoRGG, simulationengine.AlgoKNN, simulationengine.AlgoDelaunay} {
+		config := simulationengine.NewMapGenerator(1000, 1000, 42, algo, 60, 3)
+		config.RadiusMode = simulationengine.Sparse
+		config.OneWayFraction = 0.7
+
+		graph := config.Generate()
+
+		oneWay := 0
+		for _, edge := range graph.Edges {
+			if edge.Bidirectional {
+				continue
+			}
+			oneWay++
+			if graph.Nodes[edge.To].Connections[edge.From] {
+				t.Errorf("%s: one-way edge %s is connected backwards", algo, edge.ID)
+			}
+		}
+		if oneWay == 0 {
+			t.Errorf("%s: expected one-way edges", algo)
+		}
+
+		if components := simulationengine.StronglyConnectedComponents(graph); len(components) != 1 {
+			t.Errorf("%s: expected 1 strongly connected component, got %d", algo, len(components))
+		}
+	}
+}
+
+func TestGraphConnectivity_StrongConnectivityRepair(t *testing.T) {
+	graph := &entities.MapGraph{Nodes: map[string]*entities.MapNode{}, Edges: map[string]*entities.MapEdge{}}
+	for i, id := range []string{"A", "B", "C", "D", "E", "F"} {
+		graph.Nodes[id] = &entities.MapNode{ID: id, Position: entities.Vector2D{X: float64(i) * 100}, Connections: map[string]bool{}}
+	}
+	for _, e := range [][2]string{{"A", "B"}, {"B", "C"}, {"C", "A"}, {"C", "D"}, {"D", "E"}, {"E", "D"}} {
+		graph.Nodes[e[0]].Connections[e[1]] = true
+	}
+
+	if err := simulationengine.BuildEdgesFromConnections(graph); err != nil {
+		t.Fatalf("BuildEdgesFromConnections failed: %v", err)
+	}
+
+	components := simulationengine.StronglyConnectedComponents(graph)
+	want := [][]string{{"D", "E"}, {"A", "B", "C"}, {"F"}}
+	if fmt.Sprint(components) != fmt.Sprint(want) {
+		t.Errorf("Expected components %v, got %v", want, components)
+	}
+
+	simulationengine.EnsureStrongConnectivity(graph)
+
+	if components := simulationengine.StronglyConnectedComponents(graph); len(components) != 1 {
+		t.Errorf("Expected 1 strongly connected component, got %v", components)
+	}
+	oneWay := 0
+	for _, edge := range graph.Edges {
+		if !edge.Bidirectional {
+			oneWay++
+		}
+	}
+	if oneWay != 3 {
+		t.Errorf("Expected the A-B-C loop to stay one-way, got %d one-way edges", oneWay)
+	}
+}
+
 func countComponents(graph *entities.MapGraph) int {
 	visited := make(map[string]bool)
 	components := 0
@@ -0,0 +1,376 @@
+package simulationengine
+
+import (
+	"math"
+
+	"github.com/m/internal/simulation/entities"
+)
+
+// DispatchCandidate is a vehicle that can take another order.
+type DispatchCandidate struct {
+	VehicleID string
+	// Node is where the vehicle heads for a new pickup from, after ReadyIn
+	// seconds of finishing its current work.
+	Node     string
+	ReadyIn  float64
+	Idle     bool
+	Capacity float64
+}
+
+// DispatchPolicy matches pending orders, oldest first, to candidates.
+// cost[i][j] is the expected seconds until candidate j picks up order i, or
+// +Inf when it cannot serve it in time. Match returns the candidate index
+// for each order, or -1; a candidate takes at most one order per round.
+type DispatchPolicy interface {
+	Match(orders []entities.Order, candidates []DispatchCandidate, cost [][]float64) []int
+}
+
+// NearestIdlePolicy gives each order, in arrival order, the idle vehicle
+// with the shortest travel time to its pickup.
+type NearestIdlePolicy struct{}
+
+func (NearestIdlePolicy) Match(orders []entities.Order, candidates []DispatchCandidate, cost [][]float64) []int {
+	used := make([]bool, len(candidates))
+	match := make([]int, len(orders))
+	for i := range orders {
+		match[i] = -1
+		best := math.Inf(1)
+		for j, c := range candidates {
+			if c.Idle && !used[j] && cost[i][j] < best {
+				match[i], best = j, cost[i][j]
+			}
+		}
+		if match[i] >= 0 {
+			used[match[i]] = true
+		}
+	}
+	return match
+}
+
+// HungarianPolicy matches the whole batch of pending orders to idle and
+// busy vehicles at once, minimising the total time to pickup.
+type HungarianPolicy struct{}
+
+func (HungarianPolicy) Match(orders []entities.Order, candidates []DispatchCandidate, cost [][]float64) []int {
+	match := make([]int, len(orders))
+	for i := range match {
+		match[i] = -1
+	}
+	if len(orders) == 0 || len(candidates) == 0 {
+		return match
+	}
+
+	// Infeasible pairs get a cost no feasible assignment can reach, so they
+	// are only chosen when nothing else is left.
+	var big float64 = 1
+	for _, row := range cost {
+		for _, c := range row {
+			if !math.IsInf(c, 0) {
+				big += math.Abs(c)
+			}
+		}
+	}
+	finite := func(c float64) float64 {
+		if math.IsInf(c, 0) {
+			return big
+		}
+		return c
+	}
+
+	if len(orders) <= len(candidates) {
+		m := make([][]float64, len(orders))
+		for i := range m {
+			m[i] = make([]float64, len(candidates))
+			for j := range m[i] {
+				m[i][j] = finite(cost[i][j])
+			}
+		}
+		for i, j := range hungarian(m) {
+			if !math.IsInf(cost[i][j], 0) {
+				match[i] = j
+			}
+		}
+		return match
+	}
+
+	m := make([][]float64, len(candidates))
+	for j := range m {
+		m[j] = make([]float64, len(orders))
+		for i := range m[j] {
+			m[j][i] = finite(cost[i][j])
+		}
+	}
+	for j, i := range hungarian(m) {
+		if !math.IsInf(cost[i][j], 0) {
+			match[i] = j
+		}
+	}
+	return match
+}
+
+// hungarian solves the assignment problem for an n x m matrix with n <= m
+// and returns the column assigned to each row.
+func hungarian(cost [][]float64) []int {
+	n, m := len(cost), len(cost[0])
+	u := make([]float64, n+1)
+	v := make([]float64, m+1)
+	p := make([]int, m+1)
+	way := make([]int, m+1)
+
+	for i := 1; i <= n; i++ {
+		p[0] = i
+		j0 := 0
+		minv := make([]float64, m+1)
+		for j := range minv {
+			minv[j] = math.Inf(1)
+		}
+		used := make([]bool, m+1)
+		for {
+			used[j0] = true
+			i0, delta, j1 := p[j0], math.Inf(1), 0
+			for j := 1; j <= m; j++ {
+				if used[j] {
+					continue
+				}
+				if cur := cost[i0-1][j-1] - u[i0] - v[j]; cur < minv[j] {
+					minv[j], way[j] = cur, j0
+				}
+				if minv[j] < delta {
+					delta, j1 = minv[j], j
+				}
+			}
+			for j := 0; j <= m; j++ {
+				if used[j] {
+					u[p[j]] += delta
+					v[j] -= delta
+				} else {
+					minv[j] -= delta
+				}
+			}
+			j0 = j1
+			if p[j0] == 0 {
+				break
+			}
+		}
+		for j0 != 0 {
+			j1 := way[j0]
+			p[j0] = p[j1]
+			j0 = j1
+		}
+	}
+
+	rows := make([]int, n)
+	for j := 1; j <= m; j++ {
+		if p[j] != 0 {
+			rows[p[j]-1] = j - 1
+		}
+	}
+	return rows
+}
+
+// dispatchOrders runs one matching round over the pending orders.
+func (s *SimulationEngine) dispatchOrders() {
+	cfg := s.Dispatch
+	if cfg == nil {
+		return
+	}
+
+	s.orderMu.Lock()
+	clock := s.orders.clock
+	orders := make([]entities.Order, 0, len(s.orders.pending))
+	for _, id := range s.orders.pending {
+		orders = append(orders, *s.orders.orders[id])
+	}
+	s.orderMu.Unlock()
+	if len(orders) == 0 {
+		return
+	}
+
+	candidates := s.dispatchCandidates(cfg)
+	if len(candidates) == 0 {
+		return
+	}
+
+	times := make(map[string]map[string]float64)
+	cost := make([][]float64, len(orders))
+	for i, o := range orders {
+		cost[i] = make([]float64, len(candidates))
+		for j, c := range candidates {
+			tt, ok := times[c.Node]
+			if !ok {
+				tt = travelTimes(s.Graph, c.Node)
+				times[c.Node] = tt
+			}
+			t, reachable := tt[o.PickupNode]
+			switch {
+			case !reachable || o.Load > c.Capacity:
+				cost[i][j] = math.Inf(1)
+			case o.PickupWindow.Latest > 0 && clock+c.ReadyIn+t > o.PickupWindow.Latest:
+				cost[i][j] = math.Inf(1)
+			default:
+				cost[i][j] = c.ReadyIn + t
+			}
+		}
+	}
+
+	for i, j := range cfg.policy().Match(orders, candidates, cost) {
+		if j >= 0 && !math.IsInf(cost[i][j], 0) {
+			s.assignOrder(orders[i].ID, candidates[j].VehicleID)
+		}
+	}
+}
+
+// dispatchCandidates lists the vehicles that can take an order: idle ones
+// where they stand, busy ones from their last stop or the end of their
+// current edge.
+func (s *SimulationEngine) dispatchCandidates(cfg *DispatchConfig) []DispatchCandidate {
+	var candidates []DispatchCandidate
+	times := make(map[string]map[string]float64)
+	legTime := func(from, to string) float64 {
+		if from == to {
+			return 0
+		}
+		tt, ok := times[from]
+		if !ok {
+			tt = travelTimes(s.Graph, from)
+			times[from] = tt
+		}
+		if t, ok := tt[to]; ok {
+			return t
+		}
+		return math.Inf(1)
+	}
+
+	for _, v := range s.ListVehicles() {
+		if b := s.arrivalBehavior(v); b != nil && b.Mode == entities.ArrivalIdle {
+			continue
+		}
+		v.Mutex.Lock()
+		r := v.Route
+		busy := r == nil || v.Breakdown != nil || v.Transit != nil || (v.Energy != nil && v.Energy.ChargerNode != "")
+		switch v.State.Status {
+		case entities.VehicleStatusCharging, entities.VehicleStatusQueued, entities.VehicleStatusStranded, entities.VehicleStatusBreakdown:
+			busy = true
+		}
+		orders := 0
+		for _, stop := range v.Stops {
+			if stop.Kind == entities.StopDropoff {
+				orders++
+			}
+		}
+		if busy || (cfg.MaxOrders > 0 && orders >= cfg.MaxOrders) {
+			v.Mutex.Unlock()
+			continue
+		}
+
+		c := DispatchCandidate{VehicleID: v.ID, Capacity: cfg.capacity(v.Type)}
+		var legs []string
+		switch {
+		case r.CompletedAt != nil:
+			c.Node = r.EndNode
+			c.Idle = len(v.Stops) == 0
+			legs = []string{r.EndNode}
+		case len(v.Stops) > 0:
+			c.ReadyIn = remainingRouteTime(v, s.Graph)
+			legs = []string{r.EndNode}
+		default:
+			// A vehicle on a free route is diverted at the end of its edge.
+			c.Node = r.CurrentNode
+			if v.State.ProgressOnEdge > 0 {
+				c.Node = r.TargetNode
+				if edge := s.Graph.Edges[v.State.CurrentEdge]; edge != nil {
+					c.ReadyIn = edgeTravelTime(edge) * (1 - clamp(v.State.ProgressOnEdge, 0, 1))
+				}
+			}
+		}
+		for _, stop := range v.Stops {
+			legs = append(legs, stop.NodeID)
+		}
+		v.Mutex.Unlock()
+
+		for i := 1; i < len(legs); i++ {
+			c.ReadyIn += legTime(legs[i-1], legs[i])
+		}
+		if len(legs) > 0 {
+			c.Node = legs[len(legs)-1]
+		}
+		if !math.IsInf(c.ReadyIn, 0) {
+			candidates = append(candidates, c)
+		}
+	}
+	return candidates
+}
+
+// remainingRouteTime is the free-flow time left on the vehicle's route.
+// Expects vehicle.Mutex to be held.
+func remainingRouteTime(vehicle *entities.Vehicle, graph *entities.MapGraph) float64 {
+	r := vehicle.Route
+	var total float64
+	for i := r.CurrentEdgeIndex; i < len(r.Edges); i++ {
+		edge := graph.Edges[r.Edges[i]]
+		if edge == nil {
+			continue
+		}
+		t := edgeTravelTime(edge)
+		if i == r.CurrentEdgeIndex {
+			t *= 1 - clamp(vehicle.State.ProgressOnEdge, 0, 1)
+		}
+		total += t
+	}
+	return total
+}
+
+// assignOrder appends the order's pickup and dropoff to the vehicle's plan,
+// sending an idle vehicle straight to the pickup.
+func (s *SimulationEngine) assignOrder(orderID, vehicleID string) {
+	vehicle, ok := s.GetVehicle(vehicleID)
+	if !ok {
+		return
+	}
+
+	s.orderMu.Lock()
+	o, ok := s.orders.orders[orderID]
+	if !ok || o.Status != entities.OrderPending {
+		s.orderMu.Unlock()
+		return
+	}
+	o.Status = entities.OrderAssigned
+	o.VehicleID = vehicleID
+	o.AssignedAt = s.orders.clock
+	s.orders.removePending(orderID)
+	order := *o
+	s.orderMu.Unlock()
+
+	vehicle.Mutex.Lock()
+	first := len(vehicle.Stops) == 0
+	vehicle.Stops = append(vehicle.Stops,
+		entities.Stop{NodeID: order.PickupNode, OrderID: order.ID, Kind: entities.StopPickup},
+		entities.Stop{NodeID: order.DropoffNode, OrderID: order.ID, Kind: entities.StopDropoff},
+	)
+	var err error
+	rerouted := false
+	if r := vehicle.Route; first {
+		if r.CompletedAt != nil && r.EndNode == order.PickupNode {
+			vehicle.State.Status = entities.VehicleStatusArrived
+		} else if err = RerouteVehicle(vehicle, s.Graph, order.PickupNode); err == nil {
+			rerouted = true
+		} else {
+			vehicle.Stops = nil
+		}
+	}
+	vehicle.Mutex.Unlock()
+
+	if err != nil {
+		s.orderMu.Lock()
+		o.Status = entities.OrderPending
+		o.VehicleID = ""
+		s.orders.pending = append([]string{orderID}, s.orders.pending...)
+		s.orderMu.Unlock()
+		return
+	}
+
+	s.emitVehicleEvent(vehicle, entities.EventOrderAssigned, entities.SeverityInfo, orderData(&order))
+	if !s.wakeVehicle(vehicle) && rerouted {
+		s.emitVehicleEvent(vehicle, entities.EventRouteStarted, entities.SeverityInfo, nil)
+	}
+}
@@ -0,0 +1,510 @@
+package simulationengine
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/m/internal/simulation/entities"
+)
+
+var ErrNoEnergyModel = errors.New("energy model is disabled")
+
+// EnergyProfile describes the energy store and consumption of a vehicle type.
+// Amounts are in the source's unit: kWh for batteries, litres for fuel.
+type EnergyProfile struct {
+	Source   entities.EnergySource
+	Capacity float64
+	// BaseRate is the consumption per km at walking pace; DragRate adds per
+	// km for every (m/s)^2 of speed.
+	BaseRate float64
+	DragRate float64
+	// AccelerationCost is paid per (m/s)^2 of speed gained.
+	AccelerationCost float64
+	// SurfacePenalty is the extra fraction consumed on a road with
+	// SurfaceQuality 0; a perfect road adds nothing.
+	SurfacePenalty float64
+}
+
+// Consumption returns the energy used to cover distance meters at speed,
+// having been at prevSpeed on the previous update.
+func (p EnergyProfile) Consumption(distance, speed, prevSpeed, surfaceQuality float64) float64 {
+	if distance <= 0 {
+		return 0
+	}
+
+	perKm := p.BaseRate + p.DragRate*speed*speed
+	if surfaceQuality > 0 {
+		perKm *= 1 + p.SurfacePenalty*(1-clamp(surfaceQuality, 0, 1))
+	}
+
+	used := perKm * distance / 1000
+	if speed > prevSpeed {
+		used += p.AccelerationCost * (speed*speed - prevSpeed*prevSpeed)
+	}
+	return used
+}
+
+// ChargeCurve gives the charging power, in units per hour, by state of
+// charge: MaxPower up to TaperStart, then falling linearly to
+// MinPowerFraction of MaxPower when full.
+type ChargeCurve struct {
+	MaxPower         float64
+	TaperStart       float64
+	MinPowerFraction float64
+}
+
+func (c ChargeCurve) Power(fraction float64) float64 {
+	if fraction < c.TaperStart || c.TaperStart >= 1 {
+		return c.MaxPower
+	}
+	taper := (1 - fraction) / (1 - c.TaperStart)
+	return c.MaxPower * math.Max(c.MinPowerFraction, taper)
+}
+
+type EnergyConfig struct {
+	Profiles map[entities.VehicleType]EnergyProfile
+	Curves   map[entities.EnergySource]ChargeCurve
+	// DefaultThreshold is the alert level, as a fraction of capacity, for
+	// vehicles whose fleet sets no EnergyThresholdAlert.
+	DefaultThreshold float64
+	// AutoRecharge sends vehicles below the threshold to the nearest depot
+	// or charging node, then on to their original destination.
+	AutoRecharge bool
+	// ChargeTarget is the fraction of capacity at which charging stops.
+	ChargeTarget float64
+	// QueuePenalty is the expected wait, in seconds, per vehicle ahead when
+	// comparing chargers.
+	QueuePenalty float64
+}
+
+func DefaultEnergyConfig() *EnergyConfig {
+	return &EnergyConfig{
+		Profiles: map[entities.VehicleType]EnergyProfile{
+			entities.VehicleTypSedan: {
+				Source:           entities.EnergyBattery,
+				Capacity:         60,
+				BaseRate:         0.12,
+				DragRate:         0.00008,
+				AccelerationCost: 0.00023,
+				SurfacePenalty:   0.5,
+			},
+			entities.VehicleTypeTruck: {
+				Source:           entities.EnergyFuel,
+				Capacity:         300,
+				BaseRate:         0.25,
+				DragRate:         0.0002,
+				AccelerationCost: 0.0004,
+				SurfacePenalty:   0.8,
+			},
+			entities.VehicleTypeDrone: {
+				Source:           entities.EnergyBattery,
+				Capacity:         1.5,
+				BaseRate:         0.02,
+				DragRate:         0.00002,
+				AccelerationCost: 0.00001,
+			},
+		},
+		Curves: map[entities.EnergySource]ChargeCurve{
+			entities.EnergyBattery: {MaxPower: 50, TaperStart: 0.8, MinPowerFraction: 0.1},
+			entities.EnergyFuel:    {MaxPower: 2400, TaperStart: 1, MinPowerFraction: 1},
+		},
+		DefaultThreshold: 0.2,
+		AutoRecharge:     true,
+		ChargeTarget:     0.9,
+		QueuePenalty:     900,
+	}
+}
+
+// Validate checks that every energy source a profile uses has a charge
+// curve that can refill it; charging on one without would never finish.
+func (c *EnergyConfig) Validate() error {
+	if _, ok := c.Profiles[entities.VehicleTypSedan]; !ok {
+		return fmt.Errorf("no energy profile for %s, the fallback for other vehicle types", entities.VehicleTypSedan)
+	}
+	if c.ChargeTarget <= 0 {
+		return fmt.Errorf("charge target must be positive")
+	}
+	for vehicleType, p := range c.Profiles {
+		if p.Capacity <= 0 {
+			return fmt.Errorf("energy profile %s: capacity must be positive", vehicleType)
+		}
+		curve, ok := c.Curves[p.Source]
+		if !ok {
+			return fmt.Errorf("energy profile %s: no charge curve for %s", vehicleType, p.Source)
+		}
+		if curve.MaxPower <= 0 || curve.MinPowerFraction < 0 {
+			return fmt.Errorf("charge curve %s: max power must be positive and the minimum fraction not negative", p.Source)
+		}
+		if curve.TaperStart < 1 && curve.MinPowerFraction == 0 && c.ChargeTarget >= 1 {
+			return fmt.Errorf("charge curve %s: tapers to zero power before the charge target", p.Source)
+		}
+	}
+	return nil
+}
+
+func (c *EnergyConfig) profile(t entities.VehicleType) EnergyProfile {
+	if p, ok := c.Profiles[t]; ok {
+		return p
+	}
+	return c.Profiles[entities.VehicleTypSedan]
+}
+
+func newEnergyState(p EnergyProfile) *entities.EnergyState {
+	return &entities.EnergyState{Source: p.Source, Capacity: p.Capacity, Level: p.Capacity}
+}
+
+type pendingEvent struct {
+	eventType entities.EventType
+	severity  entities.Severity
+	data      map[string]interface{}
+}
+
+type energyUpdate struct {
+	used     float64
+	charging float64
+	events   []pendingEvent
+}
+
+// holdVehicle reports whether the vehicle must not move this update.
+// Expects vehicle.Mutex to be held.
+func holdVehicle(vehicle *entities.Vehicle) bool {
+	if r := vehicle.Route; len(vehicle.Stops) > 0 && r != nil && r.CompletedAt != nil &&
+		vehicle.State.Status == entities.VehicleStatusArrived {
+		// Waiting at a stop for its time window.
+		return true
+	}
+	if r := vehicle.Route; r != nil && r.DwellRemaining > 0 {
+		// Dwelling at a waypoint.
+		return true
+	}
+	if a := vehicle.Arrival; a != nil && a.IdleRemaining > 0 {
+		// Idling before the next task.
+		return true
+	}
+	if vehicle.Intersection != nil && vehicle.State.Status == entities.VehicleStatusStopped {
+		// Waiting to enter an intersection.
+		return true
+	}
+	switch vehicle.State.Status {
+	case entities.VehicleStatusCharging, entities.VehicleStatusStranded, entities.VehicleStatusQueued:
+		return true
+	case entities.VehicleStatusBreakdown:
+		// Towed vehicles move along their route to the depot.
+		return vehicle.Breakdown == nil || vehicle.Breakdown.Phase != entities.BreakdownTowing
+	}
+	return false
+}
+
+// energyThreshold resolves the alert fraction for the vehicle's fleet.
+// EnergyThresholdAlert values above 1 are read as percentages.
+func (s *SimulationEngine) energyThreshold(vehicle *entities.Vehicle) float64 {
+	if s.Energy == nil {
+		return 0
+	}
+
+	vehicle.Mutex.Lock()
+	fleetID := vehicle.AssignedFleetID
+	vehicle.Mutex.Unlock()
+
+	threshold := s.Energy.DefaultThreshold
+	s.fleetMu.RLock()
+	if fleet, ok := s.fleets[fleetID]; ok && fleet.Config.EnergyThresholdAlert > 0 {
+		threshold = fleet.Config.EnergyThresholdAlert
+	}
+	s.fleetMu.RUnlock()
+
+	if threshold > 1 {
+		threshold /= 100
+	}
+	return threshold
+}
+
+// updateEnergy charges or drains the vehicle after it moved distance meters
+// along edgeID. Expects vehicle.Mutex to be held.
+func (s *SimulationEngine) updateEnergy(vehicle *entities.Vehicle, edgeID string, distance, dt, threshold float64) energyUpdate {
+	var u energyUpdate
+	cfg := s.Energy
+	if cfg == nil || vehicle.Breakdown != nil {
+		return u
+	}
+
+	profile := cfg.profile(vehicle.Type)
+	if vehicle.Energy == nil {
+		vehicle.Energy = newEnergyState(profile)
+	}
+	e := vehicle.Energy
+
+	switch vehicle.State.Status {
+	case entities.VehicleStatusCharging:
+		s.chargeVehicle(vehicle, dt, &u)
+		return u
+	case entities.VehicleStatusQueued:
+		if e.ChargerNode != "" {
+			s.startCharging(vehicle, dt, &u)
+		}
+		return u
+	case entities.VehicleStatusStranded:
+		return u
+	}
+
+	var quality, speed float64
+	if edge := s.Graph.Edges[edgeID]; edge != nil {
+		quality = edge.SurfaceQuality
+	}
+	if dt > 0 {
+		speed = distance / dt
+	}
+
+	used := math.Min(profile.Consumption(distance, speed, e.LastSpeed, quality), e.Level)
+	e.LastSpeed = speed
+	e.Level -= used
+	e.Consumed += used
+	e.Distance += distance
+	if e.Consumed > 0 {
+		e.EstimatedRange = e.Level * e.Distance / e.Consumed
+	}
+	u.used = used
+
+	if e.Level <= 0 {
+		vehicle.State.Status = entities.VehicleStatusStranded
+		vehicle.State.Velocity = entities.Vector2D{}
+		e.LastSpeed = 0
+		u.events = append(u.events, pendingEvent{entities.EventEnergyDepleted, entities.SeverityCritical, energyData(e)})
+		return u
+	}
+
+	if !e.LowAlerted && e.Fraction() <= threshold {
+		e.LowAlerted = true
+		data := energyData(e)
+		if cfg.AutoRecharge && e.ChargerNode == "" {
+			s.routeToCharger(vehicle, data)
+		}
+		u.events = append(u.events, pendingEvent{entities.EventEnergyLow, entities.SeverityWarning, data})
+	}
+
+	if r := vehicle.Route; r != nil && r.CompletedAt != nil && e.ChargerNode != "" && r.EndNode == e.ChargerNode {
+		s.startCharging(vehicle, dt, &u)
+	}
+	return u
+}
+
+// startCharging plugs the vehicle in at its charger, or queues it until a
+// slot frees up.
+func (s *SimulationEngine) startCharging(vehicle *entities.Vehicle, dt float64, u *energyUpdate) {
+	e := vehicle.Energy
+	ref := slotRef{node: e.ChargerNode, kind: FacilityCharger}
+	res := s.acquireSlot(vehicle.ID, ref, dt)
+
+	vehicle.State.Velocity = entities.Vector2D{}
+	e.LastSpeed = 0
+	if !res.granted {
+		if res.entered {
+			vehicle.State.Status = entities.VehicleStatusQueued
+			u.events = append(u.events, queueEvent(entities.EventQueueEntered, ref, res))
+		}
+		return
+	}
+	if res.exited {
+		u.events = append(u.events, queueEvent(entities.EventQueueExited, ref, res))
+	}
+
+	vehicle.State.Status = entities.VehicleStatusCharging
+	e.ChargeSessions++
+	u.events = append(u.events, pendingEvent{entities.EventChargingStarted, entities.SeverityInfo, energyData(e)})
+}
+
+// routeToCharger diverts the vehicle to the nearest node that can refill its
+// energy source, remembering where it was going.
+func (s *SimulationEngine) routeToCharger(vehicle *entities.Vehicle, data map[string]interface{}) {
+	e := vehicle.Energy
+	r := vehicle.Route
+	if r == nil {
+		return
+	}
+
+	from := r.CurrentNode
+	if r.CompletedAt != nil {
+		from = r.EndNode
+	} else if vehicle.State.ProgressOnEdge > 0 {
+		from = r.TargetNode
+	}
+
+	charger := s.chooseCharger(from, e.Source)
+	if charger == "" {
+		return
+	}
+
+	resume := ""
+	if r.CompletedAt == nil {
+		resume = r.EndNode
+	}
+	if err := RerouteVehicle(vehicle, s.Graph, charger); err != nil {
+		return
+	}
+	e.ChargerNode = charger
+	e.ResumeNode = resume
+	data["charger_node"] = charger
+}
+
+// chooseCharger picks the depot or charging node with the lowest travel
+// time plus QueuePenalty for every vehicle that would be ahead in its queue.
+func (s *SimulationEngine) chooseCharger(from string, source entities.EnergySource) string {
+	best, bestCost := "", math.Inf(1)
+	for id, t := range travelTimes(s.Graph, from) {
+		node := s.Graph.Nodes[id]
+		if slotCapacity(node, FacilityCharger) == 0 {
+			continue
+		}
+		if node.Type == entities.NodeTypeCharging && source != entities.EnergyBattery {
+			continue
+		}
+		cost := t + s.Energy.QueuePenalty*float64(s.slotDemand(slotRef{node: id, kind: FacilityCharger}))
+		if cost < bestCost || (cost == bestCost && id < best) {
+			best, bestCost = id, cost
+		}
+	}
+	return best
+}
+
+// chargeVehicle refills along the charge curve. Once ChargeTarget is reached
+// the vehicle continues to the destination it was diverted from.
+func (s *SimulationEngine) chargeVehicle(vehicle *entities.Vehicle, dt float64, u *energyUpdate) {
+	e := vehicle.Energy
+	curve := s.Energy.Curves[e.Source]
+	target := math.Min(s.Energy.ChargeTarget, 1)
+	maxPower := math.Inf(1)
+	if node := s.Graph.Nodes[e.ChargerNode]; node != nil && node.Facility != nil && node.Facility.ChargePower > 0 && e.Source == entities.EnergyBattery {
+		maxPower = node.Facility.ChargePower
+	}
+
+	// Integrate in steps of at most a second so large dt follows the taper.
+	for remaining := dt; remaining > 0 && e.Fraction() < target; {
+		step := math.Min(remaining, 1)
+		power := math.Min(curve.Power(e.Fraction()), maxPower)
+		e.Level = math.Min(e.Level+power*step/3600, target*e.Capacity)
+		e.ChargingTime += step
+		u.charging += step
+		remaining -= step
+	}
+	if e.Consumed > 0 {
+		e.EstimatedRange = e.Level * e.Distance / e.Consumed
+	}
+
+	if e.Fraction() < target-1e-9 {
+		return
+	}
+
+	u.events = append(u.events, pendingEvent{entities.EventChargingCompleted, entities.SeverityInfo, energyData(e)})
+	s.releaseSlot(vehicle.ID)
+	resume := e.ResumeNode
+	e.ChargerNode, e.ResumeNode = "", ""
+	e.LowAlerted = false
+	vehicle.State.Status = entities.VehicleStatusArrived
+
+	if r := vehicle.Route; resume != "" && r != nil && resume != r.EndNode {
+		if err := AssignVehicleRouteWithNodes(vehicle, s.Graph, r.EndNode, resume); err == nil {
+			u.events = append(u.events, pendingEvent{entities.EventRouteStarted, entities.SeverityInfo, nil})
+		}
+	}
+}
+
+// RefillVehicle sets the vehicle's energy level, e.g. after a tow or a manual
+// top-up. A stranded vehicle resumes its route.
+func (s *SimulationEngine) RefillVehicle(id string, level float64) error {
+	vehicle, ok := s.GetVehicle(id)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrVehicleNotFound, id)
+	}
+
+	vehicle.Mutex.Lock()
+	defer vehicle.Mutex.Unlock()
+
+	if vehicle.Energy == nil {
+		if s.Energy == nil {
+			return ErrNoEnergyModel
+		}
+		vehicle.Energy = newEnergyState(s.Energy.profile(vehicle.Type))
+	}
+	e := vehicle.Energy
+	e.Level = clamp(level, 0, e.Capacity)
+	e.LowAlerted = false
+	if vehicle.State.Status == entities.VehicleStatusStranded && e.Level > 0 {
+		vehicle.State.Status = entities.VehicleStatusStopped
+	}
+	return nil
+}
+
+func energyData(e *entities.EnergyState) map[string]interface{} {
+	return map[string]interface{}{
+		"source":          e.Source,
+		"level":           e.Level,
+		"capacity":        e.Capacity,
+		"fraction":        e.Fraction(),
+		"estimated_range": e.EstimatedRange,
+		"charging_time":   e.ChargingTime,
+	}
+}
+
+// travelTimes runs Dijkstra from start and returns the free-flow travel
+// time in seconds to every reachable node.
+func travelTimes(g *entities.MapGraph, start string) map[string]float64 {
+	if _, ok := g.Nodes[start]; !ok {
+		return nil
+	}
+	if hasTurnRules(g) {
+		return turnAwareTravelTimes(g, start)
+	}
+
+	dist := map[string]float64{start: 0}
+	pq := &nodeQueue{{id: start}}
+	for pq.Len() > 0 {
+		item := heap.Pop(pq).(nodeQueueItem)
+		if item.dist > dist[item.id] {
+			continue
+		}
+
+		for neighbor := range g.Nodes[item.id].Connections {
+			edge := findEdge(g, item.id, neighbor)
+			if edge == nil {
+				continue
+			}
+			alt := item.dist + edgeTravelTime(edge)
+			if d, seen := dist[neighbor]; !seen || alt < d {
+				dist[neighbor] = alt
+				heap.Push(pq, nodeQueueItem{id: neighbor, dist: alt})
+			}
+		}
+	}
+	return dist
+}
+
+func edgeTravelTime(edge *entities.MapEdge) float64 {
+	speed := edge.BaseSpeedLimit
+	if edge.Conditions != nil && edge.Conditions.EffectiveSpeedLimit > 0 {
+		speed = edge.Conditions.EffectiveSpeedLimit
+	}
+	if speed <= 0 {
+		return math.Inf(1)
+	}
+	return edge.Length / speed
+}
+
+type nodeQueueItem struct {
+	id   string
+	dist float64
+}
+
+type nodeQueue []nodeQueueItem
+
+func (q nodeQueue) Len() int            { return len(q) }
+func (q nodeQueue) Less(i, j int) bool  { return q[i].dist < q[j].dist }
+func (q nodeQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *nodeQueue) Push(x interface{}) { *q = append(*q, x.(nodeQueueItem)) }
+func (q *nodeQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
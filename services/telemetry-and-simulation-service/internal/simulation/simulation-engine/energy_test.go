@@ -0,0 +1,194 @@
+package simulationengine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/m/internal/simulation/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnergyProfile_Consumption(t *testing.T) {
+	p := EnergyProfile{BaseRate: 0.1, DragRate: 0.0001, AccelerationCost: 0.001, SurfacePenalty: 0.5}
+
+	assert.InDelta(t, 0.1+0.01, p.Consumption(1000, 10, 10, 1), 1e-12)
+	assert.InDelta(t, (0.1+0.01)*1.25, p.Consumption(1000, 10, 10, 0.5), 1e-12)
+	assert.InDelta(t, 0.11+0.001*100, p.Consumption(1000, 10, 0, 1), 1e-12)
+	assert.Zero(t, p.Consumption(0, 10, 0, 1))
+
+	c := ChargeCurve{MaxPower: 50, TaperStart: 0.8, MinPowerFraction: 0.1}
+	assert.Equal(t, 50.0, c.Power(0.5))
+	assert.InDelta(t, 25.0, c.Power(0.9), 1e-9)
+	assert.InDelta(t, 5.0, c.Power(1), 1e-9)
+}
+
+// energyTestEngine builds A --1000m-- B --200m-- C with a depot D 100m off B.
+func energyTestEngine(t *testing.T) (*SimulationEngine, *TelemetryEmitterImpl) {
+	edge := func(id, from, to string, length float64) *entities.MapEdge {
+		return &entities.MapEdge{ID: id, From: from, To: to, Length: length, Bidirectional: true,
+			Conditions: &entities.RoadConditions{EffectiveSpeedLimit: 10}}
+	}
+	graph := &entities.MapGraph{
+		Nodes: map[string]*entities.MapNode{
+			"A": {ID: "A", Connections: map[string]bool{"B": true}},
+			"B": {ID: "B", Position: entities.Vector2D{X: 1000}, Connections: map[string]bool{"A": true, "C": true, "D": true}},
+			"C": {ID: "C", Position: entities.Vector2D{X: 1200}, Connections: map[string]bool{"B": true}},
+			"D": {ID: "D", Type: entities.NodeTypeDepot, Position: entities.Vector2D{X: 1000, Y: 100}, Connections: map[string]bool{"B": true}},
+		},
+		Edges: map[string]*entities.MapEdge{
+			"A-B": edge("A-B", "A", "B", 1000),
+			"B-C": edge("B-C", "B", "C", 200),
+			"B-D": edge("B-D", "B", "D", 100),
+		},
+	}
+
+	engine := NewSimulationEngine(graph, time.Hour)
+	engine.Energy = &EnergyConfig{
+		Profiles: map[entities.VehicleType]EnergyProfile{
+			entities.VehicleTypSedan: {Source: entities.EnergyBattery, Capacity: 1, BaseRate: 0.5},
+		},
+		Curves: map[entities.EnergySource]ChargeCurve{
+			entities.EnergyBattery: {MaxPower: 3.6, TaperStart: 1},
+		},
+		AutoRecharge: true,
+		ChargeTarget: 0.9,
+	}
+	emitter := captureTelemetry(engine)
+	return engine, emitter
+}
+
+// captureTelemetry points the engine's emitter at buffered channels the
+// test reads with drainEvents.
+func captureTelemetry(engine *SimulationEngine) *TelemetryEmitterImpl {
+	emitter := &TelemetryEmitterImpl{
+		Events:        make(chan entities.BasicVehiclePosEvent, 256),
+		VehicleEvents: make(chan entities.VehicleEvent, 256),
+	}
+	engine.Emitter = emitter
+	return emitter
+}
+
+func drainEvents(emitter *TelemetryEmitterImpl) []entities.EventType {
+	var types []entities.EventType
+	for len(emitter.VehicleEvents) > 0 {
+		types = append(types, (<-emitter.VehicleEvents).EventType)
+	}
+	return types
+}
+
+func TestEnergy_LowAlertRechargeAndResume(t *testing.T) {
+	engine, emitter := energyTestEngine(t)
+	_, err := engine.CreateFleet(entities.Fleet{ID: "ev", Config: entities.FleetConfig{EnergyThresholdAlert: 52}})
+	require.NoError(t, err)
+
+	v := &entities.Vehicle{ID: "v1", Type: entities.VehicleTypSedan}
+	require.NoError(t, AssignVehicleRouteWithNodes(v, engine.Graph, "A", "C"))
+	require.NoError(t, engine.AddVehicleToFleet(v, "ev", "test", 0))
+
+	// 1000m at 0.5 per km reaches the 52% threshold at B.
+	for i := 0; i < 10; i++ {
+		require.NoError(t, engine.Step(10*time.Second))
+	}
+	assert.Equal(t, []entities.EventType{entities.EventEnergyLow}, drainEvents(emitter))
+	assert.InDelta(t, 0.5, v.Energy.Level, 1e-9)
+	assert.Equal(t, "D", v.Energy.ChargerNode)
+	assert.Equal(t, "C", v.Energy.ResumeNode)
+	assert.Equal(t, "D", v.Route.EndNode)
+
+	require.NoError(t, engine.Step(10*time.Second))
+	assert.Equal(t, entities.VehicleStatusCharging, v.State.Status)
+	assert.Equal(t, []entities.EventType{entities.EventRouteCompleted, entities.EventChargingStarted}, drainEvents(emitter))
+
+	// 0.001 per second from 0.45 to the 0.9 target.
+	require.NoError(t, engine.Step(300*time.Second))
+	assert.Equal(t, entities.VehicleStatusCharging, v.State.Status)
+	require.NoError(t, engine.Step(300*time.Second))
+	assert.InDelta(t, 0.9, v.Energy.Level, 1e-9)
+	assert.InDelta(t, 450.0, v.Energy.ChargingTime, 1e-6)
+	assert.Equal(t, 1, v.Energy.ChargeSessions)
+	assert.Equal(t, "C", v.Route.EndNode)
+	assert.Equal(t, []entities.EventType{entities.EventChargingCompleted, entities.EventRouteStarted}, drainEvents(emitter))
+
+	for i := 0; i < 20 && v.Route.CompletedAt == nil; i++ {
+		require.NoError(t, engine.Step(10*time.Second))
+	}
+	assert.Equal(t, entities.VehicleStatusArrived, v.State.Status)
+	assert.InDelta(t, 0.9-0.15, v.Energy.Level, 1e-9)
+
+	m, err := engine.FleetMetrics("ev")
+	require.NoError(t, err)
+	assert.InDelta(t, 0.7, m.TotalEnergyConsumed, 1e-9)
+	assert.InDelta(t, 450.0, m.TotalChargingTime, 1e-6)
+}
+
+func TestEnergy_StrandedAndRefill(t *testing.T) {
+	engine, emitter := energyTestEngine(t)
+	engine.Energy.AutoRecharge = false
+
+	v := &entities.Vehicle{ID: "v1"}
+	require.NoError(t, AssignVehicleRouteWithNodes(v, engine.Graph, "A", "C"))
+	engine.AddVehicle(v)
+	v.Energy = &entities.EnergyState{Source: entities.EnergyBattery, Capacity: 1, Level: 0.1}
+
+	require.NoError(t, engine.Step(100*time.Second))
+	require.NoError(t, engine.Step(100*time.Second))
+	assert.Equal(t, entities.VehicleStatusStranded, v.State.Status)
+	assert.Zero(t, v.Energy.Level)
+	assert.Contains(t, drainEvents(emitter), entities.EventEnergyDepleted)
+
+	x := v.State.CurrentPosition.X
+	require.NoError(t, engine.Step(10*time.Second))
+	assert.Equal(t, x, v.State.CurrentPosition.X)
+
+	m := engine.GlobalMetrics()
+	assert.Equal(t, 1, m.VehiclesStranded)
+
+	require.NoError(t, engine.RefillVehicle("v1", 1))
+	require.NoError(t, engine.Step(10*time.Second))
+	assert.Equal(t, entities.VehicleStatusMoving, v.State.Status)
+	assert.Greater(t, v.State.CurrentPosition.X, x)
+
+	assert.ErrorIs(t, engine.RefillVehicle("nope", 1), ErrVehicleNotFound)
+}
+
+func TestPlaceFacilities(t *testing.T) {
+	graph := NewMapGenerator(1000, 1000, 1, AlgoDelaunay, 60, 0).Generate()
+	PlaceFacilities(graph, 2, 3)
+
+	counts := map[entities.NodeType]int{}
+	for _, n := range graph.Nodes {
+		counts[n.Type]++
+	}
+	assert.Equal(t, 2, counts[entities.NodeTypeDepot])
+	assert.Equal(t, 3, counts[entities.NodeTypeCharging])
+	assert.Equal(t, 55, counts[entities.NodeTypeIntersection])
+
+	for _, n := range graph.Nodes {
+		if n.Type == entities.NodeTypeDepot {
+			require.NotNil(t, n.Facility)
+			assert.Equal(t, 4, n.Facility.Chargers)
+		}
+	}
+}
+
+func TestEnergyConfig_Validate(t *testing.T) {
+	require.NoError(t, DefaultEnergyConfig().Validate())
+
+	cfg := DefaultEnergyConfig()
+	delete(cfg.Curves, entities.EnergyFuel)
+	assert.ErrorContains(t, cfg.Validate(), "no charge curve for fuel")
+
+	cfg = DefaultEnergyConfig()
+	cfg.Curves[entities.EnergyBattery] = ChargeCurve{}
+	assert.Error(t, cfg.Validate())
+
+	cfg = DefaultEnergyConfig()
+	cfg.ChargeTarget = 1
+	cfg.Curves[entities.EnergyBattery] = ChargeCurve{MaxPower: 50, TaperStart: 0.8}
+	assert.Error(t, cfg.Validate())
+
+	cfg = DefaultEnergyConfig()
+	delete(cfg.Profiles, entities.VehicleTypSedan)
+	assert.Error(t, cfg.Validate())
+}
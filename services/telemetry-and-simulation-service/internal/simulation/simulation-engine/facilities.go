@@ -0,0 +1,296 @@
+package simulationengine
+
+import (
+	"sort"
+
+	"github.com/m/internal/simulation/entities"
+)
+
+type FacilityKind string
+
+const (
+	FacilityCharger FacilityKind = "charger"
+	FacilityParking FacilityKind = "parking"
+)
+
+// FacilityStats describes one service pool at a node. Waits are in
+// simulated seconds and cover vehicles that have left the queue.
+type FacilityStats struct {
+	NodeID      string       `json:"node_id"`
+	Kind        FacilityKind `json:"kind"`
+	Slots       int          `json:"slots"`
+	Occupied    int          `json:"occupied"`
+	Queued      int          `json:"queued"`
+	Served      int          `json:"served"`
+	TotalWait   float64      `json:"total_wait"`
+	MaxWait     float64      `json:"max_wait"`
+	AverageWait float64      `json:"average_wait"`
+}
+
+type slotRef struct {
+	node string
+	kind FacilityKind
+}
+
+type queueEntry struct {
+	vehicleID string
+	waited    float64
+}
+
+type facilityPool struct {
+	occupants map[string]bool
+	queue     []*queueEntry
+	served    int
+	totalWait float64
+	maxWait   float64
+}
+
+type slotResult struct {
+	granted bool
+	entered bool
+	exited  bool
+	waited  float64
+	ahead   int
+}
+
+// facilityState tracks slot occupancy and FIFO queues. Its mutex is a leaf:
+// it may be taken with vehicle.Mutex held but nothing is locked under it.
+type facilityState struct {
+	pools map[slotRef]*facilityPool
+	// held maps a vehicle to the slot it occupies or is queued for.
+	held map[string]slotRef
+}
+
+func newFacilityState() *facilityState {
+	return &facilityState{pools: make(map[slotRef]*facilityPool), held: make(map[string]slotRef)}
+}
+
+func (f *facilityState) pool(ref slotRef) *facilityPool {
+	p, ok := f.pools[ref]
+	if !ok {
+		p = &facilityPool{occupants: make(map[string]bool)}
+		f.pools[ref] = p
+	}
+	return p
+}
+
+// slotCapacity returns the number of slots of kind at node; -1 means
+// unlimited and 0 that the node does not offer the service.
+func slotCapacity(node *entities.MapNode, kind FacilityKind) int {
+	if node == nil {
+		return 0
+	}
+	if node.Facility == nil {
+		if kind == FacilityCharger && (node.Type == entities.NodeTypeDepot || node.Type == entities.NodeTypeCharging) {
+			return -1
+		}
+		return 0
+	}
+	if kind == FacilityCharger {
+		return node.Facility.Chargers
+	}
+	return node.Facility.ParkingSlots
+}
+
+// acquireSlot takes a slot for the vehicle or queues it. Queued vehicles
+// call it again on every update with the elapsed dt and are admitted in
+// arrival order.
+func (s *SimulationEngine) acquireSlot(vehicleID string, ref slotRef, dt float64) slotResult {
+	capacity := slotCapacity(s.Graph.Nodes[ref.node], ref.kind)
+
+	s.facilityMu.Lock()
+	defer s.facilityMu.Unlock()
+
+	f := s.facilities
+	p := f.pool(ref)
+	if p.occupants[vehicleID] {
+		return slotResult{granted: true}
+	}
+	free := capacity < 0 || len(p.occupants) < capacity
+
+	for i, entry := range p.queue {
+		if entry.vehicleID != vehicleID {
+			continue
+		}
+		entry.waited += dt
+		if i > 0 || !free {
+			return slotResult{ahead: i}
+		}
+		p.queue = p.queue[1:]
+		p.occupy(vehicleID, entry.waited)
+		return slotResult{granted: true, exited: true, waited: entry.waited}
+	}
+
+	f.held[vehicleID] = ref
+	if len(p.queue) == 0 && free {
+		p.occupy(vehicleID, 0)
+		return slotResult{granted: true}
+	}
+	p.queue = append(p.queue, &queueEntry{vehicleID: vehicleID})
+	return slotResult{entered: true, ahead: len(p.queue) - 1}
+}
+
+func (p *facilityPool) occupy(vehicleID string, waited float64) {
+	p.occupants[vehicleID] = true
+	p.served++
+	p.totalWait += waited
+	if waited > p.maxWait {
+		p.maxWait = waited
+	}
+}
+
+// releaseSlot frees whatever slot or queue place the vehicle holds.
+func (s *SimulationEngine) releaseSlot(vehicleID string) (slotRef, bool) {
+	s.facilityMu.Lock()
+	defer s.facilityMu.Unlock()
+
+	ref, ok := s.facilities.held[vehicleID]
+	if !ok {
+		return slotRef{}, false
+	}
+	delete(s.facilities.held, vehicleID)
+
+	p := s.facilities.pool(ref)
+	delete(p.occupants, vehicleID)
+	for i, entry := range p.queue {
+		if entry.vehicleID == vehicleID {
+			p.queue = append(p.queue[:i], p.queue[i+1:]...)
+			break
+		}
+	}
+	return ref, true
+}
+
+func (s *SimulationEngine) heldSlot(vehicleID string) (slotRef, bool) {
+	s.facilityMu.Lock()
+	defer s.facilityMu.Unlock()
+
+	ref, ok := s.facilities.held[vehicleID]
+	return ref, ok
+}
+
+// slotDemand is the number of vehicles that would be ahead of a newcomer
+// for a free slot; 0 when one is available right away.
+func (s *SimulationEngine) slotDemand(ref slotRef) int {
+	capacity := slotCapacity(s.Graph.Nodes[ref.node], ref.kind)
+	if capacity < 0 {
+		return 0
+	}
+
+	s.facilityMu.Lock()
+	defer s.facilityMu.Unlock()
+
+	p, ok := s.facilities.pools[ref]
+	if !ok {
+		return 0
+	}
+	if ahead := len(p.occupants) + len(p.queue) - capacity + 1; ahead > 0 {
+		return ahead
+	}
+	return 0
+}
+
+// updateFacilities releases slots the vehicle no longer uses and parks it
+// at its destination, queueing when the parking is full. Expects
+// vehicle.Mutex to be held.
+func (s *SimulationEngine) updateFacilities(vehicle *entities.Vehicle, dt float64) []pendingEvent {
+	atNode := ""
+	if r := vehicle.Route; r != nil && r.CompletedAt != nil {
+		atNode = r.EndNode
+	}
+	status := vehicle.State.Status
+
+	ref, held := s.heldSlot(vehicle.ID)
+	if held {
+		inUse := ref.node == atNode
+		switch ref.kind {
+		case FacilityCharger:
+			inUse = inUse && (status == entities.VehicleStatusCharging || status == entities.VehicleStatusQueued)
+		case FacilityParking:
+			inUse = inUse && (status == entities.VehicleStatusArrived || status == entities.VehicleStatusQueued)
+		}
+		if !inUse {
+			s.releaseSlot(vehicle.ID)
+			held = false
+		}
+	}
+	if held && (ref.kind == FacilityCharger || status == entities.VehicleStatusArrived) {
+		return nil
+	}
+
+	// Park vehicles that just arrived, or retry the parking queue.
+	if atNode == "" || (!held && status != entities.VehicleStatusArrived) {
+		return nil
+	}
+	if slotCapacity(s.Graph.Nodes[atNode], FacilityParking) <= 0 {
+		return nil
+	}
+
+	var events []pendingEvent
+	ref = slotRef{node: atNode, kind: FacilityParking}
+	res := s.acquireSlot(vehicle.ID, ref, dt)
+	switch {
+	case res.granted:
+		vehicle.State.Status = entities.VehicleStatusArrived
+		if res.exited {
+			events = append(events, queueEvent(entities.EventQueueExited, ref, res))
+		}
+	case res.entered:
+		vehicle.State.Status = entities.VehicleStatusQueued
+		vehicle.State.Velocity = entities.Vector2D{}
+		events = append(events, queueEvent(entities.EventQueueEntered, ref, res))
+	}
+	return events
+}
+
+func queueEvent(eventType entities.EventType, ref slotRef, res slotResult) pendingEvent {
+	data := map[string]interface{}{"node_id": ref.node, "kind": ref.kind}
+	if eventType == entities.EventQueueEntered {
+		data["ahead"] = res.ahead
+	} else {
+		data["wait"] = res.waited
+	}
+	return pendingEvent{eventType, entities.SeverityInfo, data}
+}
+
+// FacilityStats lists every pool that has seen a vehicle, plus the
+// configured ones that have not, sorted by node and kind.
+func (s *SimulationEngine) FacilityStats() []FacilityStats {
+	refs := make(map[slotRef]bool)
+	for id, node := range s.Graph.Nodes {
+		for _, kind := range []FacilityKind{FacilityCharger, FacilityParking} {
+			if slotCapacity(node, kind) != 0 {
+				refs[slotRef{node: id, kind: kind}] = true
+			}
+		}
+	}
+
+	s.facilityMu.Lock()
+	for ref := range s.facilities.pools {
+		refs[ref] = true
+	}
+	stats := make([]FacilityStats, 0, len(refs))
+	for ref := range refs {
+		st := FacilityStats{NodeID: ref.node, Kind: ref.kind, Slots: slotCapacity(s.Graph.Nodes[ref.node], ref.kind)}
+		if p, ok := s.facilities.pools[ref]; ok {
+			st.Occupied = len(p.occupants)
+			st.Queued = len(p.queue)
+			st.Served = p.served
+			st.TotalWait = p.totalWait
+			st.MaxWait = p.maxWait
+			if p.served > 0 {
+				st.AverageWait = p.totalWait / float64(p.served)
+			}
+		}
+		stats = append(stats, st)
+	}
+	s.facilityMu.Unlock()
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].NodeID != stats[j].NodeID {
+			return stats[i].NodeID < stats[j].NodeID
+		}
+		return stats[i].Kind < stats[j].Kind
+	})
+	return stats
+}
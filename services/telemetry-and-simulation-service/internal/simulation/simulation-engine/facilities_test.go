@@ -0,0 +1,112 @@
+package simulationengine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/m/internal/simulation/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFacilities_ChargerQueue(t *testing.T) {
+	engine, emitter := energyTestEngine(t)
+	// 1.8kW per slot halves the 3.6kW curve: 0.4 units take 800s.
+	engine.Graph.Nodes["D"].Facility = &entities.NodeFacility{Chargers: 1, ChargePower: 1.8}
+
+	vehicles := map[string]*entities.Vehicle{}
+	for _, id := range []string{"v1", "v2"} {
+		v := &entities.Vehicle{ID: id}
+		require.NoError(t, AssignVehicleRouteWithNodes(v, engine.Graph, "B", "D"))
+		v.Energy = &entities.EnergyState{Source: entities.EnergyBattery, Capacity: 1, Level: 0.55, LowAlerted: true, ChargerNode: "D"}
+		engine.AddVehicle(v)
+		vehicles[id] = v
+	}
+
+	require.NoError(t, engine.Step(10*time.Second))
+	statuses := map[entities.VehicleStatus]string{}
+	for id, v := range vehicles {
+		statuses[v.State.Status] = id
+	}
+	require.Len(t, statuses, 2)
+	first, second := statuses[entities.VehicleStatusCharging], statuses[entities.VehicleStatusQueued]
+	require.NotEmpty(t, first)
+	require.NotEmpty(t, second)
+	assert.Contains(t, drainEvents(emitter), entities.EventQueueEntered)
+
+	require.NoError(t, engine.Step(400*time.Second))
+	assert.Equal(t, entities.VehicleStatusQueued, vehicles[second].State.Status)
+	require.NoError(t, engine.Step(400*time.Second))
+	require.NoError(t, engine.Step(10*time.Second))
+
+	assert.Equal(t, entities.VehicleStatusArrived, vehicles[first].State.Status)
+	assert.Equal(t, entities.VehicleStatusCharging, vehicles[second].State.Status)
+	assert.Contains(t, drainEvents(emitter), entities.EventQueueExited)
+
+	stats := engine.FacilityStats()
+	require.Len(t, stats, 1)
+	assert.Equal(t, FacilityStats{NodeID: "D", Kind: FacilityCharger, Slots: 1, Occupied: 1, Served: 2,
+		TotalWait: stats[0].TotalWait, MaxWait: stats[0].MaxWait, AverageWait: stats[0].AverageWait}, stats[0])
+	assert.GreaterOrEqual(t, stats[0].MaxWait, 800.0)
+	assert.InDelta(t, stats[0].TotalWait/2, stats[0].AverageWait, 1e-9)
+}
+
+func TestFacilities_ParkingQueue(t *testing.T) {
+	engine, emitter := energyTestEngine(t)
+	engine.Energy = nil
+	engine.Graph.Nodes["C"].Facility = &entities.NodeFacility{ParkingSlots: 1}
+
+	vehicles := map[string]*entities.Vehicle{}
+	for _, id := range []string{"v1", "v2"} {
+		v := &entities.Vehicle{ID: id}
+		require.NoError(t, AssignVehicleRouteWithNodes(v, engine.Graph, "B", "C"))
+		engine.AddVehicle(v)
+		vehicles[id] = v
+	}
+
+	require.NoError(t, engine.Step(20*time.Second))
+	statuses := map[entities.VehicleStatus]string{}
+	for id, v := range vehicles {
+		statuses[v.State.Status] = id
+	}
+	parked, waiting := statuses[entities.VehicleStatusArrived], statuses[entities.VehicleStatusQueued]
+	require.NotEmpty(t, parked)
+	require.NotEmpty(t, waiting)
+
+	require.NoError(t, engine.RouteVehicle(parked, "B"))
+	require.NoError(t, engine.Step(5*time.Second))
+	require.NoError(t, engine.Step(5*time.Second))
+	assert.Equal(t, entities.VehicleStatusArrived, vehicles[waiting].State.Status)
+	assert.Equal(t, entities.VehicleStatusMoving, vehicles[parked].State.Status)
+
+	events := drainEvents(emitter)
+	assert.Contains(t, events, entities.EventQueueEntered)
+	assert.Contains(t, events, entities.EventQueueExited)
+
+	// A removed vehicle gives its slot back.
+	engine.RemoveVehicle(waiting)
+	for _, st := range engine.FacilityStats() {
+		assert.Zero(t, st.Occupied)
+	}
+}
+
+func TestChooseCharger_AvoidsQueues(t *testing.T) {
+	engine, _ := energyTestEngine(t)
+	g := engine.Graph
+	g.Nodes["C"].Connections["E"] = true
+	g.Nodes["E"] = &entities.MapNode{ID: "E", Type: entities.NodeTypeCharging, Position: entities.Vector2D{X: 1200, Y: 100},
+		Connections: map[string]bool{"C": true}}
+	g.Edges["C-E"] = &entities.MapEdge{ID: "C-E", From: "C", To: "E", Length: 100, Bidirectional: true,
+		Conditions: &entities.RoadConditions{EffectiveSpeedLimit: 10}}
+	g.Nodes["D"].Facility = &entities.NodeFacility{Chargers: 1}
+	engine.Energy.QueuePenalty = 900
+
+	assert.Equal(t, "D", engine.chooseCharger("B", entities.EnergyBattery))
+	assert.Equal(t, "D", engine.chooseCharger("B", entities.EnergyFuel))
+
+	engine.acquireSlot("other", slotRef{node: "D", kind: FacilityCharger}, 0)
+	assert.Equal(t, "E", engine.chooseCharger("B", entities.EnergyBattery))
+
+	engine.Energy.QueuePenalty = 0
+	assert.Equal(t, "D", engine.chooseCharger("B", entities.EnergyBattery))
+}
@@ -0,0 +1,311 @@
+package simulationengine
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/m/internal/simulation/entities"
+)
+
+// metricsCounters are the cumulative parts of FleetMetrics, updated as
+// vehicles move and emit events. Vehicle counts are snapshots and computed
+// when metrics are read.
+type metricsCounters struct {
+	distance        float64
+	movingSeconds   float64
+	energy          float64
+	chargingSeconds float64
+	downtimeSeconds float64
+	breakdowns      int
+	completedRoutes int
+	totalEvents     int
+	criticalEvents  int
+}
+
+type fleetMetricsAggregator struct {
+	mu sync.Mutex
+	// Keyed by fleet ID; vehicles without a fleet count under "".
+	counters map[string]*metricsCounters
+}
+
+func newFleetMetricsAggregator() *fleetMetricsAggregator {
+	return &fleetMetricsAggregator{counters: make(map[string]*metricsCounters)}
+}
+
+func (a *fleetMetricsAggregator) fleet(fleetID string) *metricsCounters {
+	c, ok := a.counters[fleetID]
+	if !ok {
+		c = &metricsCounters{}
+		a.counters[fleetID] = c
+	}
+	return c
+}
+
+func (a *fleetMetricsAggregator) recordMovement(fleetID string, distance, seconds, energy float64) {
+	if distance <= 0 && energy <= 0 {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	c := a.fleet(fleetID)
+	c.energy += energy
+	if distance > 0 {
+		c.distance += distance
+		c.movingSeconds += seconds
+	}
+}
+
+func (a *fleetMetricsAggregator) recordCharging(fleetID string, seconds float64) {
+	if seconds <= 0 {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.fleet(fleetID).chargingSeconds += seconds
+}
+
+func (a *fleetMetricsAggregator) recordDowntime(fleetID string, seconds float64) {
+	if seconds <= 0 {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.fleet(fleetID).downtimeSeconds += seconds
+}
+
+func (a *fleetMetricsAggregator) recordEvent(fleetID string, eventType entities.EventType, severity entities.Severity) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	c := a.fleet(fleetID)
+	c.totalEvents++
+	if severity == entities.SeverityCritical {
+		c.criticalEvents++
+	}
+	switch eventType {
+	case entities.EventRouteCompleted:
+		c.completedRoutes++
+	case entities.EventBreakdownOccurred:
+		c.breakdowns++
+	}
+}
+
+func (a *fleetMetricsAggregator) snapshot() map[string]metricsCounters {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	out := make(map[string]metricsCounters, len(a.counters))
+	for id, c := range a.counters {
+		out[id] = *c
+	}
+	return out
+}
+
+// FleetMetrics returns the live metrics of a registered fleet.
+func (s *SimulationEngine) FleetMetrics(fleetID string) (entities.FleetMetrics, error) {
+	fleet, ok := s.GetFleet(fleetID)
+	if !ok {
+		return entities.FleetMetrics{}, fmt.Errorf("%w: %s", ErrFleetNotFound, fleetID)
+	}
+	return fleet.Metrics, nil
+}
+
+// GlobalMetrics aggregates all vehicles, with or without a fleet.
+func (s *SimulationEngine) GlobalMetrics() entities.FleetMetrics {
+	_, global := s.computeMetrics()
+	return global
+}
+
+// fillMetrics sets the live metrics on fleets copied out of the registry.
+// It must not be called with fleetMu held.
+func (s *SimulationEngine) fillMetrics(fleets []entities.Fleet) {
+	if len(fleets) == 0 {
+		return
+	}
+	perFleet, _ := s.computeMetrics()
+	for i := range fleets {
+		m, ok := perFleet[fleets[i].ID]
+		if !ok {
+			m = entities.FleetMetrics{LastUpdated: time.Now()}
+		}
+		fleets[i].Metrics = m
+	}
+}
+
+// computeMetrics combines the cumulative counters with a scan of the
+// current vehicle states. It returns metrics per fleet ID and the total.
+func (s *SimulationEngine) computeMetrics() (map[string]entities.FleetMetrics, entities.FleetMetrics) {
+	now := time.Now()
+	perFleet := make(map[string]*entities.FleetMetrics)
+	global := &entities.FleetMetrics{LastUpdated: now}
+
+	get := func(fleetID string) *entities.FleetMetrics {
+		m, ok := perFleet[fleetID]
+		if !ok {
+			m = &entities.FleetMetrics{LastUpdated: now}
+			perFleet[fleetID] = m
+		}
+		return m
+	}
+
+	for _, v := range s.ListVehicles() {
+		v.Mutex.Lock()
+		fleetID := v.AssignedFleetID
+		status := v.State.Status
+		activeRoute := v.Route != nil && v.Route.CompletedAt == nil
+		v.Mutex.Unlock()
+
+		for _, m := range []*entities.FleetMetrics{get(fleetID), global} {
+			m.TotalVehicles++
+			switch status {
+			case entities.VehicleStatusMoving:
+				m.VehiclesInTransit++
+				m.ActiveVehicles++
+			case entities.VehicleStatusStopped:
+				m.ActiveVehicles++
+			case entities.VehicleStatusCharging:
+				m.VehiclesCharging++
+				m.ActiveVehicles++
+			case entities.VehicleStatusStranded:
+				m.VehiclesStranded++
+			case entities.VehicleStatusBreakdown:
+				m.VehiclesBreakdown++
+			default:
+				m.IdleVehicles++
+			}
+			if activeRoute {
+				m.ActiveRoutes++
+			}
+		}
+	}
+
+	var globalCounters metricsCounters
+	for fleetID, c := range s.metrics.snapshot() {
+		applyCounters(get(fleetID), c)
+		globalCounters.distance += c.distance
+		globalCounters.movingSeconds += c.movingSeconds
+		globalCounters.energy += c.energy
+		globalCounters.chargingSeconds += c.chargingSeconds
+		globalCounters.downtimeSeconds += c.downtimeSeconds
+		globalCounters.breakdowns += c.breakdowns
+		globalCounters.completedRoutes += c.completedRoutes
+		globalCounters.totalEvents += c.totalEvents
+		globalCounters.criticalEvents += c.criticalEvents
+	}
+	applyCounters(global, globalCounters)
+
+	out := make(map[string]entities.FleetMetrics, len(perFleet))
+	for id, m := range perFleet {
+		out[id] = *m
+	}
+	return out, *global
+}
+
+func applyCounters(m *entities.FleetMetrics, c metricsCounters) {
+	m.TotalDistanceTraveled = c.distance
+	m.TotalEnergyConsumed = c.energy
+	m.TotalChargingTime = c.chargingSeconds
+	m.Breakdowns = c.breakdowns
+	m.TotalDowntime = c.downtimeSeconds
+	m.CompletedRoutes = c.completedRoutes
+	m.TotalEvents = c.totalEvents
+	m.CriticalEvents = c.criticalEvents
+	if c.movingSeconds > 0 {
+		m.AverageSpeed = c.distance / c.movingSeconds
+	}
+}
+
+// runMetricsReporter emits a fleet_summary event per registered fleet, and
+// one with an empty fleet ID for the global totals, every MetricsInterval.
+func (s *SimulationEngine) runMetricsReporter(stop, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(s.MetricsInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if !s.IsPaused() {
+				s.EmitFleetSummaries()
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// EmitFleetSummaries emits the current metrics as fleet_summary events.
+func (s *SimulationEngine) EmitFleetSummaries() {
+	if s.Emitter == nil {
+		return
+	}
+
+	fleets := s.ListFleets()
+	global := s.GlobalMetrics()
+
+	now := time.Now()
+	emit := func(fleetID string, m entities.FleetMetrics) {
+		s.Emitter.EmitEvent(entities.VehicleEvent{
+			SchemaVersion: entities.TelemetrySchemaVersion,
+			RunID:         s.RunID,
+			FleetID:       fleetID,
+			Sequence:      s.nextSequence("fleet/" + fleetID),
+			EventType:     entities.EventFleetSummary,
+			Timestamp:     now,
+			Data:          map[string]interface{}{"metrics": m},
+			Severity:      entities.SeverityInfo,
+		})
+	}
+
+	for _, f := range fleets {
+		emit(f.ID, f.Metrics)
+	}
+	emit("", global)
+}
+
+// routeMark is a vehicle's place on its route at the start of a tick.
+type routeMark struct {
+	route    *entities.AssignedRoute
+	index    int
+	progress float64
+}
+
+// markRoute expects vehicle.Mutex to be held.
+func markRoute(vehicle *entities.Vehicle) routeMark {
+	if vehicle.Route == nil {
+		return routeMark{}
+	}
+	return routeMark{route: vehicle.Route, index: vehicle.Route.CurrentEdgeIndex, progress: vehicle.State.ProgressOnEdge}
+}
+
+// distanceSince is the distance covered along the current route since mark.
+// It only walks the edges passed since then, usually none or one. A route
+// replaced since the mark counts as no movement. Expects vehicle.Mutex to
+// be held.
+func distanceSince(vehicle *entities.Vehicle, graph *entities.MapGraph, mark routeMark) float64 {
+	r := vehicle.Route
+	if r == nil || r != mark.route || r.CurrentEdgeIndex < mark.index {
+		return 0
+	}
+
+	length := func(i int) float64 {
+		if i >= len(r.Edges) {
+			return 0
+		}
+		if edge := graph.Edges[r.Edges[i]]; edge != nil {
+			return edge.Length
+		}
+		return 0
+	}
+
+	total := -length(mark.index) * clamp(mark.progress, 0, 1)
+	for i := mark.index; i < r.CurrentEdgeIndex; i++ {
+		total += length(i)
+	}
+	return total + length(r.CurrentEdgeIndex)*clamp(vehicle.State.ProgressOnEdge, 0, 1)
+}
@@ -0,0 +1,99 @@
+package simulationengine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/m/internal/simulation/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFleetMetrics_Aggregation(t *testing.T) {
+	engine := fleetTestEngine(t)
+	emitter := captureTelemetry(engine)
+
+	_, err := engine.CreateFleet(entities.Fleet{ID: "f1"})
+	require.NoError(t, err)
+	for _, id := range []string{"v1", "v2"} {
+		_, err = engine.AssignVehicle("f1", id, "test", 0)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, engine.Step(time.Second))
+	require.NoError(t, engine.Step(time.Second))
+
+	m, err := engine.FleetMetrics("f1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, m.TotalVehicles)
+	assert.Equal(t, 2, m.VehiclesInTransit)
+	assert.Equal(t, 2, m.ActiveRoutes)
+	assert.InDelta(t, 40.0, m.TotalDistanceTraveled, 1e-9)
+	assert.InDelta(t, 10.0, m.AverageSpeed, 1e-9)
+
+	v1, _ := engine.GetVehicle("v1")
+	engine.emitVehicleEvent(v1, entities.EventBreakdownOccurred, entities.SeverityCritical, nil)
+
+	// Drive everyone to the end of the 1000m edge.
+	require.NoError(t, engine.Step(100*time.Second))
+
+	m, err = engine.FleetMetrics("f1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, m.CompletedRoutes)
+	assert.Equal(t, 2, m.IdleVehicles)
+	assert.Zero(t, m.ActiveRoutes)
+	assert.Equal(t, 3, m.TotalEvents)
+	assert.Equal(t, 1, m.CriticalEvents)
+	assert.InDelta(t, 2000.0, m.TotalDistanceTraveled, 1e-9)
+
+	global := engine.GlobalMetrics()
+	assert.Equal(t, 3, global.TotalVehicles)
+	assert.Equal(t, 3, global.CompletedRoutes)
+	assert.InDelta(t, 3000.0, global.TotalDistanceTraveled, 1e-9)
+
+	fleet, _ := engine.GetFleet("f1")
+	assert.Equal(t, m.CompletedRoutes, fleet.Metrics.CompletedRoutes)
+
+	_, err = engine.FleetMetrics("missing")
+	assert.ErrorIs(t, err, ErrFleetNotFound)
+
+	// Drain the vehicle events, then check the summaries.
+	for len(emitter.VehicleEvents) > 0 {
+		<-emitter.VehicleEvents
+	}
+	engine.EmitFleetSummaries()
+	engine.EmitFleetSummaries()
+
+	var summaries []entities.VehicleEvent
+	for len(emitter.VehicleEvents) > 0 {
+		summaries = append(summaries, <-emitter.VehicleEvents)
+	}
+	require.Len(t, summaries, 4)
+	assert.Equal(t, entities.EventFleetSummary, summaries[0].EventType)
+	assert.Equal(t, "f1", summaries[0].FleetID)
+	assert.Empty(t, summaries[0].VehicleID)
+	assert.Equal(t, m.CompletedRoutes, summaries[0].Data["metrics"].(entities.FleetMetrics).CompletedRoutes)
+	assert.Equal(t, "", summaries[1].FleetID)
+	assert.Equal(t, 3, summaries[1].Data["metrics"].(entities.FleetMetrics).TotalVehicles)
+	assert.Equal(t, uint64(2), summaries[2].Sequence)
+}
+
+func TestDistanceSince(t *testing.T) {
+	graph := &entities.MapGraph{Edges: map[string]*entities.MapEdge{
+		"e0": {ID: "e0", Length: 100}, "e1": {ID: "e1", Length: 50}, "e2": {ID: "e2", Length: 200},
+	}}
+	vehicle := &entities.Vehicle{Route: &entities.AssignedRoute{Edges: []string{"e0", "e1", "e2"}}}
+	vehicle.State.ProgressOnEdge = 0.5
+	mark := markRoute(vehicle)
+
+	vehicle.Route.CurrentEdgeIndex = 2
+	vehicle.State.ProgressOnEdge = 0.25
+	assert.InDelta(t, 50+50+50, distanceSince(vehicle, graph, mark), 1e-9)
+
+	vehicle.Route.CurrentEdgeIndex = 3
+	vehicle.State.ProgressOnEdge = 0
+	assert.InDelta(t, 50+50+200, distanceSince(vehicle, graph, mark), 1e-9)
+
+	vehicle.Route = &entities.AssignedRoute{Edges: []string{"e0"}}
+	assert.Zero(t, distanceSince(vehicle, graph, mark))
+}
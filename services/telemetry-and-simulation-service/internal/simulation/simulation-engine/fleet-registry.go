@@ -0,0 +1,414 @@
+package simulationengine
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/m/internal/simulation/entities"
+)
+
+var (
+	ErrFleetNotFound      = errors.New("fleet not found")
+	ErrFleetExists        = errors.New("fleet already exists")
+	ErrFleetFull          = errors.New("fleet is at max vehicles")
+	ErrVehicleNotFound    = errors.New("vehicle not found")
+	ErrVehicleExists      = errors.New("vehicle already exists")
+	ErrAssignmentConflict = errors.New("vehicle is assigned to another fleet with higher priority")
+	ErrInvalidFleet       = errors.New("invalid fleet")
+)
+
+// FleetUpdate is a partial update; nil fields are left unchanged.
+type FleetUpdate struct {
+	Name        *string                `json:"name,omitempty"`
+	Description *string                `json:"description,omitempty"`
+	Status      *entities.FleetStatus  `json:"status,omitempty"`
+	Config      *entities.FleetConfig  `json:"config,omitempty"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+}
+
+func (s *SimulationEngine) CreateFleet(fleet entities.Fleet) (entities.Fleet, error) {
+	if fleet.ID == "" {
+		fleet.ID = uuid.New().String()
+	}
+	if fleet.Status == "" {
+		fleet.Status = entities.FleetStatusActive
+	}
+	if err := validateFleet(fleet.Status, fleet.Config, 0); err != nil {
+		return entities.Fleet{}, err
+	}
+
+	s.fleetMu.Lock()
+	defer s.fleetMu.Unlock()
+
+	if _, exists := s.fleets[fleet.ID]; exists {
+		return entities.Fleet{}, fmt.Errorf("%w: %s", ErrFleetExists, fleet.ID)
+	}
+
+	fleet.Vehicles = nil
+	fleet.VehicleIDs = nil
+	fleet.Metrics = entities.FleetMetrics{}
+	fleet.UpdatedAt = time.Now()
+	s.fleets[fleet.ID] = &fleet
+	return copyFleet(&fleet), nil
+}
+
+func (s *SimulationEngine) GetFleet(id string) (entities.Fleet, bool) {
+	s.fleetMu.RLock()
+	fleet, ok := s.fleets[id]
+	if !ok {
+		s.fleetMu.RUnlock()
+		return entities.Fleet{}, false
+	}
+	out := []entities.Fleet{copyFleet(fleet)}
+	s.fleetMu.RUnlock()
+
+	s.fillMetrics(out)
+	return out[0], true
+}
+
+func (s *SimulationEngine) ListFleets() []entities.Fleet {
+	s.fleetMu.RLock()
+	fleets := make([]entities.Fleet, 0, len(s.fleets))
+	for _, f := range s.fleets {
+		fleets = append(fleets, copyFleet(f))
+	}
+	s.fleetMu.RUnlock()
+
+	sort.Slice(fleets, func(i, j int) bool { return fleets[i].ID < fleets[j].ID })
+	s.fillMetrics(fleets)
+	return fleets
+}
+
+// UpdateFleet applies a partial update. Pausing or deactivating a fleet
+// stops its vehicles where they are; activating it lets them continue.
+func (s *SimulationEngine) UpdateFleet(id string, update FleetUpdate) (entities.Fleet, error) {
+	s.fleetMu.Lock()
+
+	fleet, ok := s.fleets[id]
+	if !ok {
+		s.fleetMu.Unlock()
+		return entities.Fleet{}, fmt.Errorf("%w: %s", ErrFleetNotFound, id)
+	}
+
+	status, config := fleet.Status, fleet.Config
+	if update.Status != nil {
+		status = *update.Status
+	}
+	if update.Config != nil {
+		config = *update.Config
+	}
+	if err := validateFleet(status, config, len(fleet.VehicleIDs)); err != nil {
+		s.fleetMu.Unlock()
+		return entities.Fleet{}, err
+	}
+
+	if update.Name != nil {
+		fleet.Name = *update.Name
+	}
+	if update.Description != nil {
+		fleet.Description = *update.Description
+	}
+	if update.Metadata != nil {
+		fleet.Metadata = update.Metadata
+	}
+	fleet.Config = config
+	fleet.UpdatedAt = time.Now()
+
+	halt := status != fleet.Status && status != entities.FleetStatusActive
+	fleet.Status = status
+	out := copyFleet(fleet)
+	s.fleetMu.Unlock()
+
+	// Vehicles are looked up after releasing fleetMu: the engine lock must
+	// never be taken while holding it.
+	if halt {
+		for _, vehicleID := range out.VehicleIDs {
+			if v, ok := s.GetVehicle(vehicleID); ok {
+				haltVehicle(v)
+			}
+		}
+	}
+	return out, nil
+}
+
+// DeleteFleet removes the fleet and unassigns its vehicles, which keep
+// running without a fleet.
+func (s *SimulationEngine) DeleteFleet(id string) error {
+	s.fleetMu.Lock()
+	fleet, ok := s.fleets[id]
+	if !ok {
+		s.fleetMu.Unlock()
+		return fmt.Errorf("%w: %s", ErrFleetNotFound, id)
+	}
+	for _, vehicleID := range fleet.VehicleIDs {
+		delete(s.assignments, vehicleID)
+	}
+	delete(s.fleets, id)
+	s.fleetMu.Unlock()
+
+	for _, vehicleID := range fleet.VehicleIDs {
+		if v, ok := s.GetVehicle(vehicleID); ok {
+			v.Mutex.Lock()
+			if v.AssignedFleetID == id {
+				v.AssignedFleetID = ""
+			}
+			v.Mutex.Unlock()
+		}
+	}
+	return nil
+}
+
+// AssignVehicle puts a vehicle into a fleet. A vehicle already in another
+// fleet is only moved if priority is at least that of its current
+// assignment. Vehicles without a type get the fleet's DefaultVehicleType.
+func (s *SimulationEngine) AssignVehicle(fleetID, vehicleID, assignedBy string, priority int) (entities.FleetAssignment, error) {
+	vehicle, ok := s.GetVehicle(vehicleID)
+	if !ok {
+		return entities.FleetAssignment{}, fmt.Errorf("%w: %s", ErrVehicleNotFound, vehicleID)
+	}
+
+	s.fleetMu.Lock()
+	defer s.fleetMu.Unlock()
+	return s.assign(fleetID, vehicle, assignedBy, priority)
+}
+
+// AddVehicleToFleet validates the assignment before adding the vehicle, so
+// a full fleet never ends up with an extra running vehicle. Mutex is held
+// from the existence check to the insert, so a vehicle with the same ID
+// added concurrently cannot leave an assignment for one that was dropped.
+func (s *SimulationEngine) AddVehicleToFleet(vehicle *entities.Vehicle, fleetID, assignedBy string, priority int) error {
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+
+	if _, exists := s.Vehicles[vehicle.ID]; exists {
+		return fmt.Errorf("%w: %s", ErrVehicleExists, vehicle.ID)
+	}
+
+	s.fleetMu.Lock()
+	_, err := s.assign(fleetID, vehicle, assignedBy, priority)
+	s.fleetMu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	s.addVehicle(vehicle)
+	return nil
+}
+
+func (s *SimulationEngine) UnassignVehicle(vehicleID string) error {
+	vehicle, ok := s.GetVehicle(vehicleID)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrVehicleNotFound, vehicleID)
+	}
+
+	s.fleetMu.Lock()
+	defer s.fleetMu.Unlock()
+
+	s.unassign(vehicleID)
+	vehicle.Mutex.Lock()
+	vehicle.AssignedFleetID = ""
+	vehicle.Mutex.Unlock()
+	return nil
+}
+
+func (s *SimulationEngine) GetAssignment(vehicleID string) (entities.FleetAssignment, bool) {
+	s.fleetMu.RLock()
+	defer s.fleetMu.RUnlock()
+
+	a, ok := s.assignments[vehicleID]
+	return a, ok
+}
+
+func (s *SimulationEngine) FleetAssignments(fleetID string) ([]entities.FleetAssignment, error) {
+	s.fleetMu.RLock()
+	defer s.fleetMu.RUnlock()
+
+	fleet, ok := s.fleets[fleetID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrFleetNotFound, fleetID)
+	}
+
+	out := make([]entities.FleetAssignment, 0, len(fleet.VehicleIDs))
+	for _, id := range fleet.VehicleIDs {
+		out = append(out, s.assignments[id])
+	}
+	return out, nil
+}
+
+// ListActiveVehicles is ListVehicles without vehicles of inactive fleets.
+func (s *SimulationEngine) ListActiveVehicles() []*entities.Vehicle {
+	vehicles := s.ListVehicles()
+	out := vehicles[:0]
+	for _, v := range vehicles {
+		if s.vehicleFleetStatus(v) != entities.FleetStatusInactive {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// vehicleRunnable reports whether the vehicle's fleet lets it move.
+// Vehicles without a fleet always run.
+func (s *SimulationEngine) vehicleRunnable(vehicle *entities.Vehicle) bool {
+	return s.vehicleFleetStatus(vehicle) == entities.FleetStatusActive
+}
+
+func (s *SimulationEngine) vehicleFleetStatus(vehicle *entities.Vehicle) entities.FleetStatus {
+	vehicle.Mutex.Lock()
+	fleetID := vehicle.AssignedFleetID
+	vehicle.Mutex.Unlock()
+
+	if fleetID == "" {
+		return entities.FleetStatusActive
+	}
+
+	s.fleetMu.RLock()
+	defer s.fleetMu.RUnlock()
+
+	fleet, ok := s.fleets[fleetID]
+	if !ok {
+		return entities.FleetStatusActive
+	}
+	return fleet.Status
+}
+
+// assign expects s.fleetMu to be held.
+func (s *SimulationEngine) assign(fleetID string, vehicle *entities.Vehicle, assignedBy string, priority int) (entities.FleetAssignment, error) {
+	fleet, ok := s.fleets[fleetID]
+	if !ok {
+		return entities.FleetAssignment{}, fmt.Errorf("%w: %s", ErrFleetNotFound, fleetID)
+	}
+
+	current, assigned := s.assignments[vehicle.ID]
+	switch {
+	case assigned && current.FleetID == fleetID:
+	case assigned && priority < current.Priority:
+		return entities.FleetAssignment{}, fmt.Errorf("%w: %s is in %s with priority %d", ErrAssignmentConflict, vehicle.ID, current.FleetID, current.Priority)
+	case fleet.Config.MaxVehicles > 0 && len(fleet.VehicleIDs) >= fleet.Config.MaxVehicles:
+		return entities.FleetAssignment{}, fmt.Errorf("%w: %s has %d", ErrFleetFull, fleetID, fleet.Config.MaxVehicles)
+	}
+
+	if assigned && current.FleetID != fleetID {
+		s.unassign(vehicle.ID)
+	}
+	if !assigned || current.FleetID != fleetID {
+		fleet.VehicleIDs = insertSorted(fleet.VehicleIDs, vehicle.ID)
+		fleet.Metrics.TotalVehicles = len(fleet.VehicleIDs)
+		fleet.UpdatedAt = time.Now()
+	}
+
+	assignment := entities.FleetAssignment{
+		FleetID:    fleetID,
+		VehicleID:  vehicle.ID,
+		AssignedAt: time.Now(),
+		AssignedBy: assignedBy,
+		Priority:   priority,
+	}
+	s.assignments[vehicle.ID] = assignment
+
+	vehicle.Mutex.Lock()
+	vehicle.AssignedFleetID = fleetID
+	if vehicle.Type == "" {
+		vehicle.Type = fleet.Config.DefaultVehicleType
+	}
+	vehicle.Mutex.Unlock()
+
+	if fleet.Status != entities.FleetStatusActive {
+		haltVehicle(vehicle)
+	}
+	return assignment, nil
+}
+
+// unassign expects s.fleetMu to be held.
+func (s *SimulationEngine) unassign(vehicleID string) {
+	current, ok := s.assignments[vehicleID]
+	if !ok {
+		return
+	}
+	delete(s.assignments, vehicleID)
+
+	if fleet, ok := s.fleets[current.FleetID]; ok {
+		fleet.VehicleIDs = removeSorted(fleet.VehicleIDs, vehicleID)
+		fleet.Metrics.TotalVehicles = len(fleet.VehicleIDs)
+		fleet.UpdatedAt = time.Now()
+	}
+}
+
+// haltVehicle marks a vehicle of a paused or inactive fleet as stopped. The
+// next position update after reactivation sets it moving again.
+func haltVehicle(vehicle *entities.Vehicle) {
+	vehicle.Mutex.Lock()
+	defer vehicle.Mutex.Unlock()
+
+	if vehicle.State.Status == entities.VehicleStatusMoving {
+		vehicle.State.Status = entities.VehicleStatusStopped
+		vehicle.State.Velocity = entities.Vector2D{}
+	}
+}
+
+func validateFleet(status entities.FleetStatus, config entities.FleetConfig, vehicles int) error {
+	switch status {
+	case entities.FleetStatusActive, entities.FleetStatusPaused, entities.FleetStatusInactive:
+	default:
+		return fmt.Errorf("%w: unknown status %q", ErrInvalidFleet, status)
+	}
+
+	switch config.DefaultVehicleType {
+	case "", entities.VehicleTypSedan, entities.VehicleTypeTruck, entities.VehicleTypeDrone:
+	default:
+		return fmt.Errorf("%w: unknown vehicle type %q", ErrInvalidFleet, config.DefaultVehicleType)
+	}
+
+	if config.MaxVehicles < 0 {
+		return fmt.Errorf("%w: max_vehicles must not be negative", ErrInvalidFleet)
+	}
+	if config.MaxVehicles > 0 && vehicles > config.MaxVehicles {
+		return fmt.Errorf("%w: fleet has %d vehicles, more than max_vehicles %d", ErrInvalidFleet, vehicles, config.MaxVehicles)
+	}
+	if err := validateArrival(config.OnArrival); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidFleet, err)
+	}
+	return nil
+}
+
+func copyFleet(f *entities.Fleet) entities.Fleet {
+	out := entities.Fleet{
+		ID:          f.ID,
+		Name:        f.Name,
+		Description: f.Description,
+		Status:      f.Status,
+		VehicleIDs:  append([]string{}, f.VehicleIDs...),
+		Config:      f.Config,
+		Metrics:     f.Metrics,
+		UpdatedAt:   f.UpdatedAt,
+	}
+	if f.Metadata != nil {
+		out.Metadata = make(map[string]interface{}, len(f.Metadata))
+		for k, v := range f.Metadata {
+			out.Metadata[k] = v
+		}
+	}
+	return out
+}
+
+func insertSorted(ids []string, id string) []string {
+	i := sort.SearchStrings(ids, id)
+	if i < len(ids) && ids[i] == id {
+		return ids
+	}
+	ids = append(ids, "")
+	copy(ids[i+1:], ids[i:])
+	ids[i] = id
+	return ids
+}
+
+func removeSorted(ids []string, id string) []string {
+	i := sort.SearchStrings(ids, id)
+	if i < len(ids) && ids[i] == id {
+		return append(ids[:i], ids[i+1:]...)
+	}
+	return ids
+}
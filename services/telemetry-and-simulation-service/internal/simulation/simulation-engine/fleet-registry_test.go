@@ -0,0 +1,194 @@
+package simulationengine
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/m/internal/simulation/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fleetTestEngine(t *testing.T) *SimulationEngine {
+	graph := &entities.MapGraph{
+		Nodes: map[string]*entities.MapNode{
+			"A": {ID: "A", Connections: map[string]bool{"B": true}},
+			"B": {ID: "B", Position: entities.Vector2D{X: 1000}, Connections: map[string]bool{"A": true}},
+		},
+		Edges: map[string]*entities.MapEdge{
+			"A-B": {ID: "A-B", From: "A", To: "B", Length: 1000, Bidirectional: true, Conditions: &entities.RoadConditions{EffectiveSpeedLimit: 10}},
+		},
+	}
+
+	engine := NewSimulationEngine(graph, time.Hour)
+	for _, id := range []string{"v1", "v2", "v3"} {
+		v := &entities.Vehicle{ID: id}
+		require.NoError(t, AssignVehicleRouteWithNodes(v, graph, "A", "B"))
+		engine.AddVehicle(v)
+	}
+	return engine
+}
+
+func TestFleetRegistry_CRUD(t *testing.T) {
+	engine := fleetTestEngine(t)
+
+	fleet, err := engine.CreateFleet(entities.Fleet{ID: "f1", Name: "Fleet 1"})
+	require.NoError(t, err)
+	assert.Equal(t, entities.FleetStatusActive, fleet.Status)
+
+	_, err = engine.CreateFleet(entities.Fleet{ID: "f1"})
+	assert.ErrorIs(t, err, ErrFleetExists)
+	_, err = engine.CreateFleet(entities.Fleet{ID: "f2", Status: "sleeping"})
+	assert.ErrorIs(t, err, ErrInvalidFleet)
+	_, err = engine.CreateFleet(entities.Fleet{ID: "f2", Config: entities.FleetConfig{DefaultVehicleType: "boat"}})
+	assert.ErrorIs(t, err, ErrInvalidFleet)
+
+	name := "Renamed"
+	fleet, err = engine.UpdateFleet("f1", FleetUpdate{Name: &name})
+	require.NoError(t, err)
+	assert.Equal(t, "Renamed", fleet.Name)
+
+	_, err = engine.UpdateFleet("missing", FleetUpdate{})
+	assert.ErrorIs(t, err, ErrFleetNotFound)
+
+	_, err = engine.AssignVehicle("f1", "v1", "test", 0)
+	require.NoError(t, err)
+	require.NoError(t, engine.DeleteFleet("f1"))
+	assert.Empty(t, engine.ListFleets())
+
+	v1, _ := engine.GetVehicle("v1")
+	assert.Empty(t, v1.AssignedFleetID)
+	_, assigned := engine.GetAssignment("v1")
+	assert.False(t, assigned)
+}
+
+func TestFleetRegistry_AssignmentRules(t *testing.T) {
+	engine := fleetTestEngine(t)
+
+	_, err := engine.CreateFleet(entities.Fleet{ID: "f1", Config: entities.FleetConfig{MaxVehicles: 2, DefaultVehicleType: entities.VehicleTypeTruck}})
+	require.NoError(t, err)
+	_, err = engine.CreateFleet(entities.Fleet{ID: "f2"})
+	require.NoError(t, err)
+
+	a, err := engine.AssignVehicle("f1", "v1", "dispatcher", 5)
+	require.NoError(t, err)
+	assert.Equal(t, "dispatcher", a.AssignedBy)
+	assert.Equal(t, 5, a.Priority)
+
+	v1, _ := engine.GetVehicle("v1")
+	assert.Equal(t, "f1", v1.AssignedFleetID)
+	assert.Equal(t, entities.VehicleTypeTruck, v1.Type)
+
+	_, err = engine.AssignVehicle("f1", "v2", "dispatcher", 0)
+	require.NoError(t, err)
+	_, err = engine.AssignVehicle("f1", "v3", "dispatcher", 0)
+	assert.ErrorIs(t, err, ErrFleetFull)
+
+	// Shrinking below the current size is rejected.
+	_, err = engine.UpdateFleet("f1", FleetUpdate{Config: &entities.FleetConfig{MaxVehicles: 1}})
+	assert.ErrorIs(t, err, ErrInvalidFleet)
+
+	// A lower priority cannot take a vehicle away, an equal or higher one can.
+	_, err = engine.AssignVehicle("f2", "v1", "other", 4)
+	assert.ErrorIs(t, err, ErrAssignmentConflict)
+	_, err = engine.AssignVehicle("f2", "v1", "other", 5)
+	require.NoError(t, err)
+
+	f1, _ := engine.GetFleet("f1")
+	f2, _ := engine.GetFleet("f2")
+	assert.Equal(t, []string{"v2"}, f1.VehicleIDs)
+	assert.Equal(t, []string{"v1"}, f2.VehicleIDs)
+	assert.Equal(t, 1, f2.Metrics.TotalVehicles)
+
+	engine.RemoveVehicle("v1")
+	f2, _ = engine.GetFleet("f2")
+	assert.Empty(t, f2.VehicleIDs)
+
+	full := &entities.Vehicle{ID: "v4"}
+	_, err = engine.AssignVehicle("f1", "v3", "dispatcher", 0)
+	require.NoError(t, err)
+	assert.ErrorIs(t, engine.AddVehicleToFleet(full, "f1", "dispatcher", 0), ErrFleetFull)
+	_, exists := engine.GetVehicle("v4")
+	assert.False(t, exists)
+
+	_, err = engine.AssignVehicle("f1", "missing", "dispatcher", 0)
+	assert.ErrorIs(t, err, ErrVehicleNotFound)
+}
+
+func TestFleetRegistry_StatusControlsMovement(t *testing.T) {
+	engine := fleetTestEngine(t)
+
+	_, err := engine.CreateFleet(entities.Fleet{ID: "paused"})
+	require.NoError(t, err)
+	_, err = engine.CreateFleet(entities.Fleet{ID: "inactive"})
+	require.NoError(t, err)
+	_, err = engine.AssignVehicle("paused", "v1", "test", 0)
+	require.NoError(t, err)
+	_, err = engine.AssignVehicle("inactive", "v2", "test", 0)
+	require.NoError(t, err)
+
+	require.NoError(t, engine.Step(time.Second))
+
+	pausedStatus, inactiveStatus := entities.FleetStatusPaused, entities.FleetStatusInactive
+	_, err = engine.UpdateFleet("paused", FleetUpdate{Status: &pausedStatus})
+	require.NoError(t, err)
+	_, err = engine.UpdateFleet("inactive", FleetUpdate{Status: &inactiveStatus})
+	require.NoError(t, err)
+
+	require.NoError(t, engine.Step(time.Second))
+
+	v1, _ := engine.GetVehicle("v1")
+	v2, _ := engine.GetVehicle("v2")
+	v3, _ := engine.GetVehicle("v3")
+	assert.InDelta(t, 10.0, v1.State.CurrentPosition.X, 1e-9)
+	assert.Equal(t, entities.VehicleStatusStopped, v1.State.Status)
+	assert.InDelta(t, 10.0, v2.State.CurrentPosition.X, 1e-9)
+	assert.InDelta(t, 20.0, v3.State.CurrentPosition.X, 1e-9)
+
+	ids := []string{}
+	for _, v := range engine.ListActiveVehicles() {
+		ids = append(ids, v.ID)
+	}
+	assert.Equal(t, []string{"v1", "v3"}, ids)
+
+	active := entities.FleetStatusActive
+	_, err = engine.UpdateFleet("paused", FleetUpdate{Status: &active})
+	require.NoError(t, err)
+	require.NoError(t, engine.Step(time.Second))
+	assert.InDelta(t, 20.0, v1.State.CurrentPosition.X, 1e-9)
+	assert.Equal(t, entities.VehicleStatusMoving, v1.State.Status)
+}
+
+func TestAddVehicleToFleet_ConcurrentDuplicates(t *testing.T) {
+	engine := fleetTestEngine(t)
+	_, err := engine.CreateFleet(entities.Fleet{ID: "f1"})
+	require.NoError(t, err)
+
+	vehicles := make([]*entities.Vehicle, 8)
+	errs := make([]error, len(vehicles))
+	var wg sync.WaitGroup
+	for i := range vehicles {
+		vehicles[i] = &entities.Vehicle{ID: "dup"}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = engine.AddVehicleToFleet(vehicles[i], "f1", "test", 0)
+		}(i)
+	}
+	wg.Wait()
+
+	stored, ok := engine.GetVehicle("dup")
+	require.True(t, ok)
+	for i, v := range vehicles {
+		if v == stored {
+			assert.NoError(t, errs[i])
+			assert.Equal(t, "f1", v.AssignedFleetID)
+		} else {
+			assert.Error(t, errs[i])
+			assert.Empty(t, v.AssignedFleetID)
+		}
+	}
+	f1, _ := engine.GetFleet("f1")
+	assert.Equal(t, []string{"dup"}, f1.VehicleIDs)
+}
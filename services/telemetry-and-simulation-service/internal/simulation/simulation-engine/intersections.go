@@ -0,0 +1,665 @@
+package simulationengine
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/m/internal/simulation/entities"
+)
+
+var (
+	ErrIntersectionNotFound = errors.New("intersection control not found")
+	ErrInvalidIntersection  = errors.New("invalid intersection control")
+)
+
+const (
+	defaultStopTime        = 2.0
+	defaultIntersectionGap = 2.0
+	defaultSignalGreen     = 30.0
+	defaultSignalMinGreen  = 10.0
+	defaultSignalExtension = 3.0
+	defaultSignalYellow    = 4.0
+	defaultSignalMaxGreen  = 45.0
+	stopLineProgress       = 0.999999
+)
+
+// IntersectionStats sums up the vehicles that have entered an intersection.
+// Delays are in seconds.
+type IntersectionStats struct {
+	Vehicles     int     `json:"vehicles"`
+	Stopped      int     `json:"stopped"`
+	AverageDelay float64 `json:"average_delay"`
+	MaxDelay     float64 `json:"max_delay"`
+	MaxQueue     int     `json:"max_queue"`
+}
+
+// IntersectionStatus is a controller and its current signal state. Phase and
+// State are only set for signals; every incoming edge not in GreenEdges is
+// red.
+type IntersectionStatus struct {
+	entities.IntersectionControl
+	Phase      int                  `json:"phase"`
+	State      entities.SignalState `json:"state,omitempty"`
+	GreenEdges []string             `json:"green_edges,omitempty"`
+	Elapsed    float64              `json:"elapsed"`
+	Queue      map[string]int       `json:"queue"`
+	Stats      IntersectionStats    `json:"stats"`
+}
+
+type queuedVehicle struct {
+	vehicleID string
+	edgeID    string
+}
+
+// intersection is a registered controller. intersectionMu is a leaf like
+// orderMu.
+type intersection struct {
+	control    entities.IntersectionControl
+	clock      float64
+	phase      int
+	yellow     bool
+	next       int
+	elapsed    float64
+	queue      []queuedVehicle
+	lastEntry  map[string]float64
+	lastArrive map[string]float64
+	lastAny    float64
+	lastMajor  float64
+	stats      IntersectionStats
+	totalDelay float64
+	lanes      map[string]int
+}
+
+func newIntersection(g *entities.MapGraph, c entities.IntersectionControl) *intersection {
+	x := &intersection{
+		control:    c,
+		lastEntry:  make(map[string]float64),
+		lastArrive: make(map[string]float64),
+		lastAny:    math.Inf(-1),
+		lastMajor:  math.Inf(-1),
+		lanes:      make(map[string]int),
+	}
+	for _, id := range incomingEdges(g, c.NodeID) {
+		x.lanes[id] = g.Edges[id].Lanes
+	}
+	if c.Type == entities.ControlFixedTime {
+		x.phase, x.yellow, x.elapsed = fixedTimePhase(&c, 0)
+	}
+	return x
+}
+
+func (x *intersection) signalled() bool {
+	return x.control.Type == entities.ControlFixedTime || x.control.Type == entities.ControlActuated
+}
+
+func (x *intersection) status() IntersectionStatus {
+	st := IntersectionStatus{
+		IntersectionControl: x.control,
+		Elapsed:             x.elapsed,
+		Queue:               make(map[string]int),
+		Stats:               x.stats,
+	}
+	for _, q := range x.queue {
+		st.Queue[q.edgeID]++
+	}
+	if x.signalled() {
+		st.Phase = x.phase
+		st.State = entities.SignalGreen
+		if x.yellow {
+			st.State = entities.SignalYellow
+		} else {
+			st.GreenEdges = append([]string{}, x.control.Phases[x.phase].Edges...)
+		}
+	}
+	return st
+}
+
+// fixedTimePhase is the phase of a fixed-time cycle at the given time, whether
+// it is in its yellow, and how long it has been running.
+func fixedTimePhase(c *entities.IntersectionControl, clock float64) (int, bool, float64) {
+	cycle := 0.0
+	for _, p := range c.Phases {
+		cycle += p.Green + p.Yellow
+	}
+	t := math.Mod(clock+c.Offset, cycle)
+	if t < 0 {
+		t += cycle
+	}
+	for i, p := range c.Phases {
+		if t < p.Green {
+			return i, false, t
+		}
+		if t < p.Green+p.Yellow {
+			return i, true, t
+		}
+		t -= p.Green + p.Yellow
+	}
+	return 0, false, 0
+}
+
+// advance runs the controller clock and reports whether the signal changed.
+func (x *intersection) advance(dt float64) bool {
+	x.clock += dt
+	c := &x.control
+	switch c.Type {
+	case entities.ControlFixedTime:
+		phase, yellow, elapsed := fixedTimePhase(c, x.clock)
+		changed := phase != x.phase || yellow != x.yellow
+		x.phase, x.yellow, x.elapsed = phase, yellow, elapsed
+		return changed
+	case entities.ControlActuated:
+		return x.actuate(dt)
+	}
+	return false
+}
+
+// actuate holds the green while traffic keeps arriving on it, for at least
+// MinGreen and at most Green seconds, and rests in it while no other phase
+// has vehicles waiting.
+func (x *intersection) actuate(dt float64) bool {
+	x.elapsed += dt
+	p := &x.control.Phases[x.phase]
+	if x.yellow {
+		if x.elapsed < p.Yellow {
+			return false
+		}
+		x.phase, x.yellow, x.elapsed = x.next, false, 0
+		return true
+	}
+	if x.elapsed < p.MinGreen {
+		return false
+	}
+	next, demand := x.nextDemand()
+	if !demand {
+		return false
+	}
+	lastArrive := math.Inf(-1)
+	for _, e := range p.Edges {
+		if t, ok := x.lastArrive[e]; ok {
+			lastArrive = math.Max(lastArrive, t)
+		}
+	}
+	if x.clock-lastArrive < p.Extension && x.elapsed < p.Green {
+		return false
+	}
+
+	x.next, x.elapsed = next, 0
+	if p.Yellow > 0 {
+		x.yellow = true
+	} else {
+		x.phase = next
+	}
+	return true
+}
+
+// nextDemand is the first phase after the current one with a vehicle waiting
+// on it.
+func (x *intersection) nextDemand() (int, bool) {
+	n := len(x.control.Phases)
+	for i := 1; i < n; i++ {
+		k := (x.phase + i) % n
+		for _, q := range x.queue {
+			if slices.Contains(x.control.Phases[k].Edges, q.edgeID) {
+				return k, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// request asks whether the vehicle, stopped for waited seconds at the end of
+// edgeID, may enter the intersection now. A vehicle that may not is queued.
+func (x *intersection) request(vehicleID, edgeID string, waited float64) bool {
+	x.lastArrive[edgeID] = x.clock
+	c := &x.control
+	ok := true
+	switch c.Type {
+	case entities.ControlPriority:
+		if !slices.Contains(c.Major, edgeID) {
+			ok = x.first(vehicleID, "") && x.clock-x.lastMajor >= c.Headway
+		}
+	case entities.ControlStopSign:
+		ok = waited >= c.StopTime && x.first(vehicleID, "") && x.clock-x.lastAny >= c.Headway
+	case entities.ControlFixedTime, entities.ControlActuated:
+		if x.controls(edgeID) {
+			ok = !x.yellow && slices.Contains(c.Phases[x.phase].Edges, edgeID) &&
+				x.first(vehicleID, edgeID) && x.clock-x.lastEntryOf(edgeID) >= c.Headway/float64(max(x.lanes[edgeID], 1))
+		}
+	}
+
+	if !ok {
+		if x.indexOf(vehicleID) < 0 {
+			x.queue = append(x.queue, queuedVehicle{vehicleID, edgeID})
+			x.stats.MaxQueue = max(x.stats.MaxQueue, len(x.queue))
+		}
+		return false
+	}
+
+	x.dequeue(vehicleID)
+	x.lastEntry[edgeID] = x.clock
+	x.lastAny = x.clock
+	if c.Type == entities.ControlPriority && slices.Contains(c.Major, edgeID) {
+		x.lastMajor = x.clock
+	}
+	x.stats.Vehicles++
+	if waited > 0 {
+		x.stats.Stopped++
+	}
+	x.totalDelay += waited
+	x.stats.AverageDelay = x.totalDelay / float64(x.stats.Vehicles)
+	x.stats.MaxDelay = math.Max(x.stats.MaxDelay, waited)
+	return true
+}
+
+func (x *intersection) controls(edgeID string) bool {
+	for _, p := range x.control.Phases {
+		if slices.Contains(p.Edges, edgeID) {
+			return true
+		}
+	}
+	return false
+}
+
+func (x *intersection) lastEntryOf(edgeID string) float64 {
+	if t, ok := x.lastEntry[edgeID]; ok {
+		return t
+	}
+	return math.Inf(-1)
+}
+
+// first reports whether no vehicle queued before this one is waiting for the
+// same edge, or for any edge when edgeID is empty.
+func (x *intersection) first(vehicleID, edgeID string) bool {
+	for _, q := range x.queue {
+		if q.vehicleID == vehicleID {
+			return true
+		}
+		if edgeID == "" || q.edgeID == edgeID {
+			return false
+		}
+	}
+	return true
+}
+
+func (x *intersection) indexOf(vehicleID string) int {
+	for i, q := range x.queue {
+		if q.vehicleID == vehicleID {
+			return i
+		}
+	}
+	return -1
+}
+
+func (x *intersection) dequeue(vehicleID string) {
+	if i := x.indexOf(vehicleID); i >= 0 {
+		x.queue = append(x.queue[:i], x.queue[i+1:]...)
+	}
+}
+
+// SetIntersectionControl installs or replaces the controller of a node.
+// Vehicles already waiting there queue again under the new one.
+func (s *SimulationEngine) SetIntersectionControl(c entities.IntersectionControl) (IntersectionStatus, error) {
+	if err := s.validateIntersection(&c); err != nil {
+		return IntersectionStatus{}, err
+	}
+
+	s.intersectionMu.Lock()
+	defer s.intersectionMu.Unlock()
+
+	x := newIntersection(s.Graph, c)
+	s.intersections[c.NodeID] = x
+	return x.status(), nil
+}
+
+func (s *SimulationEngine) validateIntersection(c *entities.IntersectionControl) error {
+	if _, ok := s.Graph.Nodes[c.NodeID]; !ok {
+		return fmt.Errorf("%w: node %s not found", ErrInvalidIntersection, c.NodeID)
+	}
+	if c.StopTime < 0 || c.Headway < 0 {
+		return fmt.Errorf("%w: stop time and headway must not be negative", ErrInvalidIntersection)
+	}
+	if c.StopTime == 0 {
+		c.StopTime = defaultStopTime
+	}
+	if c.Headway == 0 {
+		c.Headway = defaultIntersectionGap
+	}
+
+	incoming := func(id string) bool {
+		e, ok := s.Graph.Edges[id]
+		return ok && (e.To == c.NodeID || (e.Bidirectional && e.From == c.NodeID))
+	}
+
+	switch c.Type {
+	case entities.ControlUncontrolled, entities.ControlStopSign:
+	case entities.ControlPriority:
+		if len(c.Major) == 0 {
+			return fmt.Errorf("%w: priority needs at least one major approach", ErrInvalidIntersection)
+		}
+		for _, id := range c.Major {
+			if !incoming(id) {
+				return fmt.Errorf("%w: edge %s does not enter %s", ErrInvalidIntersection, id, c.NodeID)
+			}
+		}
+	case entities.ControlFixedTime, entities.ControlActuated:
+		if len(c.Phases) == 0 {
+			return fmt.Errorf("%w: a signal needs at least one phase", ErrInvalidIntersection)
+		}
+		for i, p := range c.Phases {
+			if len(p.Edges) == 0 {
+				return fmt.Errorf("%w: phase %d has no edges", ErrInvalidIntersection, i)
+			}
+			for _, id := range p.Edges {
+				if !incoming(id) {
+					return fmt.Errorf("%w: edge %s does not enter %s", ErrInvalidIntersection, id, c.NodeID)
+				}
+			}
+			if p.Green <= 0 || p.Yellow < 0 || p.MinGreen < 0 || p.Extension < 0 {
+				return fmt.Errorf("%w: phase %d needs a positive green and no negative times", ErrInvalidIntersection, i)
+			}
+			if c.Type == entities.ControlActuated && p.MinGreen > p.Green {
+				return fmt.Errorf("%w: phase %d has a min green above its max", ErrInvalidIntersection, i)
+			}
+		}
+	default:
+		return fmt.Errorf("%w: unknown control type %q", ErrInvalidIntersection, c.Type)
+	}
+	return nil
+}
+
+// DefaultIntersectionControl builds a controller of the given type for a
+// node. Signals get two phases, one for approaches from the east and west
+// and one for those from the north and south. Priority goes to the
+// approaches of the highest road class, or to the larger of the two groups
+// where all are of one class.
+func DefaultIntersectionControl(graph *entities.MapGraph, nodeID string, t entities.ControlType) entities.IntersectionControl {
+	c := entities.IntersectionControl{NodeID: nodeID, Type: t}
+	var eastWest, northSouth []string
+	for _, id := range incomingEdges(graph, nodeID) {
+		e := graph.Edges[id]
+		other := e.From
+		if other == nodeID {
+			other = e.To
+		}
+		to, from := graph.Nodes[nodeID].Position, graph.Nodes[other].Position
+		if math.Abs(to.X-from.X) >= math.Abs(to.Y-from.Y) {
+			eastWest = append(eastWest, id)
+		} else {
+			northSouth = append(northSouth, id)
+		}
+	}
+
+	switch t {
+	case entities.ControlPriority:
+		c.Major = eastWest
+		if len(northSouth) > len(eastWest) {
+			c.Major = northSouth
+		}
+		if major := topClassEdges(graph, slices.Concat(eastWest, northSouth)); len(major) < len(eastWest)+len(northSouth) {
+			c.Major = major
+		}
+	case entities.ControlFixedTime, entities.ControlActuated:
+		for _, edges := range [][]string{eastWest, northSouth} {
+			if len(edges) == 0 {
+				continue
+			}
+			p := entities.SignalPhase{Edges: edges, Green: defaultSignalGreen, Yellow: defaultSignalYellow}
+			if t == entities.ControlActuated {
+				p.Green, p.MinGreen, p.Extension = defaultSignalMaxGreen, defaultSignalMinGreen, defaultSignalExtension
+			}
+			c.Phases = append(c.Phases, p)
+		}
+	}
+	return c
+}
+
+// topClassEdges keeps the edges of the highest road class among them.
+func topClassEdges(graph *entities.MapGraph, ids []string) []string {
+	top := 0
+	for _, id := range ids {
+		top = max(top, roadRank(graph.Edges[id].Class))
+	}
+	var edges []string
+	for _, id := range ids {
+		if roadRank(graph.Edges[id].Class) == top {
+			edges = append(edges, id)
+		}
+	}
+	return edges
+}
+
+// incomingEdges lists the edges vehicles can arrive at the node on, sorted.
+func incomingEdges(graph *entities.MapGraph, nodeID string) []string {
+	var ids []string
+	for id, e := range graph.Edges {
+		if e.To == nodeID || (e.Bidirectional && e.From == nodeID) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// ControlIntersections installs a default controller of the given type at
+// every intersection node with at least minApproaches incoming edges that
+// has none yet, and returns how many it installed.
+func (s *SimulationEngine) ControlIntersections(t entities.ControlType, minApproaches int) int {
+	approaches := make(map[string]int)
+	for _, e := range s.Graph.Edges {
+		approaches[e.To]++
+		if e.Bidirectional {
+			approaches[e.From]++
+		}
+	}
+
+	s.intersectionMu.Lock()
+	defer s.intersectionMu.Unlock()
+
+	n := 0
+	for id, node := range s.Graph.Nodes {
+		if node.Type != entities.NodeTypeIntersection || approaches[id] < minApproaches {
+			continue
+		}
+		if _, exists := s.intersections[id]; exists {
+			continue
+		}
+		c := DefaultIntersectionControl(s.Graph, id, t)
+		if err := s.validateIntersection(&c); err != nil {
+			continue
+		}
+		s.intersections[id] = newIntersection(s.Graph, c)
+		n++
+	}
+	return n
+}
+
+func (s *SimulationEngine) GetIntersection(nodeID string) (IntersectionStatus, bool) {
+	s.intersectionMu.Lock()
+	defer s.intersectionMu.Unlock()
+
+	x, ok := s.intersections[nodeID]
+	if !ok {
+		return IntersectionStatus{}, false
+	}
+	return x.status(), true
+}
+
+func (s *SimulationEngine) ListIntersections() []IntersectionStatus {
+	s.intersectionMu.Lock()
+	list := make([]IntersectionStatus, 0, len(s.intersections))
+	for _, x := range s.intersections {
+		list = append(list, x.status())
+	}
+	s.intersectionMu.Unlock()
+
+	sort.Slice(list, func(i, j int) bool { return list[i].NodeID < list[j].NodeID })
+	return list
+}
+
+// RemoveIntersectionControl lets vehicles pass the node freely again; those
+// waiting there move on at their next update.
+func (s *SimulationEngine) RemoveIntersectionControl(nodeID string) error {
+	s.intersectionMu.Lock()
+	defer s.intersectionMu.Unlock()
+
+	if _, ok := s.intersections[nodeID]; !ok {
+		return fmt.Errorf("%w: %s", ErrIntersectionNotFound, nodeID)
+	}
+	delete(s.intersections, nodeID)
+	return nil
+}
+
+// stepIntersections runs the controller clocks and emits a signal_changed
+// for every signal that changed phase or went yellow.
+func (s *SimulationEngine) stepIntersections(dt float64) {
+	var changed []IntersectionStatus
+	s.intersectionMu.Lock()
+	for _, x := range s.intersections {
+		if x.advance(dt) {
+			changed = append(changed, x.status())
+		}
+	}
+	s.intersectionMu.Unlock()
+
+	sort.Slice(changed, func(i, j int) bool { return changed[i].NodeID < changed[j].NodeID })
+	for i := range changed {
+		s.emitSignalEvent(&changed[i])
+	}
+}
+
+func (s *SimulationEngine) runIntersections(stop, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(s.UpdateRate)
+	defer ticker.Stop()
+
+	lastUpdate := time.Now()
+	for {
+		select {
+		case now := <-ticker.C:
+			dt := now.Sub(lastUpdate).Seconds()
+			lastUpdate = now
+			if !s.IsPaused() {
+				s.stepIntersections(dt)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (s *SimulationEngine) emitSignalEvent(st *IntersectionStatus) {
+	if s.Emitter == nil {
+		return
+	}
+	s.Emitter.EmitEvent(entities.VehicleEvent{
+		SchemaVersion: entities.TelemetrySchemaVersion,
+		RunID:         s.RunID,
+		Sequence:      s.nextSequence("intersections"),
+		EventType:     entities.EventSignalChanged,
+		Timestamp:     time.Now(),
+		Data: map[string]interface{}{
+			"node_id":     st.NodeID,
+			"control":     st.Type,
+			"phase":       st.Phase,
+			"state":       st.State,
+			"green_edges": st.GreenEdges,
+		},
+		Severity: entities.SeverityInfo,
+	})
+}
+
+// approachIntersection stops a vehicle that would pass through a controlled
+// node during this update at the end of its edge, unless it may enter right
+// away, and reports whether it did. delta is the update's driving time as
+// passed to UpdateVehiclePosition. Expects vehicle.Mutex to be held.
+func (s *SimulationEngine) approachIntersection(vehicle *entities.Vehicle, delta float64) bool {
+	r := vehicle.Route
+	if r == nil || r.CompletedAt != nil || r.CurrentEdgeIndex+1 >= len(r.Edges) ||
+		vehicle.State.ProgressOnEdge >= stopLineProgress {
+		return false
+	}
+	edge := s.Graph.Edges[r.Edges[r.CurrentEdgeIndex]]
+	if edge == nil || edge.Length <= 0 || edge.Conditions == nil {
+		return false
+	}
+	speed := edge.Conditions.EffectiveSpeedLimit
+	if speed <= 0 {
+		speed = edge.BaseSpeedLimit
+	}
+	if vehicle.State.ProgressOnEdge+speed*delta/edge.Length < stopLineProgress {
+		return false
+	}
+
+	// TargetNode is the end the edge is driven into, which is its From on a
+	// bidirectional edge taken in reverse.
+	s.intersectionMu.Lock()
+	x, ok := s.intersections[r.TargetNode]
+	enter := !ok || x.request(vehicle.ID, edge.ID, 0)
+	s.intersectionMu.Unlock()
+	if enter {
+		return false
+	}
+
+	vehicle.Intersection = &entities.IntersectionWait{NodeID: r.TargetNode, EdgeID: edge.ID}
+	vehicle.State.ProgressOnEdge = 1
+	vehicle.State.CurrentPosition = s.Graph.Nodes[r.TargetNode].Position
+	vehicle.State.Velocity = entities.Vector2D{}
+	vehicle.State.Status = entities.VehicleStatusStopped
+	return true
+}
+
+// updateIntersection asks again on behalf of a vehicle waiting at an
+// intersection and releases it once it may enter. A vehicle whose route no
+// longer waits there, or whose node lost its controller, is released too.
+// Expects vehicle.Mutex to be held.
+func (s *SimulationEngine) updateIntersection(vehicle *entities.Vehicle, dt float64) {
+	w := vehicle.Intersection
+	if w == nil || vehicle.State.Status != entities.VehicleStatusStopped {
+		return
+	}
+	w.Waited += dt
+
+	r := vehicle.Route
+	stale := r == nil || r.CompletedAt != nil || vehicle.State.CurrentEdge != w.EdgeID ||
+		vehicle.State.ProgressOnEdge < stopLineProgress || r.TargetNode != w.NodeID
+
+	s.intersectionMu.Lock()
+	x, ok := s.intersections[w.NodeID]
+	enter := true
+	if ok {
+		if stale {
+			x.dequeue(vehicle.ID)
+		} else {
+			enter = x.request(vehicle.ID, w.EdgeID, w.Waited)
+		}
+	}
+	s.intersectionMu.Unlock()
+	if !enter {
+		return
+	}
+
+	vehicle.Intersection = nil
+	vehicle.State.Status = entities.VehicleStatusMoving
+}
+
+// releaseIntersection drops a removed vehicle from the queue it waits in.
+func (s *SimulationEngine) releaseIntersection(vehicle *entities.Vehicle) {
+	vehicle.Mutex.Lock()
+	w := vehicle.Intersection
+	vehicle.Mutex.Unlock()
+	if w == nil {
+		return
+	}
+
+	s.intersectionMu.Lock()
+	defer s.intersectionMu.Unlock()
+
+	if x, ok := s.intersections[w.NodeID]; ok {
+		x.dequeue(vehicle.ID)
+	}
+}
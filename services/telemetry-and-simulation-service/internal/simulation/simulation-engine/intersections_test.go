@@ -0,0 +1,270 @@
+package simulationengine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/m/internal/simulation/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// crossGraph is a junction C with one-way 100m arms at 10 m/s: W -> C -> E
+// and N -> C -> S.
+func crossGraph() *entities.MapGraph {
+	g := &entities.MapGraph{Nodes: map[string]*entities.MapNode{}, Edges: map[string]*entities.MapEdge{}}
+	for id, p := range map[string]entities.Vector2D{
+		"C": {X: 100, Y: 100}, "W": {X: 0, Y: 100}, "E": {X: 200, Y: 100}, "N": {X: 100, Y: 0}, "S": {X: 100, Y: 200},
+	} {
+		g.Nodes[id] = &entities.MapNode{ID: id, Type: entities.NodeTypeIntersection, Position: p, Connections: map[string]bool{}}
+	}
+	for _, e := range [][2]string{{"W", "C"}, {"C", "E"}, {"N", "C"}, {"C", "S"}} {
+		id := e[0] + "-" + e[1]
+		g.Edges[id] = &entities.MapEdge{ID: id, From: e[0], To: e[1], Length: 100,
+			Conditions: &entities.RoadConditions{EffectiveSpeedLimit: 10}}
+		g.Nodes[e[0]].Connections[e[1]] = true
+	}
+	return g
+}
+
+func intersectionTestEngine(t *testing.T, c entities.IntersectionControl) (*SimulationEngine, *TelemetryEmitterImpl) {
+	engine := NewSimulationEngine(crossGraph(), time.Hour)
+	emitter := captureTelemetry(engine)
+	c.NodeID = "C"
+	_, err := engine.SetIntersectionControl(c)
+	require.NoError(t, err)
+	return engine, emitter
+}
+
+func addCrossVehicle(t *testing.T, engine *SimulationEngine, id, from, to string) *entities.Vehicle {
+	v := &entities.Vehicle{ID: id}
+	require.NoError(t, AssignVehicleRouteWithNodes(v, engine.Graph, from, to))
+	engine.AddVehicle(v)
+	return v
+}
+
+func TestIntersections_FixedTimeSignal(t *testing.T) {
+	engine, emitter := intersectionTestEngine(t, entities.IntersectionControl{
+		Type: entities.ControlFixedTime,
+		Phases: []entities.SignalPhase{
+			{Edges: []string{"W-C"}, Green: 20, Yellow: 5},
+			{Edges: []string{"N-C"}, Green: 20, Yellow: 5},
+		},
+	})
+	west := addCrossVehicle(t, engine, "west", "W", "E")
+	north := addCrossVehicle(t, engine, "north", "N", "S")
+	ending := addCrossVehicle(t, engine, "ending", "N", "C")
+
+	require.NoError(t, engine.Step(5*time.Second))
+	require.NoError(t, engine.Step(5*time.Second))
+	assert.Equal(t, "C-E", west.State.CurrentEdge)
+	assert.Equal(t, entities.VehicleStatusArrived, ending.State.Status)
+	assert.Equal(t, entities.VehicleStatusStopped, north.State.Status)
+	assert.Equal(t, engine.Graph.Nodes["C"].Position, north.State.CurrentPosition)
+	require.NotNil(t, north.Intersection)
+
+	st, ok := engine.GetIntersection("C")
+	require.True(t, ok)
+	assert.Equal(t, entities.SignalGreen, st.State)
+	assert.Equal(t, []string{"W-C"}, st.GreenEdges)
+	assert.Equal(t, map[string]int{"N-C": 1}, st.Queue)
+
+	// Still red through the yellow at 20s; the green for N-C starts at 25s.
+	require.NoError(t, engine.Step(5*time.Second))
+	require.NoError(t, engine.Step(5*time.Second))
+	assert.Equal(t, "N-C", north.State.CurrentEdge)
+	require.NoError(t, engine.Step(5*time.Second))
+	assert.Equal(t, "C-S", north.State.CurrentEdge)
+	assert.Equal(t, entities.VehicleStatusMoving, north.State.Status)
+	assert.Nil(t, north.Intersection)
+
+	st, _ = engine.GetIntersection("C")
+	assert.Equal(t, 1, st.Phase)
+	assert.Empty(t, st.Queue)
+	assert.Equal(t, 2, st.Stats.Vehicles)
+	assert.Equal(t, 1, st.Stats.Stopped)
+	assert.Equal(t, 15.0, st.Stats.MaxDelay)
+
+	var signals []map[string]interface{}
+	for len(emitter.VehicleEvents) > 0 {
+		if ev := <-emitter.VehicleEvents; ev.EventType == entities.EventSignalChanged {
+			assert.Empty(t, ev.VehicleID)
+			signals = append(signals, ev.Data)
+		}
+	}
+	require.Len(t, signals, 2)
+	assert.Equal(t, entities.SignalYellow, signals[0]["state"])
+	assert.Equal(t, 1, signals[1]["phase"])
+	assert.Equal(t, []string{"N-C"}, signals[1]["green_edges"])
+}
+
+func TestIntersections_SignalOnReversedApproach(t *testing.T) {
+	engine := NewSimulationEngine(crossGraph(), time.Hour)
+	captureTelemetry(engine)
+	// C-S is stored leaving C, so a vehicle from S drives it backwards into C.
+	engine.Graph.Edges["C-S"].Bidirectional = true
+	engine.Graph.Nodes["S"].Connections["C"] = true
+	_, err := engine.SetIntersectionControl(entities.IntersectionControl{
+		NodeID: "C",
+		Type:   entities.ControlFixedTime,
+		Phases: []entities.SignalPhase{
+			{Edges: []string{"W-C"}, Green: 20, Yellow: 5},
+			{Edges: []string{"C-S"}, Green: 20, Yellow: 5},
+		},
+	})
+	require.NoError(t, err)
+	south := addCrossVehicle(t, engine, "south", "S", "E")
+	assert.Equal(t, []string{"C-S", "C-E"}, south.Route.Edges)
+
+	require.NoError(t, engine.Step(5*time.Second))
+	require.NoError(t, engine.Step(5*time.Second))
+	assert.Equal(t, entities.VehicleStatusStopped, south.State.Status)
+	assert.Equal(t, engine.Graph.Nodes["C"].Position, south.State.CurrentPosition)
+	require.NotNil(t, south.Intersection)
+	assert.Equal(t, "C", south.Intersection.NodeID)
+	st, _ := engine.GetIntersection("C")
+	assert.Equal(t, map[string]int{"C-S": 1}, st.Queue)
+
+	for i := 0; i < 4; i++ {
+		require.NoError(t, engine.Step(5*time.Second))
+	}
+	assert.Equal(t, "C-E", south.State.CurrentEdge)
+	assert.Nil(t, south.Intersection)
+}
+
+func TestIntersections_StopSignServesInTurn(t *testing.T) {
+	engine, _ := intersectionTestEngine(t, entities.IntersectionControl{Type: entities.ControlStopSign})
+	west := addCrossVehicle(t, engine, "west", "W", "E")
+	north := addCrossVehicle(t, engine, "north", "N", "S")
+
+	require.NoError(t, engine.Step(5*time.Second))
+	require.NoError(t, engine.Step(5*time.Second))
+	assert.Equal(t, entities.VehicleStatusStopped, west.State.Status)
+	assert.Equal(t, entities.VehicleStatusStopped, north.State.Status)
+
+	// One goes after its stop, the other a headway later.
+	require.NoError(t, engine.Step(5*time.Second))
+	assert.Equal(t, 1, countStopped(west, north))
+	require.NoError(t, engine.Step(5*time.Second))
+	assert.Equal(t, 0, countStopped(west, north))
+
+	st, _ := engine.GetIntersection("C")
+	assert.Equal(t, 2, st.Stats.Stopped)
+	assert.Equal(t, 10.0, st.Stats.MaxDelay)
+	assert.Equal(t, 7.5, st.Stats.AverageDelay)
+	assert.Equal(t, 2, st.Stats.MaxQueue)
+}
+
+func countStopped(vehicles ...*entities.Vehicle) int {
+	n := 0
+	for _, v := range vehicles {
+		if v.State.Status == entities.VehicleStatusStopped {
+			n++
+		}
+	}
+	return n
+}
+
+func TestIntersections_ActuatedSignalAnswersDemand(t *testing.T) {
+	engine, _ := intersectionTestEngine(t, entities.IntersectionControl{
+		Type: entities.ControlActuated,
+		Phases: []entities.SignalPhase{
+			{Edges: []string{"W-C"}, Green: 60, MinGreen: 10, Extension: 3, Yellow: 4},
+			{Edges: []string{"N-C"}, Green: 60, MinGreen: 10, Extension: 3, Yellow: 4},
+		},
+	})
+
+	// With nobody waiting the signal rests in its first phase.
+	for i := 0; i < 10; i++ {
+		require.NoError(t, engine.Step(5*time.Second))
+	}
+	st, _ := engine.GetIntersection("C")
+	assert.Equal(t, 0, st.Phase)
+	assert.Equal(t, entities.SignalGreen, st.State)
+
+	north := addCrossVehicle(t, engine, "north", "N", "S")
+	require.NoError(t, engine.Step(5*time.Second))
+	require.NoError(t, engine.Step(5*time.Second))
+	assert.Equal(t, entities.VehicleStatusStopped, north.State.Status)
+	require.NoError(t, engine.Step(5*time.Second))
+	st, _ = engine.GetIntersection("C")
+	assert.Equal(t, entities.SignalYellow, st.State)
+	require.NoError(t, engine.Step(5*time.Second))
+	assert.Equal(t, "C-S", north.State.CurrentEdge)
+}
+
+func TestIntersections_PriorityGapAcceptance(t *testing.T) {
+	x := newIntersection(crossGraph(), entities.IntersectionControl{NodeID: "C", Type: entities.ControlPriority, Major: []string{"W-C"}, Headway: 2})
+	assert.True(t, x.request("a", "W-C", 0))
+	assert.False(t, x.request("b", "N-C", 0))
+	x.advance(1)
+	assert.True(t, x.request("c", "W-C", 0))
+	assert.False(t, x.request("b", "N-C", 1))
+	x.advance(2)
+	assert.True(t, x.request("b", "N-C", 3))
+	assert.Equal(t, 3, x.stats.Vehicles)
+	assert.Equal(t, 1, x.stats.Stopped)
+}
+
+func TestIntersections_ValidationAndDefaults(t *testing.T) {
+	engine := NewSimulationEngine(crossGraph(), time.Hour)
+	for _, c := range []entities.IntersectionControl{
+		{NodeID: "X", Type: entities.ControlStopSign},
+		{NodeID: "C", Type: "roundabout"},
+		{NodeID: "C", Type: entities.ControlPriority},
+		{NodeID: "C", Type: entities.ControlPriority, Major: []string{"C-E"}},
+		{NodeID: "C", Type: entities.ControlFixedTime},
+		{NodeID: "C", Type: entities.ControlFixedTime, Phases: []entities.SignalPhase{{Edges: []string{"W-C"}}}},
+		{NodeID: "C", Type: entities.ControlActuated, Phases: []entities.SignalPhase{{Edges: []string{"W-C"}, Green: 5, MinGreen: 10}}},
+		{NodeID: "C", Type: entities.ControlStopSign, StopTime: -1},
+	} {
+		_, err := engine.SetIntersectionControl(c)
+		assert.ErrorIs(t, err, ErrInvalidIntersection)
+	}
+
+	c := DefaultIntersectionControl(engine.Graph, "C", entities.ControlFixedTime)
+	require.Len(t, c.Phases, 2)
+	assert.Equal(t, []string{"W-C"}, c.Phases[0].Edges)
+	assert.Equal(t, []string{"N-C"}, c.Phases[1].Edges)
+
+	assert.Equal(t, 1, engine.ControlIntersections(entities.ControlFixedTime, 2))
+	assert.Equal(t, 0, engine.ControlIntersections(entities.ControlStopSign, 2))
+	st, ok := engine.GetIntersection("C")
+	require.True(t, ok)
+	assert.Equal(t, 2.0, st.Headway)
+	assert.Len(t, engine.ListIntersections(), 1)
+
+	require.NoError(t, engine.RemoveIntersectionControl("C"))
+	assert.ErrorIs(t, engine.RemoveIntersectionControl("C"), ErrIntersectionNotFound)
+}
+
+func TestIntersections_RoadClassesAndLanes(t *testing.T) {
+	g := crossGraph()
+	SetRoadClass(g.Edges["N-C"], entities.RoadArterial)
+	SetRoadClass(g.Edges["W-C"], entities.RoadLocal)
+	c := DefaultIntersectionControl(g, "C", entities.ControlPriority)
+	assert.Equal(t, []string{"N-C"}, c.Major)
+
+	engine := NewSimulationEngine(g, time.Hour)
+	_, err := engine.SetIntersectionControl(entities.IntersectionControl{
+		NodeID: "C",
+		Type:   entities.ControlFixedTime,
+		Phases: []entities.SignalPhase{
+			{Edges: []string{"N-C"}, Green: 20, Yellow: 5},
+			{Edges: []string{"W-C"}, Green: 20, Yellow: 5},
+		},
+	})
+	require.NoError(t, err)
+
+	// Two lanes discharge at twice the rate of one.
+	x := engine.intersections["C"]
+	assert.True(t, x.request("a", "N-C", 0))
+	x.advance(1)
+	assert.True(t, x.request("b", "N-C", 0))
+	assert.False(t, x.request("c", "N-C", 0))
+
+	// Signals installed automatically know the lanes too.
+	engine = NewSimulationEngine(g, time.Hour)
+	require.Equal(t, 1, engine.ControlIntersections(entities.ControlFixedTime, 2))
+	assert.Equal(t, 2, engine.intersections["C"].lanes["N-C"])
+}
@@ -0,0 +1,154 @@
+package simulationengine
+
+import (
+	"math/rand/v2"
+	"sort"
+
+	"github.com/m/internal/simulation/entities"
+)
+
+// MakeOneWayStreets turns about fraction of the graph's edges into one-way
+// streets in a random direction. Motorways stay two-way, and so do pairs of
+// nodes joined by more than one edge, where one would still carry the other
+// direction.
+func MakeOneWayStreets(g *entities.MapGraph, fraction float64) {
+	makeOneWayStreets(g, fraction, randomSource())
+}
+
+func makeOneWayStreets(g *entities.MapGraph, fraction float64, rng *rand.Rand) {
+	if fraction <= 0 {
+		return
+	}
+
+	pairs := make(map[[2]string]int)
+	for _, e := range g.Edges {
+		pairs[nodePair(e.From, e.To)]++
+	}
+
+	ids := make([]string, 0, len(g.Edges))
+	for id := range g.Edges {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		e := g.Edges[id]
+		if !e.Bidirectional || e.Class == entities.RoadMotorway || pairs[nodePair(e.From, e.To)] > 1 || rng.Float64() >= fraction {
+			continue
+		}
+		if rng.IntN(2) == 1 {
+			e.From, e.To = e.To, e.From
+		}
+		e.Bidirectional = false
+		g.Nodes[e.From].Connections[e.To] = true
+		delete(g.Nodes[e.To].Connections, e.From)
+	}
+	g.Invalidate()
+}
+
+func nodePair(a, b string) [2]string {
+	if a > b {
+		a, b = b, a
+	}
+	return [2]string{a, b}
+}
+
+// EnsureStrongConnectivity makes every node reachable from every other over
+// directed edges. While the graph has more than one strongly connected
+// component, the shortest one-way street between two of them is opened in
+// both directions, which merges them; components with no street between them
+// at all are joined like EnsureGraphConnectivity does. Bridges are picked by
+// length and then edge ID, so the repair repeats on a graph that repeats.
+func EnsureStrongConnectivity(g *entities.MapGraph) {
+	for {
+		components := StronglyConnectedComponents(g)
+		if len(components) <= 1 {
+			return
+		}
+
+		component := make(map[string]int, len(g.Nodes))
+		for i, c := range components {
+			for _, id := range c {
+				component[id] = i
+			}
+		}
+
+		var bridge *entities.MapEdge
+		for _, e := range g.Edges {
+			if component[e.From] == component[e.To] {
+				continue
+			}
+			if bridge == nil || e.Length < bridge.Length || (e.Length == bridge.Length && e.ID < bridge.ID) {
+				bridge = e
+			}
+		}
+		if bridge == nil {
+			EnsureGraphConnectivity(g)
+			continue
+		}
+
+		bridge.Bidirectional = true
+		g.Nodes[bridge.From].Connections[bridge.To] = true
+		g.Nodes[bridge.To].Connections[bridge.From] = true
+		g.Invalidate()
+	}
+}
+
+// StronglyConnectedComponents splits the graph into sets of nodes that can
+// all reach each other over directed edges, using Tarjan's algorithm. Each
+// component is sorted, and components come out in reverse topological order:
+// no edge leads from a component to one listed after it.
+func StronglyConnectedComponents(g *entities.MapGraph) [][]string {
+	arcs := outgoingArcs(g)
+
+	ids := make([]string, 0, len(g.Nodes))
+	for id := range g.Nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	index := make(map[string]int, len(ids))
+	low := make(map[string]int, len(ids))
+	onStack := make(map[string]bool)
+	stack := []string{}
+	components := [][]string{}
+
+	var visit func(id string)
+	visit = func(id string) {
+		index[id] = len(index)
+		low[id] = index[id]
+		stack = append(stack, id)
+		onStack[id] = true
+
+		for _, a := range arcs[id] {
+			if _, seen := index[a.to]; !seen {
+				visit(a.to)
+				low[id] = min(low[id], low[a.to])
+			} else if onStack[a.to] {
+				low[id] = min(low[id], index[a.to])
+			}
+		}
+
+		if low[id] == index[id] {
+			component := []string{}
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				component = append(component, top)
+				if top == id {
+					break
+				}
+			}
+			sort.Strings(component)
+			components = append(components, component)
+		}
+	}
+
+	for _, id := range ids {
+		if _, seen := index[id]; !seen {
+			visit(id)
+		}
+	}
+	return components
+}
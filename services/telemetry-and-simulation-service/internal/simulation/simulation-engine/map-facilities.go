@@ -0,0 +1,109 @@
+package simulationengine
+
+import (
+	"math"
+	"sort"
+
+	"github.com/m/internal/simulation/entities"
+)
+
+// PlaceFacilities turns intersections into depots (4 x 50kW chargers, 20
+// parking slots) and charging nodes (2 x 150kW) spread evenly over the map:
+// the first depot goes to the node nearest the centre and each further
+// facility to the node farthest from all placed so far.
+func PlaceFacilities(g *entities.MapGraph, depots, chargers int) {
+	placeFacilities(g, depots, chargers, 0)
+}
+
+// placeFacilities is PlaceFacilities with parking lots (40 slots) as well.
+// On a zoned map depots are placed in industrial zones and parking lots in
+// commercial ones for as long as those have nodes left.
+func placeFacilities(g *entities.MapGraph, depots, chargers, parking int) {
+	ids := make([]string, 0, len(g.Nodes))
+	for id, n := range g.Nodes {
+		if n.Type != entities.NodeTypeIntersection && n.Type != "" {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return
+	}
+	sort.Strings(ids)
+	var cx, cy float64
+	for _, id := range ids {
+		cx += g.Nodes[id].Position.X
+		cy += g.Nodes[id].Position.Y
+	}
+	center := entities.Vector2D{X: cx / float64(len(ids)), Y: cy / float64(len(ids))}
+
+	// nearest holds each candidate's distance to the closest facility.
+	nearest := make(map[string]float64, len(ids))
+	for _, id := range ids {
+		nearest[id] = math.Inf(1)
+	}
+
+	for i := 0; i < depots+chargers+parking && len(nearest) > 0; i++ {
+		var zone entities.ZoneType
+		switch {
+		case i < depots:
+			zone = entities.ZoneIndustrial
+		case i >= depots+chargers:
+			zone = entities.ZoneCommercial
+		}
+		candidates := ids
+		if zoned := nodesInZone(g, ids, nearest, zone); len(zoned) > 0 {
+			candidates = zoned
+		}
+
+		best := ""
+		if i == 0 {
+			bestDist := math.Inf(1)
+			for _, id := range candidates {
+				if d := distance(g.Nodes[id].Position, center); d < bestDist {
+					best, bestDist = id, d
+				}
+			}
+		} else {
+			bestDist := -1.0
+			for _, id := range candidates {
+				if d, ok := nearest[id]; ok && d > bestDist {
+					best, bestDist = id, d
+				}
+			}
+		}
+
+		node := g.Nodes[best]
+		switch {
+		case i < depots:
+			node.Type = entities.NodeTypeDepot
+			node.Facility = &entities.NodeFacility{Chargers: 4, ChargePower: 50, ParkingSlots: 20}
+		case i < depots+chargers:
+			node.Type = entities.NodeTypeCharging
+			node.Facility = &entities.NodeFacility{Chargers: 2, ChargePower: 150}
+		default:
+			node.Type = entities.NodeTypeParking
+			node.Facility = &entities.NodeFacility{ParkingSlots: 40}
+		}
+		delete(nearest, best)
+
+		for id, d := range nearest {
+			nearest[id] = math.Min(d, distance(g.Nodes[id].Position, node.Position))
+		}
+	}
+}
+
+// nodesInZone keeps the ids not yet taken by a facility that lie in zone;
+// there are none for an empty zone.
+func nodesInZone(g *entities.MapGraph, ids []string, nearest map[string]float64, zone entities.ZoneType) []string {
+	if zone == "" {
+		return nil
+	}
+	var zoned []string
+	for _, id := range ids {
+		if _, free := nearest[id]; free && g.Nodes[id].Zone == zone {
+			zoned = append(zoned, id)
+		}
+	}
+	return zoned
+}
@@ -2,6 +2,7 @@ package simulationengine
 
 import (
 	"fmt"
+	"math/rand/v2"
 	"sort"
 
 	"github.com/fogleman/delaunay"
@@ -24,6 +25,8 @@ const (
 	AlgoRGG      Algorithm = "rgg"
 	AlgoKNN      Algorithm = "knn"
 	AlgoDelaunay Algorithm = "delaunay"
+	AlgoGrid     Algorithm = "grid"
+	AlgoRadial   Algorithm = "radial"
 )
 
 type WeightVariationConfig struct {
@@ -35,6 +38,11 @@ type WeightVariationConfig struct {
 	UseDistanceFromCenter bool
 }
 
+// MapGeneratorConfig describes a generated map. Seed drives the grid and
+// radial layouts and the random passes after the layout (one-way streets,
+// weight variation and zoning). RGG, KNN and Delaunay place their nodes with
+// the global source and name them with UUIDs, so only grid and radial maps
+// repeat for a seed.
 type MapGeneratorConfig struct {
 	Bounds             MapBounds
 	Seed               int64
@@ -42,8 +50,18 @@ type MapGeneratorConfig struct {
 	N                  int
 	K                  int
 	RadiusMode         RadiusMode
+	Grid               *GridConfig
+	Radial             *RadialConfig
 	WeightVariation    *WeightVariationConfig
+	RoadHierarchy      *RoadHierarchyConfig
+	Zoning             *ZoningConfig
 	EnsureConnectivity bool
+	// OneWayFraction of the streets are made one-way; connectivity is then
+	// ensured over directed edges.
+	OneWayFraction   float64
+	Depots           int
+	ChargingStations int
+	ParkingLots      int
 }
 
 func NewMapGenerator(height int, width int, seed int64, algorithm Algorithm, n, k int) *MapGeneratorConfig {
@@ -70,6 +88,7 @@ func NewMapGenerator(height int, width int, seed int64, algorithm Algorithm, n,
 
 func (cfg *MapGeneratorConfig) Generate() *entities.MapGraph {
 	var graph *entities.MapGraph
+	rng := rand.New(rand.NewPCG(uint64(cfg.Seed), uint64(cfg.Seed)))
 
 	switch cfg.Algorithm {
 	case AlgoRGG:
@@ -78,6 +97,18 @@ func (cfg *MapGeneratorConfig) Generate() *entities.MapGraph {
 		graph = KNNGraph(cfg.N, cfg.Bounds.Height, cfg.Bounds.Width, cfg.K)
 	case AlgoDelaunay:
 		graph = DelaunayGraph(cfg.N, cfg.Bounds.Height, cfg.Bounds.Width)
+	case AlgoGrid:
+		grid := cfg.Grid
+		if grid == nil {
+			grid = &GridConfig{}
+		}
+		graph = GridGraph(cfg.N, cfg.Bounds.Height, cfg.Bounds.Width, grid, rng)
+	case AlgoRadial:
+		radial := cfg.Radial
+		if radial == nil {
+			radial = &RadialConfig{}
+		}
+		graph = RadialGraph(cfg.N, cfg.Bounds.Height, cfg.Bounds.Width, radial)
 	default:
 		return &entities.MapGraph{Nodes: map[string]*entities.MapNode{}, Edges: map[string]*entities.MapEdge{}}
 	}
@@ -86,8 +117,27 @@ func (cfg *MapGeneratorConfig) Generate() *entities.MapGraph {
 		EnsureGraphConnectivity(graph)
 	}
 
+	if cfg.RoadHierarchy != nil {
+		BuildRoadHierarchy(graph, cfg.Bounds, cfg.RoadHierarchy)
+	}
+
+	if cfg.OneWayFraction > 0 {
+		makeOneWayStreets(graph, cfg.OneWayFraction, rng)
+		if cfg.EnsureConnectivity {
+			EnsureStrongConnectivity(graph)
+		}
+	}
+
 	if cfg.WeightVariation != nil {
-		ApplyWeightVariation(graph, cfg.WeightVariation, cfg.Bounds)
+		applyWeightVariation(graph, cfg.WeightVariation, cfg.Bounds, rng)
+	}
+
+	if cfg.Zoning != nil {
+		ZoneMap(graph, cfg.Bounds, cfg.Zoning, rng)
+	}
+
+	if cfg.Depots > 0 || cfg.ChargingStations > 0 || cfg.ParkingLots > 0 {
+		placeFacilities(graph, cfg.Depots, cfg.ChargingStations, cfg.ParkingLots)
 	}
 
 	return graph
@@ -116,31 +166,7 @@ func RandomGeometricGraph(N int, heightBound int, widthBound int, mode RadiusMod
 				a.Connections[b.ID] = true
 				b.Connections[a.ID] = true
 				id := a.ID + "->" + b.ID
-
-				var baseSpeedLimit float64
-				if dist < 100 {
-					baseSpeedLimit = 13.4
-				} else if dist < 300 {
-					baseSpeedLimit = 22.2
-				} else {
-					baseSpeedLimit = 33.3
-				}
-
-				edge := &entities.MapEdge{
-					ID:             id,
-					From:           a.ID,
-					To:             b.ID,
-					Length:         dist,
-					BaseSpeedLimit: baseSpeedLimit,
-					SurfaceQuality: 0.95,
-					Bidirectional:  true,
-					Conditions: &entities.RoadConditions{
-						Congestion:          0.0,
-						WeatherMultiplier:   1.0,
-						EffectiveSpeedLimit: baseSpeedLimit,
-					},
-				}
-				edges[id] = edge
+				edges[id] = newRoadEdge(id, a, b, 0.95)
 			}
 		}
 	}
@@ -187,32 +213,7 @@ func KNNGraph(N int, heightBound int, widthBound int, K int) *entities.MapGraph
 			cur.Connections[other.ID] = true
 			other.Connections[cur.ID] = true
 			id := cur.ID + "->" + other.ID
-
-			dist := distances[i].dist
-			var baseSpeedLimit float64
-			if dist < 100 {
-				baseSpeedLimit = 13.4
-			} else if dist < 300 {
-				baseSpeedLimit = 22.2
-			} else {
-				baseSpeedLimit = 33.3
-			}
-
-			edge := &entities.MapEdge{
-				ID:             id,
-				From:           cur.ID,
-				To:             other.ID,
-				Length:         dist,
-				BaseSpeedLimit: baseSpeedLimit,
-				SurfaceQuality: 0.95,
-				Bidirectional:  true,
-				Conditions: &entities.RoadConditions{
-					Congestion:          0.0,
-					WeatherMultiplier:   1.0,
-					EffectiveSpeedLimit: baseSpeedLimit,
-				},
-			}
-			edges[id] = edge
+			edges[id] = newRoadEdge(id, cur, other, 0.95)
 		}
 	}
 
@@ -249,38 +250,11 @@ func DelaunayGraph(N int, heightBound int, widthBound int) *entities.MapGraph {
 		fmt.Sscanf(edge, "%d-%d", &a, &b)
 		na := nodes[indexMap[a]]
 		nb := nodes[indexMap[b]]
-		dist := distance(na.Position, nb.Position)
 		na.Connections[nb.ID] = true
 		nb.Connections[na.ID] = true
 		id := na.ID + "->" + nb.ID
-
-		var baseSpeedLimit float64
-		if dist < 100 {
-			baseSpeedLimit = 13.4
-		} else if dist < 300 {
-			baseSpeedLimit = 22.2
-		} else {
-			baseSpeedLimit = 33.3
-		}
-
-		mapEdge := &entities.MapEdge{
-			ID:             id,
-			From:           na.ID,
-			To:             nb.ID,
-			Length:         dist,
-			BaseSpeedLimit: baseSpeedLimit,
-			SurfaceQuality: 0.95,
-			Bidirectional:  true,
-			Conditions: &entities.RoadConditions{
-				Congestion:          0.0,
-				WeatherMultiplier:   1.0,
-				EffectiveSpeedLimit: baseSpeedLimit,
-			},
-		}
-		edges[id] = mapEdge
+		edges[id] = newRoadEdge(id, na, nb, 0.95)
 	}
 
 	return &entities.MapGraph{Nodes: nodes, Edges: edges}
 }
-
-
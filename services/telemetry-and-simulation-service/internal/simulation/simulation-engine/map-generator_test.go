@@ -1,6 +1,7 @@
 package simulationengine_test
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/m/internal/simulation/entities"
@@ -133,33 +134,23 @@ func TestBuildEdgesFromConnections_InvalidConnection(t *testing.T) {
 	}
 }
 
-func TestBuildEdgesFromConnections_SpeedLimitCategories(t *testing.T) {
+func TestBuildEdgesFromConnections_LocalStreets(t *testing.T) {
 	graph := &entities.MapGraph{
 		Nodes: make(map[string]*entities.MapNode),
 		Edges: make(map[string]*entities.MapEdge),
 	}
 
-	tests := []struct {
-		name          string
-		distance      float64
-		expectedSpeed float64
-		category      string
-	}{
-		{"urban", 50, 13.4, "urban"},
-		{"suburban", 200, 22.2, "suburban"},
-		{"highway", 400, 33.3, "highway"},
-	}
-
-	for i, tt := range tests {
+	// Speed limits come from the road class, however long the edge is.
+	for i, dist := range []float64{50, 200, 400} {
 		nodeA := &entities.MapNode{
-			ID:          "node-" + tt.name + "-a",
+			ID:          fmt.Sprintf("node-%d-a", i),
 			Position:    entities.Vector2D{X: 0, Y: 0},
 			Type:        entities.NodeTypeIntersection,
 			Connections: make(map[string]bool),
 		}
 		nodeB := &entities.MapNode{
-			ID:          "node-" + tt.name + "-b",
-			Position:    entities.Vector2D{X: tt.distance, Y: 0},
+			ID:          fmt.Sprintf("node-%d-b", i),
+			Position:    entities.Vector2D{X: dist, Y: 0},
 			Type:        entities.NodeTypeIntersection,
 			Connections: make(map[string]bool),
 		}
@@ -169,8 +160,6 @@ func TestBuildEdgesFromConnections_SpeedLimitCategories(t *testing.T) {
 
 		graph.Nodes[nodeA.ID] = nodeA
 		graph.Nodes[nodeB.ID] = nodeB
-
-		t.Logf("Test %d: %s - distance=%.2f, expected speed=%.2f", i, tt.category, tt.distance, tt.expectedSpeed)
 	}
 
 	err := simulationengine.BuildEdgesFromConnections(graph)
@@ -182,21 +171,16 @@ func TestBuildEdgesFromConnections_SpeedLimitCategories(t *testing.T) {
 		t.Errorf("Expected 3 edges, got %d", len(graph.Edges))
 	}
 
+	local := simulationengine.RoadClasses[entities.RoadLocal]
 	for edgeID, edge := range graph.Edges {
 		t.Logf("Edge %s: Length=%.2f, BaseSpeedLimit=%.2f", edgeID, edge.Length, edge.BaseSpeedLimit)
 
-		var expectedSpeed float64
-		if edge.Length < 100 {
-			expectedSpeed = 13.4
-		} else if edge.Length < 300 {
-			expectedSpeed = 22.2
-		} else {
-			expectedSpeed = 33.3
+		if edge.Class != entities.RoadLocal || edge.Lanes != local.Lanes || edge.BaseSpeedLimit != local.Speed {
+			t.Errorf("Edge %s is not a local street: class=%s lanes=%d speed=%.2f",
+				edgeID, edge.Class, edge.Lanes, edge.BaseSpeedLimit)
 		}
-
-		if edge.BaseSpeedLimit != expectedSpeed {
-			t.Errorf("Edge %s has incorrect speed limit: got %.2f, expected %.2f (length=%.2f)",
-				edgeID, edge.BaseSpeedLimit, expectedSpeed, edge.Length)
+		if edge.Capacity != float64(local.Lanes)*local.LaneCapacity {
+			t.Errorf("Edge %s has capacity %.0f", edgeID, edge.Capacity)
 		}
 	}
 }
@@ -0,0 +1,120 @@
+package simulationengine
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+
+	"github.com/m/internal/simulation/entities"
+)
+
+type GridConfig struct {
+	// BlockSize is the distance in meters between neighbouring streets.
+	// Zero picks the size that puts about N nodes on the map.
+	BlockSize float64
+	// Jitter moves each node up to this fraction of a block in each
+	// direction.
+	Jitter float64
+	// RemoveProbability is the chance each street between two neighbouring
+	// nodes is left out.
+	RemoveProbability float64
+}
+
+type RadialConfig struct {
+	// Rings and Spokes default to 8 spokes and enough rings for about N
+	// nodes. There are at least 3 spokes.
+	Rings  int
+	Spokes int
+}
+
+// GridGraph lays out a Manhattan grid of streets, centred in the bounds.
+// Nodes are named grid-<column>-<row>.
+func GridGraph(N int, heightBound int, widthBound int, cfg *GridConfig, rng *rand.Rand) *entities.MapGraph {
+	bs := cfg.BlockSize
+	if bs <= 0 {
+		bs = math.Sqrt(float64(heightBound) * float64(widthBound) / float64(max(N, 1)))
+	}
+	cols := int(float64(widthBound)/bs) + 1
+	rows := int(float64(heightBound)/bs) + 1
+	offsetX := (float64(widthBound) - float64(cols-1)*bs) / 2
+	offsetY := (float64(heightBound) - float64(rows-1)*bs) / 2
+
+	g := &entities.MapGraph{Nodes: map[string]*entities.MapNode{}, Edges: map[string]*entities.MapEdge{}}
+	id := func(c, r int) string { return fmt.Sprintf("grid-%d-%d", c, r) }
+	for c := 0; c < cols; c++ {
+		for r := 0; r < rows; r++ {
+			x := offsetX + float64(c)*bs + (rng.Float64()*2-1)*cfg.Jitter*bs
+			y := offsetY + float64(r)*bs + (rng.Float64()*2-1)*cfg.Jitter*bs
+			addLayoutNode(g, id(c, r), clamp(x, 0, float64(widthBound)), clamp(y, 0, float64(heightBound)))
+		}
+	}
+
+	for c := 0; c < cols; c++ {
+		for r := 0; r < rows; r++ {
+			if c+1 < cols && rng.Float64() >= cfg.RemoveProbability {
+				addLayoutEdge(g, id(c, r), id(c+1, r))
+			}
+			if r+1 < rows && rng.Float64() >= cfg.RemoveProbability {
+				addLayoutEdge(g, id(c, r), id(c, r+1))
+			}
+		}
+	}
+	return g
+}
+
+// RadialGraph lays out concentric ring roads crossed by spokes from a centre
+// node. Nodes are named radial-<ring>-<spoke>, and the centre radial-0.
+func RadialGraph(N int, heightBound int, widthBound int, cfg *RadialConfig) *entities.MapGraph {
+	spokes := cfg.Spokes
+	if spokes <= 0 {
+		spokes = 8
+	}
+	spokes = max(spokes, 3)
+	rings := cfg.Rings
+	if rings <= 0 {
+		rings = max((N-1)/spokes, 1)
+	}
+	cx, cy := float64(widthBound)/2, float64(heightBound)/2
+	radius := 0.45 * float64(min(widthBound, heightBound))
+
+	g := &entities.MapGraph{Nodes: map[string]*entities.MapNode{}, Edges: map[string]*entities.MapEdge{}}
+	centre := "radial-0"
+	addLayoutNode(g, centre, cx, cy)
+	id := func(ring, spoke int) string { return fmt.Sprintf("radial-%d-%d", ring, spoke) }
+	for ring := 1; ring <= rings; ring++ {
+		r := radius * float64(ring) / float64(rings)
+		for k := 0; k < spokes; k++ {
+			angle := 2 * math.Pi * float64(k) / float64(spokes)
+			addLayoutNode(g, id(ring, k), cx+r*math.Cos(angle), cy+r*math.Sin(angle))
+		}
+	}
+
+	for ring := 1; ring <= rings; ring++ {
+		for k := 0; k < spokes; k++ {
+			addLayoutEdge(g, id(ring, k), id(ring, (k+1)%spokes))
+			if ring == 1 {
+				addLayoutEdge(g, centre, id(ring, k))
+			} else {
+				addLayoutEdge(g, id(ring-1, k), id(ring, k))
+			}
+		}
+	}
+	return g
+}
+
+func addLayoutNode(g *entities.MapGraph, id string, x, y float64) {
+	g.Nodes[id] = &entities.MapNode{
+		ID:          id,
+		Position:    entities.Vector2D{X: x, Y: y},
+		Type:        entities.NodeTypeIntersection,
+		Connections: make(map[string]bool),
+	}
+}
+
+func addLayoutEdge(g *entities.MapGraph, from, to string) {
+	a, b := g.Nodes[from], g.Nodes[to]
+	a.Connections[to] = true
+	b.Connections[from] = true
+	id := from + "->" + to
+	g.Edges[id] = newRoadEdge(id, a, b, 0.95)
+}
@@ -0,0 +1,66 @@
+package simulationengine
+
+import (
+	"testing"
+
+	"github.com/m/internal/simulation/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLayouts_Grid(t *testing.T) {
+	cfg := NewMapGenerator(1000, 1000, 7, AlgoGrid, 0, 0)
+	cfg.Grid = &GridConfig{BlockSize: 100}
+	cfg.WeightVariation = nil
+	g := cfg.Generate()
+
+	assert.Len(t, g.Nodes, 121)
+	assert.Len(t, g.Edges, 220)
+	assert.Equal(t, entities.Vector2D{X: 300, Y: 400}, g.Nodes["grid-3-4"].Position)
+	assert.Equal(t, 100.0, g.Edges["grid-3-4->grid-4-4"].Length)
+
+	cfg.Grid = &GridConfig{BlockSize: 100, Jitter: 0.2, RemoveProbability: 0.3}
+	g = cfg.Generate()
+	assert.Less(t, len(g.Edges), 220)
+	assert.Len(t, StronglyConnectedComponents(g), 1)
+	for _, n := range g.Nodes {
+		assert.InDelta(t, 500, n.Position.X, 500)
+		assert.InDelta(t, 500, n.Position.Y, 500)
+	}
+}
+
+func TestLayouts_Radial(t *testing.T) {
+	cfg := NewMapGenerator(1000, 1000, 7, AlgoRadial, 0, 0)
+	cfg.Radial = &RadialConfig{Rings: 4, Spokes: 6}
+	cfg.WeightVariation = nil
+	g := cfg.Generate()
+
+	assert.Len(t, g.Nodes, 25)
+	assert.Len(t, g.Edges, 48)
+	p := g.Nodes["radial-2-0"].Position
+	assert.InDelta(t, 725, p.X, 1e-9)
+	assert.InDelta(t, 500, p.Y, 1e-9)
+	assert.Len(t, g.Nodes["radial-0"].Connections, 6)
+	assert.Len(t, g.Nodes["radial-2-3"].Connections, 4)
+
+	// Rings and spokes follow N when not given.
+	cfg.Radial = nil
+	cfg.N = 41
+	assert.Len(t, cfg.Generate().Nodes, 41)
+}
+
+func TestLayouts_SeedRepeatsMaps(t *testing.T) {
+	for _, algo := range []Algorithm{AlgoGrid, AlgoRadial} {
+		generate := func(seed int64) *entities.MapGraph {
+			cfg := NewMapGenerator(1500, 1500, seed, algo, 80, 0)
+			cfg.Grid = &GridConfig{Jitter: 0.25, RemoveProbability: 0.2}
+			cfg.OneWayFraction = 0.5
+			cfg.Depots = 2
+			cfg.ChargingStations = 2
+			return cfg.Generate()
+		}
+		a, b := generate(3), generate(3)
+		require.Equal(t, a, b, algo)
+		assert.NotEqual(t, a, generate(4), algo)
+	}
+}
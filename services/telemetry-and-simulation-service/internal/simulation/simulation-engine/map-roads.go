@@ -0,0 +1,229 @@
+package simulationengine
+
+import (
+	"math"
+	"sort"
+
+	"github.com/m/internal/simulation/entities"
+)
+
+// RoadClassSpec is the cross-section of a class of road. Speeds are in m/s
+// and LaneCapacity is in vehicles per hour.
+type RoadClassSpec struct {
+	Lanes        int
+	Speed        float64
+	LaneCapacity float64
+}
+
+var RoadClasses = map[entities.RoadClass]RoadClassSpec{
+	entities.RoadMotorway:  {Lanes: 3, Speed: 33.3, LaneCapacity: 2000},
+	entities.RoadArterial:  {Lanes: 2, Speed: 22.2, LaneCapacity: 900},
+	entities.RoadCollector: {Lanes: 1, Speed: 16.7, LaneCapacity: 800},
+	entities.RoadLocal:     {Lanes: 1, Speed: 13.4, LaneCapacity: 600},
+}
+
+// roadRank orders the classes from local streets up to motorways; edges
+// without a class rank below all of them.
+func roadRank(c entities.RoadClass) int {
+	switch c {
+	case entities.RoadMotorway:
+		return 4
+	case entities.RoadArterial:
+		return 3
+	case entities.RoadCollector:
+		return 2
+	case entities.RoadLocal:
+		return 1
+	}
+	return 0
+}
+
+// SetRoadClass gives the edge the lanes, speed limit and capacity of its
+// class.
+func SetRoadClass(e *entities.MapEdge, class entities.RoadClass) {
+	spec := RoadClasses[class]
+	e.Class = class
+	e.Lanes = spec.Lanes
+	e.BaseSpeedLimit = spec.Speed
+	e.Capacity = float64(spec.Lanes) * spec.LaneCapacity
+	if e.Conditions != nil {
+		e.Conditions.EffectiveSpeedLimit = spec.Speed
+	}
+}
+
+// newRoadEdge is a two-way local street between two nodes.
+func newRoadEdge(id string, from, to *entities.MapNode, quality float64) *entities.MapEdge {
+	e := &entities.MapEdge{
+		ID:             id,
+		From:           from.ID,
+		To:             to.ID,
+		Length:         distance(from.Position, to.Position),
+		SurfaceQuality: quality,
+		Bidirectional:  true,
+		Conditions: &entities.RoadConditions{
+			Congestion:        0.0,
+			WeatherMultiplier: 1.0,
+		},
+	}
+	SetRoadClass(e, entities.RoadLocal)
+	return e
+}
+
+type RoadHierarchyConfig struct {
+	// HubSpacing is roughly the distance in meters between the nodes the
+	// arterial network joins; collectors join nodes half as far apart. Zero
+	// spaces hubs a quarter of the map apart.
+	HubSpacing float64
+	// RingRoad lays a motorway around the centre of the map.
+	RingRoad bool
+}
+
+// BuildRoadHierarchy lays a backbone of faster roads over a mesh of local
+// streets. The node closest to the centre of each cell of a grid is a hub;
+// hubs are joined along a minimum spanning tree, each tree link following
+// the shortest path through the mesh, and the edges on those paths are
+// upgraded: collectors for a fine grid, arterials for a coarse one. The
+// optional ring road is a motorway through the nodes closest to a circle
+// around the centre. Edges are only ever upgraded, and the graph is set to
+// route by travel time so traffic takes the fast corridors.
+func BuildRoadHierarchy(g *entities.MapGraph, bounds MapBounds, cfg *RoadHierarchyConfig) {
+	for _, e := range g.Edges {
+		if e.Class == "" {
+			SetRoadClass(e, entities.RoadLocal)
+		}
+	}
+
+	spacing := cfg.HubSpacing
+	if spacing <= 0 {
+		spacing = float64(max(bounds.Width, bounds.Height)) / 4
+	}
+	if spacing > 0 {
+		for _, level := range []struct {
+			class   entities.RoadClass
+			spacing float64
+		}{
+			{entities.RoadCollector, spacing / 2},
+			{entities.RoadArterial, spacing},
+		} {
+			hubs := hubNodes(g, bounds, level.spacing)
+			for _, link := range spanningTree(g, hubs) {
+				upgradePath(g, link[0], link[1], level.class)
+			}
+		}
+	}
+
+	if cfg.RingRoad {
+		ring := ringNodes(g, bounds)
+		for i := range ring {
+			if len(ring) < 3 {
+				break
+			}
+			upgradePath(g, ring[i], ring[(i+1)%len(ring)], entities.RoadMotorway)
+		}
+	}
+
+	g.FastestRoutes = true
+}
+
+// hubNodes picks, for each spacing-sized cell of the map, the node in it
+// closest to the cell's centre.
+func hubNodes(g *entities.MapGraph, bounds MapBounds, spacing float64) []string {
+	type cell struct{ x, y int }
+	best := make(map[cell]string)
+	bestDist := make(map[cell]float64)
+	for id, n := range g.Nodes {
+		c := cell{int(n.Position.X / spacing), int(n.Position.Y / spacing)}
+		centre := entities.Vector2D{X: (float64(c.x) + 0.5) * spacing, Y: (float64(c.y) + 0.5) * spacing}
+		d := distance(n.Position, centre)
+		if cur, ok := bestDist[c]; !ok || d < cur || (d == cur && id < best[c]) {
+			best[c], bestDist[c] = id, d
+		}
+	}
+
+	hubs := make([]string, 0, len(best))
+	for _, id := range best {
+		hubs = append(hubs, id)
+	}
+	sort.Strings(hubs)
+	return hubs
+}
+
+// ringNodes are the nodes closest to twelve points on a circle around the
+// centre of the map, in order around it.
+func ringNodes(g *entities.MapGraph, bounds MapBounds) []string {
+	cx, cy := float64(bounds.Width)/2, float64(bounds.Height)/2
+	r := 0.35 * float64(min(bounds.Width, bounds.Height))
+
+	var ring []string
+	seen := make(map[string]bool)
+	for k := 0; k < 12; k++ {
+		angle := float64(k) * math.Pi / 6
+		p := entities.Vector2D{X: cx + r*math.Cos(angle), Y: cy + r*math.Sin(angle)}
+		if id := nearestNode(g, p); id != "" && !seen[id] {
+			seen[id] = true
+			ring = append(ring, id)
+		}
+	}
+	return ring
+}
+
+func nearestNode(g *entities.MapGraph, p entities.Vector2D) string {
+	best, bestDist := "", math.Inf(1)
+	for id, n := range g.Nodes {
+		if d := distance(n.Position, p); d < bestDist || (d == bestDist && id < best) {
+			best, bestDist = id, d
+		}
+	}
+	return best
+}
+
+// spanningTree links the nodes along a Euclidean minimum spanning tree,
+// built with Prim's algorithm.
+func spanningTree(g *entities.MapGraph, ids []string) [][2]string {
+	if len(ids) < 2 {
+		return nil
+	}
+	in := map[string]bool{ids[0]: true}
+	dist := make(map[string]float64, len(ids))
+	parent := make(map[string]string, len(ids))
+	for _, id := range ids[1:] {
+		dist[id] = distance(g.Nodes[ids[0]].Position, g.Nodes[id].Position)
+		parent[id] = ids[0]
+	}
+
+	links := make([][2]string, 0, len(ids)-1)
+	for len(in) < len(ids) {
+		next := ""
+		for _, id := range ids {
+			if !in[id] && (next == "" || dist[id] < dist[next]) {
+				next = id
+			}
+		}
+		in[next] = true
+		links = append(links, [2]string{parent[next], next})
+		for _, id := range ids {
+			if in[id] {
+				continue
+			}
+			if d := distance(g.Nodes[next].Position, g.Nodes[id].Position); d < dist[id] {
+				dist[id], parent[id] = d, next
+			}
+		}
+	}
+	return links
+}
+
+// upgradePath raises the edges on the shortest path from one node to
+// another to at least the given class.
+func upgradePath(g *entities.MapGraph, from, to string, class entities.RoadClass) {
+	weight := func(e *entities.MapEdge, turn float64) float64 { return e.Length }
+	_, prev, goal, found := turnSearch(g, "", from, to, weight, func(string) float64 { return 0 })
+	if !found {
+		return
+	}
+	for _, id := range searchPath(prev, goal) {
+		if e := g.Edges[id]; roadRank(class) > roadRank(e.Class) {
+			SetRoadClass(e, class)
+		}
+	}
+}
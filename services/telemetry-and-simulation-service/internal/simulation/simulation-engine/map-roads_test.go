@@ -0,0 +1,79 @@
+package simulationengine
+
+import (
+	"testing"
+
+	"github.com/m/internal/simulation/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoads_UpgradesAlongShortestPaths(t *testing.T) {
+	g := gridGraph()
+	links := spanningTree(g, []string{"G00", "G20", "G22"})
+	assert.Equal(t, [][2]string{{"G00", "G20"}, {"G20", "G22"}}, links)
+
+	for _, link := range links {
+		upgradePath(g, link[0], link[1], entities.RoadArterial)
+	}
+	upgradePath(g, "G00", "G02", entities.RoadCollector)
+	upgradePath(g, "G00", "G10", entities.RoadLocal)
+
+	classes := map[entities.RoadClass][]string{}
+	for id, e := range g.Edges {
+		classes[e.Class] = append(classes[e.Class], id)
+	}
+	assert.ElementsMatch(t, []string{"G00-G10", "G10-G20", "G20-G21", "G21-G22"}, classes[entities.RoadArterial])
+	assert.ElementsMatch(t, []string{"G00-G01", "G01-G02"}, classes[entities.RoadCollector])
+
+	e := g.Edges["G10-G20"]
+	assert.Equal(t, 2, e.Lanes)
+	assert.Equal(t, 22.2, e.BaseSpeedLimit)
+	assert.Equal(t, 22.2, e.Conditions.EffectiveSpeedLimit)
+	assert.Equal(t, 1800.0, e.Capacity)
+}
+
+func TestRoads_GeneratedHierarchy(t *testing.T) {
+	cfg := NewMapGenerator(2000, 2000, 1, AlgoDelaunay, 150, 0)
+	require.Nil(t, cfg.RoadHierarchy, "the hierarchy is opt-in")
+	cfg.WeightVariation = nil
+	cfg.RoadHierarchy = &RoadHierarchyConfig{RingRoad: true}
+	g := cfg.Generate()
+	require.True(t, g.FastestRoutes)
+
+	counts := map[entities.RoadClass]int{}
+	for _, e := range g.Edges {
+		counts[e.Class]++
+		spec := RoadClasses[e.Class]
+		assert.Equal(t, spec.Speed, e.BaseSpeedLimit)
+		assert.Equal(t, spec.Lanes, e.Lanes)
+	}
+	assert.Positive(t, counts[entities.RoadMotorway])
+	assert.Positive(t, counts[entities.RoadArterial])
+	assert.Positive(t, counts[entities.RoadCollector])
+	assert.Greater(t, counts[entities.RoadLocal], len(g.Edges)/2)
+
+	// Routing by time finds routes no slower, and usually faster, than the
+	// shortest ones.
+	routeTime := func(r *entities.Route) float64 {
+		total := 0.0
+		for _, id := range r.Edges {
+			total += edgeTravelTime(g.Edges[id])
+		}
+		return total
+	}
+	ids := collectIDs(g.Nodes)
+	saved := 0.0
+	for i := 0; i+1 < len(ids); i += 10 {
+		g.FastestRoutes = true
+		fastest := Dijkstra(g, ids[i], ids[i+1])
+		g.FastestRoutes = false
+		shortest := Dijkstra(g, ids[i], ids[i+1])
+		require.Len(t, fastest, 1)
+		require.Len(t, shortest, 1)
+		assert.LessOrEqual(t, routeTime(fastest[0]), routeTime(shortest[0])+1e-9)
+		assert.GreaterOrEqual(t, fastest[0].TotalDistance, shortest[0].TotalDistance-1e-9)
+		saved += routeTime(shortest[0]) - routeTime(fastest[0])
+	}
+	assert.Positive(t, saved)
+}
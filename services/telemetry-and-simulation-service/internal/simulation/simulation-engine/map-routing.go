@@ -32,6 +32,10 @@ func NewRoutingConfig(algo Algorithm) *RoutingConfig {
 }
 
 func Dijkstra(g *entities.MapGraph, start, end string) []*entities.Route {
+	if hasTurnRules(g) || g.FastestRoutes {
+		return turnAwareRoute(g, "", start, end)
+	}
+
 	dist := make(map[string]float64)
 	prev := make(map[string]string)
 	unvisited := make(map[string]bool)
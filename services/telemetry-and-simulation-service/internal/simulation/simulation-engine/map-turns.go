@@ -0,0 +1,320 @@
+package simulationengine
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+
+	"github.com/m/internal/simulation/entities"
+)
+
+const defaultUTurnAngle = 170.0
+
+// Keys of the graph indexes built in this file.
+const (
+	arcsIndex         = "turns/arcs"
+	restrictionsIndex = "turns/restrictions"
+)
+
+func DefaultTurnCosts() *entities.TurnCosts {
+	return &entities.TurnCosts{
+		Penalty:    12,
+		LeftFactor: 1.5,
+		UTurnAngle: defaultUTurnAngle,
+		NoUTurns:   true,
+	}
+}
+
+// hasTurnRules reports whether routing on g has to look at turns, which
+// takes an edge-based search.
+func hasTurnRules(g *entities.MapGraph) bool {
+	if g.Turns != nil {
+		return true
+	}
+	return g.Derived(restrictionsIndex, func() interface{} {
+		for _, n := range g.Nodes {
+			if len(n.Restrictions) > 0 {
+				return true
+			}
+		}
+		return false
+	}).(bool)
+}
+
+// DijkstraFrom is Dijkstra for a vehicle that reaches start over inEdge, so
+// the first turn is priced and restricted like any other.
+func DijkstraFrom(g *entities.MapGraph, inEdge, start, end string) []*entities.Route {
+	if !hasTurnRules(g) {
+		return Dijkstra(g, start, end)
+	}
+	return turnAwareRoute(g, inEdge, start, end)
+}
+
+// arc is an edge in the direction it is driven.
+type arc struct {
+	edge *entities.MapEdge
+	from string
+	to   string
+}
+
+// outgoingArcs lists the arcs leaving every node, sorted by edge ID so that
+// ties resolve the same way on every run. The index is built once per graph
+// and shared, so callers must not modify it.
+func outgoingArcs(g *entities.MapGraph) map[string][]arc {
+	return g.Derived(arcsIndex, func() interface{} { return buildArcs(g) }).(map[string][]arc)
+}
+
+func buildArcs(g *entities.MapGraph) map[string][]arc {
+	out := make(map[string][]arc, len(g.Nodes))
+	for _, e := range g.Edges {
+		out[e.From] = append(out[e.From], arc{e, e.From, e.To})
+		if e.Bidirectional {
+			out[e.To] = append(out[e.To], arc{e, e.To, e.From})
+		}
+	}
+	for _, arcs := range out {
+		sort.Slice(arcs, func(i, j int) bool { return arcs[i].edge.ID < arcs[j].edge.ID })
+	}
+	return out
+}
+
+// headings are the directions the arc is driven in as it leaves its from
+// node and as it reaches its to node, along the edge's geometry.
+func (a arc) headings(g *entities.MapGraph) (leave, reach entities.Vector2D) {
+	from, to := g.Nodes[a.from].Position, g.Nodes[a.to].Position
+	next, prev := to, from
+	if n := len(a.edge.Geometry); n > 0 {
+		next, prev = a.edge.Geometry[0], a.edge.Geometry[n-1]
+		if a.from != a.edge.From {
+			next, prev = prev, next
+		}
+	}
+	return entities.Vector2D{X: next.X - from.X, Y: next.Y - from.Y}, entities.Vector2D{X: to.X - prev.X, Y: to.Y - prev.Y}
+}
+
+// turnAngle is the signed angle in degrees between driving in over `in` and
+// out over `out`, positive to the left. On curved roads it is measured
+// between the last stretch of in and the first of out.
+func turnAngle(g *entities.MapGraph, in, out arc) float64 {
+	if in.edge.ID == out.edge.ID {
+		return 180
+	}
+	_, reach := in.headings(g)
+	leave, _ := out.headings(g)
+	x1, y1 := reach.X, reach.Y
+	x2, y2 := leave.X, leave.Y
+	if (x1 == 0 && y1 == 0) || (x2 == 0 && y2 == 0) {
+		return 0
+	}
+	return math.Atan2(x1*y2-y1*x2, x1*x2+y1*y2) * 180 / math.Pi
+}
+
+// turnCost is the penalty in seconds for turning from in onto out, and false
+// if the turn is not allowed.
+func turnCost(g *entities.MapGraph, in, out arc) (float64, bool) {
+	for _, r := range g.Nodes[in.to].Restrictions {
+		if r.From == in.edge.ID && (r.To == out.edge.ID) != r.Only {
+			return 0, false
+		}
+	}
+
+	t := g.Turns
+	if t == nil {
+		return 0, true
+	}
+	angle := turnAngle(g, in, out)
+	uTurn := t.UTurnAngle
+	if uTurn <= 0 {
+		uTurn = defaultUTurnAngle
+	}
+	if t.NoUTurns && math.Abs(angle) >= uTurn {
+		return 0, false
+	}
+
+	cost := t.Penalty * (angle / 180) * (angle / 180)
+	if angle > 0 && t.LeftFactor > 0 {
+		cost *= t.LeftFactor
+	}
+	return cost, true
+}
+
+// turnState is a node reached over an edge; the start has no edge.
+type turnState struct {
+	node string
+	edge string
+}
+
+type turnItem struct {
+	state turnState
+	in    arc
+	cost  float64
+	prio  float64
+}
+
+type turnQueue []turnItem
+
+func (q turnQueue) Len() int            { return len(q) }
+func (q turnQueue) Less(i, j int) bool  { return q[i].prio < q[j].prio }
+func (q turnQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *turnQueue) Push(x interface{}) { *q = append(*q, x.(turnItem)) }
+func (q *turnQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// turnSearch runs an edge-based A* from start, entered over inEdge, until it
+// settles end, or over the whole graph when end is empty. weight prices
+// driving an edge after a turn costing the given seconds; h must not
+// overestimate the remaining cost. It returns the cost of every settled
+// state, the state each was reached from, and the state end was settled in.
+func turnSearch(g *entities.MapGraph, inEdge, start, end string, weight func(e *entities.MapEdge, turn float64) float64, h func(node string) float64) (map[turnState]float64, map[turnState]turnState, turnState, bool) {
+	arcs := outgoingArcs(g)
+	dist := make(map[turnState]float64)
+	prev := make(map[turnState]turnState)
+	settled := make(map[turnState]bool)
+
+	first := turnItem{state: turnState{start, ""}, prio: h(start)}
+	if e, ok := g.Edges[inEdge]; ok {
+		if e.To == start {
+			first.in = arc{e, e.From, e.To}
+		} else if e.Bidirectional && e.From == start {
+			first.in = arc{e, e.To, e.From}
+		}
+	}
+	dist[first.state] = 0
+	pq := &turnQueue{first}
+
+	for pq.Len() > 0 {
+		item := heap.Pop(pq).(turnItem)
+		if settled[item.state] {
+			continue
+		}
+		settled[item.state] = true
+		if item.state.node == end {
+			return dist, prev, item.state, true
+		}
+
+		for _, a := range arcs[item.state.node] {
+			turn := 0.0
+			if item.in.edge != nil {
+				var ok bool
+				if turn, ok = turnCost(g, item.in, a); !ok {
+					continue
+				}
+			}
+			next := turnState{a.to, a.edge.ID}
+			alt := item.cost + weight(a.edge, turn)
+			if d, seen := dist[next]; !seen || alt < d {
+				dist[next] = alt
+				prev[next] = item.state
+				heap.Push(pq, turnItem{state: next, in: a, cost: alt, prio: alt + h(a.to)})
+			}
+		}
+	}
+	return dist, prev, turnState{}, false
+}
+
+// turnAwareRoute finds the shortest route from start to end that obeys the
+// graph's turn rules, with turn penalties converted to distance at the speed
+// of the edge turned onto. With FastestRoutes it finds the fastest one.
+func turnAwareRoute(g *entities.MapGraph, inEdge, start, end string) []*entities.Route {
+	if _, ok := g.Nodes[start]; !ok {
+		return []*entities.Route{}
+	}
+	target, ok := g.Nodes[end]
+	if !ok {
+		return []*entities.Route{}
+	}
+
+	scale := heuristicScale(g)
+	weight := func(e *entities.MapEdge, turn float64) float64 {
+		if turn == 0 {
+			return e.Length
+		}
+		return e.Length + turn*edgeSpeed(e)
+	}
+	if g.FastestRoutes {
+		weight = func(e *entities.MapEdge, turn float64) float64 { return edgeTravelTime(e) + turn }
+		if top := maxEdgeSpeed(g); top > 0 {
+			scale /= top
+		} else {
+			scale = 0
+		}
+	}
+	h := func(node string) float64 {
+		p := g.Nodes[node].Position
+		return scale * math.Hypot(p.X-target.Position.X, p.Y-target.Position.Y)
+	}
+
+	_, prev, goal, found := turnSearch(g, inEdge, start, end, weight, h)
+	if !found {
+		return []*entities.Route{}
+	}
+
+	route := &entities.Route{StartNode: start, EndNode: end, Edges: searchPath(prev, goal)}
+	for _, id := range route.Edges {
+		route.TotalDistance += g.Edges[id].Length
+	}
+	return []*entities.Route{route}
+}
+
+// searchPath lists the edges turnSearch took to reach goal, in order.
+func searchPath(prev map[turnState]turnState, goal turnState) []string {
+	edges := []string{}
+	for s := goal; s.edge != ""; s = prev[s] {
+		edges = append(edges, s.edge)
+	}
+	for i, j := 0, len(edges)-1; i < j; i, j = i+1, j-1 {
+		edges[i], edges[j] = edges[j], edges[i]
+	}
+	return edges
+}
+
+// heuristicScale bounds edge length per unit of straight-line distance from
+// below, so the A* heuristic stays admissible on maps whose edges are
+// shorter than the gap between their nodes.
+func heuristicScale(g *entities.MapGraph) float64 {
+	scale := 1.0
+	for _, e := range g.Edges {
+		a, b := g.Nodes[e.From], g.Nodes[e.To]
+		if a == nil || b == nil {
+			continue
+		}
+		if d := math.Hypot(a.Position.X-b.Position.X, a.Position.Y-b.Position.Y); d > 0 {
+			scale = math.Min(scale, e.Length/d)
+		}
+	}
+	return math.Max(scale, 0)
+}
+
+func maxEdgeSpeed(g *entities.MapGraph) float64 {
+	top := 0.0
+	for _, e := range g.Edges {
+		top = math.Max(top, edgeSpeed(e))
+	}
+	return top
+}
+
+func edgeSpeed(e *entities.MapEdge) float64 {
+	if e.Conditions != nil && e.Conditions.EffectiveSpeedLimit > 0 {
+		return e.Conditions.EffectiveSpeedLimit
+	}
+	return e.BaseSpeedLimit
+}
+
+// turnAwareTravelTimes is travelTimes for graphs with turn rules: the
+// fastest arrival at each node over any edge, turn penalties included.
+func turnAwareTravelTimes(g *entities.MapGraph, start string) map[string]float64 {
+	weight := func(e *entities.MapEdge, turn float64) float64 { return edgeTravelTime(e) + turn }
+	dist, _, _, _ := turnSearch(g, "", start, "", weight, func(string) float64 { return 0 })
+
+	times := make(map[string]float64)
+	for s, d := range dist {
+		if t, seen := times[s.node]; !seen || d < t {
+			times[s.node] = d
+		}
+	}
+	return times
+}
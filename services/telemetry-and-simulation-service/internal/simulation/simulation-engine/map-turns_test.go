@@ -0,0 +1,137 @@
+package simulationengine
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/m/internal/simulation/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gridGraph is a 3x3 grid of nodes Gxy at (100x, 100y) joined by
+// bidirectional 100m edges at 10 m/s, named like "G00-G10".
+func gridGraph() *entities.MapGraph {
+	g := &entities.MapGraph{Nodes: map[string]*entities.MapNode{}, Edges: map[string]*entities.MapEdge{}}
+	id := func(x, y int) string { return fmt.Sprintf("G%d%d", x, y) }
+	for x := 0; x < 3; x++ {
+		for y := 0; y < 3; y++ {
+			g.Nodes[id(x, y)] = &entities.MapNode{ID: id(x, y), Type: entities.NodeTypeIntersection,
+				Position: entities.Vector2D{X: float64(x) * 100, Y: float64(y) * 100}, Connections: map[string]bool{}}
+		}
+	}
+	link := func(a, b string) {
+		g.Edges[a+"-"+b] = &entities.MapEdge{ID: a + "-" + b, From: a, To: b, Length: 100, Bidirectional: true,
+			Conditions: &entities.RoadConditions{EffectiveSpeedLimit: 10}}
+		g.Nodes[a].Connections[b] = true
+		g.Nodes[b].Connections[a] = true
+	}
+	for x := 0; x < 3; x++ {
+		for y := 0; y < 3; y++ {
+			if x < 2 {
+				link(id(x, y), id(x+1, y))
+			}
+			if y < 2 {
+				link(id(x, y), id(x, y+1))
+			}
+		}
+	}
+	return g
+}
+
+func TestTurns_Restrictions(t *testing.T) {
+	g := gridGraph()
+	route := Dijkstra(g, "G00", "G20")[0]
+	assert.Equal(t, []string{"G00-G10", "G10-G20"}, route.Edges)
+
+	g.Nodes["G10"].Restrictions = []entities.TurnRestriction{{From: "G00-G10", To: "G10-G20"}}
+	g.Invalidate()
+	route = Dijkstra(g, "G00", "G20")[0]
+	assert.Equal(t, 400.0, route.TotalDistance)
+	assert.NotEqual(t, "G10-G20", route.Edges[1])
+
+	g.Nodes["G10"].Restrictions = []entities.TurnRestriction{{From: "G00-G10", To: "G10-G11", Only: true}}
+	g.Invalidate()
+	route = Dijkstra(g, "G00", "G20")[0]
+	assert.Equal(t, 400.0, route.TotalDistance)
+	assert.NotEqual(t, "G10-G20", route.Edges[1])
+
+	// Restrictions only bind vehicles arriving over their From edge.
+	route = Dijkstra(g, "G10", "G20")[0]
+	assert.Equal(t, []string{"G10-G20"}, route.Edges)
+}
+
+func TestOutgoingArcs_CachedUntilInvalidated(t *testing.T) {
+	g := gridGraph()
+	require.Len(t, outgoingArcs(g)["G00"], 2)
+
+	g.Edges["G00-G11"] = &entities.MapEdge{ID: "G00-G11", From: "G00", To: "G11", Length: 141}
+	assert.Len(t, outgoingArcs(g)["G00"], 2)
+	assert.False(t, hasTurnRules(g))
+
+	g.Nodes["G10"].Restrictions = []entities.TurnRestriction{{From: "G00-G10", To: "G10-G20"}}
+	g.Invalidate()
+	assert.Len(t, outgoingArcs(g)["G00"], 3)
+	assert.True(t, hasTurnRules(g))
+}
+
+func TestTurns_CurvedRoadsUseTheirGeometry(t *testing.T) {
+	// A hook: east into V, then north from V and round to B behind it. The
+	// straight line from V to B would be a U-turn.
+	g := &entities.MapGraph{
+		Nodes: map[string]*entities.MapNode{
+			"A": {ID: "A", Connections: map[string]bool{"V": true}},
+			"V": {ID: "V", Position: entities.Vector2D{X: 100}, Connections: map[string]bool{"B": true}},
+			"B": {ID: "B", Position: entities.Vector2D{X: 50, Y: 10}, Connections: map[string]bool{}},
+		},
+		Edges: map[string]*entities.MapEdge{
+			"A-V": {ID: "A-V", From: "A", To: "V", Length: 100, Conditions: &entities.RoadConditions{EffectiveSpeedLimit: 10}},
+			"B-V": {ID: "B-V", From: "B", To: "V", Length: 240, Bidirectional: true,
+				Geometry:   []entities.Vector2D{{X: 50, Y: 100}, {X: 100, Y: 100}},
+				Conditions: &entities.RoadConditions{EffectiveSpeedLimit: 10}},
+		},
+		Turns: DefaultTurnCosts(),
+	}
+
+	in := arc{g.Edges["A-V"], "A", "V"}
+	out := arc{g.Edges["B-V"], "V", "B"}
+	assert.InDelta(t, 90.0, turnAngle(g, in, out), 1e-9)
+	assert.InDelta(t, -90.0, turnAngle(g, arc{g.Edges["B-V"], "B", "V"}, arc{g.Edges["A-V"], "V", "A"}), 1e-9)
+
+	routes := Dijkstra(g, "A", "B")
+	require.NotEmpty(t, routes)
+	assert.Equal(t, []string{"A-V", "B-V"}, routes[0].Edges)
+}
+
+func TestTurns_PenaltiesAvoidZigZags(t *testing.T) {
+	g := gridGraph()
+	g.Turns = &entities.TurnCosts{Penalty: 12, LeftFactor: 1.5}
+
+	// Every route to the far corner is 400m; the one turn to the right wins.
+	route := Dijkstra(g, "G00", "G22")[0]
+	assert.Equal(t, []string{"G00-G01", "G01-G02", "G02-G12", "G12-G22"}, route.Edges)
+	assert.Equal(t, 400.0, route.TotalDistance)
+
+	assert.InDelta(t, 43.0, travelTimes(g, "G00")["G22"], 1e-9)
+}
+
+func TestTurns_NoUTurns(t *testing.T) {
+	g := gridGraph()
+	route := DijkstraFrom(g, "G00-G10", "G10", "G00")[0]
+	assert.Equal(t, []string{"G00-G10"}, route.Edges)
+
+	g.Turns = &entities.TurnCosts{NoUTurns: true}
+	route = DijkstraFrom(g, "G00-G10", "G10", "G00")[0]
+	assert.Equal(t, 300.0, route.TotalDistance)
+	assert.NotEqual(t, "G00-G10", route.Edges[0])
+
+	// A vehicle halfway along an edge has to go round the block.
+	v := &entities.Vehicle{ID: "truck"}
+	require.NoError(t, AssignVehicleRouteWithNodes(v, g, "G00", "G20"))
+	require.NoError(t, UpdateVehiclePosition(v, g, 5))
+	require.NoError(t, RerouteVehicle(v, g, "G00"))
+	assert.Equal(t, []string{"G00-G10", "G10-G11", "G01-G11", "G00-G01"}, v.Route.Edges)
+
+	require.NoError(t, RerouteVehicleVia(v, g, []entities.Waypoint{{NodeID: "G20"}, {NodeID: "G10"}}))
+	assert.Equal(t, []string{"G00-G10", "G10-G20", "G20-G21", "G11-G21", "G10-G11"}, v.Route.Edges)
+}
@@ -0,0 +1,167 @@
+package simulationengine
+
+import (
+	"math"
+	"math/rand/v2"
+	"sort"
+
+	"github.com/m/internal/simulation/entities"
+)
+
+type ZoningConfig struct {
+	// CommercialRadius is the share of the centre-to-corner distance
+	// within which the map is commercial.
+	CommercialRadius float64
+	// IndustrialShare of the nodes outside the centre are industrial,
+	// grouped into districts. It is clamped to [0, 1].
+	IndustrialShare float64
+	// NoiseScale is the size in meters of the noise features that blur the
+	// edge of the centre and shape the industrial districts. Zero means a
+	// quarter of the map.
+	NoiseScale float64
+}
+
+// TripWeights are the Production and Attraction given to nodes of a zone.
+type TripWeights struct {
+	Production float64
+	Attraction float64
+}
+
+// ZoneTripWeights send the morning rush from homes to shops, offices and
+// factories; the evening rush runs the other way.
+var ZoneTripWeights = map[entities.ZoneType]TripWeights{
+	entities.ZoneResidential: {Production: 1.0, Attraction: 0.2},
+	entities.ZoneCommercial:  {Production: 0.3, Attraction: 1.0},
+	entities.ZoneIndustrial:  {Production: 0.1, Attraction: 0.6},
+}
+
+// ZoneMap labels every node with a land use and its trip weights. The
+// centre of the map is commercial out to CommercialRadius, and of the rest
+// the IndustrialShare where a second noise field is highest is industrial;
+// everything else is residential.
+func ZoneMap(g *entities.MapGraph, bounds MapBounds, cfg *ZoningConfig, rng *rand.Rand) {
+	scale := cfg.NoiseScale
+	if scale <= 0 {
+		scale = float64(max(bounds.Width, bounds.Height)) / 4
+	}
+	if scale <= 0 {
+		scale = 1
+	}
+	edge := newValueNoise(bounds, scale, rng)
+	industry := newValueNoise(bounds, scale, rng)
+
+	cx, cy := float64(bounds.Width)/2, float64(bounds.Height)/2
+	maxDist := math.Hypot(cx, cy)
+	if maxDist == 0 {
+		maxDist = 1
+	}
+
+	ids := collectIDs(g.Nodes)
+	sort.Strings(ids)
+	var outer []string
+	for _, id := range ids {
+		n := g.Nodes[id]
+		d := math.Hypot(n.Position.X-cx, n.Position.Y-cy)/maxDist + 0.2*(edge.at(n.Position)-0.5)
+		if d < cfg.CommercialRadius {
+			n.Zone = entities.ZoneCommercial
+		} else {
+			n.Zone = entities.ZoneResidential
+			outer = append(outer, id)
+		}
+	}
+
+	sort.SliceStable(outer, func(i, j int) bool {
+		return industry.at(g.Nodes[outer[i]].Position) > industry.at(g.Nodes[outer[j]].Position)
+	})
+	industrial := int(math.Round(clamp(cfg.IndustrialShare, 0, 1) * float64(len(outer))))
+	for _, id := range outer[:industrial] {
+		g.Nodes[id].Zone = entities.ZoneIndustrial
+	}
+
+	for _, n := range g.Nodes {
+		w := ZoneTripWeights[n.Zone]
+		n.Production, n.Attraction = w.Production, w.Attraction
+	}
+
+	g.Invalidate()
+	productionWeights(g)
+	attractionWeights(g)
+}
+
+// tripWeightTable holds one trip weight of every node as running totals in
+// ID order, so a weighted pick is a binary search.
+type tripWeightTable struct {
+	ids []string
+	// cum[i] is the total weight of ids[:i+1]; negative weights count as
+	// zero.
+	cum []float64
+}
+
+// productionWeights and attractionWeights are built when zoning is
+// assigned, or on first use for graphs weighted by hand, and cached on the
+// graph. Changing weights afterwards takes g.Invalidate.
+func productionWeights(g *entities.MapGraph) *tripWeightTable {
+	return g.Derived("zoning/production", func() interface{} { return newTripWeightTable(g, productionWeight) }).(*tripWeightTable)
+}
+
+func attractionWeights(g *entities.MapGraph) *tripWeightTable {
+	return g.Derived("zoning/attraction", func() interface{} { return newTripWeightTable(g, attractionWeight) }).(*tripWeightTable)
+}
+
+func productionWeight(n *entities.MapNode) float64 { return n.Production }
+func attractionWeight(n *entities.MapNode) float64 { return n.Attraction }
+
+func newTripWeightTable(g *entities.MapGraph, weight func(*entities.MapNode) float64) *tripWeightTable {
+	t := &tripWeightTable{ids: collectIDs(g.Nodes)}
+	sort.Strings(t.ids)
+	t.cum = make([]float64, len(t.ids))
+	total := 0.0
+	for i, id := range t.ids {
+		total += math.Max(weight(g.Nodes[id]), 0)
+		t.cum[i] = total
+	}
+	return t
+}
+
+// weight returns the weight of ids[i].
+func (t *tripWeightTable) weight(i int) float64 {
+	if i == 0 {
+		return t.cum[0]
+	}
+	return t.cum[i] - t.cum[i-1]
+}
+
+// valueNoise is smooth noise in [0, 1]: random values on a lattice of the
+// given spacing, blended between lattice points.
+type valueNoise struct {
+	scale  float64
+	cols   int
+	values []float64
+}
+
+func newValueNoise(bounds MapBounds, scale float64, rng *rand.Rand) *valueNoise {
+	cols := int(float64(bounds.Width)/scale) + 2
+	rows := int(float64(bounds.Height)/scale) + 2
+	v := &valueNoise{scale: scale, cols: cols, values: make([]float64, cols*rows)}
+	for i := range v.values {
+		v.values[i] = rng.Float64()
+	}
+	return v
+}
+
+func (v *valueNoise) at(p entities.Vector2D) float64 {
+	rows := len(v.values) / v.cols
+	x := clamp(p.X/v.scale, 0, float64(v.cols-1))
+	y := clamp(p.Y/v.scale, 0, float64(rows-1))
+	x0, y0 := min(int(x), v.cols-2), min(int(y), rows-2)
+	fx, fy := smoothstep(x-float64(x0)), smoothstep(y-float64(y0))
+
+	value := func(c, r int) float64 { return v.values[r*v.cols+c] }
+	top := value(x0, y0) + (value(x0+1, y0)-value(x0, y0))*fx
+	bottom := value(x0, y0+1) + (value(x0+1, y0+1)-value(x0, y0+1))*fx
+	return top + (bottom-top)*fy
+}
+
+func smoothstep(t float64) float64 {
+	return t * t * (3 - 2*t)
+}
@@ -0,0 +1,122 @@
+package simulationengine
+
+import (
+	"testing"
+
+	"github.com/m/internal/simulation/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestZoning_LabelsNodesAndPlacesFacilities(t *testing.T) {
+	cfg := NewMapGenerator(2000, 2000, 5, AlgoGrid, 0, 0)
+	require.Nil(t, cfg.Zoning, "zoning is opt-in")
+	cfg.Grid = &GridConfig{BlockSize: 100}
+	cfg.Zoning = &ZoningConfig{CommercialRadius: 0.25, IndustrialShare: 0.2}
+	cfg.Depots = 2
+	cfg.ChargingStations = 1
+	cfg.ParkingLots = 3
+	g := cfg.Generate()
+
+	zones := map[entities.ZoneType]int{}
+	for _, n := range g.Nodes {
+		zones[n.Zone]++
+		w := ZoneTripWeights[n.Zone]
+		assert.Equal(t, w.Production, n.Production)
+		assert.Equal(t, w.Attraction, n.Attraction)
+
+		switch n.Type {
+		case entities.NodeTypeDepot:
+			assert.Equal(t, entities.ZoneIndustrial, n.Zone)
+		case entities.NodeTypeParking:
+			assert.Equal(t, entities.ZoneCommercial, n.Zone)
+			require.NotNil(t, n.Facility)
+			assert.Equal(t, 40, n.Facility.ParkingSlots)
+		}
+	}
+	assert.Len(t, zones, 3)
+	assert.InDelta(t, 0.2*float64(zones[entities.ZoneResidential]+zones[entities.ZoneIndustrial]), zones[entities.ZoneIndustrial], 0.5)
+
+	assert.Equal(t, entities.ZoneCommercial, g.Nodes["grid-10-10"].Zone)
+	assert.NotEqual(t, entities.ZoneCommercial, g.Nodes["grid-0-0"].Zone)
+
+	types := map[entities.NodeType]int{}
+	for _, n := range g.Nodes {
+		types[n.Type]++
+	}
+	assert.Equal(t, 2, types[entities.NodeTypeDepot])
+	assert.Equal(t, 1, types[entities.NodeTypeCharging])
+	assert.Equal(t, 3, types[entities.NodeTypeParking])
+}
+
+func TestZoning_ClampsIndustrialShare(t *testing.T) {
+	for share, want := range map[float64]entities.ZoneType{-0.5: entities.ZoneResidential, 3: entities.ZoneIndustrial} {
+		cfg := NewMapGenerator(600, 600, 5, AlgoGrid, 0, 0)
+		cfg.Grid = &GridConfig{BlockSize: 100}
+		cfg.Zoning = &ZoningConfig{IndustrialShare: share}
+		g := cfg.Generate()
+		for _, n := range g.Nodes {
+			if n.Zone != entities.ZoneCommercial {
+				assert.Equal(t, want, n.Zone, n.ID)
+			}
+		}
+	}
+}
+
+func TestZoning_WeightedStrategies(t *testing.T) {
+	g := ringGraph(4)
+	g.Nodes["N0"].Production = 1
+	g.Nodes["N1"].Attraction = 1
+	g.Nodes["N2"].Production, g.Nodes["N2"].Attraction = 3, 3
+	ids := collectIDs(g.Nodes)
+
+	picks := map[string]int{}
+	for i := 0; i < 400; i++ {
+		picks[selectSpawnNode(ids, g, SpawnProduction)]++
+		assert.NotEqual(t, "N1", selectSpawnNode(ids, g, SpawnProduction))
+		assert.Equal(t, "N1", selectTargetNode(ids, g, "N2", TargetAttraction, false))
+	}
+	assert.Zero(t, picks["N3"])
+	assert.InDelta(t, 300, picks["N2"], 50)
+
+	// With every weight excluded the choice falls back to any other node.
+	g.Nodes["N2"].Production, g.Nodes["N2"].Attraction = 0, 0
+	g.Invalidate()
+	for i := 0; i < 20; i++ {
+		assert.NotEqual(t, "N0", selectTargetNode(ids, g, "N0", TargetProduction, false))
+	}
+
+	v := &entities.Vehicle{ID: "commuter"}
+	require.NoError(t, AssignVehicleRoute(v, g, &VehicleSpawnConfig{SpawnStrategy: SpawnProduction, TargetStrategy: TargetAttraction}))
+	assert.Equal(t, "N0", v.Route.StartNode)
+	assert.Equal(t, "N1", v.Route.EndNode)
+
+	assert.NoError(t, validateArrival(&entities.ArrivalBehavior{Mode: entities.ArrivalNewTarget, Target: string(TargetAttraction)}))
+}
+
+func TestSelectWeightedNode_SkipsExcluded(t *testing.T) {
+	g := ringGraph(4)
+	for id, w := range map[string]float64{"N0": 1, "N1": 0, "N2": 3, "N3": 1} {
+		g.Nodes[id].Production = w
+	}
+	weights := productionWeights(g)
+	assert.Equal(t, []float64{1, 1, 4, 5}, weights.cum)
+
+	picks := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		picks[selectWeightedNode(weights, "N2")]++
+	}
+	assert.Zero(t, picks["N1"]+picks["N2"])
+	assert.InDelta(t, 500, picks["N0"], 80)
+
+	picks = map[string]int{}
+	for i := 0; i < 1000; i++ {
+		picks[selectWeightedNode(weights, "N0")]++
+	}
+	assert.Zero(t, picks["N0"]+picks["N1"])
+	assert.InDelta(t, 750, picks["N2"], 80)
+
+	single := productionWeights(ringGraph(1))
+	assert.Equal(t, "", selectWeightedNode(single, "N0"))
+	assert.Equal(t, "N0", selectWeightedNode(single, ""))
+}
@@ -0,0 +1,568 @@
+package simulationengine
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"time"
+
+	"github.com/m/internal/simulation/entities"
+)
+
+var (
+	ErrNoDispatch      = errors.New("order dispatch is disabled")
+	ErrOrderNotFound   = errors.New("order not found")
+	ErrInvalidOrder    = errors.New("invalid order")
+	ErrOrderInProgress = errors.New("order is already picked up")
+)
+
+// OrderGeneratorConfig creates orders between random nodes as a Poisson
+// process.
+type OrderGeneratorConfig struct {
+	// Rate is the mean number of orders per second.
+	Rate float64
+	Load float64
+	// PickupWindow and DeliveryWindow are the seconds after creation by
+	// which an order must be picked up and delivered; 0 leaves them open.
+	PickupWindow   float64
+	DeliveryWindow float64
+	Seed           uint64
+}
+
+type DispatchConfig struct {
+	Policy DispatchPolicy
+	// Interval is how often a running engine matches pending orders.
+	Interval time.Duration
+	// Capacity is the largest load each vehicle type carries; types
+	// without an entry fall back to sedans, an empty map means unlimited.
+	Capacity map[entities.VehicleType]float64
+	// MaxOrders is how many orders a vehicle holds at once, including the
+	// one it is serving; 0 means no limit.
+	MaxOrders int
+	// Generator is nil when orders only come from SubmitOrder.
+	Generator *OrderGeneratorConfig
+}
+
+func DefaultDispatchConfig() *DispatchConfig {
+	return &DispatchConfig{
+		Policy:   NearestIdlePolicy{},
+		Interval: time.Second,
+		Capacity: map[entities.VehicleType]float64{
+			entities.VehicleTypSedan:  4,
+			entities.VehicleTypeTruck: 20,
+			entities.VehicleTypeDrone: 1,
+		},
+		MaxOrders: 2,
+	}
+}
+
+func (c *DispatchConfig) capacity(t entities.VehicleType) float64 {
+	if len(c.Capacity) == 0 {
+		return math.Inf(1)
+	}
+	if capacity, ok := c.Capacity[t]; ok {
+		return capacity
+	}
+	return c.Capacity[entities.VehicleTypSedan]
+}
+
+func (c *DispatchConfig) policy() DispatchPolicy {
+	if c.Policy == nil {
+		return NearestIdlePolicy{}
+	}
+	return c.Policy
+}
+
+// OrderStats summarises all orders. Times are in seconds; waits run from
+// creation to pickup.
+type OrderStats struct {
+	Total           int     `json:"total"`
+	Pending         int     `json:"pending"`
+	Assigned        int     `json:"assigned"`
+	PickedUp        int     `json:"picked_up"`
+	Delivered       int     `json:"delivered"`
+	Expired         int     `json:"expired"`
+	Cancelled       int     `json:"cancelled"`
+	Late            int     `json:"late"`
+	AverageWait     float64 `json:"average_wait"`
+	AverageDelivery float64 `json:"average_delivery"`
+	OnTimeRate      float64 `json:"on_time_rate"`
+}
+
+// orderBook holds every order and the dispatch clock. orderMu is a leaf: it
+// may be taken with vehicle.Mutex held but nothing is locked under it.
+type orderBook struct {
+	clock   float64
+	orders  map[string]*entities.Order
+	pending []string
+	nextID  int
+
+	rng         *rand.Rand
+	nextArrival float64
+}
+
+func newOrderBook() *orderBook {
+	return &orderBook{orders: make(map[string]*entities.Order)}
+}
+
+func (b *orderBook) removePending(id string) {
+	for i, p := range b.pending {
+		if p == id {
+			b.pending = append(b.pending[:i], b.pending[i+1:]...)
+			return
+		}
+	}
+}
+
+// OrderClock returns the dispatch clock in seconds. It advances with Step
+// and, while the engine runs, in real time.
+func (s *SimulationEngine) OrderClock() float64 {
+	s.orderMu.Lock()
+	defer s.orderMu.Unlock()
+
+	return s.orders.clock
+}
+
+// SubmitOrder queues an order for dispatch. Its time windows are relative
+// to submission and stored on the dispatch clock.
+func (s *SimulationEngine) SubmitOrder(order entities.Order) (entities.Order, error) {
+	if s.Dispatch == nil {
+		return entities.Order{}, ErrNoDispatch
+	}
+	if err := s.validateOrder(order); err != nil {
+		return entities.Order{}, err
+	}
+
+	s.orderMu.Lock()
+	created, err := s.addOrder(order)
+	s.orderMu.Unlock()
+	if err != nil {
+		return entities.Order{}, err
+	}
+
+	s.emitOrderEvent(entities.EventOrderCreated, entities.SeverityInfo, &created)
+	return created, nil
+}
+
+func (s *SimulationEngine) validateOrder(order entities.Order) error {
+	if _, ok := s.Graph.Nodes[order.PickupNode]; !ok {
+		return fmt.Errorf("%w: pickup node %q not found", ErrInvalidOrder, order.PickupNode)
+	}
+	if _, ok := s.Graph.Nodes[order.DropoffNode]; !ok {
+		return fmt.Errorf("%w: dropoff node %q not found", ErrInvalidOrder, order.DropoffNode)
+	}
+	if order.PickupNode == order.DropoffNode {
+		return fmt.Errorf("%w: pickup and dropoff are the same node", ErrInvalidOrder)
+	}
+	if order.Load < 0 {
+		return fmt.Errorf("%w: load must not be negative", ErrInvalidOrder)
+	}
+	for _, w := range []entities.TimeWindow{order.PickupWindow, order.DropoffWindow} {
+		if w.Earliest < 0 || w.Latest < 0 || (w.Latest > 0 && w.Latest < w.Earliest) {
+			return fmt.Errorf("%w: bad time window %+v", ErrInvalidOrder, w)
+		}
+	}
+	return nil
+}
+
+// addOrder stores a validated order. Expects orderMu to be held.
+func (s *SimulationEngine) addOrder(order entities.Order) (entities.Order, error) {
+	b := s.orders
+	if order.ID == "" {
+		b.nextID++
+		order.ID = fmt.Sprintf("order-%d", b.nextID)
+	}
+	if _, exists := b.orders[order.ID]; exists {
+		return entities.Order{}, fmt.Errorf("%w: %s already exists", ErrInvalidOrder, order.ID)
+	}
+
+	for _, w := range []*entities.TimeWindow{&order.PickupWindow, &order.DropoffWindow} {
+		w.Earliest += b.clock
+		if w.Latest > 0 {
+			w.Latest += b.clock
+		}
+	}
+	order.Status = entities.OrderPending
+	order.VehicleID = ""
+	order.CreatedAt = b.clock
+	order.AssignedAt, order.PickedUpAt, order.DeliveredAt = 0, 0, 0
+	order.Late = false
+
+	b.orders[order.ID] = &order
+	b.pending = append(b.pending, order.ID)
+	return order, nil
+}
+
+func (s *SimulationEngine) GetOrder(id string) (entities.Order, bool) {
+	s.orderMu.Lock()
+	defer s.orderMu.Unlock()
+
+	o, ok := s.orders.orders[id]
+	if !ok {
+		return entities.Order{}, false
+	}
+	return *o, true
+}
+
+// ListOrders returns all orders in creation order.
+func (s *SimulationEngine) ListOrders() []entities.Order {
+	s.orderMu.Lock()
+	out := make([]entities.Order, 0, len(s.orders.orders))
+	for _, o := range s.orders.orders {
+		out = append(out, *o)
+	}
+	s.orderMu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].CreatedAt != out[j].CreatedAt {
+			return out[i].CreatedAt < out[j].CreatedAt
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+// CancelOrder withdraws an order that has not been picked up yet and takes
+// it off its vehicle's plan.
+func (s *SimulationEngine) CancelOrder(id string) error {
+	s.orderMu.Lock()
+	o, ok := s.orders.orders[id]
+	if !ok {
+		s.orderMu.Unlock()
+		return fmt.Errorf("%w: %s", ErrOrderNotFound, id)
+	}
+	switch o.Status {
+	case entities.OrderPending, entities.OrderAssigned:
+	case entities.OrderPickedUp:
+		s.orderMu.Unlock()
+		return fmt.Errorf("%w: %s", ErrOrderInProgress, id)
+	default:
+		s.orderMu.Unlock()
+		return fmt.Errorf("%w: %s is already %s", ErrInvalidOrder, id, o.Status)
+	}
+	o.Status = entities.OrderCancelled
+	s.orders.removePending(id)
+	order := *o
+	s.orderMu.Unlock()
+
+	vehicle, ok := s.GetVehicle(order.VehicleID)
+	if !ok {
+		s.emitOrderEvent(entities.EventOrderCancelled, entities.SeverityInfo, &order)
+		return nil
+	}
+
+	vehicle.Mutex.Lock()
+	headChanged := len(vehicle.Stops) > 0 && vehicle.Stops[0].OrderID == id
+	stops := vehicle.Stops[:0]
+	for _, stop := range vehicle.Stops {
+		if stop.OrderID != id {
+			stops = append(stops, stop)
+		}
+	}
+	vehicle.Stops = stops
+	rerouted := false
+	charging := vehicle.Energy != nil && vehicle.Energy.ChargerNode != ""
+	if r := vehicle.Route; headChanged && len(stops) > 0 && r != nil && !charging && vehicle.Breakdown == nil &&
+		(r.CompletedAt == nil || r.EndNode != stops[0].NodeID) {
+		rerouted = RerouteVehicle(vehicle, s.Graph, stops[0].NodeID) == nil
+	}
+	vehicle.Mutex.Unlock()
+
+	s.emitVehicleEvent(vehicle, entities.EventOrderCancelled, entities.SeverityInfo, orderData(&order))
+	if rerouted {
+		s.emitVehicleEvent(vehicle, entities.EventRouteStarted, entities.SeverityInfo, nil)
+	}
+	return nil
+}
+
+func (s *SimulationEngine) OrderStats() OrderStats {
+	s.orderMu.Lock()
+	defer s.orderMu.Unlock()
+
+	var st OrderStats
+	var waits, deliveries float64
+	var pickedUp, onTime int
+	for _, o := range s.orders.orders {
+		st.Total++
+		switch o.Status {
+		case entities.OrderPending:
+			st.Pending++
+		case entities.OrderAssigned:
+			st.Assigned++
+		case entities.OrderPickedUp:
+			st.PickedUp++
+		case entities.OrderDelivered:
+			st.Delivered++
+			deliveries += o.DeliveredAt - o.CreatedAt
+			if !o.Late {
+				onTime++
+			}
+		case entities.OrderExpired:
+			st.Expired++
+		case entities.OrderCancelled:
+			st.Cancelled++
+		}
+		if o.Late {
+			st.Late++
+		}
+		if o.Status == entities.OrderPickedUp || o.Status == entities.OrderDelivered {
+			pickedUp++
+			waits += o.PickedUpAt - o.CreatedAt
+		}
+	}
+	if pickedUp > 0 {
+		st.AverageWait = waits / float64(pickedUp)
+	}
+	if st.Delivered > 0 {
+		st.AverageDelivery = deliveries / float64(st.Delivered)
+		st.OnTimeRate = float64(onTime) / float64(st.Delivered)
+	}
+	return st
+}
+
+// stepOrders advances the dispatch clock by dt seconds, creates generated
+// orders, expires those whose pickup window has passed unassigned and runs
+// a dispatch round.
+func (s *SimulationEngine) stepOrders(dt float64) {
+	cfg := s.Dispatch
+	if cfg == nil {
+		return
+	}
+
+	var created, expired []entities.Order
+	s.orderMu.Lock()
+	b := s.orders
+	b.clock += dt
+	if gen := cfg.Generator; gen != nil && gen.Rate > 0 {
+		created = s.generateOrders(gen)
+	}
+	for _, id := range append([]string(nil), b.pending...) {
+		o := b.orders[id]
+		if o.PickupWindow.Latest > 0 && b.clock > o.PickupWindow.Latest {
+			o.Status = entities.OrderExpired
+			b.removePending(id)
+			expired = append(expired, *o)
+		}
+	}
+	s.orderMu.Unlock()
+
+	for i := range created {
+		s.emitOrderEvent(entities.EventOrderCreated, entities.SeverityInfo, &created[i])
+	}
+	for i := range expired {
+		s.emitOrderEvent(entities.EventOrderExpired, entities.SeverityWarning, &expired[i])
+	}
+	s.dispatchOrders()
+}
+
+// generateOrders draws the arrivals up to the current clock. Expects orderMu
+// to be held.
+func (s *SimulationEngine) generateOrders(gen *OrderGeneratorConfig) []entities.Order {
+	b := s.orders
+	if b.rng == nil {
+		seed := gen.Seed
+		if seed == 0 {
+			seed = rand.Uint64()
+		}
+		b.rng = rand.New(rand.NewPCG(seed, seed))
+		b.nextArrival = b.rng.ExpFloat64() / gen.Rate
+	}
+
+	nodes := make([]string, 0, len(s.Graph.Nodes))
+	for id := range s.Graph.Nodes {
+		nodes = append(nodes, id)
+	}
+	if len(nodes) < 2 {
+		return nil
+	}
+	sort.Strings(nodes)
+
+	var created []entities.Order
+	for b.nextArrival <= b.clock {
+		pickup := b.rng.IntN(len(nodes))
+		dropoff := b.rng.IntN(len(nodes) - 1)
+		if dropoff >= pickup {
+			dropoff++
+		}
+		order, err := s.addOrder(entities.Order{
+			PickupNode:    nodes[pickup],
+			DropoffNode:   nodes[dropoff],
+			Load:          gen.Load,
+			PickupWindow:  entities.TimeWindow{Latest: gen.PickupWindow},
+			DropoffWindow: entities.TimeWindow{Latest: gen.DeliveryWindow},
+		})
+		if err == nil {
+			created = append(created, order)
+		}
+		b.nextArrival += b.rng.ExpFloat64() / gen.Rate
+	}
+	return created
+}
+
+// runDispatcher steps the orders every Dispatch.Interval of real time.
+func (s *SimulationEngine) runDispatcher(stop, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(s.Dispatch.Interval)
+	defer ticker.Stop()
+
+	lastUpdate := time.Now()
+	for {
+		select {
+		case now := <-ticker.C:
+			dt := now.Sub(lastUpdate).Seconds()
+			lastUpdate = now
+			if !s.IsPaused() {
+				s.stepOrders(dt)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// updateOrders serves the stops at the node the vehicle has arrived at and
+// sends it on to the next one. A stop reached before its time window opens
+// holds the vehicle there. Expects vehicle.Mutex to be held.
+func (s *SimulationEngine) updateOrders(vehicle *entities.Vehicle) []pendingEvent {
+	r := vehicle.Route
+	if len(vehicle.Stops) == 0 || r == nil || r.CompletedAt == nil || vehicle.State.Status != entities.VehicleStatusArrived {
+		return nil
+	}
+
+	var events []pendingEvent
+	s.orderMu.Lock()
+	clock := s.orders.clock
+	for len(vehicle.Stops) > 0 && vehicle.Stops[0].NodeID == r.EndNode {
+		stop := vehicle.Stops[0]
+		o, ok := s.orders.orders[stop.OrderID]
+		if !ok || o.Status == entities.OrderCancelled {
+			vehicle.Stops = vehicle.Stops[1:]
+			continue
+		}
+
+		window := o.PickupWindow
+		if stop.Kind == entities.StopDropoff {
+			window = o.DropoffWindow
+		}
+		if clock < window.Earliest {
+			break
+		}
+		if window.Latest > 0 && clock > window.Latest {
+			o.Late = true
+		}
+
+		if stop.Kind == entities.StopPickup {
+			o.Status = entities.OrderPickedUp
+			o.PickedUpAt = clock
+			vehicle.Load += o.Load
+			events = append(events, pendingEvent{entities.EventOrderPickedUp, entities.SeverityInfo, orderData(o)})
+		} else {
+			o.Status = entities.OrderDelivered
+			o.DeliveredAt = clock
+			vehicle.Load = math.Max(0, vehicle.Load-o.Load)
+			events = append(events, pendingEvent{entities.EventOrderDelivered, entities.SeverityInfo, orderData(o)})
+		}
+		vehicle.Stops = vehicle.Stops[1:]
+	}
+	s.orderMu.Unlock()
+
+	if len(vehicle.Stops) == 0 {
+		vehicle.Stops = nil
+		return events
+	}
+	if next := vehicle.Stops[0].NodeID; next != r.EndNode {
+		if err := AssignVehicleRouteWithNodes(vehicle, s.Graph, r.EndNode, next); err != nil {
+			return append(events, s.dropUnreachable(vehicle)...)
+		}
+		events = append(events, pendingEvent{entities.EventRouteStarted, entities.SeverityInfo, nil})
+	}
+	return events
+}
+
+// dropUnreachable cancels the order of the vehicle's next stop when no
+// route leads there. Expects vehicle.Mutex to be held.
+func (s *SimulationEngine) dropUnreachable(vehicle *entities.Vehicle) []pendingEvent {
+	id := vehicle.Stops[0].OrderID
+	stops := vehicle.Stops[:0]
+	for _, stop := range vehicle.Stops {
+		if stop.OrderID != id {
+			stops = append(stops, stop)
+		}
+	}
+	vehicle.Stops = stops
+
+	s.orderMu.Lock()
+	defer s.orderMu.Unlock()
+
+	o, ok := s.orders.orders[id]
+	if !ok {
+		return nil
+	}
+	o.Status = entities.OrderCancelled
+	data := orderData(o)
+	data["reason"] = "unreachable"
+	return []pendingEvent{{entities.EventOrderCancelled, entities.SeverityWarning, data}}
+}
+
+// releaseOrders puts the assigned orders of a removed vehicle back in the
+// queue and cancels those it was carrying.
+func (s *SimulationEngine) releaseOrders(vehicle *entities.Vehicle) {
+	vehicle.Mutex.Lock()
+	stops := vehicle.Stops
+	vehicle.Stops = nil
+	vehicle.Mutex.Unlock()
+
+	var cancelled []entities.Order
+	s.orderMu.Lock()
+	for _, stop := range stops {
+		o, ok := s.orders.orders[stop.OrderID]
+		if !ok || stop.Kind != entities.StopDropoff {
+			continue
+		}
+		switch o.Status {
+		case entities.OrderAssigned:
+			o.Status = entities.OrderPending
+			o.VehicleID = ""
+			s.orders.pending = append(s.orders.pending, o.ID)
+		case entities.OrderPickedUp:
+			o.Status = entities.OrderCancelled
+			cancelled = append(cancelled, *o)
+		}
+	}
+	s.orderMu.Unlock()
+
+	for i := range cancelled {
+		s.emitOrderEvent(entities.EventOrderCancelled, entities.SeverityWarning, &cancelled[i])
+	}
+}
+
+// emitOrderEvent reports an order that has no vehicle, with an empty
+// vehicle ID and its own sequence.
+func (s *SimulationEngine) emitOrderEvent(eventType entities.EventType, severity entities.Severity, o *entities.Order) {
+	if s.Emitter == nil {
+		return
+	}
+	s.Emitter.EmitEvent(entities.VehicleEvent{
+		SchemaVersion: entities.TelemetrySchemaVersion,
+		RunID:         s.RunID,
+		Sequence:      s.nextSequence("orders"),
+		EventType:     eventType,
+		Timestamp:     time.Now(),
+		Data:          orderData(o),
+		Severity:      severity,
+	})
+}
+
+func orderData(o *entities.Order) map[string]interface{} {
+	return map[string]interface{}{
+		"order_id":     o.ID,
+		"pickup_node":  o.PickupNode,
+		"dropoff_node": o.DropoffNode,
+		"load":         o.Load,
+		"status":       o.Status,
+		"late":         o.Late,
+	}
+}
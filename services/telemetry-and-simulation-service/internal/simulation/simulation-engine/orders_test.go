@@ -0,0 +1,170 @@
+package simulationengine
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/m/internal/simulation/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// orderTestEngine has one idle vehicle parked at B on the energy test map.
+func orderTestEngine(t *testing.T, policy DispatchPolicy) (*SimulationEngine, *entities.Vehicle, *TelemetryEmitterImpl) {
+	engine, emitter := energyTestEngine(t)
+	engine.Energy = nil
+	engine.Dispatch = &DispatchConfig{Policy: policy, MaxOrders: 2}
+
+	v := &entities.Vehicle{ID: "v1"}
+	require.NoError(t, AssignVehicleRouteWithNodes(v, engine.Graph, "C", "B"))
+	engine.AddVehicle(v)
+	require.NoError(t, engine.Step(20*time.Second))
+	require.Equal(t, entities.VehicleStatusArrived, v.State.Status)
+	drainEvents(emitter)
+	return engine, v, emitter
+}
+
+func stepUntil(t *testing.T, engine *SimulationEngine, done func() bool) {
+	for i := 0; i < 100 && !done(); i++ {
+		require.NoError(t, engine.Step(10*time.Second))
+	}
+	require.True(t, done())
+}
+
+func TestOrders_PickupAndDropoff(t *testing.T) {
+	engine, v, emitter := orderTestEngine(t, NearestIdlePolicy{})
+
+	order, err := engine.SubmitOrder(entities.Order{PickupNode: "A", DropoffNode: "C", Load: 1})
+	require.NoError(t, err)
+	assert.Equal(t, "order-1", order.ID)
+	assert.Equal(t, entities.OrderPending, order.Status)
+
+	require.NoError(t, engine.Step(10*time.Second))
+	order, _ = engine.GetOrder("order-1")
+	assert.Equal(t, entities.OrderAssigned, order.Status)
+	assert.Equal(t, "v1", order.VehicleID)
+	assert.Equal(t, "A", v.Route.EndNode)
+	assert.Equal(t, []entities.EventType{entities.EventOrderCreated, entities.EventOrderAssigned, entities.EventRouteStarted}, drainEvents(emitter))
+
+	stepUntil(t, engine, func() bool { o, _ := engine.GetOrder("order-1"); return o.Status == entities.OrderPickedUp })
+	assert.Equal(t, 1.0, v.Load)
+	assert.Equal(t, "C", v.Route.EndNode)
+
+	stepUntil(t, engine, func() bool { o, _ := engine.GetOrder("order-1"); return o.Status == entities.OrderDelivered })
+	assert.Empty(t, v.Stops)
+	assert.Zero(t, v.Load)
+	assert.Equal(t, []entities.EventType{
+		entities.EventRouteCompleted, entities.EventOrderPickedUp, entities.EventRouteStarted,
+		entities.EventRouteCompleted, entities.EventOrderDelivered,
+	}, drainEvents(emitter))
+
+	stats := engine.OrderStats()
+	assert.Equal(t, 1, stats.Total)
+	assert.Equal(t, 1, stats.Delivered)
+	assert.Equal(t, 1.0, stats.OnTimeRate)
+	assert.Greater(t, stats.AverageDelivery, stats.AverageWait)
+}
+
+func TestOrders_ChainedWithHungarian(t *testing.T) {
+	engine, v, _ := orderTestEngine(t, HungarianPolicy{})
+
+	_, err := engine.SubmitOrder(entities.Order{ID: "far", PickupNode: "A", DropoffNode: "C"})
+	require.NoError(t, err)
+	_, err = engine.SubmitOrder(entities.Order{ID: "near", PickupNode: "C", DropoffNode: "D"})
+	require.NoError(t, err)
+
+	// One vehicle takes the closer pickup first and queues the other behind
+	// it on the next round.
+	require.NoError(t, engine.Step(10*time.Second))
+	near, _ := engine.GetOrder("near")
+	assert.Equal(t, entities.OrderAssigned, near.Status)
+	require.NoError(t, engine.Step(time.Second))
+	far, _ := engine.GetOrder("far")
+	assert.Equal(t, entities.OrderAssigned, far.Status)
+	require.Len(t, v.Stops, 4)
+	assert.Equal(t, "near", v.Stops[0].OrderID)
+	assert.Equal(t, "far", v.Stops[2].OrderID)
+
+	stepUntil(t, engine, func() bool { return engine.OrderStats().Delivered == 2 })
+	assert.Equal(t, "C", v.Route.EndNode)
+}
+
+func TestOrders_TimeWindows(t *testing.T) {
+	engine, v, emitter := orderTestEngine(t, NearestIdlePolicy{})
+
+	_, err := engine.SubmitOrder(entities.Order{ID: "later", PickupNode: "B", DropoffNode: "C", PickupWindow: entities.TimeWindow{Earliest: 60}})
+	require.NoError(t, err)
+	_, err = engine.SubmitOrder(entities.Order{ID: "rushed", PickupNode: "A", DropoffNode: "C", PickupWindow: entities.TimeWindow{Latest: 5}})
+	require.NoError(t, err)
+
+	// The vehicle is already at B but waits for the window to open.
+	require.NoError(t, engine.Step(10*time.Second))
+	require.NoError(t, engine.Step(10*time.Second))
+	later, _ := engine.GetOrder("later")
+	assert.Equal(t, entities.OrderAssigned, later.Status)
+	assert.Equal(t, entities.VehicleStatusArrived, v.State.Status)
+
+	rushed, _ := engine.GetOrder("rushed")
+	assert.Equal(t, entities.OrderExpired, rushed.Status)
+	assert.Contains(t, drainEvents(emitter), entities.EventOrderExpired)
+
+	stepUntil(t, engine, func() bool { o, _ := engine.GetOrder("later"); return o.Status == entities.OrderPickedUp })
+	later, _ = engine.GetOrder("later")
+	assert.GreaterOrEqual(t, later.PickedUpAt, 60.0)
+
+	_, err = engine.SubmitOrder(entities.Order{PickupNode: "B", DropoffNode: "B"})
+	assert.ErrorIs(t, err, ErrInvalidOrder)
+	assert.ErrorIs(t, engine.CancelOrder("later"), ErrOrderInProgress)
+	assert.ErrorIs(t, engine.CancelOrder("nope"), ErrOrderNotFound)
+}
+
+func TestOrders_CancelAndRemove(t *testing.T) {
+	engine, v, _ := orderTestEngine(t, NearestIdlePolicy{})
+
+	_, err := engine.SubmitOrder(entities.Order{ID: "o1", PickupNode: "A", DropoffNode: "C"})
+	require.NoError(t, err)
+	require.NoError(t, engine.Step(10*time.Second))
+	require.NoError(t, engine.CancelOrder("o1"))
+	assert.Empty(t, v.Stops)
+
+	_, err = engine.SubmitOrder(entities.Order{ID: "o2", PickupNode: "A", DropoffNode: "C"})
+	require.NoError(t, err)
+	require.NoError(t, engine.Step(10*time.Second))
+	engine.RemoveVehicle("v1")
+	o2, _ := engine.GetOrder("o2")
+	assert.Equal(t, entities.OrderPending, o2.Status)
+	assert.Empty(t, o2.VehicleID)
+}
+
+func TestOrders_PoissonGenerator(t *testing.T) {
+	engine, _ := energyTestEngine(t)
+	engine.Dispatch = &DispatchConfig{Generator: &OrderGeneratorConfig{Rate: 0.1, Seed: 7}}
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, engine.Step(100*time.Second))
+	}
+	orders := engine.ListOrders()
+	assert.InDelta(t, 100, len(orders), 40)
+	for _, o := range orders {
+		assert.NotEqual(t, o.PickupNode, o.DropoffNode)
+		assert.LessOrEqual(t, o.CreatedAt, 1000.0)
+	}
+}
+
+func TestDispatchPolicies(t *testing.T) {
+	inf := math.Inf(1)
+	orders := make([]entities.Order, 3)
+	candidates := []DispatchCandidate{{Idle: true}, {Idle: true}}
+
+	// Greedy gives the first order its nearest vehicle; the batch does better
+	// by sending it the other one.
+	cost := [][]float64{{1, 2}, {1, 100}, {inf, inf}}
+	assert.Equal(t, []int{0, 1, -1}, NearestIdlePolicy{}.Match(orders, candidates, cost))
+	assert.Equal(t, []int{1, 0, -1}, HungarianPolicy{}.Match(orders, candidates, cost))
+
+	candidates[0].Idle = false
+	assert.Equal(t, []int{1, -1, -1}, NearestIdlePolicy{}.Match(orders, candidates, cost))
+
+	assert.Equal(t, []int{1, 0, 2}, hungarian([][]float64{{4, 1, 3}, {2, 0, 5}, {3, 2, 2}}))
+}
@@ -0,0 +1,267 @@
+package simulationengine
+
+import (
+	"math"
+	"math/rand/v2"
+
+	"github.com/m/internal/simulation/entities"
+)
+
+type ReliabilityConfig struct {
+	// MTBF is the mean driving time between failures, in seconds, by
+	// vehicle type.
+	MTBF map[entities.VehicleType]float64
+	// SurfaceFactor raises the failure rate on rough roads: SurfaceQuality 0
+	// multiplies it by 1+SurfaceFactor, a perfect road leaves it unchanged.
+	SurfaceFactor float64
+	// RepairTime is the mean repair duration in seconds; each repair takes
+	// between half and one and a half times as long.
+	RepairTime float64
+	// TowProbability is the share of breakdowns that need a tow to the
+	// nearest depot rather than a repair on the spot.
+	TowProbability float64
+	// LaneFactor is the fraction of its speed other traffic keeps on the
+	// edge of a broken-down vehicle; 0 blocks the edge.
+	LaneFactor float64
+	// Seed makes failures reproducible; 0 picks a random one.
+	Seed uint64
+}
+
+func DefaultReliabilityConfig() *ReliabilityConfig {
+	return &ReliabilityConfig{
+		MTBF: map[entities.VehicleType]float64{
+			entities.VehicleTypSedan:  400 * 3600,
+			entities.VehicleTypeTruck: 250 * 3600,
+			entities.VehicleTypeDrone: 100 * 3600,
+		},
+		SurfaceFactor:  3,
+		RepairTime:     1800,
+		TowProbability: 0.3,
+		LaneFactor:     0.5,
+	}
+}
+
+// failureRate is the hazard per second of driving on a road of the given
+// surface quality.
+func (c *ReliabilityConfig) failureRate(t entities.VehicleType, surfaceQuality float64) float64 {
+	mtbf, ok := c.MTBF[t]
+	if !ok {
+		mtbf = c.MTBF[entities.VehicleTypSedan]
+	}
+	if mtbf <= 0 {
+		return 0
+	}
+
+	rate := 1 / mtbf
+	if surfaceQuality > 0 {
+		rate *= 1 + c.SurfaceFactor*(1-clamp(surfaceQuality, 0, 1))
+	}
+	return rate
+}
+
+// incidentState counts the broken-down vehicles per edge and owns the
+// random source for failures. Its mutex is a leaf like facilityMu.
+type incidentState struct {
+	rng   *rand.Rand
+	edges map[string]int
+}
+
+func newIncidentState() *incidentState {
+	return &incidentState{edges: make(map[string]int)}
+}
+
+func (s *SimulationEngine) random() float64 {
+	s.incidentMu.Lock()
+	defer s.incidentMu.Unlock()
+
+	if s.incidents.rng == nil {
+		seed := rand.Uint64()
+		if s.Reliability != nil && s.Reliability.Seed != 0 {
+			seed = s.Reliability.Seed
+		}
+		s.incidents.rng = rand.New(rand.NewPCG(seed, seed))
+	}
+	return s.incidents.rng.Float64()
+}
+
+func (s *SimulationEngine) blockEdge(edgeID string) {
+	if edgeID == "" {
+		return
+	}
+	s.incidentMu.Lock()
+	defer s.incidentMu.Unlock()
+
+	s.incidents.edges[edgeID]++
+}
+
+func (s *SimulationEngine) clearEdge(edgeID string) {
+	s.incidentMu.Lock()
+	defer s.incidentMu.Unlock()
+
+	if s.incidents.edges[edgeID] > 1 {
+		s.incidents.edges[edgeID]--
+	} else {
+		delete(s.incidents.edges, edgeID)
+	}
+}
+
+// edgeSpeedFactor is the fraction of its speed a vehicle keeps on edgeID
+// while a broken-down vehicle occupies it.
+func (s *SimulationEngine) edgeSpeedFactor(edgeID string) float64 {
+	if s.Reliability == nil || edgeID == "" {
+		return 1
+	}
+	s.incidentMu.Lock()
+	defer s.incidentMu.Unlock()
+
+	if s.incidents.edges[edgeID] > 0 {
+		return clamp(s.Reliability.LaneFactor, 0, 1)
+	}
+	return 1
+}
+
+func (s *SimulationEngine) repairDuration() float64 {
+	if s.Reliability == nil {
+		return 0
+	}
+	return s.Reliability.RepairTime * (0.5 + s.random())
+}
+
+// updateReliability draws a failure for a vehicle that moved distance
+// meters along edgeID, or advances the repair of one that broke down. It
+// returns the events and the seconds of downtime. Expects vehicle.Mutex to
+// be held.
+func (s *SimulationEngine) updateReliability(vehicle *entities.Vehicle, edgeID string, distance, dt float64) ([]pendingEvent, float64) {
+	if vehicle.Breakdown != nil {
+		return s.repairVehicle(vehicle, dt), dt
+	}
+
+	cfg := s.Reliability
+	if cfg == nil || distance <= 0 || vehicle.State.Status != entities.VehicleStatusMoving {
+		return nil, 0
+	}
+
+	var quality float64
+	if edge := s.Graph.Edges[edgeID]; edge != nil {
+		quality = edge.SurfaceQuality
+	}
+	rate := cfg.failureRate(vehicle.Type, quality)
+	if rate <= 0 || s.random() >= 1-math.Exp(-rate*dt) {
+		return nil, 0
+	}
+	return []pendingEvent{s.breakDown(vehicle)}, 0
+}
+
+// breakDown stalls the vehicle where it is, narrowing its edge, and either
+// starts an on-site repair or calls a tow from the nearest depot.
+func (s *SimulationEngine) breakDown(vehicle *entities.Vehicle) pendingEvent {
+	r := vehicle.Route
+	b := &entities.BreakdownState{
+		Mode:       entities.RepairOnSite,
+		Phase:      entities.BreakdownRepairing,
+		EdgeID:     vehicle.State.CurrentEdge,
+		ResumeNode: r.EndNode,
+	}
+	data := map[string]interface{}{"edge_id": b.EdgeID}
+
+	if s.Reliability.TowProbability > 0 && s.random() < s.Reliability.TowProbability {
+		from := r.CurrentNode
+		if vehicle.State.ProgressOnEdge > 0 {
+			from = r.TargetNode
+		}
+		if depot, eta := s.nearestDepot(from); depot != "" {
+			b.Mode, b.Phase, b.Depot, b.Remaining = entities.RepairTow, entities.BreakdownAwaitingTow, depot, eta
+			data["depot"] = depot
+			data["tow_eta"] = eta
+		}
+	}
+	if b.Mode == entities.RepairOnSite {
+		b.Remaining = s.repairDuration()
+		data["repair_time"] = b.Remaining
+	}
+	data["mode"] = b.Mode
+
+	vehicle.Breakdown = b
+	vehicle.State.Status = entities.VehicleStatusBreakdown
+	vehicle.State.Velocity = entities.Vector2D{}
+	if vehicle.Energy != nil {
+		vehicle.Energy.LastSpeed = 0
+	}
+	s.blockEdge(b.EdgeID)
+	return pendingEvent{entities.EventBreakdownOccurred, entities.SeverityCritical, data}
+}
+
+// repairVehicle moves a broken-down vehicle through its repair: waiting for
+// the tow, being towed to the depot, then the repair itself.
+func (s *SimulationEngine) repairVehicle(vehicle *entities.Vehicle, dt float64) []pendingEvent {
+	b := vehicle.Breakdown
+	b.Downtime += dt
+	vehicle.State.Status = entities.VehicleStatusBreakdown
+
+	switch b.Phase {
+	case entities.BreakdownAwaitingTow:
+		if b.Remaining -= dt; b.Remaining > 0 {
+			return nil
+		}
+		if err := RerouteVehicle(vehicle, s.Graph, b.Depot); err != nil {
+			b.Mode, b.Phase, b.Remaining = entities.RepairOnSite, entities.BreakdownRepairing, s.repairDuration()
+			return nil
+		}
+		s.clearEdge(b.EdgeID)
+		b.Phase = entities.BreakdownTowing
+		return []pendingEvent{{entities.EventTowArrived, entities.SeverityInfo, map[string]interface{}{"depot": b.Depot}}}
+	case entities.BreakdownTowing:
+		if r := vehicle.Route; r == nil || r.CompletedAt == nil {
+			return nil
+		}
+		b.Phase, b.Remaining = entities.BreakdownRepairing, s.repairDuration()
+		return nil
+	}
+
+	if b.Remaining -= dt; b.Remaining > 0 {
+		return nil
+	}
+	vehicle.Breakdown = nil
+	events := []pendingEvent{{entities.EventRepairCompleted, entities.SeverityInfo, map[string]interface{}{
+		"mode":     b.Mode,
+		"downtime": b.Downtime,
+	}}}
+
+	if b.Mode == entities.RepairOnSite {
+		s.clearEdge(b.EdgeID)
+		vehicle.State.Status = entities.VehicleStatusStopped
+		return events
+	}
+
+	vehicle.State.Status = entities.VehicleStatusArrived
+	if r := vehicle.Route; b.ResumeNode != "" && r != nil && b.ResumeNode != r.EndNode {
+		if err := AssignVehicleRouteWithNodes(vehicle, s.Graph, r.EndNode, b.ResumeNode); err == nil {
+			events = append(events, pendingEvent{entities.EventRouteStarted, entities.SeverityInfo, nil})
+		}
+	}
+	return events
+}
+
+func (s *SimulationEngine) nearestDepot(from string) (string, float64) {
+	best, bestTime := "", math.Inf(1)
+	for id, t := range travelTimes(s.Graph, from) {
+		if s.Graph.Nodes[id].Type != entities.NodeTypeDepot {
+			continue
+		}
+		if t < bestTime || (t == bestTime && id < best) {
+			best, bestTime = id, t
+		}
+	}
+	return best, bestTime
+}
+
+// releaseIncident clears the edge a removed vehicle was blocking.
+func (s *SimulationEngine) releaseIncident(vehicle *entities.Vehicle) {
+	vehicle.Mutex.Lock()
+	b := vehicle.Breakdown
+	vehicle.Mutex.Unlock()
+
+	if b != nil && (b.Mode == entities.RepairOnSite || b.Phase == entities.BreakdownAwaitingTow) {
+		s.clearEdge(b.EdgeID)
+	}
+}
@@ -0,0 +1,116 @@
+package simulationengine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/m/internal/simulation/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// reliabilityTestEngine makes sedans fail almost surely within a 10s step
+// while trucks never do.
+func reliabilityTestEngine(t *testing.T) (*SimulationEngine, *TelemetryEmitterImpl) {
+	engine, emitter := energyTestEngine(t)
+	engine.Energy = nil
+	engine.Reliability = &ReliabilityConfig{
+		MTBF: map[entities.VehicleType]float64{
+			entities.VehicleTypSedan:  1,
+			entities.VehicleTypeTruck: 0,
+		},
+		RepairTime: 100,
+		LaneFactor: 0.5,
+		Seed:       1,
+	}
+	return engine, emitter
+}
+
+func TestReliabilityConfig_FailureRate(t *testing.T) {
+	c := ReliabilityConfig{MTBF: map[entities.VehicleType]float64{entities.VehicleTypSedan: 100}, SurfaceFactor: 2}
+
+	assert.InDelta(t, 0.01, c.failureRate(entities.VehicleTypSedan, 1), 1e-12)
+	assert.InDelta(t, 0.02, c.failureRate(entities.VehicleTypSedan, 0.5), 1e-12)
+	assert.InDelta(t, 0.01, c.failureRate(entities.VehicleTypeDrone, 0), 1e-12)
+}
+
+func TestReliability_OnSiteRepair(t *testing.T) {
+	engine, emitter := reliabilityTestEngine(t)
+
+	v := &entities.Vehicle{ID: "v1", Type: entities.VehicleTypSedan}
+	require.NoError(t, AssignVehicleRouteWithNodes(v, engine.Graph, "A", "C"))
+	engine.AddVehicle(v)
+
+	require.NoError(t, engine.Step(10*time.Second))
+	require.NotNil(t, v.Breakdown)
+	assert.Equal(t, entities.VehicleStatusBreakdown, v.State.Status)
+	assert.Equal(t, entities.RepairOnSite, v.Breakdown.Mode)
+	assert.Equal(t, "A-B", v.Breakdown.EdgeID)
+	assert.Equal(t, []entities.EventType{entities.EventBreakdownOccurred}, drainEvents(emitter))
+	engine.Reliability.MTBF[entities.VehicleTypSedan] = 0
+
+	// Traffic behind the broken-down vehicle crawls at half speed.
+	truck := &entities.Vehicle{ID: "v2", Type: entities.VehicleTypeTruck}
+	require.NoError(t, AssignVehicleRouteWithNodes(truck, engine.Graph, "A", "B"))
+	engine.AddVehicle(truck)
+	x := v.State.CurrentPosition.X
+	require.NoError(t, engine.Step(10*time.Second))
+	assert.InDelta(t, 0.05, truck.State.ProgressOnEdge, 1e-9)
+	assert.Equal(t, x, v.State.CurrentPosition.X)
+
+	for i := 0; i < 20 && v.Breakdown != nil; i++ {
+		require.NoError(t, engine.Step(10*time.Second))
+	}
+	require.Nil(t, v.Breakdown)
+	assert.Equal(t, 1.0, engine.edgeSpeedFactor("A-B"))
+	assert.Contains(t, drainEvents(emitter), entities.EventRepairCompleted)
+
+	require.NoError(t, engine.Step(10*time.Second))
+	assert.Equal(t, entities.VehicleStatusMoving, v.State.Status)
+	assert.Greater(t, v.State.CurrentPosition.X, x)
+
+	m := engine.GlobalMetrics()
+	assert.Equal(t, 1, m.Breakdowns)
+	assert.GreaterOrEqual(t, m.TotalDowntime, 50.0)
+}
+
+func TestReliability_TowToDepot(t *testing.T) {
+	engine, emitter := reliabilityTestEngine(t)
+	engine.Reliability.TowProbability = 1
+
+	v := &entities.Vehicle{ID: "v1", Type: entities.VehicleTypSedan}
+	require.NoError(t, AssignVehicleRouteWithNodes(v, engine.Graph, "A", "C"))
+	engine.AddVehicle(v)
+
+	require.NoError(t, engine.Step(10*time.Second))
+	require.NotNil(t, v.Breakdown)
+	engine.Reliability.MTBF[entities.VehicleTypSedan] = 0
+	assert.Equal(t, entities.RepairTow, v.Breakdown.Mode)
+	assert.Equal(t, "D", v.Breakdown.Depot)
+	// The tow drives 100m from D to B at 10 m/s.
+	assert.InDelta(t, 10.0, v.Breakdown.Remaining, 1e-9)
+	assert.Equal(t, 0.5, engine.edgeSpeedFactor("A-B"))
+
+	require.NoError(t, engine.Step(10*time.Second))
+	assert.Equal(t, entities.BreakdownTowing, v.Breakdown.Phase)
+	assert.Equal(t, "D", v.Route.EndNode)
+	assert.Equal(t, 1.0, engine.edgeSpeedFactor("A-B"))
+
+	for i := 0; i < 20 && v.Breakdown.Phase == entities.BreakdownTowing; i++ {
+		require.NoError(t, engine.Step(10*time.Second))
+		assert.Equal(t, entities.VehicleStatusBreakdown, v.State.Status)
+	}
+	assert.Equal(t, entities.BreakdownRepairing, v.Breakdown.Phase)
+	assert.Equal(t, engine.Graph.Nodes["D"].Position, v.State.CurrentPosition)
+	assert.Equal(t, []entities.EventType{entities.EventBreakdownOccurred, entities.EventTowArrived}, drainEvents(emitter))
+
+	for i := 0; i < 20 && v.Breakdown != nil; i++ {
+		require.NoError(t, engine.Step(10*time.Second))
+	}
+	require.Nil(t, v.Breakdown)
+	assert.Equal(t, "C", v.Route.EndNode)
+	assert.Equal(t, []entities.EventType{entities.EventRepairCompleted, entities.EventRouteStarted}, drainEvents(emitter))
+
+	// Towed meters are not driven.
+	assert.InDelta(t, 100.0, engine.GlobalMetrics().TotalDistanceTraveled, 1e-9)
+}
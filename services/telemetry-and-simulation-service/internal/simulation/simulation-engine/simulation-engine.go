@@ -1,33 +1,105 @@
 package simulationengine
 
 import (
+	"errors"
 	"fmt"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/m/internal/simulation/entities"
 )
 
 type SimulationEngine struct {
-	Graph      *entities.MapGraph
-	Vehicles   map[string]*entities.Vehicle
-	UpdateRate time.Duration
-	Mutex      sync.RWMutex
-	IsRunning  bool
-	wg         sync.WaitGroup
+	RunID             string
+	Graph             *entities.MapGraph
+	Vehicles          map[string]*entities.Vehicle
+	UpdateRate        time.Duration
+	TelemetryInterval time.Duration
+	MetricsInterval   time.Duration             // fleet_summary period, zero disables
+	Energy            *EnergyConfig             // nil disables the energy model
+	Reliability       *ReliabilityConfig        // nil disables breakdowns
+	Dispatch          *DispatchConfig           // nil disables order dispatch
+	OnArrival         *entities.ArrivalBehavior // default for vehicles and fleets without one
+	Emitter           entities.TelemetryEmitter
+	Mutex             sync.RWMutex
+	IsRunning         bool
+	wg                sync.WaitGroup
+	paused            atomic.Bool
+
+	activeMu sync.Mutex
+	active   map[*entities.Vehicle]bool
+
+	seqMu     sync.Mutex
+	sequences map[string]uint64
+
+	// fleetMu guards the fleet registry. It is never held while acquiring
+	// Mutex.
+	fleetMu     sync.RWMutex
+	fleets      map[string]*entities.Fleet
+	assignments map[string]entities.FleetAssignment
+
+	metrics     *fleetMetricsAggregator
+	facilityMu  sync.Mutex
+	facilities  *facilityState
+	incidentMu  sync.Mutex
+	incidents   *incidentState
+	orderMu     sync.Mutex
+	orders      *orderBook
+	transitMu   sync.Mutex
+	transit     map[string]*transitLine
+	metricsStop chan struct{}
+	metricsDone chan struct{}
+
+	dispatchStop chan struct{}
+	dispatchDone chan struct{}
+
+	// intersectionMu is a leaf like orderMu; signalStop and signalDone
+	// control the goroutine that runs the signal clocks.
+	intersectionMu sync.Mutex
+	intersections  map[string]*intersection
+	signalStop     chan struct{}
+	signalDone     chan struct{}
 }
 
 type TelemetryEmitterImpl struct {
 	// Will send to message queue later
-	Events chan entities.BasicVehiclePosEvent
+	Events        chan entities.BasicVehiclePosEvent
+	VehicleEvents chan entities.VehicleEvent
+	Dropped       atomic.Uint64
+}
+
+// MultiEmitter fans telemetry out to several emitters, e.g. the WebSocket
+// hub and the SSE broker.
+type MultiEmitter []entities.TelemetryEmitter
+
+// VehicleForgetter is implemented by emitters that keep state per vehicle,
+// which RemoveVehicle lets them drop.
+type VehicleForgetter interface {
+	Forget(vehicleID string)
 }
 
 func NewSimulationEngine(graph *entities.MapGraph, updateRate time.Duration) *SimulationEngine {
 	return &SimulationEngine{
-		Graph:      graph,
-		Vehicles:   make(map[string]*entities.Vehicle),
-		UpdateRate: updateRate,
-		IsRunning:  false,
+		RunID:             uuid.New().String(),
+		Graph:             graph,
+		Vehicles:          make(map[string]*entities.Vehicle),
+		UpdateRate:        updateRate,
+		TelemetryInterval: 1 * time.Second,
+		MetricsInterval:   10 * time.Second,
+		IsRunning:         false,
+		active:            make(map[*entities.Vehicle]bool),
+		sequences:         make(map[string]uint64),
+		fleets:            make(map[string]*entities.Fleet),
+		assignments:       make(map[string]entities.FleetAssignment),
+		metrics:           newFleetMetricsAggregator(),
+		facilities:        newFacilityState(),
+		incidents:         newIncidentState(),
+		orders:            newOrderBook(),
+		transit:           make(map[string]*transitLine),
+		intersections:     make(map[string]*intersection),
 	}
 }
 
@@ -39,35 +111,188 @@ func (s *SimulationEngine) Start() {
 		return
 	}
 	s.IsRunning = true
+	s.paused.Store(false)
 
 	for _, vehicle := range s.Vehicles {
+		select {
+		case <-vehicle.StopChan:
+			vehicle.StopChan = make(chan struct{})
+		default:
+		}
 		s.RunVehicleGoroutine(vehicle)
 	}
+
+	if s.MetricsInterval > 0 {
+		s.metricsStop = make(chan struct{})
+		s.metricsDone = make(chan struct{})
+		go s.runMetricsReporter(s.metricsStop, s.metricsDone)
+	}
+	if s.Dispatch != nil && s.Dispatch.Interval > 0 {
+		s.dispatchStop = make(chan struct{})
+		s.dispatchDone = make(chan struct{})
+		go s.runDispatcher(s.dispatchStop, s.dispatchDone)
+	}
+	s.signalStop = make(chan struct{})
+	s.signalDone = make(chan struct{})
+	go s.runIntersections(s.signalStop, s.signalDone)
 }
 
 func (s *SimulationEngine) Stop() {
 	s.Mutex.Lock()
-	defer s.Mutex.Unlock()
 
 	if !s.IsRunning {
+		s.Mutex.Unlock()
 		return
 	}
 	s.IsRunning = false
+	s.paused.Store(false)
 
 	for _, vehicle := range s.Vehicles {
 		s.stopVehicle(vehicle)
 	}
 	s.wg.Wait()
+
+	// The reporter and the dispatcher read vehicles through Mutex, so wait
+	// for them only after releasing the lock.
+	done := []chan struct{}{s.metricsDone, s.dispatchDone, s.signalDone}
+	if s.metricsStop != nil {
+		close(s.metricsStop)
+		s.metricsStop, s.metricsDone = nil, nil
+	}
+	if s.dispatchStop != nil {
+		close(s.dispatchStop)
+		s.dispatchStop, s.dispatchDone = nil, nil
+	}
+	if s.signalStop != nil {
+		close(s.signalStop)
+		s.signalStop, s.signalDone = nil, nil
+	}
+	s.Mutex.Unlock()
+
+	for _, ch := range done {
+		if ch != nil {
+			<-ch
+		}
+	}
+}
+
+// Pause freezes all vehicles in place without stopping their goroutines.
+// Step can then be used to advance the simulation manually.
+func (s *SimulationEngine) Pause() {
+	s.Mutex.RLock()
+	defer s.Mutex.RUnlock()
+
+	if s.IsRunning {
+		s.paused.Store(true)
+	}
+}
+
+func (s *SimulationEngine) Resume() {
+	s.paused.Store(false)
+}
+
+func (s *SimulationEngine) IsPaused() bool {
+	return s.paused.Load()
+}
+
+func (s *SimulationEngine) Step(dt time.Duration) error {
+	s.Mutex.RLock()
+	if s.IsRunning && !s.IsPaused() {
+		s.Mutex.RUnlock()
+		return fmt.Errorf("engine must be paused or stopped to step")
+	}
+	vehicles := make([]*entities.Vehicle, 0, len(s.Vehicles))
+	for _, v := range s.Vehicles {
+		vehicles = append(vehicles, v)
+	}
+	s.Mutex.RUnlock()
+
+	s.stepIntersections(dt.Seconds())
+	for _, v := range vehicles {
+		if s.vehicleRunnable(v) {
+			s.advanceVehicle(v, dt.Seconds(), true)
+		}
+	}
+	s.stepOrders(dt.Seconds())
+	return nil
+}
+
+func (s *SimulationEngine) GetVehicle(id string) (*entities.Vehicle, bool) {
+	s.Mutex.RLock()
+	defer s.Mutex.RUnlock()
+
+	v, ok := s.Vehicles[id]
+	return v, ok
+}
+
+func (s *SimulationEngine) ListVehicles() []*entities.Vehicle {
+	s.Mutex.RLock()
+	defer s.Mutex.RUnlock()
+
+	vehicles := make([]*entities.Vehicle, 0, len(s.Vehicles))
+	for _, v := range s.Vehicles {
+		vehicles = append(vehicles, v)
+	}
+	sort.Slice(vehicles, func(i, j int) bool { return vehicles[i].ID < vehicles[j].ID })
+	return vehicles
+}
+
+// RouteVehicle sends an existing vehicle to endNode from wherever it is now,
+// restarting its goroutine if it had already arrived.
+func (s *SimulationEngine) RouteVehicle(id, endNode string) error {
+	s.Mutex.RLock()
+	vehicle, ok := s.Vehicles[id]
+	running := s.IsRunning
+	s.Mutex.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("vehicle %s not found", id)
+	}
+
+	vehicle.Mutex.Lock()
+	err := RerouteVehicle(vehicle, s.Graph, endNode)
+	vehicle.Mutex.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if running && s.wakeVehicle(vehicle) {
+		return nil
+	}
+
+	s.emitVehicleEvent(vehicle, entities.EventRouteStarted, entities.SeverityInfo, nil)
+	return nil
+}
+
+// wakeVehicle restarts the goroutine of a vehicle that was given new work
+// after it finished, and reports whether it did.
+func (s *SimulationEngine) wakeVehicle(vehicle *entities.Vehicle) bool {
+	s.Mutex.RLock()
+	defer s.Mutex.RUnlock()
+
+	return s.IsRunning && s.startVehicle(vehicle)
 }
 
 func (s *SimulationEngine) AddVehicle(vehicle *entities.Vehicle) {
+	s.InsertVehicle(vehicle)
+}
+
+// InsertVehicle adds vehicle unless one with the same ID exists, in which
+// case it returns ErrVehicleExists. The check and the insert happen under
+// one lock, so concurrent callers cannot both add the same ID.
+func (s *SimulationEngine) InsertVehicle(vehicle *entities.Vehicle) error {
 	s.Mutex.Lock()
 	defer s.Mutex.Unlock()
 
 	if _, exists := s.Vehicles[vehicle.ID]; exists {
-		return
+		return fmt.Errorf("%w: %s", ErrVehicleExists, vehicle.ID)
 	}
+	s.addVehicle(vehicle)
+	return nil
+}
 
+// addVehicle expects s.Mutex to be held.
+func (s *SimulationEngine) addVehicle(vehicle *entities.Vehicle) {
 	vehicle.StopChan = make(chan struct{})
 
 	s.Vehicles[vehicle.ID] = vehicle
@@ -79,10 +304,9 @@ func (s *SimulationEngine) AddVehicle(vehicle *entities.Vehicle) {
 
 func (s *SimulationEngine) RemoveVehicle(id string) {
 	s.Mutex.Lock()
-	defer s.Mutex.Unlock()
-
 	vehicle, exists := s.Vehicles[id]
 	if !exists {
+		s.Mutex.Unlock()
 		return
 	}
 
@@ -91,6 +315,23 @@ func (s *SimulationEngine) RemoveVehicle(id string) {
 	}
 
 	delete(s.Vehicles, id)
+	s.Mutex.Unlock()
+
+	s.fleetMu.Lock()
+	s.unassign(id)
+	s.fleetMu.Unlock()
+
+	s.releaseSlot(id)
+	s.releaseIncident(vehicle)
+	s.releaseOrders(vehicle)
+	s.releaseTransit(vehicle)
+	s.releaseIntersection(vehicle)
+
+	s.forgetSequence(id)
+
+	if f, ok := s.Emitter.(VehicleForgetter); ok {
+		f.Forget(id)
+	}
 }
 
 func (s *SimulationEngine) stopVehicle(vehicle *entities.Vehicle) {
@@ -102,6 +343,23 @@ func (s *SimulationEngine) stopVehicle(vehicle *entities.Vehicle) {
 }
 
 func (s *SimulationEngine) RunVehicleGoroutine(vehicle *entities.Vehicle) {
+	s.startVehicle(vehicle)
+}
+
+func (s *SimulationEngine) startVehicle(vehicle *entities.Vehicle) bool {
+	s.activeMu.Lock()
+	if s.active == nil {
+		s.active = make(map[*entities.Vehicle]bool)
+	}
+	if s.active[vehicle] {
+		s.activeMu.Unlock()
+		return false
+	}
+	s.active[vehicle] = true
+	s.activeMu.Unlock()
+
+	stop := vehicle.StopChan
+
 	s.wg.Add(1)
 	go func() {
 		defer s.wg.Done()
@@ -112,6 +370,15 @@ func (s *SimulationEngine) RunVehicleGoroutine(vehicle *entities.Vehicle) {
 		lastUpdate := time.Now()
 		lastTelemetryEmit := time.Now()
 
+		vehicle.Mutex.Lock()
+		starting := vehicle.Route != nil && vehicle.Route.CompletedAt == nil &&
+			vehicle.Route.CurrentEdgeIndex == 0 && vehicle.State.ProgressOnEdge == 0
+		vehicle.Mutex.Unlock()
+
+		if starting {
+			s.emitVehicleEvent(vehicle, entities.EventRouteStarted, entities.SeverityInfo, nil)
+		}
+
 		for {
 			select {
 			case <-ticker.C:
@@ -119,27 +386,111 @@ func (s *SimulationEngine) RunVehicleGoroutine(vehicle *entities.Vehicle) {
 				dt := now.Sub(lastUpdate).Seconds()
 				lastUpdate = now
 
-				vehicle.Mutex.Lock()
-				err := UpdateVehiclePosition(vehicle, s.Graph, dt)
-				vehicle.Mutex.Unlock()
-
-				if now.Sub(lastTelemetryEmit) >= 1*time.Second {
-					s.emitTelemetry(vehicle)
-					lastTelemetryEmit = now
+				if s.IsPaused() || !s.vehicleRunnable(vehicle) {
+					continue
 				}
 
-				if err != nil {
+				emit := now.Sub(lastTelemetryEmit) >= s.TelemetryInterval
+				if emit {
+					lastTelemetryEmit = now
 				}
 
-				if vehicle.Route != nil && vehicle.Route.CompletedAt != nil {
+				if s.advanceVehicle(vehicle, dt, emit) && s.retireVehicle(vehicle, false) {
 					return
 				}
 
-			case <-vehicle.StopChan:
+			case <-stop:
+				s.retireVehicle(vehicle, true)
 				return
 			}
 		}
 	}()
+
+	return true
+}
+
+// retireVehicle clears the active flag unless the vehicle was given a new
+// route after its last update; RouteVehicle relies on this to decide whether
+// a fresh goroutine is needed.
+func (s *SimulationEngine) retireVehicle(vehicle *entities.Vehicle, force bool) bool {
+	s.activeMu.Lock()
+	defer s.activeMu.Unlock()
+
+	if !force {
+		vehicle.Mutex.Lock()
+		completed := vehicle.Route != nil && vehicle.Route.CompletedAt != nil && !holdVehicle(vehicle)
+		vehicle.Mutex.Unlock()
+		if !completed {
+			return false
+		}
+	}
+
+	delete(s.active, vehicle)
+	return true
+}
+
+// advanceVehicle moves the vehicle by dt seconds and reports whether its route
+// is complete. Arrival telemetry is emitted exactly once, on the transition.
+func (s *SimulationEngine) advanceVehicle(vehicle *entities.Vehicle, dt float64, emit bool) bool {
+	threshold := s.energyThreshold(vehicle)
+	behavior := s.arrivalBehavior(vehicle)
+
+	vehicle.Mutex.Lock()
+	wasCompleted := vehicle.Route != nil && vehicle.Route.CompletedAt != nil
+	edgeID := vehicle.State.CurrentEdge
+	towed := vehicle.Breakdown != nil
+	var moved float64
+	var err error
+	drive, events := s.updateWaypoints(vehicle, dt)
+	if vehicle.Transit != nil {
+		vehicle.Transit.Clock += dt
+	}
+	s.updateIntersection(vehicle, dt)
+	if !holdVehicle(vehicle) {
+		mark := markRoute(vehicle)
+		delta := drive * s.edgeSpeedFactor(edgeID)
+		if !s.approachIntersection(vehicle, delta) {
+			err = UpdateVehiclePosition(vehicle, s.Graph, delta)
+			events = append(events, s.arriveAtWaypoints(vehicle)...)
+		}
+		moved = distanceSince(vehicle, s.Graph, mark)
+	}
+	// Checked before the energy update, which may send the vehicle on to a
+	// charger. Arriving on a tow does not complete the route.
+	arrived := !wasCompleted && vehicle.Route != nil && vehicle.Route.CompletedAt != nil && !towed
+	energy := s.updateEnergy(vehicle, edgeID, moved, dt, threshold)
+	failures, downtime := s.updateReliability(vehicle, edgeID, moved, dt)
+	energy.events = append(events, energy.events...)
+	energy.events = append(energy.events, failures...)
+	energy.events = append(energy.events, s.updateOrders(vehicle)...)
+	energy.events = append(energy.events, s.updateFacilities(vehicle, dt)...)
+	energy.events = append(energy.events, s.nextTrip(vehicle)...)
+	energy.events = append(energy.events, s.retask(vehicle, behavior, arrived, dt)...)
+	finished := vehicle.Route != nil && vehicle.Route.CompletedAt != nil && !holdVehicle(vehicle)
+	fleetID := vehicle.AssignedFleetID
+	vehicle.Mutex.Unlock()
+
+	if towed {
+		moved = 0
+	}
+	s.metrics.recordMovement(fleetID, moved, dt, energy.used)
+	s.metrics.recordCharging(fleetID, energy.charging)
+	s.metrics.recordDowntime(fleetID, downtime)
+
+	if err != nil {
+	}
+
+	if emit || arrived {
+		s.emitTelemetry(vehicle)
+	}
+	if arrived {
+		s.emitVehicleEvent(vehicle, entities.EventRouteCompleted, entities.SeverityInfo, nil)
+	}
+	for _, ev := range energy.events {
+		s.emitVehicleEvent(vehicle, ev.eventType, ev.severity, ev.data)
+	}
+
+	return finished
 }
 
 func (t *TelemetryEmitterImpl) EmitPosition(event entities.BasicVehiclePosEvent) error {
@@ -147,29 +498,136 @@ func (t *TelemetryEmitterImpl) EmitPosition(event entities.BasicVehiclePosEvent)
 	case t.Events <- event:
 		return nil
 	default:
+		t.Dropped.Add(1)
 		return fmt.Errorf("telemetry channel full")
 	}
 }
 
+func (t *TelemetryEmitterImpl) EmitEvent(event entities.VehicleEvent) error {
+	select {
+	case t.VehicleEvents <- event:
+		return nil
+	default:
+		t.Dropped.Add(1)
+		return fmt.Errorf("event channel full")
+	}
+}
+
+func (m MultiEmitter) EmitPosition(event entities.BasicVehiclePosEvent) error {
+	var errs []error
+	for _, e := range m {
+		if err := e.EmitPosition(event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m MultiEmitter) EmitEvent(event entities.VehicleEvent) error {
+	var errs []error
+	for _, e := range m {
+		if err := e.EmitEvent(event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m MultiEmitter) Forget(vehicleID string) {
+	for _, e := range m {
+		if f, ok := e.(VehicleForgetter); ok {
+			f.Forget(vehicleID)
+		}
+	}
+}
+
 func (s *SimulationEngine) emitTelemetry(vehicle *entities.Vehicle) {
+	vehicle.Mutex.Lock()
 	if vehicle.Route == nil || len(vehicle.Route.Edges) == 0 {
+		vehicle.Mutex.Unlock()
 		return
 	}
 
-	currentEdge := vehicle.State.CurrentEdge
-	if currentEdge == "" && vehicle.Route.CurrentEdgeIndex > 0 {
-		currentEdge = vehicle.Route.Edges[vehicle.Route.CurrentEdgeIndex-1]
+	r := vehicle.Route
+	currentEdge, fromNode := vehicle.State.CurrentEdge, r.CurrentNode
+	if (currentEdge == "" || r.CurrentEdgeIndex >= len(r.Edges)) && r.CurrentEdgeIndex > 0 {
+		// Past the end of its last edge, CurrentNode is where that edge led.
+		currentEdge = r.Edges[r.CurrentEdgeIndex-1]
+		if edge, ok := s.Graph.Edges[currentEdge]; ok {
+			fromNode = edge.OtherEnd(fromNode)
+		}
 	}
 
 	event := entities.BasicVehiclePosEvent{
-		VehicleID:  vehicle.ID,
-		EdgeID:     currentEdge,
-		FromNodeID: vehicle.Route.CurrentNode,
-		Progress:   vehicle.State.ProgressOnEdge,
-		Timestamp:  time.Now(),
+		SchemaVersion: entities.TelemetrySchemaVersion,
+		RunID:         s.RunID,
+		FleetID:       vehicle.AssignedFleetID,
+		VehicleID:     vehicle.ID,
+		Sequence:      s.nextSequence(vehicle.ID),
+		EdgeID:        currentEdge,
+		FromNodeID:    fromNode,
+		Progress:      vehicle.State.ProgressOnEdge,
+		Position:      vehicle.State.CurrentPosition,
+		Timestamp:     time.Now(),
+	}
+	vehicle.Mutex.Unlock()
+
+	if s.Emitter != nil {
+		s.Emitter.EmitPosition(event)
+		return
 	}
 
 	//  Later: send to Kafka/RabbitMQ/REDIS pub sub
 	fmt.Printf("[TELEMETRY] %s | Edge: %s | Progress: %.2f\n",
 		vehicle.ID, event.EdgeID, event.Progress)
 }
+
+func (s *SimulationEngine) emitVehicleEvent(vehicle *entities.Vehicle, eventType entities.EventType, severity entities.Severity, data map[string]interface{}) {
+	vehicle.Mutex.Lock()
+	if data == nil {
+		data = make(map[string]interface{})
+	}
+	if vehicle.Route != nil {
+		data["start_node"] = vehicle.Route.StartNode
+		data["end_node"] = vehicle.Route.EndNode
+	}
+	data["position"] = vehicle.State.CurrentPosition
+	event := entities.VehicleEvent{
+		SchemaVersion: entities.TelemetrySchemaVersion,
+		RunID:         s.RunID,
+		FleetID:       vehicle.AssignedFleetID,
+		VehicleID:     vehicle.ID,
+		Sequence:      s.nextSequence(vehicle.ID),
+		EventType:     eventType,
+		Timestamp:     time.Now(),
+		Data:          data,
+		Severity:      severity,
+	}
+	vehicle.Mutex.Unlock()
+
+	s.metrics.recordEvent(event.FleetID, eventType, severity)
+	if s.Emitter == nil {
+		return
+	}
+	s.Emitter.EmitEvent(event)
+}
+
+// nextSequence numbers positions and events of one vehicle so consumers can
+// detect gaps and reordering. Vehicle sequences are taken under
+// vehicle.Mutex so they follow the order of the state they describe.
+func (s *SimulationEngine) nextSequence(vehicleID string) uint64 {
+	s.seqMu.Lock()
+	defer s.seqMu.Unlock()
+
+	if s.sequences == nil {
+		s.sequences = make(map[string]uint64)
+	}
+	s.sequences[vehicleID]++
+	return s.sequences[vehicleID]
+}
+
+func (s *SimulationEngine) forgetSequence(vehicleID string) {
+	s.seqMu.Lock()
+	delete(s.sequences, vehicleID)
+	s.seqMu.Unlock()
+}
@@ -39,6 +39,29 @@ func TestSimulationEngine_Lifecycle(t *testing.T) {
 	assert.False(t, engine.IsRunning)
 }
 
+type forgetter struct {
+	TelemetryEmitterImpl
+	forgotten []string
+}
+
+func (f *forgetter) Forget(vehicleID string) {
+	f.forgotten = append(f.forgotten, vehicleID)
+}
+
+func TestRemoveVehicle_ForgetsTelemetryState(t *testing.T) {
+	engine := NewSimulationEngine(&entities.MapGraph{Nodes: map[string]*entities.MapNode{}, Edges: map[string]*entities.MapEdge{}}, time.Second)
+	f := &forgetter{}
+	engine.Emitter = MultiEmitter{&TelemetryEmitterImpl{}, f}
+
+	engine.AddVehicle(&entities.Vehicle{ID: "v1"})
+	engine.emitVehicleEvent(engine.Vehicles["v1"], entities.EventRouteStarted, entities.SeverityInfo, nil)
+	assert.Contains(t, engine.sequences, "v1")
+	engine.RemoveVehicle("v1")
+	engine.RemoveVehicle("v2")
+	assert.Equal(t, []string{"v1"}, f.forgotten)
+	assert.NotContains(t, engine.sequences, "v1")
+}
+
 func TestSimulationEngine_ConcurrentVehicles(t *testing.T) {
 	nodeA := &entities.MapNode{ID: "A", Position: entities.Vector2D{X: 0, Y: 0}}
 	nodeB := &entities.MapNode{ID: "B", Position: entities.Vector2D{X: 1000, Y: 0}}
@@ -85,3 +108,127 @@ func TestSimulationEngine_ConcurrentVehicles(t *testing.T) {
 	}
 	engine.Mutex.RUnlock()
 }
+
+func TestSimulationEngine_EmitsRouteEvents(t *testing.T) {
+	nodeA := &entities.MapNode{ID: "A", Position: entities.Vector2D{X: 0, Y: 0}}
+	nodeB := &entities.MapNode{ID: "B", Position: entities.Vector2D{X: 10, Y: 0}}
+	edge := &entities.MapEdge{ID: "A-B", From: "A", To: "B", Length: 10, Conditions: &entities.RoadConditions{EffectiveSpeedLimit: 1000}}
+
+	graph := &entities.MapGraph{
+		Nodes: map[string]*entities.MapNode{"A": nodeA, "B": nodeB},
+		Edges: map[string]*entities.MapEdge{"A-B": edge},
+	}
+
+	emitter := &TelemetryEmitterImpl{
+		Events:        make(chan entities.BasicVehiclePosEvent, 16),
+		VehicleEvents: make(chan entities.VehicleEvent, 16),
+	}
+
+	engine := NewSimulationEngine(graph, 5*time.Millisecond)
+	engine.Emitter = emitter
+	engine.AddVehicle(&entities.Vehicle{
+		ID:              "v1",
+		AssignedFleetID: "fleet-1",
+		Route: &entities.AssignedRoute{
+			Edges:       []string{"A-B"},
+			StartNode:   "A",
+			EndNode:     "B",
+			CurrentNode: "A",
+			TargetNode:  "B",
+		},
+	})
+	engine.Start()
+	defer engine.Stop()
+
+	var types []entities.EventType
+	var lastSeq uint64
+	timeout := time.After(time.Second)
+	for len(types) < 2 {
+		select {
+		case ev := <-emitter.VehicleEvents:
+			assert.Equal(t, "fleet-1", ev.FleetID)
+			assert.Equal(t, engine.RunID, ev.RunID)
+			assert.Equal(t, entities.TelemetrySchemaVersion, ev.SchemaVersion)
+			assert.Greater(t, ev.Sequence, lastSeq)
+			lastSeq = ev.Sequence
+			types = append(types, ev.EventType)
+		case <-timeout:
+			t.Fatalf("timed out waiting for events, got %v", types)
+		}
+	}
+
+	assert.Equal(t, []entities.EventType{entities.EventRouteStarted, entities.EventRouteCompleted}, types)
+
+	select {
+	case pos := <-emitter.Events:
+		assert.Equal(t, "v1", pos.VehicleID)
+		assert.Equal(t, 10.0, pos.Position.X)
+		assert.Less(t, pos.Sequence, lastSeq)
+	default:
+		t.Error("expected a final position event on arrival")
+	}
+}
+
+func TestSimulationEngine_PauseStepAndReroute(t *testing.T) {
+	nodeA := &entities.MapNode{ID: "A", Position: entities.Vector2D{X: 0, Y: 0}, Connections: map[string]bool{"B": true}}
+	nodeB := &entities.MapNode{ID: "B", Position: entities.Vector2D{X: 100, Y: 0}, Connections: map[string]bool{"A": true, "C": true}}
+	nodeC := &entities.MapNode{ID: "C", Position: entities.Vector2D{X: 200, Y: 0}, Connections: map[string]bool{"B": true}}
+	graph := &entities.MapGraph{
+		Nodes: map[string]*entities.MapNode{"A": nodeA, "B": nodeB, "C": nodeC},
+		Edges: map[string]*entities.MapEdge{
+			"A-B": {ID: "A-B", From: "A", To: "B", Length: 100, Bidirectional: true, Conditions: &entities.RoadConditions{EffectiveSpeedLimit: 50}},
+			"B-C": {ID: "B-C", From: "B", To: "C", Length: 100, Bidirectional: true, Conditions: &entities.RoadConditions{EffectiveSpeedLimit: 50}},
+		},
+	}
+
+	engine := NewSimulationEngine(graph, time.Hour)
+	v := &entities.Vehicle{ID: "v1"}
+	assert.NoError(t, AssignVehicleRouteWithNodes(v, graph, "A", "B"))
+	engine.AddVehicle(v)
+
+	engine.Start()
+	defer engine.Stop()
+
+	assert.Error(t, engine.Step(time.Second))
+
+	engine.Pause()
+	assert.True(t, engine.IsPaused())
+	assert.NoError(t, engine.Step(time.Second))
+	assert.InDelta(t, 50.0, v.State.CurrentPosition.X, 1e-9)
+
+	assert.NoError(t, engine.Step(time.Second))
+	assert.NotNil(t, v.Route.CompletedAt)
+
+	assert.NoError(t, engine.RouteVehicle("v1", "C"))
+	assert.Nil(t, v.Route.CompletedAt)
+	assert.Equal(t, []string{"B-C"}, v.Route.Edges)
+
+	assert.NoError(t, engine.Step(2*time.Second))
+	assert.InDelta(t, 200.0, v.State.CurrentPosition.X, 1e-9)
+
+	assert.Error(t, engine.RouteVehicle("missing", "C"))
+}
+
+func TestSimulationEngine_RestartAfterStop(t *testing.T) {
+	nodeA := &entities.MapNode{ID: "A", Position: entities.Vector2D{X: 0, Y: 0}}
+	nodeB := &entities.MapNode{ID: "B", Position: entities.Vector2D{X: 1000, Y: 0}}
+	graph := &entities.MapGraph{
+		Nodes: map[string]*entities.MapNode{"A": nodeA, "B": nodeB},
+		Edges: map[string]*entities.MapEdge{"A-B": {ID: "A-B", From: "A", To: "B", Length: 1000, Conditions: &entities.RoadConditions{EffectiveSpeedLimit: 100}}},
+	}
+
+	engine := NewSimulationEngine(graph, 5*time.Millisecond)
+	v := &entities.Vehicle{ID: "v1", Route: &entities.AssignedRoute{Edges: []string{"A-B"}, StartNode: "A", EndNode: "B", CurrentNode: "A", TargetNode: "B"}}
+	engine.AddVehicle(v)
+
+	engine.Start()
+	engine.Stop()
+	engine.Start()
+	defer engine.Stop()
+
+	time.Sleep(30 * time.Millisecond)
+
+	v.Mutex.Lock()
+	defer v.Mutex.Unlock()
+	assert.Greater(t, v.State.ProgressOnEdge, 0.0)
+}
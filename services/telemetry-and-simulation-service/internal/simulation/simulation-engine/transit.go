@@ -0,0 +1,350 @@
+package simulationengine
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/m/internal/simulation/entities"
+)
+
+var (
+	ErrTransitLineNotFound = errors.New("transit line not found")
+	ErrTransitLineExists   = errors.New("transit line already exists")
+	ErrInvalidTransitLine  = errors.New("invalid transit line")
+)
+
+const (
+	defaultEarlyTolerance = 60.0
+	defaultLateTolerance  = 300.0
+)
+
+// TransitStats counts a line's stop arrivals by schedule adherence.
+// Deviations are in seconds, positive when late.
+type TransitStats struct {
+	Arrivals         int     `json:"arrivals"`
+	Early            int     `json:"early"`
+	OnTime           int     `json:"on_time"`
+	Late             int     `json:"late"`
+	AverageDeviation float64 `json:"average_deviation"`
+	MaxLateness      float64 `json:"max_lateness"`
+}
+
+type TransitLineStatus struct {
+	entities.TransitLine
+	VehicleIDs []string     `json:"vehicle_ids"`
+	Stats      TransitStats `json:"stats"`
+}
+
+// transitLine is a registered line. transitMu is a leaf like orderMu.
+type transitLine struct {
+	line           entities.TransitLine
+	vehicleIDs     []string
+	stats          TransitStats
+	totalDeviation float64
+}
+
+func (l *transitLine) status() TransitLineStatus {
+	return TransitLineStatus{
+		TransitLine: l.line,
+		VehicleIDs:  append([]string{}, l.vehicleIDs...),
+		Stats:       l.stats,
+	}
+}
+
+// departure is when the given trip leaves the first stop.
+func departure(line *entities.TransitLine, trip int) (float64, bool) {
+	if len(line.Departures) > 0 {
+		if trip >= len(line.Departures) {
+			return 0, false
+		}
+		return line.Departures[trip], true
+	}
+	return float64(trip) * line.Headway, true
+}
+
+// AddTransitLine schedules the line and spawns its vehicles at the first
+// stop, the j-th taking trips j, j+Vehicles, j+2*Vehicles and so on.
+func (s *SimulationEngine) AddTransitLine(line entities.TransitLine) (TransitLineStatus, error) {
+	if err := s.validateTransitLine(&line); err != nil {
+		return TransitLineStatus{}, err
+	}
+	if line.VehicleType == "" {
+		line.VehicleType = entities.VehicleTypeTruck
+	}
+
+	if _, exists := s.GetTransitLine(line.ID); exists {
+		return TransitLineStatus{}, fmt.Errorf("%w: %s", ErrTransitLineExists, line.ID)
+	}
+
+	ids := make([]string, line.Vehicles)
+	for j := range ids {
+		ids[j] = fmt.Sprintf("%s-%d", line.ID, j+1)
+		if _, exists := s.GetVehicle(ids[j]); exists {
+			return TransitLineStatus{}, fmt.Errorf("%w: vehicle %s already exists", ErrInvalidTransitLine, ids[j])
+		}
+	}
+
+	vehicles := make([]*entities.Vehicle, line.Vehicles)
+	for j := range vehicles {
+		start, _ := departure(&line, j)
+		v := &entities.Vehicle{
+			ID:      ids[j],
+			Type:    line.VehicleType,
+			Transit: &entities.TransitState{LineID: line.ID, Trip: j, TripStart: start, InService: true},
+		}
+		if err := AssignVehicleRouteWithWaypoints(v, s.Graph, line.Stops[0].NodeID, tripWaypoints(&line)); err != nil {
+			return TransitLineStatus{}, fmt.Errorf("%w: %v", ErrInvalidTransitLine, err)
+		}
+		vehicles[j] = v
+	}
+
+	s.transitMu.Lock()
+	if _, exists := s.transit[line.ID]; exists {
+		s.transitMu.Unlock()
+		return TransitLineStatus{}, fmt.Errorf("%w: %s", ErrTransitLineExists, line.ID)
+	}
+	l := &transitLine{line: line, vehicleIDs: ids}
+	s.transit[line.ID] = l
+	status := l.status()
+	s.transitMu.Unlock()
+
+	for _, v := range vehicles {
+		s.AddVehicle(v)
+	}
+	return status, nil
+}
+
+// validateTransitLine checks the line and fills in its Schedule.
+func (s *SimulationEngine) validateTransitLine(line *entities.TransitLine) error {
+	switch {
+	case line.ID == "":
+		return fmt.Errorf("%w: id is required", ErrInvalidTransitLine)
+	case len(line.Stops) < 2:
+		return fmt.Errorf("%w: a line needs at least two stops", ErrInvalidTransitLine)
+	case line.Vehicles < 1:
+		return fmt.Errorf("%w: a line needs at least one vehicle", ErrInvalidTransitLine)
+	case line.EarlyTolerance < 0 || line.LateTolerance < 0:
+		return fmt.Errorf("%w: tolerances must not be negative", ErrInvalidTransitLine)
+	}
+
+	if len(line.Departures) > 0 {
+		if !sort.Float64sAreSorted(line.Departures) || line.Departures[0] < 0 {
+			return fmt.Errorf("%w: departures must be sorted and not negative", ErrInvalidTransitLine)
+		}
+		if line.Vehicles > len(line.Departures) {
+			return fmt.Errorf("%w: more vehicles than departures", ErrInvalidTransitLine)
+		}
+	} else if line.Headway <= 0 {
+		return fmt.Errorf("%w: headway or departures is required", ErrInvalidTransitLine)
+	}
+
+	nodes := make([]string, 0, len(line.Stops)+1)
+	for _, stop := range line.Stops {
+		if _, ok := s.Graph.Nodes[stop.NodeID]; !ok {
+			return fmt.Errorf("%w: stop %s not found", ErrInvalidTransitLine, stop.NodeID)
+		}
+		if stop.Dwell < 0 || stop.Offset < 0 {
+			return fmt.Errorf("%w: stop %s has a negative dwell or offset", ErrInvalidTransitLine, stop.NodeID)
+		}
+		nodes = append(nodes, stop.NodeID)
+	}
+	nodes = append(nodes, line.Stops[0].NodeID)
+
+	route, ends, err := routeThrough(s.Graph, "", nodes)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidTransitLine, err)
+	}
+
+	// The first stop's dwell is spent before departure.
+	line.Schedule = make([]float64, len(nodes))
+	t := 0.0
+	for i := 1; i < len(nodes); i++ {
+		for _, id := range route.Edges[ends[i-1]:ends[i]] {
+			t += edgeTravelTime(s.Graph.Edges[id])
+		}
+		if i < len(line.Stops) && line.Stops[i].Offset > 0 {
+			t = line.Stops[i].Offset
+		}
+		line.Schedule[i] = t
+		if i < len(line.Stops) {
+			t += line.Stops[i].Dwell
+		}
+	}
+	if math.IsInf(t, 1) {
+		return fmt.Errorf("%w: a segment has no speed limit", ErrInvalidTransitLine)
+	}
+	return nil
+}
+
+// tripWaypoints runs from the first stop, where the vehicle waits for its
+// departure, through every stop and back.
+func tripWaypoints(line *entities.TransitLine) []entities.Waypoint {
+	waypoints := make([]entities.Waypoint, 0, len(line.Stops)+1)
+	waypoints = append(waypoints, entities.Waypoint{NodeID: line.Stops[0].NodeID})
+	for _, stop := range line.Stops[1:] {
+		waypoints = append(waypoints, entities.Waypoint{NodeID: stop.NodeID, Dwell: stop.Dwell})
+	}
+	return append(waypoints, entities.Waypoint{NodeID: line.Stops[0].NodeID})
+}
+
+func (s *SimulationEngine) GetTransitLine(id string) (TransitLineStatus, bool) {
+	s.transitMu.Lock()
+	defer s.transitMu.Unlock()
+
+	l, ok := s.transit[id]
+	if !ok {
+		return TransitLineStatus{}, false
+	}
+	return l.status(), true
+}
+
+func (s *SimulationEngine) ListTransitLines() []TransitLineStatus {
+	s.transitMu.Lock()
+	lines := make([]TransitLineStatus, 0, len(s.transit))
+	for _, l := range s.transit {
+		lines = append(lines, l.status())
+	}
+	s.transitMu.Unlock()
+
+	sort.Slice(lines, func(i, j int) bool { return lines[i].ID < lines[j].ID })
+	return lines
+}
+
+// RemoveTransitLine takes the line and its vehicles out of the simulation.
+func (s *SimulationEngine) RemoveTransitLine(id string) error {
+	s.transitMu.Lock()
+	l, ok := s.transit[id]
+	if !ok {
+		s.transitMu.Unlock()
+		return fmt.Errorf("%w: %s", ErrTransitLineNotFound, id)
+	}
+	delete(s.transit, id)
+	s.transitMu.Unlock()
+
+	for _, vid := range l.vehicleIDs {
+		s.RemoveVehicle(vid)
+	}
+	return nil
+}
+
+// transitArrival reports schedule adherence at the waypoint the vehicle
+// just reached and returns how long it must hold there to leave on time.
+// Leg 0 is the wait at the first stop for the trip's departure. Expects
+// vehicle.Mutex to be held.
+func (s *SimulationEngine) transitArrival(vehicle *entities.Vehicle, leg int) (float64, []pendingEvent) {
+	ts := vehicle.Transit
+	if leg == 0 {
+		return ts.TripStart - ts.Clock, nil
+	}
+
+	s.transitMu.Lock()
+	defer s.transitMu.Unlock()
+
+	l, ok := s.transit[ts.LineID]
+	if !ok || leg >= len(l.line.Schedule) {
+		return 0, nil
+	}
+	line := &l.line
+	stop := leg % len(line.Stops)
+
+	scheduled := ts.TripStart + line.Schedule[leg]
+	deviation := ts.Clock - scheduled
+	ts.Deviation = deviation
+
+	early, late := line.EarlyTolerance, line.LateTolerance
+	if early == 0 {
+		early = defaultEarlyTolerance
+	}
+	if late == 0 {
+		late = defaultLateTolerance
+	}
+	adherence, severity := entities.AdherenceOnTime, entities.SeverityInfo
+	switch {
+	case deviation < -early:
+		adherence = entities.AdherenceEarly
+		l.stats.Early++
+	case deviation > late:
+		adherence, severity = entities.AdherenceLate, entities.SeverityWarning
+		l.stats.Late++
+	default:
+		l.stats.OnTime++
+	}
+	l.stats.Arrivals++
+	l.totalDeviation += deviation
+	l.stats.AverageDeviation = l.totalDeviation / float64(l.stats.Arrivals)
+	l.stats.MaxLateness = math.Max(l.stats.MaxLateness, deviation)
+
+	hold := 0.0
+	if stop != 0 {
+		hold = scheduled + line.Stops[stop].Dwell - ts.Clock
+	}
+	return hold, []pendingEvent{{entities.EventScheduleAdherence, severity, map[string]interface{}{
+		"line_id":   line.ID,
+		"trip":      ts.Trip,
+		"stop":      stop,
+		"node_id":   line.Stops[stop].NodeID,
+		"scheduled": scheduled,
+		"actual":    ts.Clock,
+		"deviation": deviation,
+		"adherence": adherence,
+	}}}
+}
+
+// nextTrip starts the vehicle's next trip once it is back at the first stop,
+// or takes it out of service when the timetable has run out. Expects
+// vehicle.Mutex to be held.
+func (s *SimulationEngine) nextTrip(vehicle *entities.Vehicle) []pendingEvent {
+	ts, r := vehicle.Transit, vehicle.Route
+	if ts == nil || !ts.InService || r == nil || r.CompletedAt == nil || r.CurrentLeg < len(r.Legs) ||
+		vehicle.Breakdown != nil || vehicle.State.Status != entities.VehicleStatusArrived {
+		return nil
+	}
+
+	s.transitMu.Lock()
+	l, ok := s.transit[ts.LineID]
+	var line entities.TransitLine
+	if ok {
+		line = l.line
+	}
+	s.transitMu.Unlock()
+
+	trip := ts.Trip + line.Vehicles
+	start, scheduled := departure(&line, trip)
+	if !ok || !scheduled {
+		ts.InService = false
+		return nil
+	}
+	if err := RerouteVehicleVia(vehicle, s.Graph, tripWaypoints(&line)); err != nil {
+		return nil
+	}
+	ts.Trip, ts.TripStart = trip, start
+	return []pendingEvent{{entities.EventRouteStarted, entities.SeverityInfo, map[string]interface{}{
+		"line_id":   line.ID,
+		"trip":      trip,
+		"departure": start,
+	}}}
+}
+
+// releaseTransit drops a removed vehicle from its line.
+func (s *SimulationEngine) releaseTransit(vehicle *entities.Vehicle) {
+	vehicle.Mutex.Lock()
+	ts := vehicle.Transit
+	vehicle.Mutex.Unlock()
+	if ts == nil {
+		return
+	}
+
+	s.transitMu.Lock()
+	defer s.transitMu.Unlock()
+
+	if l, ok := s.transit[ts.LineID]; ok {
+		for i, id := range l.vehicleIDs {
+			if id == vehicle.ID {
+				l.vehicleIDs = append(l.vehicleIDs[:i], l.vehicleIDs[i+1:]...)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package simulationengine
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/m/internal/simulation/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ringGraph is a one-way loop N0 -> N1 -> ... -> N(n-1) -> N0 of 100m
+// edges at 10 m/s.
+func ringGraph(n int) *entities.MapGraph {
+	g := &entities.MapGraph{Nodes: map[string]*entities.MapNode{}, Edges: map[string]*entities.MapEdge{}}
+	for i := 0; i < n; i++ {
+		id := fmt.Sprintf("N%d", i)
+		g.Nodes[id] = &entities.MapNode{ID: id, Position: entities.Vector2D{X: float64(i) * 100}, Connections: map[string]bool{}}
+	}
+	for i := 0; i < n; i++ {
+		from, to := fmt.Sprintf("N%d", i), fmt.Sprintf("N%d", (i+1)%n)
+		edge := from + "-" + to
+		g.Edges[edge] = &entities.MapEdge{ID: edge, From: from, To: to, Length: 100,
+			Conditions: &entities.RoadConditions{EffectiveSpeedLimit: 10}}
+		g.Nodes[from].Connections[to] = true
+	}
+	return g
+}
+
+func transitTestEngine(t *testing.T) (*SimulationEngine, *TelemetryEmitterImpl) {
+	engine := NewSimulationEngine(ringGraph(6), time.Hour)
+	emitter := captureTelemetry(engine)
+	return engine, emitter
+}
+
+func adherenceEvents(emitter *TelemetryEmitterImpl) []map[string]interface{} {
+	var data []map[string]interface{}
+	for len(emitter.VehicleEvents) > 0 {
+		ev := <-emitter.VehicleEvents
+		if ev.EventType == entities.EventScheduleAdherence {
+			data = append(data, ev.Data)
+		}
+	}
+	return data
+}
+
+func TestTransit_HeadwayHoldingAndAdherence(t *testing.T) {
+	engine, emitter := transitTestEngine(t)
+	status, err := engine.AddTransitLine(entities.TransitLine{
+		ID:             "L1",
+		Stops:          []entities.TransitStop{{NodeID: "N0"}, {NodeID: "N2", Dwell: 10}, {NodeID: "N4", Offset: 60}},
+		Headway:        40,
+		Vehicles:       2,
+		EarlyTolerance: 5,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []float64{0, 20, 60, 80}, status.Schedule)
+	assert.Equal(t, []string{"L1-1", "L1-2"}, status.VehicleIDs)
+
+	v1, _ := engine.GetVehicle("L1-1")
+	v2, _ := engine.GetVehicle("L1-2")
+	for i := 0; i < 7; i++ {
+		require.NoError(t, engine.Step(5*time.Second))
+	}
+	// The second vehicle waits at the first stop for its departure at 40s.
+	assert.Equal(t, entities.VehicleStatusStopped, v2.State.Status)
+	assert.Equal(t, engine.Graph.Nodes["N0"].Position, v2.State.CurrentPosition)
+	require.NoError(t, engine.Step(5*time.Second))
+	require.NoError(t, engine.Step(5*time.Second))
+	assert.Equal(t, entities.VehicleStatusMoving, v2.State.Status)
+
+	// The first vehicle reaches N4 ten seconds ahead of schedule and holds.
+	require.NoError(t, engine.Step(5*time.Second))
+	require.NoError(t, engine.Step(5*time.Second))
+	assert.Equal(t, entities.VehicleStatusStopped, v1.State.Status)
+	assert.Equal(t, engine.Graph.Nodes["N4"].Position, v1.State.CurrentPosition)
+
+	data := adherenceEvents(emitter)
+	require.Len(t, data, 2)
+	assert.Equal(t, "N2", data[0]["node_id"])
+	assert.Equal(t, entities.AdherenceOnTime, data[0]["adherence"])
+	assert.Equal(t, 0.0, data[0]["deviation"])
+	assert.Equal(t, "N4", data[1]["node_id"])
+	assert.Equal(t, entities.AdherenceEarly, data[1]["adherence"])
+	assert.Equal(t, -10.0, data[1]["deviation"])
+
+	// Back at N0 on time, the first vehicle takes trip 2 at 80s.
+	for v1.Transit.Trip == 0 {
+		require.NoError(t, engine.Step(5*time.Second))
+	}
+	assert.Equal(t, 2, v1.Transit.Trip)
+	assert.Equal(t, 80.0, v1.Transit.TripStart)
+	assert.Equal(t, 80.0, v1.Transit.Clock)
+	assert.Zero(t, v1.Transit.Deviation)
+
+	line, ok := engine.GetTransitLine("L1")
+	require.True(t, ok)
+	assert.Equal(t, 1, line.Stats.Early)
+	assert.GreaterOrEqual(t, line.Stats.OnTime, 3)
+	assert.Zero(t, line.Stats.Late)
+}
+
+func TestTransit_TimetableRunsOut(t *testing.T) {
+	engine, _ := transitTestEngine(t)
+	_, err := engine.AddTransitLine(entities.TransitLine{
+		ID:            "L1",
+		Stops:         []entities.TransitStop{{NodeID: "N0"}, {NodeID: "N3", Offset: 20}},
+		Departures:    []float64{10},
+		Vehicles:      1,
+		LateTolerance: 5,
+	})
+	require.NoError(t, err)
+	v, _ := engine.GetVehicle("L1-1")
+
+	stepUntil(t, engine, func() bool { return !v.Transit.InService })
+	assert.Equal(t, entities.VehicleStatusArrived, v.State.Status)
+	assert.Equal(t, "N0", v.Route.EndNode)
+
+	line, _ := engine.GetTransitLine("L1")
+	assert.Equal(t, 2, line.Stats.Arrivals)
+	assert.Equal(t, 2, line.Stats.Late)
+	assert.Equal(t, 10.0, line.Stats.MaxLateness)
+}
+
+func TestTransit_ValidationAndRemoval(t *testing.T) {
+	engine, _ := transitTestEngine(t)
+	stops := []entities.TransitStop{{NodeID: "N0"}, {NodeID: "N3"}}
+
+	for _, line := range []entities.TransitLine{
+		{Stops: stops, Headway: 60, Vehicles: 1},
+		{ID: "L", Stops: stops[:1], Headway: 60, Vehicles: 1},
+		{ID: "L", Stops: stops, Vehicles: 1},
+		{ID: "L", Stops: stops, Headway: 60},
+		{ID: "L", Stops: []entities.TransitStop{{NodeID: "N0"}, {NodeID: "X"}}, Headway: 60, Vehicles: 1},
+		{ID: "L", Stops: stops, Departures: []float64{20, 10}, Vehicles: 1},
+		{ID: "L", Stops: stops, Departures: []float64{10}, Vehicles: 2},
+	} {
+		_, err := engine.AddTransitLine(line)
+		assert.ErrorIs(t, err, ErrInvalidTransitLine)
+	}
+
+	_, err := engine.AddTransitLine(entities.TransitLine{ID: "L", Stops: stops, Headway: 60, Vehicles: 2})
+	require.NoError(t, err)
+	_, err = engine.AddTransitLine(entities.TransitLine{ID: "L", Stops: stops, Headway: 60, Vehicles: 1})
+	assert.ErrorIs(t, err, ErrTransitLineExists)
+
+	engine.RemoveVehicle("L-2")
+	line, _ := engine.GetTransitLine("L")
+	assert.Equal(t, []string{"L-1"}, line.VehicleIDs)
+
+	require.NoError(t, engine.RemoveTransitLine("L"))
+	_, ok := engine.GetVehicle("L-1")
+	assert.False(t, ok)
+	assert.Empty(t, engine.ListTransitLines())
+	assert.ErrorIs(t, engine.RemoveTransitLine("L"), ErrTransitLineNotFound)
+}
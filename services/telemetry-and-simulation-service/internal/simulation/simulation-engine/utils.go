@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math"
 	"math/rand/v2"
+	"sort"
 
 	"github.com/google/uuid"
 	"github.com/m/internal/simulation/entities"
@@ -32,6 +33,16 @@ func collectIDs(nodes map[string]*entities.MapNode) []string {
 	return ids
 }
 
+// graphNodeIDs lists the graph's node IDs, sorted. The list is built once
+// per graph and shared, so callers must not modify it.
+func graphNodeIDs(g *entities.MapGraph) []string {
+	return g.Derived("nodes/ids", func() interface{} {
+		ids := collectIDs(g.Nodes)
+		sort.Strings(ids)
+		return ids
+	}).([]string)
+}
+
 func distance(a, b entities.Vector2D) float64 {
 	dx := a.X - b.X
 	dy := a.Y - b.Y
@@ -43,6 +54,12 @@ func optimalRadius(N int, area float64) float64 {
 	return math.Sqrt((d * area) / (math.Pi * float64(N)))
 }
 
+// randomSource is an unseeded rng for callers that do not need to repeat
+// a run.
+func randomSource() *rand.Rand {
+	return rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+}
+
 func uniformRandomDistributionSampler(heightBound int, widthBound int) (int, int) {
 	x := rand.IntN(widthBound)
 	y := rand.IntN(heightBound)
@@ -66,6 +83,8 @@ func findEdge(g *entities.MapGraph, from, to string) *entities.MapEdge {
 	return nil
 }
 
+// BuildEdgesFromConnections creates an edge for every pair of connected
+// nodes. A connection listed on only one of the two nodes is a one-way street.
 func BuildEdgesFromConnections(mg *entities.MapGraph) error {
 	if mg.Edges == nil {
 		mg.Edges = make(map[string]*entities.MapEdge)
@@ -91,36 +110,13 @@ func BuildEdgesFromConnections(mg *entities.MapGraph) error {
 				continue
 			}
 
-			dist := distance(fromNode.Position, toNode.Position)
-
-			var baseSpeedLimit float64
-			if dist < 100 {
-				baseSpeedLimit = 13.4
-			} else if dist < 300 {
-				baseSpeedLimit = 22.2
-			} else {
-				baseSpeedLimit = 33.3
-			}
-
-			edge := &entities.MapEdge{
-				ID:             edgeKey,
-				From:           fromID,
-				To:             toID,
-				Length:         dist,
-				BaseSpeedLimit: baseSpeedLimit,
-				SurfaceQuality: 0.95 + (0.05 * float64(len(edgeKey)%100) / 100.0),
-				Bidirectional:  true,
-				Conditions: &entities.RoadConditions{
-					Congestion:          0.0,
-					WeatherMultiplier:   1.0,
-					EffectiveSpeedLimit: baseSpeedLimit,
-				},
-			}
-
+			edge := newRoadEdge(edgeKey, fromNode, toNode, 0.95+(0.05*float64(len(edgeKey)%100)/100.0))
+			edge.Bidirectional = toNode.Connections[fromID]
 			mg.Edges[edgeKey] = edge
 			created[edgeKey] = true
 		}
 	}
+	mg.Invalidate()
 
 	return nil
 }
@@ -150,7 +146,9 @@ func findConnectedComponents(g *entities.MapGraph) [][]string {
 	visited := make(map[string]bool)
 	components := [][]string{}
 
-	for nodeID := range g.Nodes {
+	ids := collectIDs(g.Nodes)
+	sort.Strings(ids)
+	for _, nodeID := range ids {
 		if !visited[nodeID] {
 			component := []string{}
 			queue := []string{nodeID}
@@ -199,56 +197,44 @@ func connectNodes(g *entities.MapGraph, node1, node2 *entities.MapNode) {
 	node1.Connections[node2.ID] = true
 	node2.Connections[node1.ID] = true
 
-	dist := distance(node1.Position, node2.Position)
-
-	var baseSpeedLimit float64
-	if dist < 100 {
-		baseSpeedLimit = 13.4
-	} else if dist < 300 {
-		baseSpeedLimit = 22.2
-	} else {
-		baseSpeedLimit = 33.3
-	}
-
 	edgeKey := node1.ID + "-" + node2.ID
 	if node1.ID > node2.ID {
 		edgeKey = node2.ID + "-" + node1.ID
 	}
 
-	edge := &entities.MapEdge{
-		ID:             edgeKey,
-		From:           node1.ID,
-		To:             node2.ID,
-		Length:         dist,
-		BaseSpeedLimit: baseSpeedLimit,
-		SurfaceQuality: 0.90,
-		Bidirectional:  true,
-		Conditions: &entities.RoadConditions{
-			Congestion:          0.0,
-			WeatherMultiplier:   1.0,
-			EffectiveSpeedLimit: baseSpeedLimit,
-		},
-	}
-
-	g.Edges[edgeKey] = edge
+	g.Edges[edgeKey] = newRoadEdge(edgeKey, node1, node2, 0.90)
+	g.Invalidate()
 }
 
 func ApplyWeightVariation(g *entities.MapGraph, config *WeightVariationConfig, bounds MapBounds) {
+	applyWeightVariation(g, config, bounds, randomSource())
+}
+
+// applyWeightVariation draws from rng in edge ID order, so a seeded rng
+// always varies a graph the same way.
+func applyWeightVariation(g *entities.MapGraph, config *WeightVariationConfig, bounds MapBounds, rng *rand.Rand) {
 	centerX := float64(bounds.Width) / 2.0
 	centerY := float64(bounds.Height) / 2.0
 	maxDistFromCenter := math.Sqrt(centerX*centerX + centerY*centerY)
 
-	for _, edge := range g.Edges {
-		curvature := config.CurvatureMin + rand.Float64()*(config.CurvatureMax-config.CurvatureMin)
+	ids := make([]string, 0, len(g.Edges))
+	for id := range g.Edges {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		edge := g.Edges[id]
+		curvature := config.CurvatureMin + rng.Float64()*(config.CurvatureMax-config.CurvatureMin)
 		edge.Length *= curvature
 
-		speedVariation := 1.0 + (rand.Float64()*2.0-1.0)*config.SpeedVariation
+		speedVariation := 1.0 + (rng.Float64()*2.0-1.0)*config.SpeedVariation
 		edge.BaseSpeedLimit *= speedVariation
 		if edge.Conditions != nil {
 			edge.Conditions.EffectiveSpeedLimit = edge.BaseSpeedLimit
 		}
 
-		quality := rand.NormFloat64()*config.QualityStdDev + config.QualityMean
+		quality := rng.NormFloat64()*config.QualityStdDev + config.QualityMean
 		quality = math.Max(0.5, math.Min(1.0, quality))
 
 		if config.UseDistanceFromCenter {
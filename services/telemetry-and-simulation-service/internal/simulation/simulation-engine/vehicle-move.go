@@ -2,6 +2,7 @@ package simulationengine
 
 import (
 	"errors"
+	"math"
 	"time"
 
 	"github.com/m/internal/simulation/entities"
@@ -56,7 +57,7 @@ func UpdateVehiclePosition(vehicle *entities.Vehicle, graph *entities.MapGraph,
 			nextEdge := graph.Edges[vehicle.State.CurrentEdge]
 
 			if nextEdge != nil {
-				vehicle.Route.TargetNode = nextEdge.To
+				vehicle.Route.TargetNode = nextEdge.OtherEnd(vehicle.Route.CurrentNode)
 				edge = nextEdge
 			} else {
 				vehicle.State.ProgressOnEdge = 1.0
@@ -76,13 +77,9 @@ func UpdateVehiclePosition(vehicle *entities.Vehicle, graph *entities.MapGraph,
 		}
 	}
 
-	fromNode := graph.Nodes[edge.From]
-	toNode := graph.Nodes[edge.To]
-
 	progress := clamp(vehicle.State.ProgressOnEdge, 0.0, 1.0)
 
-	vehicle.State.CurrentPosition = interpolatePosition(fromNode, toNode, progress)
-	vehicle.State.Velocity = calculateVelocity(fromNode, toNode, speed)
+	vehicle.State.CurrentPosition, vehicle.State.Velocity = edgePosition(graph, edge, vehicle.Route.CurrentNode, progress, speed)
 
 	vehicle.State.LastUpdateTime = time.Now()
 
@@ -93,6 +90,59 @@ func UpdateVehiclePosition(vehicle *entities.Vehicle, graph *entities.MapGraph,
 	return nil
 }
 
+// edgePosition is where a vehicle that entered the edge at from is after
+// progress of the way along it, and its velocity there, following the edge's
+// geometry when it has one.
+func edgePosition(graph *entities.MapGraph, edge *entities.MapEdge, from string, progress, speed float64) (entities.Vector2D, entities.Vector2D) {
+	fromNode := graph.Nodes[edge.From]
+	toNode := graph.Nodes[edge.To]
+	reversed := from == edge.To && from != edge.From
+	if reversed {
+		fromNode, toNode = toNode, fromNode
+	}
+	if len(edge.Geometry) == 0 {
+		return interpolatePosition(fromNode, toNode, progress), calculateVelocity(fromNode, toNode, speed)
+	}
+
+	points := make([]entities.Vector2D, 0, len(edge.Geometry)+2)
+	points = append(points, fromNode.Position)
+	if reversed {
+		for i := len(edge.Geometry) - 1; i >= 0; i-- {
+			points = append(points, edge.Geometry[i])
+		}
+	} else {
+		points = append(points, edge.Geometry...)
+	}
+	points = append(points, toNode.Position)
+
+	total := 0.0
+	for i := 1; i < len(points); i++ {
+		total += distance(points[i-1], points[i])
+	}
+	remaining := progress * total
+
+	// The last segment with any length takes what is left, so rounding
+	// never runs past the end.
+	last := len(points) - 1
+	for last > 1 && distance(points[last-1], points[last]) == 0 {
+		last--
+	}
+	for i := 1; i <= last; i++ {
+		a, b := points[i-1], points[i]
+		d := distance(a, b)
+		if d == 0 {
+			continue
+		}
+		if remaining <= d || i == last {
+			t := math.Min(remaining/d, 1)
+			velocity := entities.Vector2D{X: (b.X - a.X) / d * speed, Y: (b.Y - a.Y) / d * speed}
+			return entities.Vector2D{X: a.X + (b.X-a.X)*t, Y: a.Y + (b.Y-a.Y)*t}, velocity
+		}
+		remaining -= d
+	}
+	return toNode.Position, entities.Vector2D{}
+}
+
 func interpolatePosition(from, to *entities.MapNode, progress float64) entities.Vector2D {
 
 	resX := from.Position.X + (to.Position.X-from.Position.X)*progress
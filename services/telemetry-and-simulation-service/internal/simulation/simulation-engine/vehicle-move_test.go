@@ -487,3 +487,162 @@ func TestCalculateVelocity_ZeroDistance(t *testing.T) {
 		t.Errorf("Expected zero velocity for zero distance, got (%.2f, %.2f)", result.X, result.Y)
 	}
 }
+
+func TestUpdateVehiclePosition_FollowsGeometry(t *testing.T) {
+	// A -> B bends through (0, 30) and (40, 30): 30 + 40 + 30 = 100m.
+	graph := &entities.MapGraph{
+		Nodes: map[string]*entities.MapNode{
+			"A": {ID: "A", Position: entities.Vector2D{X: 0, Y: 0}},
+			"B": {ID: "B", Position: entities.Vector2D{X: 40, Y: 0}},
+		},
+		Edges: map[string]*entities.MapEdge{
+			"A-B": {
+				ID: "A-B", From: "A", To: "B", Length: 100, BaseSpeedLimit: 10,
+				Geometry:   []entities.Vector2D{{X: 0, Y: 30}, {X: 40, Y: 30}},
+				Conditions: &entities.RoadConditions{EffectiveSpeedLimit: 10},
+			},
+		},
+	}
+	vehicle := &entities.Vehicle{
+		ID: "test-vehicle",
+		Route: &entities.AssignedRoute{
+			Edges:      []string{"A-B"},
+			TargetNode: "B",
+			EndNode:    "B",
+		},
+	}
+
+	tests := []struct {
+		delta    float64
+		pos, vel entities.Vector2D
+	}{
+		{2, entities.Vector2D{X: 0, Y: 20}, entities.Vector2D{X: 0, Y: 10}},
+		{3, entities.Vector2D{X: 20, Y: 30}, entities.Vector2D{X: 10, Y: 0}},
+		{4, entities.Vector2D{X: 40, Y: 10}, entities.Vector2D{X: 0, Y: -10}},
+	}
+	for _, tt := range tests {
+		if err := UpdateVehiclePosition(vehicle, graph, tt.delta); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		pos, vel := vehicle.State.CurrentPosition, vehicle.State.Velocity
+		if math.Abs(pos.X-tt.pos.X) > 1e-9 || math.Abs(pos.Y-tt.pos.Y) > 1e-9 {
+			t.Errorf("Expected position (%.2f, %.2f), got (%.2f, %.2f)", tt.pos.X, tt.pos.Y, pos.X, pos.Y)
+		}
+		if math.Abs(vel.X-tt.vel.X) > 1e-9 || math.Abs(vel.Y-tt.vel.Y) > 1e-9 {
+			t.Errorf("Expected velocity (%.2f, %.2f), got (%.2f, %.2f)", tt.vel.X, tt.vel.Y, vel.X, vel.Y)
+		}
+	}
+}
+
+func TestUpdateVehiclePosition_ReversedEdges(t *testing.T) {
+	// Both edges are bidirectional and stored pointing at B.
+	edge := func(id, from, to string) *entities.MapEdge {
+		return &entities.MapEdge{ID: id, From: from, To: to, Length: 100, Bidirectional: true,
+			Conditions: &entities.RoadConditions{EffectiveSpeedLimit: 10}}
+	}
+	graph := &entities.MapGraph{
+		Nodes: map[string]*entities.MapNode{
+			"A": {ID: "A", Connections: map[string]bool{"B": true}},
+			"B": {ID: "B", Position: entities.Vector2D{X: 100}, Connections: map[string]bool{"A": true, "C": true}},
+			"C": {ID: "C", Position: entities.Vector2D{X: 100, Y: 100}, Connections: map[string]bool{"B": true}},
+		},
+		Edges: map[string]*entities.MapEdge{"A-B": edge("A-B", "A", "B"), "C-B": edge("C-B", "C", "B")},
+	}
+	engine := NewSimulationEngine(graph, time.Second)
+	emitter := captureTelemetry(engine)
+	drive := func(vehicle *entities.Vehicle, seconds float64) entities.BasicVehiclePosEvent {
+		if err := UpdateVehiclePosition(vehicle, graph, seconds); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		engine.emitTelemetry(vehicle)
+		return <-emitter.Events
+	}
+
+	vehicle := &entities.Vehicle{ID: "test-vehicle"}
+	if err := AssignVehicleRouteWithNodes(vehicle, graph, "B", "A"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if vehicle.Route.TargetNode != "A" {
+		t.Errorf("Expected TargetNode A, got %s", vehicle.Route.TargetNode)
+	}
+	if ev := drive(vehicle, 10); ev.EdgeID != "A-B" || ev.FromNodeID != "B" {
+		t.Errorf("Expected arrival over A-B from B, got %s from %s", ev.EdgeID, ev.FromNodeID)
+	}
+
+	if err := RerouteVehicleVia(vehicle, graph, []entities.Waypoint{{NodeID: "C"}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if vehicle.Route.TargetNode != "B" {
+		t.Errorf("Expected TargetNode B, got %s", vehicle.Route.TargetNode)
+	}
+	drive(vehicle, 10)
+	ev := drive(vehicle, 5)
+	if vehicle.Route.CurrentNode != "B" || vehicle.Route.TargetNode != "C" {
+		t.Errorf("Expected to drive C-B from B to C, got %s to %s", vehicle.Route.CurrentNode, vehicle.Route.TargetNode)
+	}
+	if ev.EdgeID != "C-B" || ev.FromNodeID != "B" {
+		t.Errorf("Expected telemetry on C-B from B, got %s from %s", ev.EdgeID, ev.FromNodeID)
+	}
+	if ev = drive(vehicle, 5); ev.FromNodeID != "B" || vehicle.Route.CompletedAt == nil {
+		t.Errorf("Expected arrival at C from B, got from %s", ev.FromNodeID)
+	}
+}
+
+func TestUpdateVehiclePosition_FollowsReversedGeometry(t *testing.T) {
+	// The same bend as above, stored A -> B but driven from B.
+	graph := &entities.MapGraph{
+		Nodes: map[string]*entities.MapNode{
+			"A": {ID: "A", Position: entities.Vector2D{X: 0, Y: 0}},
+			"B": {ID: "B", Position: entities.Vector2D{X: 40, Y: 0}},
+		},
+		Edges: map[string]*entities.MapEdge{
+			"A-B": {
+				ID: "A-B", From: "A", To: "B", Length: 100, BaseSpeedLimit: 10, Bidirectional: true,
+				Geometry:   []entities.Vector2D{{X: 0, Y: 30}, {X: 40, Y: 30}},
+				Conditions: &entities.RoadConditions{EffectiveSpeedLimit: 10},
+			},
+		},
+	}
+	vehicle := &entities.Vehicle{
+		ID: "test-vehicle",
+		Route: &entities.AssignedRoute{
+			Edges:       []string{"A-B"},
+			CurrentNode: "B",
+			TargetNode:  "A",
+			EndNode:     "A",
+		},
+	}
+
+	tests := []struct {
+		delta    float64
+		pos, vel entities.Vector2D
+	}{
+		{2, entities.Vector2D{X: 40, Y: 20}, entities.Vector2D{X: 0, Y: 10}},
+		{3, entities.Vector2D{X: 20, Y: 30}, entities.Vector2D{X: -10, Y: 0}},
+		{4, entities.Vector2D{X: 0, Y: 10}, entities.Vector2D{X: 0, Y: -10}},
+	}
+	for _, tt := range tests {
+		if err := UpdateVehiclePosition(vehicle, graph, tt.delta); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		pos, vel := vehicle.State.CurrentPosition, vehicle.State.Velocity
+		if math.Abs(pos.X-tt.pos.X) > 1e-9 || math.Abs(pos.Y-tt.pos.Y) > 1e-9 {
+			t.Errorf("Expected position (%.2f, %.2f), got (%.2f, %.2f)", tt.pos.X, tt.pos.Y, pos.X, pos.Y)
+		}
+		if math.Abs(vel.X-tt.vel.X) > 1e-9 || math.Abs(vel.Y-tt.vel.Y) > 1e-9 {
+			t.Errorf("Expected velocity (%.2f, %.2f), got (%.2f, %.2f)", tt.vel.X, tt.vel.Y, vel.X, vel.Y)
+		}
+	}
+
+	// Straight edges are interpolated from the same end.
+	graph.Edges["A-B"].Geometry = nil
+	vehicle.Route.CompletedAt = nil
+	vehicle.Route.CurrentEdgeIndex = 0
+	vehicle.State.ProgressOnEdge = 0
+	if err := UpdateVehiclePosition(vehicle, graph, 1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if pos, vel := vehicle.State.CurrentPosition, vehicle.State.Velocity; pos.X != 36 || vel.X >= 0 {
+		t.Errorf("Expected to head west from B, got position %.2f velocity %.2f", pos.X, vel.X)
+	}
+}
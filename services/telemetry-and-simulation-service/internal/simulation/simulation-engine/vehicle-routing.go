@@ -3,6 +3,7 @@ package simulationengine
 import (
 	"fmt"
 	"math/rand/v2"
+	"sort"
 	"time"
 
 	"github.com/m/internal/simulation/entities"
@@ -21,12 +22,18 @@ const (
 	SpawnRandom      SpawnStrategy = "random"
 	SpawnSpecific    SpawnStrategy = "specific"
 	SpawnDistributed SpawnStrategy = "distributed"
+	// SpawnProduction and SpawnAttraction sample nodes by their trip
+	// weights: homes in the morning rush, workplaces in the evening.
+	SpawnProduction SpawnStrategy = "production"
+	SpawnAttraction SpawnStrategy = "attraction"
 )
 
 const (
-	TargetRandom   TargetStrategy = "random"
-	TargetSpecific TargetStrategy = "specific"
-	TargetFarthest TargetStrategy = "farthest"
+	TargetRandom     TargetStrategy = "random"
+	TargetSpecific   TargetStrategy = "specific"
+	TargetFarthest   TargetStrategy = "farthest"
+	TargetAttraction TargetStrategy = "attraction"
+	TargetProduction TargetStrategy = "production"
 )
 
 func AssignVehicleRoute(vehicle *entities.Vehicle, graph *entities.MapGraph, config *VehicleSpawnConfig) error {
@@ -39,7 +46,7 @@ func AssignVehicleRoute(vehicle *entities.Vehicle, graph *entities.MapGraph, con
 		nodeIDs = append(nodeIDs, id)
 	}
 
-	spawnNode := selectSpawnNode(nodeIDs, config.SpawnStrategy)
+	spawnNode := selectSpawnNode(nodeIDs, graph, config.SpawnStrategy)
 	targetNode := selectTargetNode(nodeIDs, graph, spawnNode, config.TargetStrategy, config.AllowSameNode)
 
 	if spawnNode == "" || targetNode == "" {
@@ -132,7 +139,42 @@ func AssignVehicleRouteWithNodes(vehicle *entities.Vehicle, graph *entities.MapG
 	return nil
 }
 
-func selectSpawnNode(nodeIDs []string, strategy SpawnStrategy) string {
+// RerouteVehicle replaces the vehicle's route with one ending at endNode. A
+// vehicle in the middle of an edge finishes that edge first.
+func RerouteVehicle(vehicle *entities.Vehicle, graph *entities.MapGraph, endNode string) error {
+	if _, exists := graph.Nodes[endNode]; !exists {
+		return fmt.Errorf("end node %s not found in graph", endNode)
+	}
+	if vehicle.Route == nil {
+		return fmt.Errorf("vehicle %s has no position on the graph", vehicle.ID)
+	}
+
+	if vehicle.Route.CompletedAt != nil {
+		return AssignVehicleRouteWithNodes(vehicle, graph, vehicle.Route.EndNode, endNode)
+	}
+
+	if vehicle.State.CurrentEdge == "" || vehicle.State.ProgressOnEdge == 0 {
+		return AssignVehicleRouteWithNodes(vehicle, graph, vehicle.Route.CurrentNode, endNode)
+	}
+
+	routes := DijkstraFrom(graph, vehicle.State.CurrentEdge, vehicle.Route.TargetNode, endNode)
+	if len(routes) == 0 {
+		return fmt.Errorf("no route found from %s to %s", vehicle.Route.TargetNode, endNode)
+	}
+
+	vehicle.Route.Edges = append([]string{vehicle.State.CurrentEdge}, routes[0].Edges...)
+	vehicle.Route.CurrentEdgeIndex = 0
+	vehicle.Route.StartNode = vehicle.Route.CurrentNode
+	vehicle.Route.EndNode = endNode
+	vehicle.Route.StartedAt = time.Now()
+	vehicle.Route.Legs = nil
+	vehicle.Route.CurrentLeg = 0
+	vehicle.Route.DwellRemaining = 0
+
+	return nil
+}
+
+func selectSpawnNode(nodeIDs []string, graph *entities.MapGraph, strategy SpawnStrategy) string {
 	if len(nodeIDs) == 0 {
 		return ""
 	}
@@ -142,6 +184,10 @@ func selectSpawnNode(nodeIDs []string, strategy SpawnStrategy) string {
 		return nodeIDs[rand.IntN(len(nodeIDs))]
 	case SpawnDistributed:
 		return selectDistributedNode(nodeIDs)
+	case SpawnProduction:
+		return selectWeightedNode(productionWeights(graph), "")
+	case SpawnAttraction:
+		return selectWeightedNode(attractionWeights(graph), "")
 	default:
 		return nodeIDs[rand.IntN(len(nodeIDs))]
 	}
@@ -165,6 +211,17 @@ func selectTargetNode(nodeIDs []string, graph *entities.MapGraph, spawnNode stri
 	case TargetFarthest:
 		return selectFarthestNode(nodeIDs, graph, spawnNode, allowSame)
 
+	case TargetAttraction, TargetProduction:
+		weights := attractionWeights(graph)
+		if strategy == TargetProduction {
+			weights = productionWeights(graph)
+		}
+		exclude := spawnNode
+		if allowSame {
+			exclude = ""
+		}
+		return selectWeightedNode(weights, exclude)
+
 	default:
 		for i := 0; i < 10; i++ {
 			target := nodeIDs[rand.IntN(len(nodeIDs))]
@@ -194,6 +251,48 @@ func selectDistributedNode(nodeIDs []string) string {
 	return candidates[rand.IntN(len(candidates))]
 }
 
+// selectWeightedNode samples a node other than exclude with probability
+// proportional to its weight, or uniformly if no node has any. The excluded
+// node's share of the running totals is skipped rather than filtered out.
+func selectWeightedNode(weights *tripWeightTable, exclude string) string {
+	n := len(weights.ids)
+	skip := sort.SearchStrings(weights.ids, exclude)
+	if skip == n || weights.ids[skip] != exclude {
+		skip = -1
+	}
+	candidates := n
+	if skip >= 0 {
+		candidates--
+	}
+	if candidates == 0 {
+		return ""
+	}
+
+	total, skipped := weights.cum[n-1], 0.0
+	if skip >= 0 {
+		skipped = weights.weight(skip)
+	}
+	if total-skipped <= 0 {
+		i := rand.IntN(candidates)
+		if skip >= 0 && i >= skip {
+			i++
+		}
+		return weights.ids[i]
+	}
+
+	r := rand.Float64() * (total - skipped)
+	if skip >= 0 && r >= weights.cum[skip]-skipped {
+		r += skipped
+	}
+	i := sort.Search(n, func(i int) bool { return weights.cum[i] > r })
+	// Rounding can put r at the very end; step back to the last candidate
+	// with any weight.
+	for i == n || i == skip || weights.weight(i) <= 0 {
+		i--
+	}
+	return weights.ids[i]
+}
+
 func selectFarthestNode(nodeIDs []string, graph *entities.MapGraph, fromNode string, allowSame bool) string {
 	if len(nodeIDs) == 0 {
 		return ""
@@ -227,7 +326,7 @@ func getFirstTargetNode(route *entities.Route, graph *entities.MapGraph) string
 
 	firstEdge := graph.Edges[route.Edges[0]]
 	if firstEdge != nil {
-		return firstEdge.To
+		return firstEdge.OtherEnd(route.StartNode)
 	}
 
 	return route.EndNode
@@ -0,0 +1,432 @@
+package simulationengine
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/m/internal/simulation/entities"
+)
+
+var ErrInvalidVRP = errors.New("invalid VRP problem")
+
+// VRPStop is a delivery to plan. Windows are in seconds from the start of
+// the tours; arriving early means waiting for Earliest, arriving after
+// Latest is not allowed.
+type VRPStop struct {
+	ID          string              `json:"id"`
+	NodeID      string              `json:"node_id"`
+	Demand      float64             `json:"demand"`
+	ServiceTime float64             `json:"service_time"`
+	Window      entities.TimeWindow `json:"window"`
+}
+
+// VRPVehicle starts its tour at Depot. A zero Capacity is unlimited.
+type VRPVehicle struct {
+	ID       string  `json:"id"`
+	Capacity float64 `json:"capacity"`
+	Depot    string  `json:"depot"`
+}
+
+type VRPProblem struct {
+	Stops    []VRPStop    `json:"stops"`
+	Vehicles []VRPVehicle `json:"vehicles"`
+	// ReturnToDepot ends every non-empty tour back at its depot.
+	ReturnToDepot bool `json:"return_to_depot"`
+	// MaxIterations bounds the local search passes; 0 means 100.
+	MaxIterations int `json:"max_iterations"`
+}
+
+// Tour is one vehicle's plan. Nodes runs from the depot through every stop
+// (and back when the problem asks for it); Arrivals and Service hold the
+// service start and service time of each stop.
+type Tour struct {
+	VehicleID string    `json:"vehicle_id"`
+	Depot     string    `json:"depot"`
+	Stops     []string  `json:"stops"`
+	Nodes     []string  `json:"nodes"`
+	Arrivals  []float64 `json:"arrivals"`
+	Service   []float64 `json:"service"`
+	Load      float64   `json:"load"`
+	Duration  float64   `json:"duration"`
+}
+
+type VRPSolution struct {
+	Tours []Tour `json:"tours"`
+	// Unassigned lists the stops no vehicle can serve within its capacity
+	// and time window.
+	Unassigned    []string `json:"unassigned"`
+	TotalDuration float64  `json:"total_duration"`
+}
+
+type vrpSolver struct {
+	p        VRPProblem
+	matrix   [][]float64
+	stopNode []int
+	depot    []int
+}
+
+// SolveVRP plans tours by cheapest insertion followed by 2-opt, relocate
+// and swap moves, minimising the sum of tour durations. Travel times are
+// free-flow times over the road graph.
+func SolveVRP(g *entities.MapGraph, p VRPProblem) (VRPSolution, error) {
+	if len(p.Vehicles) == 0 {
+		return VRPSolution{}, fmt.Errorf("%w: no vehicles", ErrInvalidVRP)
+	}
+
+	index := make(map[string]int)
+	var nodes []string
+	addNode := func(id string) (int, error) {
+		if _, ok := g.Nodes[id]; !ok {
+			return 0, fmt.Errorf("%w: node %q not found", ErrInvalidVRP, id)
+		}
+		i, ok := index[id]
+		if !ok {
+			i = len(nodes)
+			index[id] = i
+			nodes = append(nodes, id)
+		}
+		return i, nil
+	}
+
+	v := &vrpSolver{p: p}
+	seen := make(map[string]bool)
+	for _, veh := range p.Vehicles {
+		i, err := addNode(veh.Depot)
+		if err != nil {
+			return VRPSolution{}, err
+		}
+		v.depot = append(v.depot, i)
+	}
+	for _, stop := range p.Stops {
+		if stop.ID == "" || seen[stop.ID] {
+			return VRPSolution{}, fmt.Errorf("%w: stop IDs must be unique and non-empty", ErrInvalidVRP)
+		}
+		seen[stop.ID] = true
+		if w := stop.Window; w.Latest > 0 && w.Latest < w.Earliest {
+			return VRPSolution{}, fmt.Errorf("%w: bad time window for stop %s", ErrInvalidVRP, stop.ID)
+		}
+		i, err := addNode(stop.NodeID)
+		if err != nil {
+			return VRPSolution{}, err
+		}
+		v.stopNode = append(v.stopNode, i)
+	}
+
+	v.matrix = make([][]float64, len(nodes))
+	for i, from := range nodes {
+		times := travelTimes(g, from)
+		v.matrix[i] = make([]float64, len(nodes))
+		for j, to := range nodes {
+			t, ok := times[to]
+			if !ok {
+				t = math.Inf(1)
+			}
+			v.matrix[i][j] = t
+		}
+	}
+
+	tours, unassigned := v.construct()
+	v.improve(tours)
+	// Moves may have freed room for stops that did not fit before.
+	unassigned = v.insertAll(tours, unassigned)
+
+	return v.solution(nodes, tours, unassigned), nil
+}
+
+// cost is the duration of vehicle k's tour, or false when it breaks a
+// capacity or time window.
+func (v *vrpSolver) cost(k int, tour []int) (float64, bool) {
+	if len(tour) == 0 {
+		return 0, true
+	}
+
+	var t, load float64
+	at := v.depot[k]
+	for _, s := range tour {
+		stop := v.p.Stops[s]
+		t = math.Max(t+v.matrix[at][v.stopNode[s]], stop.Window.Earliest)
+		if math.IsInf(t, 1) || (stop.Window.Latest > 0 && t > stop.Window.Latest) {
+			return 0, false
+		}
+		t += stop.ServiceTime
+		load += stop.Demand
+		at = v.stopNode[s]
+	}
+	if capacity := v.p.Vehicles[k].Capacity; capacity > 0 && load > capacity {
+		return 0, false
+	}
+	if v.p.ReturnToDepot {
+		t += v.matrix[at][v.depot[k]]
+		if math.IsInf(t, 1) {
+			return 0, false
+		}
+	}
+	return t, true
+}
+
+// construct inserts stops, tightest deadline first, where they add the
+// least duration.
+func (v *vrpSolver) construct() ([][]int, []int) {
+	order := make([]int, len(v.p.Stops))
+	for i := range order {
+		order[i] = i
+	}
+	deadline := func(s int) float64 {
+		if l := v.p.Stops[s].Window.Latest; l > 0 {
+			return l
+		}
+		return math.Inf(1)
+	}
+	sort.SliceStable(order, func(a, b int) bool { return deadline(order[a]) < deadline(order[b]) })
+
+	tours := make([][]int, len(v.p.Vehicles))
+	return tours, v.insertAll(tours, order)
+}
+
+// insertAll adds each stop at its cheapest feasible position and returns
+// those that fit nowhere.
+func (v *vrpSolver) insertAll(tours [][]int, stops []int) []int {
+	var left []int
+	for _, s := range stops {
+		bestK, bestPos, bestDelta := -1, 0, math.Inf(1)
+		for k, tour := range tours {
+			base, _ := v.cost(k, tour)
+			for pos := 0; pos <= len(tour); pos++ {
+				c, ok := v.cost(k, insertAt(tour, pos, s))
+				if ok && c-base < bestDelta {
+					bestK, bestPos, bestDelta = k, pos, c-base
+				}
+			}
+		}
+		if bestK < 0 {
+			left = append(left, s)
+			continue
+		}
+		tours[bestK] = insertAt(tours[bestK], bestPos, s)
+	}
+	return left
+}
+
+// improve applies improving 2-opt, relocate and swap moves until none is
+// left or the iteration limit is reached.
+func (v *vrpSolver) improve(tours [][]int) {
+	limit := v.p.MaxIterations
+	if limit <= 0 {
+		limit = 100
+	}
+	for i := 0; i < limit; i++ {
+		if !v.twoOpt(tours) && !v.relocate(tours) && !v.swap(tours) {
+			return
+		}
+	}
+}
+
+const vrpEpsilon = 1e-9
+
+func (v *vrpSolver) twoOpt(tours [][]int) bool {
+	improved := false
+	for k, tour := range tours {
+		base, _ := v.cost(k, tour)
+		for i := 0; i < len(tour)-1; i++ {
+			for j := i + 1; j < len(tour); j++ {
+				next := append([]int(nil), tour...)
+				for a, b := i, j; a < b; a, b = a+1, b-1 {
+					next[a], next[b] = next[b], next[a]
+				}
+				if c, ok := v.cost(k, next); ok && c < base-vrpEpsilon {
+					tour, base, improved = next, c, true
+					tours[k] = next
+				}
+			}
+		}
+	}
+	return improved
+}
+
+func (v *vrpSolver) relocate(tours [][]int) bool {
+	for a := range tours {
+		for i := range tours[a] {
+			s := tours[a][i]
+			from := removeAt(tours[a], i)
+			costA, _ := v.cost(a, tours[a])
+			fromCost, ok := v.cost(a, from)
+			if !ok {
+				continue
+			}
+			for b := range tours {
+				target := tours[b]
+				if b == a {
+					target = from
+				}
+				costB, _ := v.cost(b, tours[b])
+				for j := 0; j <= len(target); j++ {
+					if b == a && j == i {
+						continue
+					}
+					to := insertAt(target, j, s)
+					c, ok := v.cost(b, to)
+					if !ok {
+						continue
+					}
+					before, after := costA+costB, fromCost+c
+					if b == a {
+						before, after = costA, c
+					}
+					if after < before-vrpEpsilon {
+						tours[a] = from
+						tours[b] = to
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+func (v *vrpSolver) swap(tours [][]int) bool {
+	for a := range tours {
+		for b := a; b < len(tours); b++ {
+			for i := range tours[a] {
+				for j := range tours[b] {
+					if a == b && j <= i {
+						continue
+					}
+					nextA := append([]int(nil), tours[a]...)
+					nextB := nextA
+					if b != a {
+						nextB = append([]int(nil), tours[b]...)
+					}
+					nextA[i], nextB[j] = tours[b][j], tours[a][i]
+
+					costA, _ := v.cost(a, tours[a])
+					costB, _ := v.cost(b, tours[b])
+					ca, okA := v.cost(a, nextA)
+					cb, okB := v.cost(b, nextB)
+					before, after := costA+costB, ca+cb
+					if a == b {
+						before, after = costA, ca
+					}
+					if okA && okB && after < before-vrpEpsilon {
+						tours[a], tours[b] = nextA, nextB
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+func (v *vrpSolver) solution(nodes []string, tours [][]int, unassigned []int) VRPSolution {
+	var sol VRPSolution
+	for k, tour := range tours {
+		veh := v.p.Vehicles[k]
+		out := Tour{VehicleID: veh.ID, Depot: veh.Depot, Stops: []string{}, Nodes: []string{veh.Depot}, Arrivals: []float64{}, Service: []float64{}}
+
+		var t float64
+		at := v.depot[k]
+		for _, s := range tour {
+			stop := v.p.Stops[s]
+			t = math.Max(t+v.matrix[at][v.stopNode[s]], stop.Window.Earliest)
+			out.Stops = append(out.Stops, stop.ID)
+			out.Nodes = append(out.Nodes, stop.NodeID)
+			out.Arrivals = append(out.Arrivals, t)
+			out.Service = append(out.Service, stop.ServiceTime)
+			out.Load += stop.Demand
+			t += stop.ServiceTime
+			at = v.stopNode[s]
+		}
+		if v.p.ReturnToDepot && len(tour) > 0 {
+			out.Nodes = append(out.Nodes, veh.Depot)
+		}
+		out.Duration, _ = v.cost(k, tour)
+		sol.TotalDuration += out.Duration
+		sol.Tours = append(sol.Tours, out)
+	}
+
+	sol.Unassigned = []string{}
+	for _, s := range unassigned {
+		sol.Unassigned = append(sol.Unassigned, v.p.Stops[s].ID)
+	}
+	sort.Strings(sol.Unassigned)
+	return sol
+}
+
+func insertAt(tour []int, pos, s int) []int {
+	out := make([]int, 0, len(tour)+1)
+	out = append(out, tour[:pos]...)
+	out = append(out, s)
+	return append(out, tour[pos:]...)
+}
+
+func removeAt(tour []int, pos int) []int {
+	out := make([]int, 0, len(tour)-1)
+	out = append(out, tour[:pos]...)
+	return append(out, tour[pos+1:]...)
+}
+
+// PlanTours solves the problem on the engine's graph. Vehicles without a
+// depot start from where they are now.
+func (s *SimulationEngine) PlanTours(p VRPProblem) (VRPSolution, error) {
+	p.Vehicles = append([]VRPVehicle(nil), p.Vehicles...)
+	for i, veh := range p.Vehicles {
+		if veh.Depot != "" {
+			continue
+		}
+		vehicle, ok := s.GetVehicle(veh.ID)
+		if !ok {
+			return VRPSolution{}, fmt.Errorf("%w: %s", ErrVehicleNotFound, veh.ID)
+		}
+		vehicle.Mutex.Lock()
+		p.Vehicles[i].Depot = currentNode(vehicle)
+		vehicle.Mutex.Unlock()
+	}
+	return SolveVRP(s.Graph, p)
+}
+
+// ExecuteTours sends each vehicle along its tour as a multi-leg route that
+// dwells for the service time at every stop. A vehicle that is not at its
+// depot drives there first; one in the middle of an edge finishes that edge.
+func (s *SimulationEngine) ExecuteTours(sol VRPSolution) error {
+	vehicles := make([]*entities.Vehicle, len(sol.Tours))
+	for i, tour := range sol.Tours {
+		vehicle, ok := s.GetVehicle(tour.VehicleID)
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrVehicleNotFound, tour.VehicleID)
+		}
+		vehicles[i] = vehicle
+	}
+
+	for i, tour := range sol.Tours {
+		if len(tour.Stops) == 0 {
+			continue
+		}
+		vehicle := vehicles[i]
+
+		vehicle.Mutex.Lock()
+		var waypoints []entities.Waypoint
+		if currentNode(vehicle) != tour.Depot {
+			waypoints = append(waypoints, entities.Waypoint{NodeID: tour.Depot})
+		}
+		for j, node := range tour.Nodes[1:] {
+			wp := entities.Waypoint{NodeID: node}
+			if j < len(tour.Service) {
+				wp.Dwell = tour.Service[j]
+			}
+			waypoints = append(waypoints, wp)
+		}
+		err := RerouteVehicleVia(vehicle, s.Graph, waypoints)
+		vehicle.Mutex.Unlock()
+		if err != nil {
+			return fmt.Errorf("vehicle %s: %w", tour.VehicleID, err)
+		}
+
+		if !s.wakeVehicle(vehicle) {
+			s.emitVehicleEvent(vehicle, entities.EventRouteStarted, entities.SeverityInfo, nil)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package simulationengine
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/m/internal/simulation/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lineGraph builds N0 - N1 - ... with 100m edges at 10 m/s.
+func lineGraph(n int) *entities.MapGraph {
+	g := &entities.MapGraph{Nodes: map[string]*entities.MapNode{}, Edges: map[string]*entities.MapEdge{}}
+	for i := 0; i < n; i++ {
+		id := fmt.Sprintf("N%d", i)
+		g.Nodes[id] = &entities.MapNode{ID: id, Position: entities.Vector2D{X: float64(i) * 100}, Connections: map[string]bool{}}
+		if i > 0 {
+			prev := fmt.Sprintf("N%d", i-1)
+			edge := prev + "-" + id
+			g.Edges[edge] = &entities.MapEdge{ID: edge, From: prev, To: id, Length: 100, Bidirectional: true,
+				Conditions: &entities.RoadConditions{EffectiveSpeedLimit: 10}}
+			g.Nodes[prev].Connections[id] = true
+			g.Nodes[id].Connections[prev] = true
+		}
+	}
+	return g
+}
+
+func TestSolveVRP_SingleVehicle(t *testing.T) {
+	g := lineGraph(10)
+	sol, err := SolveVRP(g, VRPProblem{
+		Vehicles: []VRPVehicle{{ID: "v1", Depot: "N0"}},
+		Stops: []VRPStop{
+			{ID: "s7", NodeID: "N7"},
+			{ID: "s1", NodeID: "N1", ServiceTime: 5},
+			{ID: "s5", NodeID: "N5"},
+			{ID: "s3", NodeID: "N3"},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, sol.Tours, 1)
+	tour := sol.Tours[0]
+	assert.Equal(t, []string{"s1", "s3", "s5", "s7"}, tour.Stops)
+	assert.Equal(t, []string{"N0", "N1", "N3", "N5", "N7"}, tour.Nodes)
+	assert.InDeltaSlice(t, []float64{10, 35, 55, 75}, tour.Arrivals, 1e-9)
+	assert.InDelta(t, 75.0, tour.Duration, 1e-9)
+	assert.Empty(t, sol.Unassigned)
+
+	_, err = SolveVRP(g, VRPProblem{Vehicles: []VRPVehicle{{ID: "v1", Depot: "X"}}})
+	assert.ErrorIs(t, err, ErrInvalidVRP)
+}
+
+func TestSolveVRP_CapacityAndWindows(t *testing.T) {
+	g := lineGraph(10)
+	sol, err := SolveVRP(g, VRPProblem{
+		Vehicles: []VRPVehicle{{ID: "v1", Depot: "N0", Capacity: 2}, {ID: "v2", Depot: "N9", Capacity: 2}},
+		Stops: []VRPStop{
+			{ID: "a", NodeID: "N1", Demand: 1},
+			{ID: "b", NodeID: "N2", Demand: 1},
+			{ID: "c", NodeID: "N8", Demand: 1, Window: entities.TimeWindow{Earliest: 100}},
+			{ID: "d", NodeID: "N7", Demand: 1},
+			{ID: "late", NodeID: "N5", Demand: 0, Window: entities.TimeWindow{Latest: 10}},
+		},
+		ReturnToDepot: true,
+	})
+	require.NoError(t, err)
+	require.Len(t, sol.Tours, 2)
+	assert.Equal(t, []string{"late"}, sol.Unassigned)
+
+	for _, tour := range sol.Tours {
+		assert.LessOrEqual(t, tour.Load, 2.0)
+		assert.Equal(t, tour.Depot, tour.Nodes[len(tour.Nodes)-1])
+	}
+	assert.ElementsMatch(t, []string{"a", "b"}, sol.Tours[0].Stops)
+	assert.ElementsMatch(t, []string{"c", "d"}, sol.Tours[1].Stops)
+	for i, id := range sol.Tours[1].Stops {
+		if id == "c" {
+			assert.GreaterOrEqual(t, sol.Tours[1].Arrivals[i], 100.0)
+		}
+	}
+}
+
+func TestVRPSolver_LocalSearch(t *testing.T) {
+	p := VRPProblem{Vehicles: []VRPVehicle{{ID: "v1"}, {ID: "v2"}}}
+	for range 5 {
+		p.Stops = append(p.Stops, VRPStop{})
+	}
+	v := &vrpSolver{p: p, depot: []int{0, 9}, stopNode: []int{7, 1, 5, 3, 8}}
+	v.matrix = make([][]float64, 10)
+	for i := range v.matrix {
+		v.matrix[i] = make([]float64, 10)
+		for j := range v.matrix[i] {
+			v.matrix[i][j] = math.Abs(float64(i-j)) * 10
+		}
+	}
+
+	// The zig-zag tour is untangled into a single sweep; handing N8 to the
+	// second vehicle would not shorten the total.
+	tours := [][]int{{0, 1, 4, 2, 3}, {}}
+	v.improve(tours)
+	assert.Equal(t, [][]int{{1, 3, 2, 0, 4}, {}}, tours)
+
+	c0, _ := v.cost(0, tours[0])
+	c1, _ := v.cost(1, tours[1])
+	assert.InDelta(t, 80.0, c0+c1, 1e-9)
+}
+
+func TestExecuteTours(t *testing.T) {
+	engine := NewSimulationEngine(lineGraph(10), time.Hour)
+	v := &entities.Vehicle{ID: "v1"}
+	require.NoError(t, AssignVehicleRouteWithNodes(v, engine.Graph, "N2", "N0"))
+	engine.AddVehicle(v)
+	stepUntil(t, engine, func() bool { return v.Route.CompletedAt != nil })
+
+	sol, err := engine.PlanTours(VRPProblem{
+		Vehicles: []VRPVehicle{{ID: "v1"}},
+		Stops:    []VRPStop{{ID: "x", NodeID: "N6"}, {ID: "y", NodeID: "N3"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "N0", sol.Tours[0].Depot)
+	require.NoError(t, engine.ExecuteTours(sol))
+	assert.Equal(t, entities.VehicleStatusMoving, v.State.Status)
+	assert.Equal(t, "N6", v.Route.EndNode)
+	assert.Len(t, v.Route.Edges, 6)
+
+	stepUntil(t, engine, func() bool { return v.Route.CompletedAt != nil })
+	assert.Equal(t, engine.Graph.Nodes["N6"].Position, v.State.CurrentPosition)
+
+	assert.ErrorIs(t, engine.ExecuteTours(VRPSolution{Tours: []Tour{{VehicleID: "nope"}}}), ErrVehicleNotFound)
+}
@@ -0,0 +1,260 @@
+package simulationengine
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/m/internal/simulation/entities"
+)
+
+// AssignVehicleRouteWithWaypoints places the vehicle at startNode and routes
+// it through the waypoints in order, ending at the last one.
+func AssignVehicleRouteWithWaypoints(vehicle *entities.Vehicle, graph *entities.MapGraph, startNode string, waypoints []entities.Waypoint) error {
+	return assignWaypoints(vehicle, graph, startNode, false, waypoints)
+}
+
+// RerouteVehicleVia replaces the vehicle's route with one through the
+// waypoints, starting from where it is now. A vehicle in the middle of an
+// edge finishes that edge first.
+func RerouteVehicleVia(vehicle *entities.Vehicle, graph *entities.MapGraph, waypoints []entities.Waypoint) error {
+	r := vehicle.Route
+	if r == nil {
+		return fmt.Errorf("vehicle %s has no position on the graph", vehicle.ID)
+	}
+	midEdge := r.CompletedAt == nil && vehicle.State.ProgressOnEdge > 0 && vehicle.State.CurrentEdge != ""
+	return assignWaypoints(vehicle, graph, currentNode(vehicle), midEdge, waypoints)
+}
+
+func assignWaypoints(vehicle *entities.Vehicle, graph *entities.MapGraph, from string, midEdge bool, waypoints []entities.Waypoint) error {
+	if len(waypoints) == 0 {
+		return fmt.Errorf("route needs at least one waypoint")
+	}
+	if _, exists := graph.Nodes[from]; !exists {
+		return fmt.Errorf("start node %s not found in graph", from)
+	}
+	nodes := []string{from}
+	for _, wp := range waypoints {
+		if _, exists := graph.Nodes[wp.NodeID]; !exists {
+			return fmt.Errorf("waypoint %s not found in graph", wp.NodeID)
+		}
+		nodes = append(nodes, wp.NodeID)
+	}
+
+	inEdge := ""
+	if midEdge {
+		inEdge = vehicle.State.CurrentEdge
+	}
+	route, ends, err := routeThrough(graph, inEdge, nodes)
+	if err != nil {
+		return err
+	}
+
+	legs := make([]entities.RouteLeg, len(waypoints))
+	for i, wp := range waypoints {
+		legs[i] = entities.RouteLeg{Waypoint: wp, EdgeEnd: ends[i+1]}
+	}
+
+	now := time.Now()
+	if midEdge {
+		r := vehicle.Route
+		for i := range legs {
+			legs[i].EdgeEnd++
+		}
+		vehicle.Route = &entities.AssignedRoute{
+			Edges:       append([]string{vehicle.State.CurrentEdge}, route.Edges...),
+			CurrentNode: r.CurrentNode,
+			TargetNode:  r.TargetNode,
+			StartNode:   r.CurrentNode,
+			EndNode:     route.EndNode,
+			StartedAt:   now,
+			Legs:        legs,
+		}
+		return nil
+	}
+
+	vehicle.Route = &entities.AssignedRoute{
+		Edges:       route.Edges,
+		CurrentNode: from,
+		TargetNode:  getFirstTargetNode(route, graph),
+		StartNode:   from,
+		EndNode:     route.EndNode,
+		StartedAt:   now,
+		Legs:        legs,
+	}
+	vehicle.State.CurrentPosition = graph.Nodes[from].Position
+	vehicle.State.Velocity = entities.Vector2D{}
+	vehicle.State.ProgressOnEdge = 0
+	vehicle.State.LastUpdateTime = now
+	vehicle.State.CurrentEdge = ""
+	vehicle.State.Status = entities.VehicleStatusIdle
+	if len(route.Edges) > 0 {
+		vehicle.State.CurrentEdge = route.Edges[0]
+		vehicle.State.Status = entities.VehicleStatusMoving
+	}
+	return nil
+}
+
+// routeThrough joins the shortest paths between consecutive nodes into one
+// route, entering nodes[0] over inEdge. ends[i] is the number of edges driven
+// on reaching nodes[i].
+func routeThrough(g *entities.MapGraph, inEdge string, nodes []string) (*entities.Route, []int, error) {
+	if len(nodes) == 0 {
+		return nil, nil, fmt.Errorf("empty route")
+	}
+
+	route := &entities.Route{StartNode: nodes[0], EndNode: nodes[len(nodes)-1], Edges: []string{}}
+	ends := make([]int, len(nodes))
+	for i := 1; i < len(nodes); i++ {
+		if nodes[i] != nodes[i-1] {
+			legs := DijkstraFrom(g, inEdge, nodes[i-1], nodes[i])
+			if len(legs) == 0 {
+				return nil, nil, fmt.Errorf("no route found from %s to %s", nodes[i-1], nodes[i])
+			}
+			route.Edges = append(route.Edges, legs[0].Edges...)
+			route.TotalDistance += legs[0].TotalDistance
+			if n := len(route.Edges); n > 0 {
+				inEdge = route.Edges[n-1]
+			}
+		}
+		ends[i] = len(route.Edges)
+	}
+	return route, ends, nil
+}
+
+// currentNode is the node a vehicle would start a new route from. Expects
+// vehicle.Mutex to be held.
+func currentNode(vehicle *entities.Vehicle) string {
+	r := vehicle.Route
+	switch {
+	case r == nil:
+		return ""
+	case r.CompletedAt != nil:
+		return r.EndNode
+	case vehicle.State.ProgressOnEdge > 0:
+		return r.TargetNode
+	default:
+		return r.CurrentNode
+	}
+}
+
+// RouteVehicleVia sends an existing vehicle through the waypoints from
+// wherever it is now, restarting its goroutine if it had already arrived.
+func (s *SimulationEngine) RouteVehicleVia(id string, waypoints []entities.Waypoint) error {
+	vehicle, ok := s.GetVehicle(id)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrVehicleNotFound, id)
+	}
+
+	vehicle.Mutex.Lock()
+	err := RerouteVehicleVia(vehicle, s.Graph, waypoints)
+	vehicle.Mutex.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if !s.wakeVehicle(vehicle) {
+		s.emitVehicleEvent(vehicle, entities.EventRouteStarted, entities.SeverityInfo, nil)
+	}
+	return nil
+}
+
+// updateWaypoints runs the dwell clock before the vehicle moves. It returns
+// the part of dt left for driving, which is zero while the vehicle dwells.
+// Expects vehicle.Mutex to be held.
+func (s *SimulationEngine) updateWaypoints(vehicle *entities.Vehicle, dt float64) (float64, []pendingEvent) {
+	r := vehicle.Route
+	if r == nil || r.CurrentLeg >= len(r.Legs) {
+		return dt, nil
+	}
+
+	var events []pendingEvent
+	for {
+		if r.DwellRemaining > 0 {
+			leg := &r.Legs[r.CurrentLeg]
+			used := min(dt, r.DwellRemaining)
+			r.DwellRemaining -= used
+			leg.DwellTime += used
+			dt -= used
+			if r.DwellRemaining > 0 {
+				return 0, events
+			}
+			events = append(events, departWaypoint(vehicle))
+		}
+		// A waypoint reached without moving, such as the start of the
+		// route, begins its dwell within this step.
+		events = append(events, s.arriveAtWaypoints(vehicle)...)
+		if r.DwellRemaining <= 0 {
+			break
+		}
+	}
+
+	if r.CurrentLeg < len(r.Legs) && r.CompletedAt == nil {
+		r.Legs[r.CurrentLeg].TravelTime += dt
+	}
+	return dt, events
+}
+
+// arriveAtWaypoints handles every leg the vehicle has just finished, stopping
+// at the first one with a dwell time. Expects vehicle.Mutex to be held.
+func (s *SimulationEngine) arriveAtWaypoints(vehicle *entities.Vehicle) []pendingEvent {
+	r := vehicle.Route
+	if r == nil {
+		return nil
+	}
+
+	var events []pendingEvent
+	for r.DwellRemaining <= 0 && r.CurrentLeg < len(r.Legs) {
+		leg := &r.Legs[r.CurrentLeg]
+		atNode := r.CompletedAt != nil || vehicle.State.ProgressOnEdge == 0
+		if r.CurrentEdgeIndex < leg.EdgeEnd || !atNode {
+			break
+		}
+
+		now := time.Now()
+		leg.ArrivedAt = &now
+		events = append(events, pendingEvent{entities.EventWaypointArrived, entities.SeverityInfo, legData(r, r.CurrentLeg)})
+		dwell := leg.Dwell
+		if vehicle.Transit != nil {
+			hold, adherence := s.transitArrival(vehicle, r.CurrentLeg)
+			dwell = max(dwell, hold)
+			events = append(events, adherence...)
+		}
+		if dwell > 0 {
+			r.DwellRemaining = dwell
+			vehicle.State.Status = entities.VehicleStatusStopped
+			vehicle.State.Velocity = entities.Vector2D{}
+			break
+		}
+		events = append(events, departWaypoint(vehicle))
+	}
+	return events
+}
+
+func departWaypoint(vehicle *entities.Vehicle) pendingEvent {
+	r := vehicle.Route
+	now := time.Now()
+	r.Legs[r.CurrentLeg].DepartedAt = &now
+	ev := pendingEvent{entities.EventWaypointDeparted, entities.SeverityInfo, legData(r, r.CurrentLeg)}
+	r.DwellRemaining = 0
+	r.CurrentLeg++
+
+	if vehicle.State.Status == entities.VehicleStatusStopped {
+		vehicle.State.Status = entities.VehicleStatusMoving
+		if r.CompletedAt != nil {
+			vehicle.State.Status = entities.VehicleStatusArrived
+		}
+	}
+	return ev
+}
+
+func legData(r *entities.AssignedRoute, i int) map[string]interface{} {
+	leg := r.Legs[i]
+	return map[string]interface{}{
+		"node_id":     leg.NodeID,
+		"leg":         i,
+		"legs":        len(r.Legs),
+		"dwell":       leg.Dwell,
+		"travel_time": leg.TravelTime,
+		"dwell_time":  leg.DwellTime,
+	}
+}
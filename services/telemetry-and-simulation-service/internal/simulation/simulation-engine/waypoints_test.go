@@ -0,0 +1,97 @@
+package simulationengine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/m/internal/simulation/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func waypointTestEngine(t *testing.T) (*SimulationEngine, *TelemetryEmitterImpl) {
+	engine := NewSimulationEngine(lineGraph(10), time.Hour)
+	emitter := captureTelemetry(engine)
+	return engine, emitter
+}
+
+func TestWaypoints_DwellAndLegTiming(t *testing.T) {
+	engine, emitter := waypointTestEngine(t)
+	v := &entities.Vehicle{ID: "v1"}
+	require.NoError(t, AssignVehicleRouteWithWaypoints(v, engine.Graph, "N0", []entities.Waypoint{
+		{NodeID: "N2", Dwell: 15},
+		{NodeID: "N4"},
+		{NodeID: "N5", Dwell: 10},
+	}))
+	engine.AddVehicle(v)
+	require.Len(t, v.Route.Edges, 5)
+	assert.Equal(t, "N5", v.Route.EndNode)
+
+	require.NoError(t, engine.Step(10*time.Second))
+	require.NoError(t, engine.Step(10*time.Second))
+	assert.Equal(t, entities.VehicleStatusStopped, v.State.Status)
+	assert.Equal(t, engine.Graph.Nodes["N2"].Position, v.State.CurrentPosition)
+	assert.Equal(t, []entities.EventType{entities.EventWaypointArrived}, drainEvents(emitter))
+
+	// Still dwelling after 10s; the last 5s of the dwell are taken out of
+	// the next step.
+	require.NoError(t, engine.Step(10*time.Second))
+	assert.Equal(t, engine.Graph.Nodes["N2"].Position, v.State.CurrentPosition)
+	require.NoError(t, engine.Step(10*time.Second))
+	assert.Equal(t, entities.VehicleStatusMoving, v.State.Status)
+	assert.InDelta(t, 0.5, v.State.ProgressOnEdge, 1e-9)
+	assert.Equal(t, []entities.EventType{entities.EventWaypointDeparted}, drainEvents(emitter))
+
+	leg := v.Route.Legs[0]
+	assert.Equal(t, 20.0, leg.TravelTime)
+	assert.Equal(t, 15.0, leg.DwellTime)
+	assert.NotNil(t, leg.DepartedAt)
+
+	stepUntil(t, engine, func() bool { return v.Route.CompletedAt != nil })
+	assert.Equal(t, entities.VehicleStatusStopped, v.State.Status)
+	assert.Equal(t, []entities.EventType{
+		entities.EventWaypointArrived, entities.EventWaypointDeparted,
+		entities.EventRouteCompleted, entities.EventWaypointArrived,
+	}, drainEvents(emitter))
+	assert.Equal(t, 25.0, v.Route.Legs[1].TravelTime)
+	assert.Zero(t, v.Route.Legs[1].DwellTime)
+
+	require.NoError(t, engine.Step(10*time.Second))
+	assert.Equal(t, entities.VehicleStatusArrived, v.State.Status)
+	assert.Equal(t, 3, v.Route.CurrentLeg)
+	assert.Equal(t, 10.0, v.Route.Legs[2].DwellTime)
+	assert.Equal(t, []entities.EventType{entities.EventWaypointDeparted}, drainEvents(emitter))
+}
+
+func TestWaypoints_RerouteMidEdge(t *testing.T) {
+	engine, emitter := waypointTestEngine(t)
+	v := &entities.Vehicle{ID: "v1"}
+	require.NoError(t, AssignVehicleRouteWithNodes(v, engine.Graph, "N0", "N9"))
+	engine.AddVehicle(v)
+	require.NoError(t, engine.Step(5*time.Second))
+
+	require.NoError(t, engine.RouteVehicleVia("v1", []entities.Waypoint{{NodeID: "N1"}, {NodeID: "N1", Dwell: 5}, {NodeID: "N3"}}))
+	assert.Equal(t, []string{"N0-N1", "N1-N2", "N2-N3"}, v.Route.Edges)
+	assert.Equal(t, []int{1, 1, 3}, []int{v.Route.Legs[0].EdgeEnd, v.Route.Legs[1].EdgeEnd, v.Route.Legs[2].EdgeEnd})
+	drainEvents(emitter)
+
+	require.NoError(t, engine.Step(5*time.Second))
+	assert.Equal(t, entities.VehicleStatusStopped, v.State.Status)
+	assert.Equal(t, 1, v.Route.CurrentLeg)
+
+	stepUntil(t, engine, func() bool { return v.Route.CurrentLeg == 3 })
+	assert.Equal(t, []entities.EventType{
+		entities.EventWaypointArrived, entities.EventWaypointDeparted, entities.EventWaypointArrived,
+		entities.EventWaypointDeparted, entities.EventRouteCompleted, entities.EventWaypointArrived,
+		entities.EventWaypointDeparted,
+	}, drainEvents(emitter))
+
+	// A plain reroute drops the remaining waypoints.
+	require.NoError(t, engine.RouteVehicleVia("v1", []entities.Waypoint{{NodeID: "N5", Dwell: 30}, {NodeID: "N6"}}))
+	require.NoError(t, engine.Step(5*time.Second))
+	require.NoError(t, RerouteVehicle(v, engine.Graph, "N7"))
+	assert.Empty(t, v.Route.Legs)
+
+	assert.ErrorIs(t, engine.RouteVehicleVia("nope", nil), ErrVehicleNotFound)
+	assert.Error(t, engine.RouteVehicleVia("v1", []entities.Waypoint{{NodeID: "X"}}))
+}
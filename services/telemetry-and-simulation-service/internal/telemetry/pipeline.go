@@ -0,0 +1,390 @@
+package telemetry
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/m/internal/simulation/entities"
+)
+
+// BatchEmitter is implemented by sinks that can take a whole batch at once.
+// Sinks that only implement entities.TelemetryEmitter get the batch one
+// item at a time, events first.
+type BatchEmitter interface {
+	EmitBatch(positions []entities.BasicVehiclePosEvent, events []entities.VehicleEvent) error
+}
+
+type PipelineConfig struct {
+	// BatchSize flushes as soon as this many positions and events are pending.
+	BatchSize     int
+	FlushInterval time.Duration
+	// QueueSize bounds the number of vehicles with a pending position and
+	// EventQueueSize the number of pending non-critical events.
+	QueueSize      int
+	EventQueueSize int
+
+	// Sampling. A position is forwarded when any enabled criterion fires;
+	// with all of them zero every position is forwarded.
+	MinDistance            float64 // meters moved since the last forwarded position
+	MinHeadingChange       float64 // degrees
+	DeadReckoningTolerance float64 // meters between the extrapolated and actual position
+	// MaxSilence forces a position through after this much simulated time.
+	MaxSilence time.Duration
+}
+
+func DefaultPipelineConfig() PipelineConfig {
+	return PipelineConfig{
+		BatchSize:              500,
+		FlushInterval:          250 * time.Millisecond,
+		QueueSize:              20000,
+		EventQueueSize:         4096,
+		MinHeadingChange:       15,
+		DeadReckoningTolerance: 2,
+		MaxSilence:             5 * time.Second,
+	}
+}
+
+type PipelineStats struct {
+	PositionsReceived  uint64 `json:"positions_received"`
+	PositionsSampled   uint64 `json:"positions_sampled"`
+	PositionsCoalesced uint64 `json:"positions_coalesced"`
+	PositionsDropped   uint64 `json:"positions_dropped"`
+	EventsReceived     uint64 `json:"events_received"`
+	EventsDropped      uint64 `json:"events_dropped"`
+	Emitted            uint64 `json:"emitted"`
+	Batches            uint64 `json:"batches"`
+	SinkErrors         uint64 `json:"sink_errors"`
+	QueueDepth         int    `json:"queue_depth"`
+	EventQueueDepth    int    `json:"event_queue_depth"`
+}
+
+// vehicleTrack is what the sampler remembers per vehicle: the last position
+// it saw and the last one it forwarded, with the velocity used to
+// extrapolate from the latter.
+type vehicleTrack struct {
+	seenPos  entities.Vector2D
+	seenAt   time.Time
+	sentPos  entities.Vector2D
+	sentAt   time.Time
+	sentEdge string
+	velocity entities.Vector2D
+	heading  float64
+	moving   bool
+}
+
+// Pipeline sits between the engine and the real emitters. It samples
+// positions per vehicle, batches by size and time, bounds its queues and
+// counts everything it suppresses or drops. Critical events skip the queue
+// and are forwarded immediately.
+type Pipeline struct {
+	sink   entities.TelemetryEmitter
+	config PipelineConfig
+
+	mu        sync.Mutex
+	tracks    map[string]*vehicleTrack
+	positions []entities.BasicVehiclePosEvent
+	index     map[string]int
+	events    []entities.VehicleEvent
+
+	positionsReceived  atomic.Uint64
+	positionsSampled   atomic.Uint64
+	positionsCoalesced atomic.Uint64
+	positionsDropped   atomic.Uint64
+	eventsReceived     atomic.Uint64
+	eventsDropped      atomic.Uint64
+	emitted            atomic.Uint64
+	batches            atomic.Uint64
+	sinkErrors         atomic.Uint64
+
+	flushMu sync.Mutex
+	running atomic.Bool
+	kick    chan struct{}
+	stop    chan struct{}
+	wg      sync.WaitGroup
+	once    sync.Once
+}
+
+func NewPipeline(sink entities.TelemetryEmitter, config PipelineConfig) *Pipeline {
+	return &Pipeline{
+		sink:   sink,
+		config: config,
+		tracks: make(map[string]*vehicleTrack),
+		index:  make(map[string]int),
+		kick:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+	}
+}
+
+// Start runs the time-based flush loop. Without it the pipeline still
+// flushes on BatchSize and on Flush/Close.
+func (p *Pipeline) Start() {
+	if !p.running.CompareAndSwap(false, true) {
+		return
+	}
+	p.wg.Add(1)
+	go p.run()
+}
+
+func (p *Pipeline) Close() {
+	p.once.Do(func() {
+		p.running.Store(false)
+		close(p.stop)
+		p.wg.Wait()
+		p.Flush()
+	})
+}
+
+func (p *Pipeline) EmitPosition(event entities.BasicVehiclePosEvent) error {
+	p.positionsReceived.Add(1)
+
+	p.mu.Lock()
+	if !p.sample(event) {
+		p.mu.Unlock()
+		p.positionsSampled.Add(1)
+		return nil
+	}
+
+	if i, ok := p.index[event.VehicleID]; ok {
+		p.positions[i] = event
+		p.mu.Unlock()
+		p.positionsCoalesced.Add(1)
+		return nil
+	}
+	if p.config.QueueSize > 0 && len(p.positions) >= p.config.QueueSize {
+		p.mu.Unlock()
+		p.positionsDropped.Add(1)
+		return nil
+	}
+	p.index[event.VehicleID] = len(p.positions)
+	p.positions = append(p.positions, event)
+	full := p.full()
+	p.mu.Unlock()
+
+	if full {
+		p.wake()
+	}
+	return nil
+}
+
+func (p *Pipeline) EmitEvent(event entities.VehicleEvent) error {
+	p.eventsReceived.Add(1)
+
+	if event.Severity == entities.SeverityCritical {
+		err := p.sink.EmitEvent(event)
+		p.record(1, err)
+		return err
+	}
+
+	p.mu.Lock()
+	if p.config.EventQueueSize > 0 && len(p.events) >= p.config.EventQueueSize {
+		p.events = p.events[1:]
+		p.eventsDropped.Add(1)
+	}
+	p.events = append(p.events, event)
+	full := p.full()
+	p.mu.Unlock()
+
+	if full {
+		p.wake()
+	}
+	return nil
+}
+
+// Forget drops the sampler state of a vehicle, e.g. after it was removed.
+func (p *Pipeline) Forget(vehicleID string) {
+	p.mu.Lock()
+	delete(p.tracks, vehicleID)
+	p.mu.Unlock()
+}
+
+// Flush forwards everything pending to the sink. Events go before positions.
+func (p *Pipeline) Flush() error {
+	p.flushMu.Lock()
+	defer p.flushMu.Unlock()
+
+	p.mu.Lock()
+	positions, events := p.positions, p.events
+	p.positions, p.events = nil, nil
+	p.index = make(map[string]int, len(positions))
+	p.mu.Unlock()
+
+	if len(positions) == 0 && len(events) == 0 {
+		return nil
+	}
+	p.batches.Add(1)
+
+	if batcher, ok := p.sink.(BatchEmitter); ok {
+		err := batcher.EmitBatch(positions, events)
+		p.record(len(positions)+len(events), err)
+		return err
+	}
+
+	var firstErr error
+	for _, ev := range events {
+		err := p.sink.EmitEvent(ev)
+		p.record(1, err)
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	for _, pos := range positions {
+		err := p.sink.EmitPosition(pos)
+		p.record(1, err)
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (p *Pipeline) Stats() PipelineStats {
+	p.mu.Lock()
+	queued, queuedEvents := len(p.positions), len(p.events)
+	p.mu.Unlock()
+
+	return PipelineStats{
+		PositionsReceived:  p.positionsReceived.Load(),
+		PositionsSampled:   p.positionsSampled.Load(),
+		PositionsCoalesced: p.positionsCoalesced.Load(),
+		PositionsDropped:   p.positionsDropped.Load(),
+		EventsReceived:     p.eventsReceived.Load(),
+		EventsDropped:      p.eventsDropped.Load(),
+		Emitted:            p.emitted.Load(),
+		Batches:            p.batches.Load(),
+		SinkErrors:         p.sinkErrors.Load(),
+		QueueDepth:         queued,
+		EventQueueDepth:    queuedEvents,
+	}
+}
+
+func (p *Pipeline) run() {
+	defer p.wg.Done()
+
+	var tick <-chan time.Time
+	if p.config.FlushInterval > 0 {
+		ticker := time.NewTicker(p.config.FlushInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-tick:
+		case <-p.kick:
+		case <-p.stop:
+			return
+		}
+		p.Flush()
+	}
+}
+
+// wake hands a full batch to the flush loop, or flushes inline when the
+// loop is not running.
+func (p *Pipeline) wake() {
+	if !p.running.Load() {
+		p.Flush()
+		return
+	}
+	select {
+	case p.kick <- struct{}{}:
+	default:
+	}
+}
+
+// full expects p.mu to be held.
+func (p *Pipeline) full() bool {
+	return p.config.BatchSize > 0 && len(p.positions)+len(p.events) >= p.config.BatchSize
+}
+
+func (p *Pipeline) record(n int, err error) {
+	if err != nil {
+		p.sinkErrors.Add(1)
+		return
+	}
+	p.emitted.Add(uint64(n))
+}
+
+// sample decides whether a position is worth forwarding and updates the
+// vehicle's track. Expects p.mu to be held.
+func (p *Pipeline) sample(event entities.BasicVehiclePosEvent) bool {
+	track, ok := p.tracks[event.VehicleID]
+	if !ok {
+		track = &vehicleTrack{}
+		p.tracks[event.VehicleID] = track
+	}
+
+	var velocity entities.Vector2D
+	heading, moving := track.heading, false
+	if ok {
+		dx, dy := event.Position.X-track.seenPos.X, event.Position.Y-track.seenPos.Y
+		if dt := event.Timestamp.Sub(track.seenAt).Seconds(); dt > 0 {
+			velocity = entities.Vector2D{X: dx / dt, Y: dy / dt}
+		}
+		if math.Hypot(dx, dy) > 1e-9 {
+			heading, moving = math.Atan2(dy, dx), true
+		}
+	}
+	track.seenPos, track.seenAt = event.Position, event.Timestamp
+
+	// The last forwarded position had no direction yet (first sample or
+	// standing still); the first observed direction becomes the reference.
+	if moving && !track.moving {
+		track.heading, track.moving = heading, true
+	}
+
+	if !ok || p.shouldEmit(track, event, heading, moving) {
+		track.sentPos, track.sentAt, track.sentEdge = event.Position, event.Timestamp, event.EdgeID
+		track.velocity, track.heading, track.moving = velocity, heading, moving
+		return true
+	}
+	return false
+}
+
+func (p *Pipeline) shouldEmit(track *vehicleTrack, event entities.BasicVehiclePosEvent, heading float64, moving bool) bool {
+	cfg := p.config
+
+	// Edge changes and arrivals carry graph information consumers cannot
+	// extrapolate.
+	if event.EdgeID != track.sentEdge || event.Progress >= 1 {
+		return true
+	}
+	if cfg.MaxSilence > 0 && event.Timestamp.Sub(track.sentAt) >= cfg.MaxSilence {
+		return true
+	}
+	if cfg.MinDistance <= 0 && cfg.MinHeadingChange <= 0 && cfg.DeadReckoningTolerance <= 0 {
+		return true
+	}
+
+	if cfg.MinDistance > 0 && distance(event.Position, track.sentPos) >= cfg.MinDistance {
+		return true
+	}
+	if cfg.MinHeadingChange > 0 && moving && track.moving && angleBetween(heading, track.heading) >= cfg.MinHeadingChange {
+		return true
+	}
+	if cfg.DeadReckoningTolerance > 0 {
+		dt := event.Timestamp.Sub(track.sentAt).Seconds()
+		predicted := entities.Vector2D{
+			X: track.sentPos.X + track.velocity.X*dt,
+			Y: track.sentPos.Y + track.velocity.Y*dt,
+		}
+		if distance(event.Position, predicted) > cfg.DeadReckoningTolerance {
+			return true
+		}
+	}
+	return false
+}
+
+func distance(a, b entities.Vector2D) float64 {
+	return math.Hypot(a.X-b.X, a.Y-b.Y)
+}
+
+// angleBetween returns the absolute difference of two headings in degrees.
+func angleBetween(a, b float64) float64 {
+	d := math.Abs(a - b)
+	if d > math.Pi {
+		d = 2*math.Pi - d
+	}
+	return d * 180 / math.Pi
+}
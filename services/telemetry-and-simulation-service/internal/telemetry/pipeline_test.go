@@ -0,0 +1,196 @@
+package telemetry
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/m/internal/simulation/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingSink struct {
+	mu        sync.Mutex
+	positions []entities.BasicVehiclePosEvent
+	events    []entities.VehicleEvent
+	err       error
+}
+
+func (s *recordingSink) EmitPosition(event entities.BasicVehiclePosEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.positions = append(s.positions, event)
+	return s.err
+}
+
+func (s *recordingSink) EmitEvent(event entities.VehicleEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+	return s.err
+}
+
+type batchSink struct {
+	recordingSink
+	batches int
+}
+
+func (s *batchSink) EmitBatch(positions []entities.BasicVehiclePosEvent, events []entities.VehicleEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches++
+	s.positions = append(s.positions, positions...)
+	s.events = append(s.events, events...)
+	return nil
+}
+
+var t0 = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func pos(id string, x, y float64, at time.Duration) entities.BasicVehiclePosEvent {
+	return entities.BasicVehiclePosEvent{
+		VehicleID: id,
+		EdgeID:    "e1",
+		Position:  entities.Vector2D{X: x, Y: y},
+		Timestamp: t0.Add(at),
+	}
+}
+
+func TestPipeline_BatchesBySize(t *testing.T) {
+	sink := &batchSink{}
+	p := NewPipeline(sink, PipelineConfig{BatchSize: 3})
+
+	p.EmitPosition(pos("v1", 0, 0, 0))
+	p.EmitPosition(pos("v2", 0, 0, 0))
+	assert.Empty(t, sink.positions)
+
+	p.EmitEvent(entities.VehicleEvent{VehicleID: "v1", EventType: entities.EventRouteStarted})
+	assert.Equal(t, 1, sink.batches)
+	assert.Len(t, sink.positions, 2)
+	assert.Len(t, sink.events, 1)
+
+	stats := p.Stats()
+	assert.Equal(t, uint64(3), stats.Emitted)
+	assert.Equal(t, 0, stats.QueueDepth)
+}
+
+func TestPipeline_FlushesOnInterval(t *testing.T) {
+	sink := &recordingSink{}
+	p := NewPipeline(sink, PipelineConfig{BatchSize: 100, FlushInterval: 5 * time.Millisecond})
+	p.Start()
+	defer p.Close()
+
+	p.EmitPosition(pos("v1", 0, 0, 0))
+
+	assert.Eventually(t, func() bool {
+		sink.mu.Lock()
+		defer sink.mu.Unlock()
+		return len(sink.positions) == 1
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestPipeline_CoalescesAndBoundsQueues(t *testing.T) {
+	sink := &recordingSink{}
+	p := NewPipeline(sink, PipelineConfig{QueueSize: 2, EventQueueSize: 1})
+
+	p.EmitPosition(pos("v1", 0, 0, 0))
+	p.EmitPosition(pos("v1", 5, 0, time.Second))
+	p.EmitPosition(pos("v2", 0, 0, 0))
+	p.EmitPosition(pos("v3", 0, 0, 0))
+	p.EmitEvent(entities.VehicleEvent{VehicleID: "v1", EventType: entities.EventRouteStarted})
+	p.EmitEvent(entities.VehicleEvent{VehicleID: "v2", EventType: entities.EventRouteStarted})
+
+	stats := p.Stats()
+	assert.Equal(t, uint64(1), stats.PositionsCoalesced)
+	assert.Equal(t, uint64(1), stats.PositionsDropped)
+	assert.Equal(t, uint64(1), stats.EventsDropped)
+	assert.Equal(t, 2, stats.QueueDepth)
+	assert.Equal(t, 1, stats.EventQueueDepth)
+
+	require.NoError(t, p.Flush())
+	require.Len(t, sink.positions, 2)
+	assert.Equal(t, 5.0, sink.positions[0].Position.X)
+	require.Len(t, sink.events, 1)
+	assert.Equal(t, "v2", sink.events[0].VehicleID)
+}
+
+func TestPipeline_CriticalEventsBypassQueue(t *testing.T) {
+	sink := &recordingSink{}
+	p := NewPipeline(sink, PipelineConfig{BatchSize: 100})
+
+	p.EmitPosition(pos("v1", 0, 0, 0))
+	p.EmitEvent(entities.VehicleEvent{VehicleID: "v1", Severity: entities.SeverityCritical})
+
+	assert.Len(t, sink.events, 1)
+	assert.Empty(t, sink.positions)
+}
+
+func TestPipeline_DeadReckoningSuppressesPredictablePositions(t *testing.T) {
+	sink := &recordingSink{}
+	p := NewPipeline(sink, PipelineConfig{DeadReckoningTolerance: 1})
+
+	// Constant 10 m/s along X: only the first two samples are needed to
+	// extrapolate the rest.
+	for i := 0; i < 5; i++ {
+		p.EmitPosition(pos("v1", float64(i)*10, 0, time.Duration(i)*time.Second))
+		p.Flush()
+	}
+	assert.Len(t, sink.positions, 2)
+
+	// Stopping diverges from the prediction.
+	p.EmitPosition(pos("v1", 40, 0, 5*time.Second))
+	p.Flush()
+	assert.Len(t, sink.positions, 3)
+	assert.Equal(t, uint64(3), p.Stats().PositionsSampled)
+}
+
+func TestPipeline_HeadingDistanceAndSilence(t *testing.T) {
+	sink := &recordingSink{}
+	p := NewPipeline(sink, PipelineConfig{MinDistance: 50, MinHeadingChange: 30, MaxSilence: 10 * time.Second})
+
+	emit := func(e entities.BasicVehiclePosEvent) int {
+		p.EmitPosition(e)
+		p.Flush()
+		return len(sink.positions)
+	}
+
+	assert.Equal(t, 1, emit(pos("v1", 0, 0, 0)))
+	assert.Equal(t, 1, emit(pos("v1", 10, 0, time.Second)))
+	assert.Equal(t, 1, emit(pos("v1", 20, 0, 2*time.Second)))
+	// 90 degree turn.
+	assert.Equal(t, 2, emit(pos("v1", 20, 10, 3*time.Second)))
+	assert.Equal(t, 2, emit(pos("v1", 20, 20, 4*time.Second)))
+	assert.Equal(t, 3, emit(pos("v1", 20, 70, 5*time.Second)))
+	// Standing still until MaxSilence forces an update.
+	assert.Equal(t, 3, emit(pos("v1", 20, 70, 10*time.Second)))
+	assert.Equal(t, 4, emit(pos("v1", 20, 70, 15*time.Second)))
+
+	// Edge changes and arrivals always go through.
+	onEdge := pos("v1", 20, 70, 16*time.Second)
+	onEdge.EdgeID = "e2"
+	assert.Equal(t, 5, emit(onEdge))
+	onEdge.Progress = 1
+	onEdge.Timestamp = onEdge.Timestamp.Add(time.Second)
+	assert.Equal(t, 6, emit(onEdge))
+}
+
+func TestPipeline_CountsSinkErrors(t *testing.T) {
+	sink := &recordingSink{err: errors.New("broker down")}
+	p := NewPipeline(sink, PipelineConfig{})
+
+	p.EmitPosition(pos("v1", 0, 0, 0))
+	assert.Error(t, p.Flush())
+	assert.Equal(t, uint64(1), p.Stats().SinkErrors)
+	assert.Equal(t, uint64(0), p.Stats().Emitted)
+}
+
+func TestPipeline_ForgetDropsTrack(t *testing.T) {
+	p := NewPipeline(&recordingSink{}, PipelineConfig{})
+	p.EmitPosition(pos("v1", 0, 0, 0))
+	p.EmitPosition(pos("v2", 0, 0, 0))
+
+	p.Forget("v1")
+	assert.Len(t, p.tracks, 1)
+	assert.Contains(t, p.tracks, "v2")
+}
@@ -0,0 +1,181 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"fmt"
+
+	telemetryv1 "github.com/m/internal/gen/telemetry/v1"
+	"github.com/m/internal/simulation/entities"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type ProtoSerializer struct{}
+
+func (ProtoSerializer) Format() string      { return FormatProto }
+func (ProtoSerializer) ContentType() string { return "application/x-protobuf" }
+func (ProtoSerializer) Binary() bool        { return true }
+
+// Single events are wrapped in a TelemetryEnvelope so a reader can decode a
+// frame without knowing its kind up front.
+func (ProtoSerializer) MarshalPosition(event entities.BasicVehiclePosEvent) ([]byte, error) {
+	return proto.Marshal(&telemetryv1.TelemetryEnvelope{
+		Payload: &telemetryv1.TelemetryEnvelope_Position{Position: ToProtoPosition(event)},
+	})
+}
+
+func (ProtoSerializer) MarshalEvent(event entities.VehicleEvent) ([]byte, error) {
+	return proto.Marshal(&telemetryv1.TelemetryEnvelope{
+		Payload: &telemetryv1.TelemetryEnvelope_Event{Event: ToProtoEvent(event)},
+	})
+}
+
+func (ProtoSerializer) MarshalBatch(positions []entities.BasicVehiclePosEvent, events []entities.VehicleEvent) ([]byte, error) {
+	batch := &telemetryv1.TelemetryBatch{
+		Items: make([]*telemetryv1.TelemetryEnvelope, 0, len(positions)+len(events)),
+	}
+	for _, p := range positions {
+		batch.Items = append(batch.Items, &telemetryv1.TelemetryEnvelope{
+			Payload: &telemetryv1.TelemetryEnvelope_Position{Position: ToProtoPosition(p)},
+		})
+	}
+	for _, e := range events {
+		batch.Items = append(batch.Items, &telemetryv1.TelemetryEnvelope{
+			Payload: &telemetryv1.TelemetryEnvelope_Event{Event: ToProtoEvent(e)},
+		})
+	}
+	return proto.Marshal(batch)
+}
+
+func (ProtoSerializer) Unmarshal(data []byte) (Record, error) {
+	var env telemetryv1.TelemetryEnvelope
+	if err := proto.Unmarshal(data, &env); err != nil {
+		return Record{}, err
+	}
+
+	switch p := env.Payload.(type) {
+	case *telemetryv1.TelemetryEnvelope_Position:
+		pos := FromProtoPosition(p.Position)
+		return Record{Position: &pos}, nil
+	case *telemetryv1.TelemetryEnvelope_Event:
+		ev := FromProtoEvent(p.Event)
+		return Record{Event: &ev}, nil
+	default:
+		return Record{}, fmt.Errorf("empty telemetry envelope")
+	}
+}
+
+func ToProtoPosition(e entities.BasicVehiclePosEvent) *telemetryv1.VehiclePosition {
+	e = NormalizePosition(e)
+	return &telemetryv1.VehiclePosition{
+		SchemaVersion: uint32(e.SchemaVersion),
+		RunId:         e.RunID,
+		FleetId:       e.FleetID,
+		VehicleId:     e.VehicleID,
+		Sequence:      e.Sequence,
+		EdgeId:        e.EdgeID,
+		FromNodeId:    e.FromNodeID,
+		Progress:      e.Progress,
+		Position:      &telemetryv1.Vector2D{X: e.Position.X, Y: e.Position.Y},
+		Timestamp:     timestamppb.New(e.Timestamp),
+	}
+}
+
+func FromProtoPosition(p *telemetryv1.VehiclePosition) entities.BasicVehiclePosEvent {
+	return entities.BasicVehiclePosEvent{
+		SchemaVersion: int(p.GetSchemaVersion()),
+		RunID:         p.GetRunId(),
+		FleetID:       p.GetFleetId(),
+		VehicleID:     p.GetVehicleId(),
+		Sequence:      p.GetSequence(),
+		EdgeID:        p.GetEdgeId(),
+		FromNodeID:    p.GetFromNodeId(),
+		Progress:      p.GetProgress(),
+		Position:      entities.Vector2D{X: p.GetPosition().GetX(), Y: p.GetPosition().GetY()},
+		Timestamp:     p.GetTimestamp().AsTime(),
+	}
+}
+
+func ToProtoEvent(e entities.VehicleEvent) *telemetryv1.VehicleEvent {
+	e = NormalizeEvent(e)
+	return &telemetryv1.VehicleEvent{
+		SchemaVersion: uint32(e.SchemaVersion),
+		RunId:         e.RunID,
+		FleetId:       e.FleetID,
+		VehicleId:     e.VehicleID,
+		Sequence:      e.Sequence,
+		EventType:     string(e.EventType),
+		Severity:      toProtoSeverity(e.Severity),
+		Timestamp:     timestamppb.New(e.Timestamp),
+		Data:          toStruct(e.Data),
+	}
+}
+
+func FromProtoEvent(p *telemetryv1.VehicleEvent) entities.VehicleEvent {
+	var data map[string]interface{}
+	if p.GetData() != nil {
+		data = p.GetData().AsMap()
+	}
+	return entities.VehicleEvent{
+		SchemaVersion: int(p.GetSchemaVersion()),
+		RunID:         p.GetRunId(),
+		FleetID:       p.GetFleetId(),
+		VehicleID:     p.GetVehicleId(),
+		Sequence:      p.GetSequence(),
+		EventType:     entities.EventType(p.GetEventType()),
+		Severity:      fromProtoSeverity(p.GetSeverity()),
+		Timestamp:     p.GetTimestamp().AsTime(),
+		Data:          data,
+	}
+}
+
+func toProtoSeverity(s entities.Severity) telemetryv1.Severity {
+	switch s {
+	case entities.SeverityInfo:
+		return telemetryv1.Severity_SEVERITY_INFO
+	case entities.SeverityWarning:
+		return telemetryv1.Severity_SEVERITY_WARNING
+	case entities.SeverityCritical:
+		return telemetryv1.Severity_SEVERITY_CRITICAL
+	default:
+		return telemetryv1.Severity_SEVERITY_UNSPECIFIED
+	}
+}
+
+func fromProtoSeverity(s telemetryv1.Severity) entities.Severity {
+	switch s {
+	case telemetryv1.Severity_SEVERITY_INFO:
+		return entities.SeverityInfo
+	case telemetryv1.Severity_SEVERITY_WARNING:
+		return entities.SeverityWarning
+	case telemetryv1.Severity_SEVERITY_CRITICAL:
+		return entities.SeverityCritical
+	default:
+		return ""
+	}
+}
+
+// toStruct round-trips through JSON so nested values such as Vector2D end up
+// as plain maps that structpb understands.
+func toStruct(data map[string]interface{}) *structpb.Struct {
+	if len(data) == 0 {
+		return nil
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil
+	}
+
+	var generic map[string]interface{}
+	if err := json.Unmarshal(raw, &generic); err != nil {
+		return nil
+	}
+
+	s, err := structpb.NewStruct(generic)
+	if err != nil {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,211 @@
+package telemetry
+
+import (
+	"bufio"
+	"compress/gzip"
+	"container/heap"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// DefaultReorderWindow bounds how far out of timestamp order lines can be
+// in a recording. Vehicles emit concurrently and the pipeline writes events
+// of a batch before its positions, so file order is only roughly sorted.
+const DefaultReorderWindow = 10 * time.Second
+
+// RunReader iterates a recorded run in timestamp order. Records with equal
+// timestamps keep their file order.
+type RunReader struct {
+	ReorderWindow time.Duration
+
+	dir      string
+	manifest Manifest
+
+	fileIdx int
+	file    *os.File
+	decoder io.Closer
+	scanner *bufio.Scanner
+
+	pending recordHeap
+	seq     uint64
+	maxSeen time.Time
+	eof     bool
+}
+
+func ReadManifest(dir string) (Manifest, error) {
+	var m Manifest
+	data, err := os.ReadFile(filepath.Join(dir, manifestFile))
+	if err != nil {
+		return m, err
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return m, fmt.Errorf("parse manifest: %w", err)
+	}
+	return m, nil
+}
+
+// OpenRun opens the run recorded in dir, i.e. RecorderConfig.Dir/<run id>.
+func OpenRun(dir string) (*RunReader, error) {
+	manifest, err := ReadManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &RunReader{
+		ReorderWindow: DefaultReorderWindow,
+		dir:           dir,
+		manifest:      manifest,
+	}, nil
+}
+
+func (r *RunReader) Manifest() Manifest {
+	return r.manifest
+}
+
+// Next returns the next record, or io.EOF after the last one.
+func (r *RunReader) Next() (Record, error) {
+	for {
+		if r.pending.Len() > 0 && (r.eof || !r.pending[0].at.After(r.maxSeen.Add(-r.ReorderWindow))) {
+			return heap.Pop(&r.pending).(pendingRecord).rec, nil
+		}
+		if r.eof {
+			return Record{}, io.EOF
+		}
+
+		line, err := r.nextLine()
+		if err == io.EOF {
+			r.eof = true
+			continue
+		}
+		if err != nil {
+			return Record{}, err
+		}
+
+		rec, err := JSONSerializer{}.Unmarshal(line)
+		if err != nil {
+			return Record{}, fmt.Errorf("%s: %w", r.manifest.Files[r.fileIdx-1], err)
+		}
+
+		at := rec.Timestamp()
+		if at.After(r.maxSeen) {
+			r.maxSeen = at
+		}
+		r.seq++
+		heap.Push(&r.pending, pendingRecord{rec: rec, at: at, seq: r.seq})
+	}
+}
+
+func (r *RunReader) Close() error {
+	return r.closeFile()
+}
+
+func (r *RunReader) nextLine() ([]byte, error) {
+	for {
+		if r.scanner != nil {
+			if r.scanner.Scan() {
+				if len(r.scanner.Bytes()) == 0 {
+					continue
+				}
+				return r.scanner.Bytes(), nil
+			}
+			if err := r.scanner.Err(); err != nil {
+				return nil, err
+			}
+			if err := r.closeFile(); err != nil {
+				return nil, err
+			}
+		}
+
+		if r.fileIdx >= len(r.manifest.Files) {
+			return nil, io.EOF
+		}
+		if err := r.openFile(r.manifest.Files[r.fileIdx]); err != nil {
+			return nil, err
+		}
+		r.fileIdx++
+	}
+}
+
+func (r *RunReader) openFile(name string) error {
+	file, err := os.Open(filepath.Join(r.dir, name))
+	if err != nil {
+		return err
+	}
+
+	var src io.Reader = file
+	switch {
+	case strings.HasSuffix(name, ".gz"):
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			file.Close()
+			return err
+		}
+		src, r.decoder = gz, gz
+	case strings.HasSuffix(name, ".zst"):
+		zr, err := zstd.NewReader(file)
+		if err != nil {
+			file.Close()
+			return err
+		}
+		src, r.decoder = zr, zstdCloser{zr}
+	}
+
+	r.file = file
+	r.scanner = bufio.NewScanner(src)
+	r.scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	return nil
+}
+
+func (r *RunReader) closeFile() error {
+	var err error
+	if r.decoder != nil {
+		err = r.decoder.Close()
+	}
+	if r.file != nil {
+		if cerr := r.file.Close(); err == nil {
+			err = cerr
+		}
+	}
+	r.file, r.decoder, r.scanner = nil, nil, nil
+	return err
+}
+
+type zstdCloser struct {
+	*zstd.Decoder
+}
+
+func (z zstdCloser) Close() error {
+	z.Decoder.Close()
+	return nil
+}
+
+type pendingRecord struct {
+	rec Record
+	at  time.Time
+	seq uint64
+}
+
+type recordHeap []pendingRecord
+
+func (h recordHeap) Len() int { return len(h) }
+func (h recordHeap) Less(i, j int) bool {
+	if h[i].at.Equal(h[j].at) {
+		return h[i].seq < h[j].seq
+	}
+	return h[i].at.Before(h[j].at)
+}
+func (h recordHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *recordHeap) Push(x interface{}) { *h = append(*h, x.(pendingRecord)) }
+func (h *recordHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
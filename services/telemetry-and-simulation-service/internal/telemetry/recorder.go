@@ -0,0 +1,367 @@
+package telemetry
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/m/internal/simulation/entities"
+)
+
+type Compression string
+
+const (
+	CompressionNone Compression = "none"
+	CompressionGzip Compression = "gzip"
+	CompressionZstd Compression = "zstd"
+)
+
+const (
+	manifestFile = "manifest.json"
+	graphFile    = "map.json"
+)
+
+func (c Compression) extension() string {
+	switch c {
+	case CompressionGzip:
+		return ".jsonl.gz"
+	case CompressionZstd:
+		return ".jsonl.zst"
+	default:
+		return ".jsonl"
+	}
+}
+
+type RecorderConfig struct {
+	// Dir is the parent directory; each run is written to Dir/<run id>.
+	Dir         string
+	Compression Compression
+	// MaxFileBytes (uncompressed) and MaxFileAge trigger rotation to a new
+	// segment file. Zero disables the respective limit.
+	MaxFileBytes int64
+	MaxFileAge   time.Duration
+	// FlushInterval, when set, flushes buffered lines to disk periodically
+	// so a crashed run loses at most that much.
+	FlushInterval time.Duration
+}
+
+// Manifest describes a recorded run: enough to load its map and to find its
+// segment files in order.
+type Manifest struct {
+	RunID         string          `json:"run_id"`
+	SchemaVersion int             `json:"schema_version"`
+	Seed          int64           `json:"seed"`
+	MapConfig     json.RawMessage `json:"map_config,omitempty"`
+	EngineConfig  json.RawMessage `json:"engine_config,omitempty"`
+	// Graph names the file in the run's directory that holds its map, once
+	// WriteGraph saved it.
+	Graph       string      `json:"graph,omitempty"`
+	Compression Compression `json:"compression"`
+	StartedAt   time.Time   `json:"started_at"`
+	EndedAt     *time.Time  `json:"ended_at,omitempty"`
+	Files       []string    `json:"files"`
+	Positions   int64       `json:"positions"`
+	Events      int64       `json:"events"`
+}
+
+// NewManifest marshals the map and engine configuration into a manifest.
+func NewManifest(runID string, seed int64, mapConfig, engineConfig interface{}) (Manifest, error) {
+	m := Manifest{RunID: runID, Seed: seed}
+
+	var err error
+	if mapConfig != nil {
+		if m.MapConfig, err = json.Marshal(mapConfig); err != nil {
+			return Manifest{}, fmt.Errorf("marshal map config: %w", err)
+		}
+	}
+	if engineConfig != nil {
+		if m.EngineConfig, err = json.Marshal(engineConfig); err != nil {
+			return Manifest{}, fmt.Errorf("marshal engine config: %w", err)
+		}
+	}
+	return m, nil
+}
+
+// Recorder is a TelemetryEmitter that appends every position and event as
+// one JSON line to rotating, optionally compressed segment files.
+type Recorder struct {
+	config RecorderConfig
+	dir    string
+
+	mu       sync.Mutex
+	manifest Manifest
+	file     *os.File
+	encoder  io.WriteCloser
+	buf      *bufio.Writer
+	written  int64
+	openedAt time.Time
+	closed   bool
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+func NewRecorder(config RecorderConfig, manifest Manifest) (*Recorder, error) {
+	if manifest.RunID == "" {
+		return nil, fmt.Errorf("manifest has no run id")
+	}
+	switch config.Compression {
+	case "":
+		config.Compression = CompressionNone
+	case CompressionNone, CompressionGzip, CompressionZstd:
+	default:
+		return nil, fmt.Errorf("unknown compression %q", config.Compression)
+	}
+
+	dir := filepath.Join(config.Dir, manifest.RunID)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	manifest.SchemaVersion = entities.TelemetrySchemaVersion
+	manifest.Compression = config.Compression
+	if manifest.StartedAt.IsZero() {
+		manifest.StartedAt = time.Now()
+	}
+
+	r := &Recorder{config: config, dir: dir, manifest: manifest, stop: make(chan struct{})}
+	if err := r.rotate(); err != nil {
+		return nil, err
+	}
+	if config.FlushInterval > 0 {
+		r.wg.Add(1)
+		go r.run()
+	}
+	return r, nil
+}
+
+func (r *Recorder) run() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.config.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.Flush()
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// Dir is the directory of the recorded run.
+func (r *Recorder) Dir() string {
+	return r.dir
+}
+
+// WriteGraph saves the map the run drives on next to the manifest, so
+// replays do not depend on regenerating it.
+func (r *Recorder) WriteGraph(g *entities.MapGraph) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := g.ExportJSON(filepath.Join(r.dir, graphFile)); err != nil {
+		return err
+	}
+	r.manifest.Graph = graphFile
+	return r.writeManifest()
+}
+
+func (r *Recorder) EmitPosition(event entities.BasicVehiclePosEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.writePosition(event)
+}
+
+func (r *Recorder) EmitEvent(event entities.VehicleEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.writeEvent(event)
+}
+
+func (r *Recorder) EmitBatch(positions []entities.BasicVehiclePosEvent, events []entities.VehicleEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, ev := range events {
+		if err := r.writeEvent(ev); err != nil {
+			return err
+		}
+	}
+	for _, pos := range positions {
+		if err := r.writePosition(pos); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush pushes buffered lines through the compressor to disk. Compressed
+// segments only become readable in full after Close or rotation.
+func (r *Recorder) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return nil
+	}
+	if err := r.buf.Flush(); err != nil {
+		return err
+	}
+	if f, ok := r.encoder.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+func (r *Recorder) Close() error {
+	r.stopOnce.Do(func() { close(r.stop) })
+	r.wg.Wait()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+
+	err := r.closeSegment()
+	now := time.Now()
+	r.manifest.EndedAt = &now
+	if merr := r.writeManifest(); err == nil {
+		err = merr
+	}
+	return err
+}
+
+func (r *Recorder) writePosition(event entities.BasicVehiclePosEvent) error {
+	data, err := JSONSerializer{}.MarshalPosition(event)
+	if err != nil {
+		return err
+	}
+	if err := r.writeLine(data); err != nil {
+		return err
+	}
+	r.manifest.Positions++
+	return nil
+}
+
+func (r *Recorder) writeEvent(event entities.VehicleEvent) error {
+	data, err := JSONSerializer{}.MarshalEvent(event)
+	if err != nil {
+		return err
+	}
+	if err := r.writeLine(data); err != nil {
+		return err
+	}
+	r.manifest.Events++
+	return nil
+}
+
+// writeLine expects r.mu to be held.
+func (r *Recorder) writeLine(data []byte) error {
+	if r.closed {
+		return fmt.Errorf("recorder closed")
+	}
+	if r.shouldRotate() {
+		if err := r.rotate(); err != nil {
+			return err
+		}
+	}
+
+	if _, err := r.buf.Write(data); err != nil {
+		return err
+	}
+	if err := r.buf.WriteByte('\n'); err != nil {
+		return err
+	}
+	r.written += int64(len(data)) + 1
+	return nil
+}
+
+func (r *Recorder) shouldRotate() bool {
+	if r.written == 0 {
+		return false
+	}
+	if r.config.MaxFileBytes > 0 && r.written >= r.config.MaxFileBytes {
+		return true
+	}
+	return r.config.MaxFileAge > 0 && time.Since(r.openedAt) >= r.config.MaxFileAge
+}
+
+// rotate closes the current segment, opens the next one and rewrites the
+// manifest so it always lists every segment.
+func (r *Recorder) rotate() error {
+	if err := r.closeSegment(); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("telemetry-%06d%s", len(r.manifest.Files)+1, r.config.Compression.extension())
+	file, err := os.Create(filepath.Join(r.dir, name))
+	if err != nil {
+		return err
+	}
+
+	var encoder io.WriteCloser
+	switch r.config.Compression {
+	case CompressionGzip:
+		encoder = gzip.NewWriter(file)
+	case CompressionZstd:
+		if encoder, err = zstd.NewWriter(file); err != nil {
+			file.Close()
+			return err
+		}
+	default:
+		encoder = nopWriteCloser{file}
+	}
+
+	r.file, r.encoder, r.buf = file, encoder, bufio.NewWriterSize(encoder, 64*1024)
+	r.written, r.openedAt = 0, time.Now()
+	r.manifest.Files = append(r.manifest.Files, name)
+	return r.writeManifest()
+}
+
+func (r *Recorder) closeSegment() error {
+	if r.file == nil {
+		return nil
+	}
+
+	err := r.buf.Flush()
+	if cerr := r.encoder.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := r.file.Close(); err == nil {
+		err = cerr
+	}
+	r.file, r.encoder, r.buf = nil, nil, nil
+	return err
+}
+
+func (r *Recorder) writeManifest() error {
+	data, err := json.MarshalIndent(r.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := filepath.Join(r.dir, manifestFile+".tmp")
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(r.dir, manifestFile))
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
@@ -0,0 +1,132 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/m/internal/simulation/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readAll(t *testing.T, dir string) []Record {
+	t.Helper()
+
+	reader, err := OpenRun(dir)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	var out []Record
+	for {
+		rec, err := reader.Next()
+		if err == io.EOF {
+			return out
+		}
+		require.NoError(t, err)
+		out = append(out, rec)
+	}
+}
+
+func TestRecorder_RoundTrip(t *testing.T) {
+	for _, compression := range []Compression{CompressionNone, CompressionGzip, CompressionZstd} {
+		t.Run(string(compression), func(t *testing.T) {
+			manifest, err := NewManifest("run-1", 42, map[string]int{"n": 100}, nil)
+			require.NoError(t, err)
+
+			rec, err := NewRecorder(RecorderConfig{Dir: t.TempDir(), Compression: compression, MaxFileBytes: 300}, manifest)
+			require.NoError(t, err)
+
+			for i := 0; i < 10; i++ {
+				require.NoError(t, rec.EmitPosition(pos("v1", float64(i), 0, time.Duration(i)*time.Second)))
+			}
+			require.NoError(t, rec.EmitEvent(entities.VehicleEvent{
+				VehicleID: "v1",
+				EventType: entities.EventRouteCompleted,
+				Timestamp: t0.Add(10 * time.Second),
+			}))
+			require.NoError(t, rec.Close())
+
+			got, err := ReadManifest(rec.Dir())
+			require.NoError(t, err)
+			assert.Equal(t, int64(42), got.Seed)
+			assert.JSONEq(t, `{"n":100}`, string(got.MapConfig))
+			assert.Equal(t, compression, got.Compression)
+			assert.Equal(t, int64(10), got.Positions)
+			assert.Equal(t, int64(1), got.Events)
+			assert.NotNil(t, got.EndedAt)
+			assert.Greater(t, len(got.Files), 1, "expected size based rotation")
+
+			records := readAll(t, rec.Dir())
+			require.Len(t, records, 11)
+			for i := 0; i < 10; i++ {
+				require.NotNil(t, records[i].Position)
+				assert.Equal(t, float64(i), records[i].Position.Position.X)
+			}
+			require.NotNil(t, records[10].Event)
+			assert.Equal(t, entities.EventRouteCompleted, records[10].Event.EventType)
+		})
+	}
+}
+
+func TestRunReader_OrdersByTimestamp(t *testing.T) {
+	rec, err := NewRecorder(RecorderConfig{Dir: t.TempDir()}, Manifest{RunID: "run-1"})
+	require.NoError(t, err)
+
+	// A batch as the pipeline writes it: the event first, although it
+	// happened after the positions.
+	require.NoError(t, rec.EmitBatch(
+		[]entities.BasicVehiclePosEvent{pos("v1", 0, 0, 0), pos("v2", 0, 0, 2*time.Second)},
+		[]entities.VehicleEvent{{VehicleID: "v1", EventType: entities.EventRouteStarted, Timestamp: t0.Add(time.Second)}},
+	))
+	require.NoError(t, rec.Close())
+
+	records := readAll(t, rec.Dir())
+	require.Len(t, records, 3)
+	for i := 1; i < len(records); i++ {
+		assert.False(t, records[i].Timestamp().Before(records[i-1].Timestamp()))
+	}
+	assert.NotNil(t, records[1].Event)
+}
+
+func TestRecorder_ValidatesConfig(t *testing.T) {
+	_, err := NewRecorder(RecorderConfig{Dir: t.TempDir()}, Manifest{})
+	assert.Error(t, err)
+
+	_, err = NewRecorder(RecorderConfig{Dir: t.TempDir(), Compression: "lz4"}, Manifest{RunID: "run-1"})
+	assert.Error(t, err)
+}
+
+func TestRecorder_ManifestWrittenOnOpen(t *testing.T) {
+	dir := t.TempDir()
+	rec, err := NewRecorder(RecorderConfig{Dir: dir, Compression: CompressionGzip}, Manifest{RunID: "run-1"})
+	require.NoError(t, err)
+	defer rec.Close()
+
+	assert.Equal(t, filepath.Join(dir, "run-1"), rec.Dir())
+	got, err := ReadManifest(rec.Dir())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"telemetry-000001.jsonl.gz"}, got.Files)
+	assert.Nil(t, got.EndedAt)
+
+	var raw map[string]interface{}
+	data, _ := json.Marshal(got)
+	require.NoError(t, json.Unmarshal(data, &raw))
+	assert.Equal(t, float64(entities.TelemetrySchemaVersion), raw["schema_version"])
+}
+
+func TestRecorder_FlushesOnInterval(t *testing.T) {
+	rec, err := NewRecorder(RecorderConfig{Dir: t.TempDir(), FlushInterval: 10 * time.Millisecond}, Manifest{RunID: "run-1"})
+	require.NoError(t, err)
+	require.NoError(t, rec.EmitPosition(pos("v1", 0, 0, 0)))
+
+	assert.Eventually(t, func() bool {
+		data, err := os.ReadFile(filepath.Join(rec.Dir(), "telemetry-000001.jsonl"))
+		return err == nil && len(data) > 0
+	}, time.Second, 5*time.Millisecond)
+	require.NoError(t, rec.Close())
+	require.NoError(t, rec.Close())
+}
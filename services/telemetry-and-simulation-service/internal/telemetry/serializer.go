@@ -0,0 +1,127 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/m/internal/simulation/entities"
+)
+
+const (
+	FormatJSON  = "json"
+	FormatProto = "proto"
+)
+
+// Record holds exactly one decoded telemetry event.
+type Record struct {
+	Position *entities.BasicVehiclePosEvent
+	Event    *entities.VehicleEvent
+}
+
+func (r Record) Timestamp() time.Time {
+	if r.Position != nil {
+		return r.Position.Timestamp
+	}
+	if r.Event != nil {
+		return r.Event.Timestamp
+	}
+	return time.Time{}
+}
+
+// Serializer encodes telemetry events in a wire format. Every emitter that
+// writes bytes (WebSocket, SSE, recorders) goes through one so consumers can
+// pick the format without the emitters caring.
+type Serializer interface {
+	Format() string
+	ContentType() string
+	Binary() bool
+	MarshalPosition(event entities.BasicVehiclePosEvent) ([]byte, error)
+	MarshalEvent(event entities.VehicleEvent) ([]byte, error)
+	MarshalBatch(positions []entities.BasicVehiclePosEvent, events []entities.VehicleEvent) ([]byte, error)
+	Unmarshal(data []byte) (Record, error)
+}
+
+func NewSerializer(format string) (Serializer, error) {
+	switch format {
+	case "", FormatJSON:
+		return JSONSerializer{}, nil
+	case FormatProto:
+		return ProtoSerializer{}, nil
+	default:
+		return nil, fmt.Errorf("unknown telemetry format %q", format)
+	}
+}
+
+// NormalizePosition stamps the current schema version on events that were
+// built without one.
+func NormalizePosition(event entities.BasicVehiclePosEvent) entities.BasicVehiclePosEvent {
+	if event.SchemaVersion == 0 {
+		event.SchemaVersion = entities.TelemetrySchemaVersion
+	}
+	return event
+}
+
+func NormalizeEvent(event entities.VehicleEvent) entities.VehicleEvent {
+	if event.SchemaVersion == 0 {
+		event.SchemaVersion = entities.TelemetrySchemaVersion
+	}
+	return event
+}
+
+type JSONSerializer struct{}
+
+type jsonBatch struct {
+	Positions []entities.BasicVehiclePosEvent `json:"positions"`
+	Events    []entities.VehicleEvent         `json:"events"`
+}
+
+func (JSONSerializer) Format() string      { return FormatJSON }
+func (JSONSerializer) ContentType() string { return "application/json" }
+func (JSONSerializer) Binary() bool        { return false }
+
+func (JSONSerializer) MarshalPosition(event entities.BasicVehiclePosEvent) ([]byte, error) {
+	return json.Marshal(NormalizePosition(event))
+}
+
+func (JSONSerializer) MarshalEvent(event entities.VehicleEvent) ([]byte, error) {
+	return json.Marshal(NormalizeEvent(event))
+}
+
+func (JSONSerializer) MarshalBatch(positions []entities.BasicVehiclePosEvent, events []entities.VehicleEvent) ([]byte, error) {
+	batch := jsonBatch{
+		Positions: make([]entities.BasicVehiclePosEvent, len(positions)),
+		Events:    make([]entities.VehicleEvent, len(events)),
+	}
+	for i, p := range positions {
+		batch.Positions[i] = NormalizePosition(p)
+	}
+	for i, e := range events {
+		batch.Events[i] = NormalizeEvent(e)
+	}
+	return json.Marshal(batch)
+}
+
+// Unmarshal tells events from positions by the presence of event_type.
+func (JSONSerializer) Unmarshal(data []byte) (Record, error) {
+	var probe struct {
+		EventType string `json:"event_type"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return Record{}, err
+	}
+
+	if probe.EventType != "" {
+		var ev entities.VehicleEvent
+		if err := json.Unmarshal(data, &ev); err != nil {
+			return Record{}, err
+		}
+		return Record{Event: &ev}, nil
+	}
+
+	var pos entities.BasicVehiclePosEvent
+	if err := json.Unmarshal(data, &pos); err != nil {
+		return Record{}, err
+	}
+	return Record{Position: &pos}, nil
+}
@@ -0,0 +1,93 @@
+package telemetry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/m/internal/simulation/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func samplePosition() entities.BasicVehiclePosEvent {
+	return entities.BasicVehiclePosEvent{
+		RunID:      "run-1",
+		FleetID:    "fleet-a",
+		VehicleID:  "v1",
+		Sequence:   7,
+		EdgeID:     "e1",
+		FromNodeID: "n1",
+		Progress:   0.25,
+		Position:   entities.Vector2D{X: 1.5, Y: -2},
+		Timestamp:  time.Date(2024, 12, 5, 19, 37, 0, 0, time.UTC),
+	}
+}
+
+func sampleEvent() entities.VehicleEvent {
+	return entities.VehicleEvent{
+		RunID:     "run-1",
+		FleetID:   "fleet-a",
+		VehicleID: "v1",
+		Sequence:  8,
+		EventType: "route_completed",
+		Timestamp: time.Date(2024, 12, 5, 19, 38, 0, 0, time.UTC),
+		Data:      map[string]interface{}{"end_node": "n9", "distance": 12.5},
+		Severity:  entities.SeverityWarning,
+	}
+}
+
+func TestNewSerializer(t *testing.T) {
+	s, err := NewSerializer("")
+	require.NoError(t, err)
+	assert.Equal(t, FormatJSON, s.Format())
+
+	s, err = NewSerializer(FormatProto)
+	require.NoError(t, err)
+	assert.True(t, s.Binary())
+
+	_, err = NewSerializer("xml")
+	assert.Error(t, err)
+}
+
+func TestSerializers_RoundTrip(t *testing.T) {
+	for _, s := range []Serializer{JSONSerializer{}, ProtoSerializer{}} {
+		t.Run(s.Format(), func(t *testing.T) {
+			pos := samplePosition()
+			data, err := s.MarshalPosition(pos)
+			require.NoError(t, err)
+
+			rec, err := s.Unmarshal(data)
+			require.NoError(t, err)
+			require.NotNil(t, rec.Position)
+			assert.Nil(t, rec.Event)
+
+			pos.SchemaVersion = entities.TelemetrySchemaVersion
+			assert.Equal(t, pos, *rec.Position)
+
+			ev := sampleEvent()
+			data, err = s.MarshalEvent(ev)
+			require.NoError(t, err)
+
+			rec, err = s.Unmarshal(data)
+			require.NoError(t, err)
+			require.NotNil(t, rec.Event)
+			assert.Nil(t, rec.Position)
+
+			assert.Equal(t, entities.TelemetrySchemaVersion, rec.Event.SchemaVersion)
+			assert.Equal(t, ev.EventType, rec.Event.EventType)
+			assert.Equal(t, ev.Severity, rec.Event.Severity)
+			assert.Equal(t, ev.Sequence, rec.Event.Sequence)
+			assert.True(t, ev.Timestamp.Equal(rec.Event.Timestamp))
+			assert.Equal(t, "n9", rec.Event.Data["end_node"])
+			assert.Equal(t, 12.5, rec.Event.Data["distance"])
+		})
+	}
+}
+
+func TestSerializers_MarshalBatch(t *testing.T) {
+	for _, s := range []Serializer{JSONSerializer{}, ProtoSerializer{}} {
+		data, err := s.MarshalBatch([]entities.BasicVehiclePosEvent{samplePosition()}, []entities.VehicleEvent{sampleEvent()})
+		require.NoError(t, err, s.Format())
+		assert.NotEmpty(t, data, s.Format())
+	}
+}
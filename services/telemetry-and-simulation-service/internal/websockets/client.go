@@ -0,0 +1,307 @@
+package websockets
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/m/internal/simulation/entities"
+	"github.com/m/internal/telemetry"
+)
+
+const (
+	pongWait   = 60 * time.Second
+	pingPeriod = (pongWait * 9) / 10
+)
+
+// Message is the JSON text frame sent to clients. Telemetry payloads are
+// produced by the client's serializer; clients that asked for format=proto
+// receive them as binary TelemetryEnvelope/TelemetryBatch frames instead.
+type Message struct {
+	Type      string            `json:"type"`
+	Positions []json.RawMessage `json:"positions,omitempty"`
+	Event     json.RawMessage   `json:"event,omitempty"`
+	Filter    *Filter           `json:"filter,omitempty"`
+	Dropped   int64             `json:"dropped,omitempty"`
+	Error     string            `json:"error,omitempty"`
+}
+
+type ClientMessage struct {
+	Type   string `json:"type"`
+	Filter Filter `json:"filter"`
+}
+
+// Client buffers outgoing data so a slow browser never blocks the emitter:
+// positions are coalesced to the latest one per vehicle and events are kept
+// in a bounded queue that drops the oldest entry when full. Dropped counts
+// evicted events and payloads that failed to serialize; JSON clients see it
+// on positions messages and binary clients in a "dropped" text frame.
+type Client struct {
+	hub        *Hub
+	conn       *websocket.Conn
+	serializer telemetry.Serializer
+
+	mu        sync.Mutex
+	filter    Filter
+	compiled  *compiledFilter
+	positions map[string]entities.BasicVehiclePosEvent
+	events    []entities.VehicleEvent
+	control   []Message
+	dropped   int64
+
+	// reported is the dropped count last sent to a binary client. Only
+	// the write pump touches it.
+	reported int64
+
+	notify chan struct{}
+	done   chan struct{}
+	once   sync.Once
+}
+
+func newClient(hub *Hub, conn *websocket.Conn, filter Filter, serializer telemetry.Serializer) *Client {
+	return &Client{
+		hub:        hub,
+		conn:       conn,
+		serializer: serializer,
+		filter:     filter,
+		compiled:   filter.compile(),
+		positions:  make(map[string]entities.BasicVehiclePosEvent),
+		notify:     make(chan struct{}, 1),
+		done:       make(chan struct{}),
+	}
+}
+
+func (c *Client) pushPosition(event entities.BasicVehiclePosEvent) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.compiled.matchPosition(event) {
+		return
+	}
+	c.positions[event.VehicleID] = event
+}
+
+func (c *Client) pushEvent(event entities.VehicleEvent) {
+	c.mu.Lock()
+	if !c.compiled.matchEvent(event) {
+		c.mu.Unlock()
+		return
+	}
+	if len(c.events) >= c.hub.config.EventBufferSize {
+		c.events = c.events[1:]
+		c.dropped++
+	}
+	c.events = append(c.events, event)
+	c.mu.Unlock()
+
+	c.wake()
+}
+
+func (c *Client) pushControl(msg Message) {
+	c.mu.Lock()
+	c.control = append(c.control, msg)
+	c.mu.Unlock()
+
+	c.wake()
+}
+
+func (c *Client) drop(n int) {
+	c.mu.Lock()
+	c.dropped += int64(n)
+	c.mu.Unlock()
+}
+
+func (c *Client) wake() {
+	select {
+	case c.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (c *Client) setFilter(filter Filter) {
+	c.mu.Lock()
+	c.filter = filter
+	c.compiled = filter.compile()
+	for id, pos := range c.positions {
+		if !c.compiled.matchPosition(pos) {
+			delete(c.positions, id)
+		}
+	}
+	c.mu.Unlock()
+}
+
+type pending struct {
+	control   []Message
+	events    []entities.VehicleEvent
+	positions []entities.BasicVehiclePosEvent
+	dropped   int64
+}
+
+func (c *Client) drain(includePositions bool) pending {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	out := pending{control: c.control, events: c.events, dropped: c.dropped}
+	c.control = nil
+	c.events = nil
+
+	if includePositions && len(c.positions) > 0 {
+		out.positions = make([]entities.BasicVehiclePosEvent, 0, len(c.positions))
+		for _, pos := range c.positions {
+			out.positions = append(out.positions, pos)
+		}
+		c.positions = make(map[string]entities.BasicVehiclePosEvent, len(out.positions))
+	}
+
+	return out
+}
+
+func (c *Client) write(messageType int, data []byte) error {
+	c.conn.SetWriteDeadline(time.Now().Add(c.hub.config.WriteTimeout))
+	return c.conn.WriteMessage(messageType, data)
+}
+
+func (c *Client) writeJSON(msg Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return c.write(websocket.TextMessage, data)
+}
+
+func (c *Client) flush(p pending) error {
+	for _, msg := range p.control {
+		if err := c.writeJSON(msg); err != nil {
+			return err
+		}
+	}
+
+	if c.serializer.Binary() && p.dropped > c.reported {
+		if err := c.writeJSON(Message{Type: "dropped", Dropped: p.dropped}); err != nil {
+			return err
+		}
+		c.reported = p.dropped
+	}
+
+	for _, ev := range p.events {
+		data, err := c.serializer.MarshalEvent(ev)
+		if err != nil {
+			c.drop(1)
+			continue
+		}
+		if c.serializer.Binary() {
+			err = c.write(websocket.BinaryMessage, data)
+		} else {
+			err = c.writeJSON(Message{Type: "event", Event: data})
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.serializer.Binary() {
+		if len(p.positions) == 0 {
+			return nil
+		}
+		data, err := c.serializer.MarshalBatch(p.positions, nil)
+		if err != nil {
+			c.drop(len(p.positions))
+			return nil
+		}
+		return c.write(websocket.BinaryMessage, data)
+	}
+
+	if len(p.positions) == 0 {
+		return nil
+	}
+
+	raws := make([]json.RawMessage, 0, len(p.positions))
+	for _, pos := range p.positions {
+		data, err := c.serializer.MarshalPosition(pos)
+		if err != nil {
+			c.drop(1)
+			continue
+		}
+		raws = append(raws, data)
+	}
+	return c.writeJSON(Message{Type: "positions", Positions: raws, Dropped: p.dropped})
+}
+
+func (c *Client) close() {
+	c.once.Do(func() {
+		close(c.done)
+		c.conn.Close()
+	})
+}
+
+func (c *Client) readPump() {
+	defer func() {
+		c.hub.unregister(c)
+		c.close()
+	}()
+
+	c.conn.SetReadLimit(64 * 1024)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var msg ClientMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			c.pushControl(Message{Type: "error", Error: "invalid message: " + err.Error()})
+			continue
+		}
+
+		switch msg.Type {
+		case "subscribe":
+			if err := msg.Filter.validate(); err != nil {
+				c.pushControl(Message{Type: "error", Error: err.Error()})
+				continue
+			}
+			c.setFilter(msg.Filter)
+			filter := msg.Filter
+			c.pushControl(Message{Type: "subscribed", Filter: &filter})
+		default:
+			c.pushControl(Message{Type: "error", Error: "unknown message type: " + msg.Type})
+		}
+	}
+}
+
+func (c *Client) writePump() {
+	flush := time.NewTicker(c.hub.config.FlushInterval)
+	ping := time.NewTicker(pingPeriod)
+	defer func() {
+		flush.Stop()
+		ping.Stop()
+		c.close()
+	}()
+
+	for {
+		var batch pending
+
+		select {
+		case <-c.notify:
+			batch = c.drain(false)
+		case <-flush.C:
+			batch = c.drain(true)
+		case <-ping.C:
+			if err := c.write(websocket.PingMessage, nil); err != nil {
+				return
+			}
+			continue
+		case <-c.done:
+			return
+		}
+
+		if err := c.flush(batch); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package websockets
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/m/internal/simulation/entities"
+)
+
+type Stream string
+
+const (
+	StreamPositions Stream = "positions"
+	StreamEvents    Stream = "events"
+)
+
+// Filter is evaluated server-side for every event so clients only receive
+// what they subscribed to. Empty fields match everything.
+type Filter struct {
+	Streams     []Stream             `json:"streams"`
+	FleetIDs    []string             `json:"fleet_ids"`
+	VehicleIDs  []string             `json:"vehicle_ids"`
+	BoundingBox *entities.Bounds     `json:"bbox,omitempty"`
+	EventTypes  []entities.EventType `json:"event_types"`
+}
+
+type compiledFilter struct {
+	positions  bool
+	events     bool
+	fleetIDs   map[string]bool
+	vehicleIDs map[string]bool
+	bbox       *entities.Bounds
+	eventTypes map[entities.EventType]bool
+}
+
+func (f Filter) validate() error {
+	for _, s := range f.Streams {
+		if s != StreamPositions && s != StreamEvents {
+			return fmt.Errorf("unknown stream %q", s)
+		}
+	}
+	return nil
+}
+
+func (f Filter) compile() *compiledFilter {
+	cf := &compiledFilter{
+		positions: len(f.Streams) == 0,
+		events:    len(f.Streams) == 0,
+		bbox:      f.BoundingBox,
+	}
+
+	for _, s := range f.Streams {
+		switch s {
+		case StreamPositions:
+			cf.positions = true
+		case StreamEvents:
+			cf.events = true
+		}
+	}
+
+	if len(f.FleetIDs) > 0 {
+		cf.fleetIDs = make(map[string]bool, len(f.FleetIDs))
+		for _, id := range f.FleetIDs {
+			cf.fleetIDs[id] = true
+		}
+	}
+
+	if len(f.VehicleIDs) > 0 {
+		cf.vehicleIDs = make(map[string]bool, len(f.VehicleIDs))
+		for _, id := range f.VehicleIDs {
+			cf.vehicleIDs[id] = true
+		}
+	}
+
+	if len(f.EventTypes) > 0 {
+		cf.eventTypes = make(map[entities.EventType]bool, len(f.EventTypes))
+		for _, t := range f.EventTypes {
+			cf.eventTypes[t] = true
+		}
+	}
+
+	return cf
+}
+
+func (cf *compiledFilter) matchVehicle(vehicleID, fleetID string) bool {
+	if cf.vehicleIDs != nil && !cf.vehicleIDs[vehicleID] {
+		return false
+	}
+	if cf.fleetIDs != nil && !cf.fleetIDs[fleetID] {
+		return false
+	}
+	return true
+}
+
+func (cf *compiledFilter) matchPosition(event entities.BasicVehiclePosEvent) bool {
+	if !cf.positions || !cf.matchVehicle(event.VehicleID, event.FleetID) {
+		return false
+	}
+	return cf.bbox == nil || inBounds(*cf.bbox, event.Position)
+}
+
+func (cf *compiledFilter) matchEvent(event entities.VehicleEvent) bool {
+	if !cf.events || !cf.matchVehicle(event.VehicleID, event.FleetID) {
+		return false
+	}
+	if cf.eventTypes != nil && !cf.eventTypes[event.EventType] {
+		return false
+	}
+	if cf.bbox != nil {
+		if pos, ok := eventPosition(event.Data["position"]); ok {
+			return inBounds(*cf.bbox, pos)
+		}
+	}
+	return true
+}
+
+// eventPosition reads an event's position whether the engine attached it as
+// a Vector2D or it came back through JSON as an {"x", "y"} object.
+func eventPosition(raw interface{}) (entities.Vector2D, bool) {
+	switch pos := raw.(type) {
+	case entities.Vector2D:
+		return pos, true
+	case *entities.Vector2D:
+		if pos != nil {
+			return *pos, true
+		}
+	case map[string]interface{}:
+		x, okX := pos["x"].(float64)
+		y, okY := pos["y"].(float64)
+		if okX && okY {
+			return entities.Vector2D{X: x, Y: y}, true
+		}
+	}
+	return entities.Vector2D{}, false
+}
+
+func inBounds(b entities.Bounds, p entities.Vector2D) bool {
+	return p.X >= b.MinX && p.X <= b.MaxX && p.Y >= b.MinY && p.Y <= b.MaxY
+}
+
+// ParseFilter reads the initial subscription from query parameters, e.g.
+// ?streams=positions&fleet=f1&vehicles=v1,v2&bbox=0,0,500,500&events=route_completed
+func ParseFilter(q url.Values) (Filter, error) {
+	f := Filter{}
+
+	for _, s := range splitList(q.Get("streams")) {
+		f.Streams = append(f.Streams, Stream(s))
+	}
+	f.FleetIDs = splitList(q.Get("fleet"))
+	f.VehicleIDs = splitList(q.Get("vehicles"))
+	for _, t := range splitList(q.Get("events")) {
+		f.EventTypes = append(f.EventTypes, entities.EventType(t))
+	}
+
+	if raw := q.Get("bbox"); raw != "" {
+		parts := splitList(raw)
+		if len(parts) != 4 {
+			return f, fmt.Errorf("bbox must be minX,minY,maxX,maxY")
+		}
+		vals := make([]float64, 4)
+		for i, p := range parts {
+			v, err := strconv.ParseFloat(p, 64)
+			if err != nil {
+				return f, fmt.Errorf("invalid bbox value %q: %w", p, err)
+			}
+			vals[i] = v
+		}
+		f.BoundingBox = &entities.Bounds{MinX: vals[0], MinY: vals[1], MaxX: vals[2], MaxY: vals[3]}
+	}
+
+	return f, f.validate()
+}
+
+func splitList(raw string) []string {
+	if raw == "" {
+		return nil
+	}
+	out := []string{}
+	for _, p := range strings.Split(raw, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
@@ -0,0 +1,169 @@
+package websockets
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/m/internal/simulation/entities"
+	"github.com/m/internal/telemetry"
+)
+
+type HubConfig struct {
+	FlushInterval   time.Duration
+	EventBufferSize int
+	WriteTimeout    time.Duration
+	// AllowedOrigins lists the origins, such as "https://ops.example.com",
+	// whose pages may connect. Empty allows only pages served by the hub's
+	// own host; "*" allows any.
+	AllowedOrigins []string
+}
+
+func DefaultHubConfig() HubConfig {
+	return HubConfig{
+		FlushInterval:   100 * time.Millisecond,
+		EventBufferSize: 256,
+		WriteTimeout:    10 * time.Second,
+	}
+}
+
+// Hub fans telemetry out to connected browsers. It implements
+// entities.TelemetryEmitter so the engine can emit into it directly.
+type Hub struct {
+	mu       sync.RWMutex
+	clients  map[*Client]bool
+	config   HubConfig
+	upgrader websocket.Upgrader
+}
+
+func NewHub() *Hub {
+	return NewHubWithConfig(DefaultHubConfig())
+}
+
+// NewHubWithConfig fills in the defaults for any unset or negative field.
+func NewHubWithConfig(config HubConfig) *Hub {
+	defaults := DefaultHubConfig()
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = defaults.FlushInterval
+	}
+	if config.EventBufferSize <= 0 {
+		config.EventBufferSize = defaults.EventBufferSize
+	}
+	if config.WriteTimeout <= 0 {
+		config.WriteTimeout = defaults.WriteTimeout
+	}
+	return &Hub{
+		clients: make(map[*Client]bool),
+		config:  config,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 4096,
+			CheckOrigin:     checkOrigin(config.AllowedOrigins),
+		},
+	}
+}
+
+// checkOrigin guards against cross-site WebSocket hijacking. Without an
+// allowlist it defers to the upgrader's same-host check, which also admits
+// clients that send no Origin, such as CLI tools.
+func checkOrigin(allowed []string) func(r *http.Request) bool {
+	if len(allowed) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(allowed))
+	for _, o := range allowed {
+		if o == "*" {
+			return func(r *http.Request) bool { return true }
+		}
+		set[strings.ToLower(strings.TrimSuffix(o, "/"))] = true
+	}
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		u, err := url.Parse(origin)
+		if err != nil {
+			return false
+		}
+		return set[strings.ToLower(u.Scheme+"://"+u.Host)] || strings.EqualFold(u.Host, r.Host)
+	}
+}
+
+func (h *Hub) EmitPosition(event entities.BasicVehiclePosEvent) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for c := range h.clients {
+		c.pushPosition(event)
+	}
+	return nil
+}
+
+func (h *Hub) EmitEvent(event entities.VehicleEvent) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for c := range h.clients {
+		c.pushEvent(event)
+	}
+	return nil
+}
+
+func (h *Hub) ClientCount() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients)
+}
+
+func (h *Hub) ServeWS(w http.ResponseWriter, r *http.Request) {
+	filter, err := ParseFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	serializer, err := telemetry.NewSerializer(r.URL.Query().Get("format"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	c := newClient(h, conn, filter, serializer)
+	h.register(c)
+	c.pushControl(Message{Type: "subscribed", Filter: &filter})
+
+	go c.writePump()
+	go c.readPump()
+}
+
+func (h *Hub) Close() {
+	h.mu.Lock()
+	clients := h.clients
+	h.clients = make(map[*Client]bool)
+	h.mu.Unlock()
+
+	for c := range clients {
+		c.close()
+	}
+}
+
+func (h *Hub) register(c *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.clients[c] = true
+}
+
+func (h *Hub) unregister(c *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.clients, c)
+}
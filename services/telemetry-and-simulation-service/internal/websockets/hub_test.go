@@ -0,0 +1,246 @@
+package websockets
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/m/internal/simulation/entities"
+	"github.com/m/internal/telemetry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func dial(t *testing.T, server *httptest.Server, query string) *websocket.Conn {
+	u := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws?" + query
+	conn, _, err := websocket.DefaultDialer.Dial(u, nil)
+	require.NoError(t, err)
+
+	var msg Message
+	require.NoError(t, conn.ReadJSON(&msg))
+	require.Equal(t, "subscribed", msg.Type)
+	return conn
+}
+
+func waitForClients(t *testing.T, hub *Hub, n int) {
+	deadline := time.Now().Add(time.Second)
+	for hub.ClientCount() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d clients, got %d", n, hub.ClientCount())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	q, _ := url.ParseQuery("streams=positions&fleet=f1&vehicles=v1,%20v2&bbox=0,0,100,50&events=route_completed")
+	f, err := ParseFilter(q)
+	require.NoError(t, err)
+
+	assert.Equal(t, []Stream{StreamPositions}, f.Streams)
+	assert.Equal(t, []string{"f1"}, f.FleetIDs)
+	assert.Equal(t, []string{"v1", "v2"}, f.VehicleIDs)
+	assert.Equal(t, &entities.Bounds{MinX: 0, MinY: 0, MaxX: 100, MaxY: 50}, f.BoundingBox)
+	assert.Equal(t, []entities.EventType{entities.EventRouteCompleted}, f.EventTypes)
+
+	_, err = ParseFilter(url.Values{"bbox": {"1,2,3"}})
+	assert.Error(t, err)
+	_, err = ParseFilter(url.Values{"streams": {"positions,position"}})
+	assert.Error(t, err)
+}
+
+func TestHub_RejectsUnknownStream(t *testing.T) {
+	hub := NewHub()
+	rec := httptest.NewRecorder()
+	hub.ServeWS(rec, httptest.NewRequest("GET", "/ws?streams=evnets", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestFilter_Match(t *testing.T) {
+	cf := Filter{
+		FleetIDs:    []string{"f1"},
+		BoundingBox: &entities.Bounds{MaxX: 100, MaxY: 100},
+		EventTypes:  []entities.EventType{entities.EventBreakdownOccurred},
+	}.compile()
+
+	assert.True(t, cf.matchPosition(entities.BasicVehiclePosEvent{VehicleID: "v1", FleetID: "f1", Position: entities.Vector2D{X: 50, Y: 50}}))
+	assert.False(t, cf.matchPosition(entities.BasicVehiclePosEvent{VehicleID: "v1", FleetID: "f2", Position: entities.Vector2D{X: 50, Y: 50}}))
+	assert.False(t, cf.matchPosition(entities.BasicVehiclePosEvent{VehicleID: "v1", FleetID: "f1", Position: entities.Vector2D{X: 150, Y: 50}}))
+
+	assert.True(t, cf.matchEvent(entities.VehicleEvent{FleetID: "f1", EventType: entities.EventBreakdownOccurred}))
+	assert.False(t, cf.matchEvent(entities.VehicleEvent{FleetID: "f1", EventType: entities.EventRouteStarted}))
+
+	breakdownAt := func(position interface{}) entities.VehicleEvent {
+		return entities.VehicleEvent{FleetID: "f1", EventType: entities.EventBreakdownOccurred, Data: map[string]interface{}{"position": position}}
+	}
+	assert.False(t, cf.matchEvent(breakdownAt(entities.Vector2D{X: 150, Y: 50})))
+	assert.True(t, cf.matchEvent(breakdownAt(map[string]interface{}{"x": 50.0, "y": 50.0})))
+	assert.False(t, cf.matchEvent(breakdownAt(map[string]interface{}{"x": 150.0, "y": 50.0})))
+}
+
+func TestHub_CoalescesPositionsPerVehicle(t *testing.T) {
+	hub := NewHubWithConfig(HubConfig{FlushInterval: 50 * time.Millisecond, EventBufferSize: 4, WriteTimeout: time.Second})
+	server := httptest.NewServer(http.HandlerFunc(hub.ServeWS))
+	defer server.Close()
+
+	conn := dial(t, server, "vehicles=v1")
+	defer conn.Close()
+	waitForClients(t, hub, 1)
+
+	for i := 1; i <= 10; i++ {
+		hub.EmitPosition(entities.BasicVehiclePosEvent{VehicleID: "v1", Progress: float64(i) / 10})
+		hub.EmitPosition(entities.BasicVehiclePosEvent{VehicleID: "v2", Progress: float64(i) / 10})
+	}
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	var msg Message
+	require.NoError(t, conn.ReadJSON(&msg))
+	assert.Equal(t, "positions", msg.Type)
+	require.Len(t, msg.Positions, 1)
+
+	var pos entities.BasicVehiclePosEvent
+	require.NoError(t, json.Unmarshal(msg.Positions[0], &pos))
+	assert.Equal(t, "v1", pos.VehicleID)
+	assert.Equal(t, 1.0, pos.Progress)
+	assert.Equal(t, entities.TelemetrySchemaVersion, pos.SchemaVersion)
+}
+
+func TestHub_ProtoFormatSendsBinaryFrames(t *testing.T) {
+	hub := NewHubWithConfig(HubConfig{FlushInterval: 20 * time.Millisecond, EventBufferSize: 4, WriteTimeout: time.Second})
+	server := httptest.NewServer(http.HandlerFunc(hub.ServeWS))
+	defer server.Close()
+
+	conn := dial(t, server, "format=proto")
+	defer conn.Close()
+	waitForClients(t, hub, 1)
+
+	hub.EmitEvent(entities.VehicleEvent{VehicleID: "v1", Sequence: 7, EventType: entities.EventRouteStarted})
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	kind, data, err := conn.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, websocket.BinaryMessage, kind)
+
+	rec, err := telemetry.ProtoSerializer{}.Unmarshal(data)
+	require.NoError(t, err)
+	require.NotNil(t, rec.Event)
+	assert.Equal(t, uint64(7), rec.Event.Sequence)
+	assert.Equal(t, entities.EventRouteStarted, rec.Event.EventType)
+}
+
+func TestHub_ProtoFormatReportsDropped(t *testing.T) {
+	hub := NewHubWithConfig(HubConfig{FlushInterval: time.Hour, EventBufferSize: 4, WriteTimeout: time.Second})
+	server := httptest.NewServer(http.HandlerFunc(hub.ServeWS))
+	defer server.Close()
+
+	conn := dial(t, server, "format=proto")
+	defer conn.Close()
+	waitForClients(t, hub, 1)
+
+	hub.mu.RLock()
+	for c := range hub.clients {
+		c.drop(3)
+	}
+	hub.mu.RUnlock()
+	hub.EmitEvent(entities.VehicleEvent{VehicleID: "v1", EventType: entities.EventRouteStarted})
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	var msg Message
+	require.NoError(t, conn.ReadJSON(&msg))
+	assert.Equal(t, "dropped", msg.Type)
+	assert.Equal(t, int64(3), msg.Dropped)
+
+	kind, _, err := conn.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, websocket.BinaryMessage, kind)
+}
+
+func TestHub_SubscribeUpdatesFilter(t *testing.T) {
+	hub := NewHubWithConfig(HubConfig{FlushInterval: time.Hour, EventBufferSize: 2, WriteTimeout: time.Second})
+	server := httptest.NewServer(http.HandlerFunc(hub.ServeWS))
+	defer server.Close()
+
+	conn := dial(t, server, "")
+	defer conn.Close()
+	waitForClients(t, hub, 1)
+
+	require.NoError(t, conn.WriteJSON(ClientMessage{
+		Type:   "subscribe",
+		Filter: Filter{Streams: []Stream{StreamEvents}, EventTypes: []entities.EventType{entities.EventRouteCompleted}},
+	}))
+
+	var msg Message
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	require.NoError(t, conn.ReadJSON(&msg))
+	require.Equal(t, "subscribed", msg.Type)
+
+	require.NoError(t, conn.WriteJSON(ClientMessage{Type: "subscribe", Filter: Filter{Streams: []Stream{"evnets"}}}))
+	require.NoError(t, conn.ReadJSON(&msg))
+	require.Equal(t, "error", msg.Type)
+
+	hub.EmitEvent(entities.VehicleEvent{VehicleID: "v1", EventType: entities.EventRouteStarted})
+	hub.EmitEvent(entities.VehicleEvent{VehicleID: "v1", EventType: entities.EventRouteCompleted})
+
+	require.NoError(t, conn.ReadJSON(&msg))
+	assert.Equal(t, "event", msg.Type)
+
+	var ev entities.VehicleEvent
+	require.NoError(t, json.Unmarshal(msg.Event, &ev))
+	assert.Equal(t, entities.EventRouteCompleted, ev.EventType)
+}
+
+func TestClient_BoundedEventBuffer(t *testing.T) {
+	hub := NewHubWithConfig(HubConfig{FlushInterval: time.Hour, EventBufferSize: 2, WriteTimeout: time.Second})
+	c := newClient(hub, nil, Filter{}, telemetry.JSONSerializer{})
+
+	for i := 0; i < 5; i++ {
+		c.pushEvent(entities.VehicleEvent{VehicleID: "v", Data: map[string]interface{}{"i": i}})
+	}
+
+	p := c.drain(false)
+	require.Len(t, p.events, 2)
+	assert.Equal(t, 3, p.events[0].Data["i"])
+	assert.Equal(t, 4, p.events[1].Data["i"])
+	assert.Equal(t, int64(3), p.dropped)
+}
+
+func TestNewHubWithConfig_FillsDefaults(t *testing.T) {
+	hub := NewHubWithConfig(HubConfig{EventBufferSize: -1})
+	assert.Equal(t, DefaultHubConfig(), hub.config)
+
+	c := newClient(hub, nil, Filter{}, telemetry.JSONSerializer{})
+	c.pushEvent(entities.VehicleEvent{VehicleID: "v"})
+	assert.Len(t, c.drain(false).events, 1)
+}
+
+func TestHub_CheckOrigin(t *testing.T) {
+	upgrade := func(hub *Hub, origin string) int {
+		server := httptest.NewServer(http.HandlerFunc(hub.ServeWS))
+		defer server.Close()
+		header := http.Header{}
+		if origin != "" {
+			header.Set("Origin", origin)
+		}
+		conn, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws", header)
+		if err == nil {
+			conn.Close()
+		}
+		return resp.StatusCode
+	}
+
+	hub := NewHub()
+	assert.Equal(t, http.StatusSwitchingProtocols, upgrade(hub, ""))
+	assert.Equal(t, http.StatusForbidden, upgrade(hub, "https://evil.example"))
+
+	hub = NewHubWithConfig(HubConfig{AllowedOrigins: []string{"https://ops.example.com/"}})
+	assert.Equal(t, http.StatusSwitchingProtocols, upgrade(hub, "https://ops.example.com"))
+	assert.Equal(t, http.StatusForbidden, upgrade(hub, "https://evil.example"))
+
+	hub = NewHubWithConfig(HubConfig{AllowedOrigins: []string{"*"}})
+	assert.Equal(t, http.StatusSwitchingProtocols, upgrade(hub, "https://evil.example"))
+}